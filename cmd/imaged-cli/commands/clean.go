@@ -18,10 +18,16 @@ func CleanCommand(c *cli.Context) error {
 	threshold := c.Float64("threshold")
 	dryRun := c.Bool("dry-run")
 	move := c.Bool("move")
+	trash := c.Bool("trash")
+	qualityMargin := c.Float64("quality-margin")
 
 	if path == "" {
 		return cli.Exit("Path is required", 1)
 	}
+	policy, err := api.ParseSelectionPolicy(c.String("policy"))
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
 
 	fmt.Printf("Cleaning directory: %s\n", path)
 	if dryRun {
@@ -30,6 +36,7 @@ func CleanCommand(c *cli.Context) error {
 
 	cfg := engine.DefaultConfig()
 	cfg.IndexPath = indexPath
+	cfg.SelectionPolicy = policy
 
 	eng, err := engine.NewEngine(cfg)
 	if err != nil {
@@ -41,7 +48,7 @@ func CleanCommand(c *cli.Context) error {
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
 		fmt.Println("Index not found, scanning directory first...")
 		ctx := context.Background()
-		if err := eng.ScanFolder(ctx, path, nil); err != nil {
+		if _, err := eng.ScanFolder(ctx, path, nil); err != nil {
 			return cli.Exit(fmt.Sprintf("Failed to scan directory: %v", err), 1)
 		}
 	}
@@ -49,11 +56,13 @@ func CleanCommand(c *cli.Context) error {
 	// Setup clean options
 	options := api.CleanOptions{
 		DryRun:                 dryRun,
-		SelectionPolicy:        api.PolicyHighestQuality,
+		SelectionPolicy:        policy,
 		MinQualityScore:        50.0,
 		MaxSimilarityThreshold: threshold,
 		MoveDuplicates:         move,
+		TrashDuplicates:        trash,
 		OutputDir:              outputDir,
+		QualityMargin:          qualityMargin,
 	}
 
 	// Perform cleaning
@@ -62,17 +71,75 @@ func CleanCommand(c *cli.Context) error {
 		return cli.Exit(fmt.Sprintf("Clean failed: %v", err), 1)
 	}
 
-	// Display results
+	printCleanReport(report, dryRun)
+	return nil
+}
+
+// printCleanReport displays the results of a clean, or its plan for a dry run
+func printCleanReport(report *api.CleanReport, dryRun bool) {
 	fmt.Printf("\nClean operation completed:\n")
 	fmt.Printf("  Total groups processed: %d\n", report.TotalProcessed)
 	fmt.Printf("  Files moved/deleted: %d\n", report.MovedFiles)
 	fmt.Printf("  Storage freed: %s\n", formatBytes(report.FreedSpace))
 	fmt.Printf("  Errors: %d\n", report.Errors)
+	if report.ManifestPath != "" {
+		fmt.Printf("  Manifest: %s (use 'imaged undo --manifest' to restore)\n", report.ManifestPath)
+	}
+	for _, group := range report.Groups {
+		switch {
+		case group.Succeeded:
+		case group.RolledBack:
+			fmt.Printf("  Group %s rolled back: %s\n", group.GroupID, group.Error)
+		default:
+			fmt.Printf("  Group %s partly cleaned: %s\n", group.GroupID, group.Error)
+			for _, path := range group.NotRestored {
+				fmt.Printf("    not restored: %s\n", path)
+			}
+		}
+	}
+	if len(report.NeedsReview) > 0 {
+		fmt.Printf("\nLeft in place for review (quality too close to choose):\n")
+		for _, path := range report.NeedsReview {
+			fmt.Printf("  %s\n", path)
+		}
+	}
 
 	if dryRun {
+		if len(report.Actions) > 0 {
+			fmt.Printf("\nPlanned actions:\n")
+			for _, action := range report.Actions {
+				if action.Dest != "" {
+					fmt.Printf("  %-5s %s -> %s (%s)\n", action.Action, action.Source, action.Dest, formatBytes(action.SizeBytes))
+				} else {
+					fmt.Printf("  %-5s %s (%s)\n", action.Action, action.Source, formatBytes(action.SizeBytes))
+				}
+			}
+		}
 		fmt.Println("\nThis was a dry run. Run without --dry-run to actually clean files.")
 	}
+}
+
+// UndoCommand restores files moved by a previous clean operation
+func UndoCommand(c *cli.Context) error {
+	manifestPath := c.String("manifest")
+	indexPath := c.String("index")
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = indexPath
+
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	report, err := eng.UndoClean(manifestPath)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Undo failed: %v", err), 1)
+	}
 
+	fmt.Printf("Restored %d of %d files\n", report.MovedFiles, report.TotalProcessed)
+	fmt.Printf("Errors: %d\n", report.Errors)
 	return nil
 }
 
@@ -35,7 +35,7 @@ func ClusterCommand(c *cli.Context) error {
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
 		fmt.Println("Index not found, scanning directory first...")
 		ctx := context.Background()
-		if err := eng.ScanFolder(ctx, path, nil); err != nil {
+		if _, err := eng.ScanFolder(ctx, path, nil); err != nil {
 			return cli.Exit(fmt.Sprintf("Failed to scan directory: %v", err), 1)
 		}
 	}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/urfave/cli/v2"
+)
+
+// DedupCommand scans a directory, updating the index, and cleans its
+// duplicates in one run
+func DedupCommand(c *cli.Context) error {
+	path := c.String("path")
+	dryRun := c.Bool("dry-run")
+
+	if path == "" {
+		return cli.Exit("Path is required", 1)
+	}
+	if c.Bool("delete") && c.Bool("trash") {
+		return cli.Exit("Choose only one of --delete and --trash", 1)
+	}
+	policy, err := api.ParseSelectionPolicy(c.String("policy"))
+	if err != nil {
+		return cli.Exit(err.Error(), 1)
+	}
+
+	fmt.Printf("Deduplicating directory: %s\n", path)
+	if dryRun {
+		fmt.Println("DRY RUN MODE - No files will be modified")
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = c.String("index")
+	cfg.NumWorkers = c.Int("workers")
+	cfg.SelectionPolicy = policy
+
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	// Unchanged files keep their fingerprints, so repeated runs only process new images
+	scanReport, err := eng.ScanFolder(context.Background(), path, nil)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to scan directory: %v", err), 1)
+	}
+
+	fmt.Printf("\nScan completed in %v:\n", scanReport.ScanDuration.Round(time.Millisecond))
+	fmt.Printf("  Images indexed: %d of %d (%d skipped, %d unchanged)\n",
+		scanReport.ProcessedImages, scanReport.TotalFiles, scanReport.SkippedFiles, scanReport.UnchangedFiles)
+	for _, scanErr := range scanReport.Errors {
+		fmt.Printf("  Skipped %s: %s\n", scanErr.Path, scanErr.Reason)
+	}
+
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		DryRun:                 dryRun,
+		SelectionPolicy:        policy,
+		MinQualityScore:        50.0,
+		MaxSimilarityThreshold: c.Float64("threshold"),
+		MoveDuplicates:         !c.Bool("delete") && !c.Bool("trash"),
+		TrashDuplicates:        c.Bool("trash"),
+		OutputDir:              c.String("output"),
+		QualityMargin:          c.Float64("quality-margin"),
+	})
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Clean failed: %v", err), 1)
+	}
+
+	printCleanReport(report, dryRun)
+	return nil
+}
@@ -3,11 +3,7 @@ package commands
 import (
 	"fmt"
 	"path/filepath"
-	"time"
 
-	"github.com/HaiderBassem/imaged/internal/report"
-	"github.com/HaiderBassem/imaged/pkg/api"
-	"github.com/HaiderBassem/imaged/pkg/engine"
 	"github.com/urfave/cli/v2"
 )
 
@@ -21,76 +17,7 @@ func ExportCommand(c *cli.Context) error {
 	fmt.Printf("Format: %s\n", format)
 	fmt.Printf("Output: %s\n", output)
 
-	cfg := engine.DefaultConfig()
-	cfg.IndexPath = indexPath
-
-	eng, err := engine.NewEngine(cfg)
-	if err != nil {
-		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
-	}
-	defer eng.Close()
-
-	// Get scan statistics (in a real implementation, you'd get actual scan data)
-	stats, err := eng.GetStats()
-	if err != nil {
-		return cli.Exit(fmt.Sprintf("Failed to get statistics: %v", err), 1)
-	}
-
-	// Run duplicate detection
-	exact, err := eng.FindExactDuplicates()
-	if err != nil {
-		return cli.Exit(fmt.Sprintf("Failed to find exact duplicates: %v", err), 1)
-	}
-
-	near, err := eng.FindNearDuplicates(0.90)
-	if err != nil {
-		return cli.Exit(fmt.Sprintf("Failed to find near duplicates: %v", err), 1)
-	}
-
-	// Build real report
-	scanReport := &api.ScanReport{
-		ScanID:              "export_" + time.Now().Format("20060102_150405"),
-		TotalFiles:          int(stats.TotalImages),
-		ProcessedImages:     int(stats.TotalImages),
-		SkippedFiles:        0,
-		ExactDuplicateCount: len(exact),
-		NearDuplicateCount:  len(near),
-		Groups:              append(exact, near...),
-		ScanDuration:        time.Hour,
-		StartedAt:           time.Now().Add(-time.Hour),
-		CompletedAt:         time.Now(),
-		GeneratedAt:         time.Now(),
-	}
-
-	// Generate report based on format
-	switch format {
-	case "json":
-		outputPath := addExtension(output, "json")
-		generator := report.NewJSONReportGenerator()
-		if err := generator.Generate(scanReport, outputPath); err != nil {
-			return cli.Exit(fmt.Sprintf("Failed to generate JSON report: %v", err), 1)
-		}
-
-	case "html":
-		outputPath := addExtension(output, "html")
-		generator := report.NewHTMLReportGenerator()
-		if err := generator.Generate(scanReport, outputPath); err != nil {
-			return cli.Exit(fmt.Sprintf("Failed to generate HTML report: %v", err), 1)
-		}
-
-	case "text":
-		outputPath := addExtension(output, "txt")
-		generator := report.NewTextReportGenerator()
-		if err := generator.Generate(scanReport, outputPath); err != nil {
-			return cli.Exit(fmt.Sprintf("Failed to generate text report: %v", err), 1)
-		}
-
-	default:
-		return cli.Exit(fmt.Sprintf("Unsupported format: %s", format), 1)
-	}
-
-	fmt.Printf("✅ Report exported successfully to: %s\n", output)
-	return nil
+	return writeIndexReport(indexPath, format, output, 0.90)
 }
 
 // addExtension adds file extension if not present
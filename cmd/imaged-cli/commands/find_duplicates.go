@@ -13,6 +13,7 @@ func FindDuplicatesCommand(c *cli.Context) error {
 	indexPath := c.String("index")
 	threshold := c.Float64("threshold")
 	exactOnly := c.Bool("exact-only")
+	recompute := c.Bool("recompute")
 
 	fmt.Printf("Finding duplicates in index: %s\n", indexPath)
 	if exactOnly {
@@ -32,17 +33,17 @@ func FindDuplicatesCommand(c *cli.Context) error {
 
 	var exactGroups, nearGroups []api.DuplicateGroup
 
-	// Find exact duplicates
-	exactGroups, err = eng.FindExactDuplicates()
-	if err != nil {
-		return cli.Exit(fmt.Sprintf("Failed to find exact duplicates: %v", err), 1)
-	}
-
-	if !exactOnly {
-		// Find near duplicates
-		nearGroups, err = eng.FindNearDuplicates(threshold)
+	if exactOnly {
+		// Exact duplicates only need the SHA256 index, so they are never cached
+		exactGroups, err = eng.FindExactDuplicates()
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("Failed to find exact duplicates: %v", err), 1)
+		}
+	} else {
+		// Groups saved by the last search are reused unless the index changed
+		exactGroups, nearGroups, err = eng.FindDuplicates(threshold, recompute)
 		if err != nil {
-			return cli.Exit(fmt.Sprintf("Failed to find near duplicates: %v", err), 1)
+			return cli.Exit(fmt.Sprintf("Failed to find duplicates: %v", err), 1)
 		}
 	}
 
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/urfave/cli/v2"
+)
+
+// PruneCommand removes index entries for files that no longer exist
+func PruneCommand(c *cli.Context) error {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = c.String("index")
+
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	pruned, err := eng.PruneMissing()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Prune failed: %v", err), 1)
+	}
+
+	fmt.Printf("Removed %d entries for missing files\n", pruned)
+	return nil
+}
@@ -1,19 +1,28 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sort"
 
+	"github.com/HaiderBassem/imaged/internal/filesystem"
+	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/HaiderBassem/imaged/pkg/engine"
 	"github.com/urfave/cli/v2"
 )
 
-// QualityCommand handles image quality analysis
+// QualityCommand handles image quality analysis of one image, or ranks a
+// whole directory when --path is given
 func QualityCommand(c *cli.Context) error {
+	if c.String("path") != "" {
+		return rankQuality(c)
+	}
+
 	imagePath := c.String("image")
 
 	if imagePath == "" {
-		return cli.Exit("Image path is required", 1)
+		return cli.Exit("Image path or directory is required", 1)
 	}
 
 	// Check if file exists
@@ -47,18 +56,8 @@ func QualityCommand(c *cli.Context) error {
 
 	// Provide recommendations
 	fmt.Printf("\nRecommendations:\n")
-	if quality.Sharpness < 0.3 {
-		fmt.Printf("    Image is blurry (sharpness: %.2f)\n", quality.Sharpness)
-	}
-	if quality.Noise > 0.7 {
-		fmt.Printf("    High noise level (%.2f)\n", quality.Noise)
-	}
-	if abs(quality.Exposure-0.5) > 0.3 {
-		status := "overexposed"
-		if quality.Exposure < 0.5 {
-			status = "underexposed"
-		}
-		fmt.Printf("    Image is %s (exposure: %.2f)\n", status, quality.Exposure)
+	for _, issue := range eng.ExplainQuality(*quality) {
+		fmt.Printf("    [%s] %s: %s\n", issue.Severity, issue.Message, issue.SuggestedAction)
 	}
 	if quality.FinalScore >= 80 {
 		fmt.Printf("   Excellent quality image\n")
@@ -71,10 +70,74 @@ func QualityCommand(c *cli.Context) error {
 	return nil
 }
 
-// abs returns absolute value of a float64
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
+// rankQuality scans the directory at --path into the index and prints its
+// images ordered by final quality score, each with its dominant issue
+func rankQuality(c *cli.Context) error {
+	path := c.String("path")
+	order := c.String("sort")
+	if order != "asc" && order != "desc" {
+		return cli.Exit(fmt.Sprintf("Unknown sort order %q: expected asc or desc", order), 1)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = c.String("index")
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	// Unchanged files keep their fingerprints, so ranking an indexed folder again is quick
+	if _, err := eng.ScanFolder(context.Background(), path, nil); err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to scan directory: %v", err), 1)
+	}
+
+	fingerprints, err := eng.GetAllFingerprints()
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to read index: %v", err), 1)
+	}
+
+	paths := filesystem.NewPathUtils()
+	var ranked []api.ImageFingerprint
+	for _, fp := range fingerprints {
+		if paths.IsSubpath(path, fp.Metadata.Path) {
+			ranked = append(ranked, fp)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i].Quality.FinalScore, ranked[j].Quality.FinalScore
+		if a == b {
+			return ranked[i].Metadata.Path < ranked[j].Metadata.Path
+		}
+		if order == "asc" {
+			return a < b
+		}
+		return a > b
+	})
+	if top := c.Int("top"); top > 0 && top < len(ranked) {
+		ranked = ranked[:top]
+	}
+
+	fmt.Printf("%6s  %-20s  %s\n", "SCORE", "ISSUE", "PATH")
+	for _, fp := range ranked {
+		fmt.Printf("%6.1f  %-20s  %s\n", fp.Quality.FinalScore, dominantIssue(eng.ExplainQuality(fp.Quality)), fp.Metadata.Path)
+	}
+	return nil
+}
+
+// dominantIssue names the first major issue, or failing that the first
+// issue, as "metric (severity)"; "-" when there are none
+func dominantIssue(issues []api.QualityIssue) string {
+	if len(issues) == 0 {
+		return "-"
+	}
+	dominant := issues[0]
+	for _, issue := range issues {
+		if issue.Severity == api.SeverityMajor {
+			dominant = issue
+			break
+		}
 	}
-	return x
+	return fmt.Sprintf("%s (%s)", dominant.Metric, dominant.Severity)
 }
@@ -2,8 +2,17 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/utils"
 	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/HaiderBassem/imaged/pkg/engine"
 	"github.com/urfave/cli/v2"
@@ -14,19 +23,34 @@ func ScanCommand(c *cli.Context) error {
 	path := c.String("path")
 	indexPath := c.String("index")
 	workers := c.Int("workers")
+	shutdownTimeout := c.Duration("shutdown-timeout")
 
 	if path == "" {
 		return cli.Exit("Path is required", 1)
 	}
 
-	fmt.Printf("Scanning directory: %s\n", path)
-	fmt.Printf("Using index: %s\n", indexPath)
-	fmt.Printf("Workers: %d\n", workers)
+	// JSON progress owns stdout, so the human-readable output moves to stderr
+	var out io.Writer = os.Stdout
+	displayProgress := displayScanProgress
+	switch format := c.String("progress-format"); format {
+	case "", "human":
+	case "json":
+		out = os.Stderr
+		displayProgress = func(progress <-chan api.ScanProgress) { writeJSONProgress(os.Stdout, progress) }
+	default:
+		return cli.Exit(fmt.Sprintf("Unknown progress format: %s", format), 1)
+	}
+
+	fmt.Fprintf(out, "Scanning directory: %s\n", path)
+	fmt.Fprintf(out, "Using index: %s\n", indexPath)
+	fmt.Fprintf(out, "Workers: %d\n", workers)
 
 	// Create engine configuration
 	cfg := engine.DefaultConfig()
 	cfg.IndexPath = indexPath
 	cfg.NumWorkers = workers
+	cfg.ForceRescan = c.Bool("force")
+	cfg.MaxImages = c.Int("max-images")
 
 	// Initialize engine
 	eng, err := engine.NewEngine(cfg)
@@ -39,16 +63,27 @@ func ScanCommand(c *cli.Context) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Setup progress channel
-	progress := make(chan api.ScanProgress, 10)
+	// Interrupts cancel the scan but give it time to save what was already processed
+	done := make(chan struct{})
+	handleInterrupt(cancel, done, shutdownTimeout)
 
-	go displayScanProgress(progress)
+	// Setup progress channel; the display finishes before the summary is printed
+	progress := make(chan api.ScanProgress, 10)
+	displayed := make(chan struct{})
+	go func() {
+		displayProgress(progress)
+		close(displayed)
+	}()
 
 	// Perform scan
-	err = eng.ScanFolder(ctx, path, progress)
+	scanReport, err := eng.ScanFolder(ctx, path, progress)
 	close(progress)
+	close(done)
+	<-displayed
 
-	if err != nil {
+	if errors.Is(err, api.ErrScanLimitReached) {
+		fmt.Fprintf(out, "\nWarning: stopped after discovering %d images (--max-images)\n", cfg.MaxImages)
+	} else if err != nil {
 		return cli.Exit(fmt.Sprintf("Scan failed: %v", err), 1)
 	}
 
@@ -58,19 +93,85 @@ func ScanCommand(c *cli.Context) error {
 		return cli.Exit(fmt.Sprintf("Failed to get stats: %v", err), 1)
 	}
 
-	fmt.Printf("\nScan completed successfully!\n")
-	fmt.Printf("Total images: %d\n", stats.TotalImages)
-	fmt.Printf("Total size: %.2f MB\n", float64(stats.TotalSizeBytes)/1024/1024)
-	fmt.Printf("Average quality: %.1f/100\n", stats.AverageQuality)
+	fmt.Fprintf(out, "\nScan completed successfully in %v!\n", scanReport.ScanDuration.Round(time.Millisecond))
+	fmt.Fprintf(out, "Images indexed: %d of %d (%d skipped, %d unchanged)\n",
+		scanReport.ProcessedImages, scanReport.TotalFiles, scanReport.SkippedFiles, scanReport.UnchangedFiles)
+	if scanReport.RenamedFiles > 0 {
+		fmt.Fprintf(out, "Moved or renamed files: %d\n", scanReport.RenamedFiles)
+	}
+	for _, scanErr := range scanReport.Errors {
+		fmt.Fprintf(out, "  Skipped %s: %s\n", scanErr.Path, scanErr.Reason)
+	}
+	fmt.Fprintf(out, "Total images: %d\n", stats.TotalImages)
+	fmt.Fprintf(out, "Total size: %.2f MB\n", float64(stats.TotalSizeBytes)/1024/1024)
+	fmt.Fprintf(out, "Average quality: %.1f/100\n", stats.AverageQuality)
 
 	return nil
 }
 
-// displayScanProgress shows real-time scan progress
+// handleInterrupt cancels the scan on SIGINT/SIGTERM, then waits up to timeout for
+// the engine to flush computed fingerprints before forcing the process to exit
+func handleInterrupt(cancel context.CancelFunc, done <-chan struct{}, timeout time.Duration) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(sigChan)
+
+		select {
+		case <-sigChan:
+		case <-done:
+			return
+		}
+
+		fmt.Println("\nReceived interrupt signal, saving processed images...")
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			fmt.Fprintln(os.Stderr, "Timed out waiting for the index to be flushed")
+			os.Exit(1)
+		case <-sigChan:
+			fmt.Fprintln(os.Stderr, "Second interrupt received, exiting immediately")
+			os.Exit(1)
+		}
+	}()
+}
+
+// displayScanProgress shows real-time scan progress, with the throughput over
+// the last ten seconds and the time left at that pace
 func displayScanProgress(progress <-chan api.ScanProgress) {
+	rate := utils.NewRateEstimator(10 * time.Second)
+	lastWidth := 0
 	for p := range progress {
-		fmt.Printf("\rProgress: %.1f%% (%d/%d) - %s",
-			p.Percentage, p.Current, p.Total, p.CurrentFile)
+		rate.Observe(time.Now(), p.Current)
+
+		line := fmt.Sprintf("%.0f%% (%d/%d)", p.Percentage, p.Current, p.Total)
+		if eta, ok := rate.ETA(p.Total); ok {
+			line += fmt.Sprintf(" %.0f img/s ETA %s", rate.Rate(), utils.FormatETA(eta))
+		}
+		line += " - " + filepath.Base(p.CurrentFile)
+
+		// Pad with spaces to overwrite the rest of a longer previous line
+		fmt.Printf("\r%-*s", lastWidth, line)
+		lastWidth = len(line)
 	}
 	fmt.Println() // New line after progress completes
 }
+
+// progressRecord is one line of JSON progress output
+type progressRecord struct {
+	api.ScanProgress
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// writeJSONProgress writes each progress update to w as a line of JSON
+func writeJSONProgress(w io.Writer, progress <-chan api.ScanProgress) {
+	encoder := json.NewEncoder(w)
+	for p := range progress {
+		if err := encoder.Encode(progressRecord{ScanProgress: p, Timestamp: time.Now().UTC()}); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write progress: %v\n", err)
+		}
+	}
+}
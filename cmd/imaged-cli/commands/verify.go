@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/urfave/cli/v2"
+)
+
+// VerifyCommand checks the index against the files on disk
+func VerifyCommand(c *cli.Context) error {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = c.String("index")
+
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	report, err := eng.VerifyIndex(api.VerifyOptions{Rehash: c.Bool("rehash"), Fix: c.Bool("fix")})
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Verify failed: %v", err), 1)
+	}
+
+	for _, path := range report.MissingPaths {
+		fmt.Printf("  Missing: %s\n", path)
+	}
+	for _, path := range report.ChangedPaths {
+		fmt.Printf("  Changed: %s\n", path)
+	}
+	for _, verifyErr := range report.Errors {
+		fmt.Printf("  Error %s: %s\n", verifyErr.Path, verifyErr.Reason)
+	}
+
+	fmt.Printf("Checked %d entries: %d ok, %d missing, %d changed\n",
+		report.Checked, report.OK, report.Missing, report.Changed)
+	if c.Bool("fix") {
+		fmt.Printf("Removed %d missing entries, updated %d changed entries\n", report.Pruned, report.Updated)
+	}
+	return nil
+}
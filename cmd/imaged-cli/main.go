@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/HaiderBassem/imaged/cmd/imaged-cli/commands"
 	"github.com/HaiderBassem/imaged/pkg/api"
@@ -61,7 +62,7 @@ func main() {
 					&cli.StringFlag{
 						Name:     "format",
 						Aliases:  []string{"f"},
-						Usage:    "Report format: json | html | text",
+						Usage:    "Report format: json | html | text | csv | interactive",
 						Required: true,
 					},
 					&cli.StringFlag{
@@ -74,6 +75,38 @@ func main() {
 				Action: commands.ExportCommand,
 			},
 
+			{
+				Name:  "report",
+				Usage: "Generate a duplicate report from an index",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "index",
+						Aliases: []string{"i"},
+						Usage:   "Index database path",
+						Value:   "imaged.db",
+					},
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Usage:   "Report format: html | json | text | csv | interactive",
+						Value:   "html",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output file path; the format's extension is added when missing",
+						Value:   "imaged-report",
+					},
+					&cli.Float64Flag{
+						Name:    "threshold",
+						Aliases: []string{"t"},
+						Usage:   "Near-duplicate similarity threshold (0.0 - 1.0)",
+						Value:   0.9,
+					},
+				},
+				Action: commands.ReportCommand,
+			},
+
 			{
 				Name:  "scan",
 				Usage: "Scan a directory and index images",
@@ -96,6 +129,24 @@ func main() {
 						Usage:   "Number of worker threads",
 						Value:   4,
 					},
+					&cli.DurationFlag{
+						Name:  "shutdown-timeout",
+						Usage: "How long to wait for processed images to be saved after an interrupt",
+						Value: 10 * time.Second,
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Reprocess files that are already indexed and unchanged",
+					},
+					&cli.IntFlag{
+						Name:  "max-images",
+						Usage: "Stop discovering images after this many (0 for no limit)",
+					},
+					&cli.StringFlag{
+						Name:  "progress-format",
+						Usage: "Progress output: human, or json for one JSON object per update on stdout",
+						Value: "human",
+					},
 				},
 				Action: commands.ScanCommand,
 			},
@@ -122,6 +173,10 @@ func main() {
 						Usage:   "Only search for exact duplicates",
 						Value:   false,
 					},
+					&cli.BoolFlag{
+						Name:  "recompute",
+						Usage: "Search again instead of reusing the groups saved by the last search",
+					},
 				},
 				Action: commands.FindDuplicatesCommand,
 			},
@@ -164,20 +219,172 @@ func main() {
 						Usage: "Move duplicates instead of deleting them",
 						Value: true,
 					},
+					&cli.BoolFlag{
+						Name:  "trash",
+						Usage: "Send duplicates to the system trash instead of moving them",
+					},
+					&cli.Float64Flag{
+						Name:  "quality-margin",
+						Usage: "Leave near duplicates for review when their quality scores differ by less than this",
+					},
+					&cli.StringFlag{
+						Name:  "policy",
+						Usage: "Which copy to keep: quality, resolution, exposure, oldest or newest",
+						Value: "quality",
+					},
 				},
 				Action: commands.CleanCommand,
 			},
 
 			{
-				Name:  "quality",
-				Usage: "Analyze image quality",
+				Name:  "dedup",
+				Usage: "Scan a directory and clean its duplicates in one run",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "image",
-						Aliases:  []string{"i"},
-						Usage:    "Image file to analyze",
+						Name:     "path",
+						Aliases:  []string{"p"},
+						Usage:    "Directory path to deduplicate",
 						Required: true,
 					},
+					&cli.StringFlag{
+						Name:    "index",
+						Aliases: []string{"i"},
+						Usage:   "Index database path",
+						Value:   "imaged.db",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Output directory for moved duplicates",
+						Value:   "duplicates",
+					},
+					&cli.Float64Flag{
+						Name:    "threshold",
+						Aliases: []string{"t"},
+						Usage:   "Similarity threshold",
+						Value:   0.9,
+					},
+					&cli.IntFlag{
+						Name:    "workers",
+						Aliases: []string{"w"},
+						Usage:   "Number of worker threads",
+						Value:   4,
+					},
+					&cli.StringFlag{
+						Name:  "policy",
+						Usage: "Which copy to keep: quality, resolution, exposure, oldest or newest",
+						Value: "quality",
+					},
+					&cli.BoolFlag{
+						Name:    "dry-run",
+						Aliases: []string{"d"},
+						Usage:   "Show what would be done without actually doing it",
+					},
+					&cli.BoolFlag{
+						Name:  "move",
+						Usage: "Move duplicates to the output directory (the default)",
+						Value: true,
+					},
+					&cli.BoolFlag{
+						Name:  "delete",
+						Usage: "Delete duplicates instead of moving them",
+					},
+					&cli.BoolFlag{
+						Name:  "trash",
+						Usage: "Send duplicates to the system trash instead of moving them",
+					},
+					&cli.Float64Flag{
+						Name:  "quality-margin",
+						Usage: "Leave near duplicates for review when their quality scores differ by less than this",
+					},
+				},
+				Action: commands.DedupCommand,
+			},
+
+			{
+				Name:  "undo",
+				Usage: "Restore files moved by a clean operation",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "manifest",
+						Aliases:  []string{"m"},
+						Usage:    "Clean manifest written to the output directory",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:    "index",
+						Aliases: []string{"i"},
+						Usage:   "Index database path",
+						Value:   "imaged.db",
+					},
+				},
+				Action: commands.UndoCommand,
+			},
+
+			{
+				Name:  "prune",
+				Usage: "Remove index entries for files that no longer exist",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "index",
+						Aliases: []string{"i"},
+						Usage:   "Index database path",
+						Value:   "imaged.db",
+					},
+				},
+				Action: commands.PruneCommand,
+			},
+
+			{
+				Name:  "verify",
+				Usage: "Check index entries against the files on disk",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "index",
+						Aliases: []string{"i"},
+						Usage:   "Index database path",
+						Value:   "imaged.db",
+					},
+					&cli.BoolFlag{
+						Name:  "rehash",
+						Usage: "Also re-hash files to detect content changes",
+					},
+					&cli.BoolFlag{
+						Name:  "fix",
+						Usage: "Remove entries for missing files and reprocess changed files",
+					},
+				},
+				Action: commands.VerifyCommand,
+			},
+
+			{
+				Name:  "quality",
+				Usage: "Analyze image quality, or rank a directory's images by quality",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "image",
+						Aliases: []string{"i"},
+						Usage:   "Image file to analyze",
+					},
+					&cli.StringFlag{
+						Name:    "path",
+						Aliases: []string{"p"},
+						Usage:   "Directory whose images to rank by quality",
+					},
+					&cli.StringFlag{
+						Name:  "index",
+						Usage: "Index database path used when ranking a directory",
+						Value: "imaged.db",
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "Ranking order by final score: asc or desc",
+						Value: "desc",
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "Show only the first N ranked images (0 shows all)",
+					},
 				},
 				Action: commands.QualityCommand,
 			},
@@ -237,7 +444,7 @@ func scanCommand(c *cli.Context) error {
 	go displayProgress(progress)
 
 	// Perform scan
-	err = eng.ScanFolder(ctx, path, progress)
+	_, err = eng.ScanFolder(ctx, path, progress)
 	close(progress)
 
 	if err != nil {
@@ -320,7 +527,7 @@ func cleanCommand(c *cli.Context) error {
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
 		fmt.Println("Index not found, scanning directory first...")
 		ctx := context.Background()
-		if err := eng.ScanFolder(ctx, path, nil); err != nil {
+		if _, err := eng.ScanFolder(ctx, path, nil); err != nil {
 			return fmt.Errorf("failed to scan directory: %w", err)
 		}
 	}
@@ -429,6 +636,8 @@ func statsCommand(c *cli.Context) error {
 	fmt.Printf("  Index size: %s\n", engine.FormatBytes(stats.IndexSizeBytes))
 	fmt.Printf("  Average quality: %.1f/100\n", stats.AverageQuality)
 	fmt.Printf("  Duplicate groups: %d\n", stats.DuplicateGroups)
+	fmt.Printf("  Reclaimable from exact duplicates: %s\n", engine.FormatBytes(stats.ExactDuplicateBytes))
+	fmt.Printf("  Reclaimable from near duplicates: %s\n", engine.FormatBytes(stats.NearDuplicateBytes))
 
 	return nil
 }
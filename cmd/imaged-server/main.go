@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/HaiderBassem/imaged/pkg/server"
+	imagedv1 "github.com/HaiderBassem/imaged/proto/imaged/v1"
+	"github.com/urfave/cli/v2"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	app := &cli.App{
+		Name:    "imaged-server",
+		Version: "1.0.0",
+		Usage:   "Serve the imaged engine over gRPC",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "listen",
+				Aliases: []string{"l"},
+				Usage:   "Address to listen on",
+				Value:   "127.0.0.1:50051",
+			},
+			&cli.StringFlag{
+				Name:    "index",
+				Aliases: []string{"i"},
+				Usage:   "Index database path",
+				Value:   "imaged.db",
+			},
+			&cli.IntFlag{
+				Name:    "workers",
+				Aliases: []string{"w"},
+				Usage:   "Number of worker goroutines",
+				Value:   4,
+			},
+			&cli.DurationFlag{
+				Name:  "shutdown-timeout",
+				Usage: "How long to wait for running calls before stopping them on shutdown",
+				Value: 30 * time.Second,
+			},
+		},
+		Action: serveCommand,
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func serveCommand(c *cli.Context) error {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = c.String("index")
+	cfg.NumWorkers = c.Int("workers")
+
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to create engine: %v", err), 1)
+	}
+	defer eng.Close()
+
+	listener, err := net.Listen("tcp", c.String("listen"))
+	if err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to listen: %v", err), 1)
+	}
+
+	grpcServer := grpc.NewServer()
+	imagedv1.RegisterImageDServer(grpcServer, server.NewGRPCService(eng))
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-sigChan
+		fmt.Println("\nReceived interrupt signal, shutting down...")
+		shutdown(grpcServer, c.Duration("shutdown-timeout"))
+	}()
+
+	fmt.Printf("Serving gRPC on %s (index: %s)\n", listener.Addr(), cfg.IndexPath)
+	if err := grpcServer.Serve(listener); err != nil {
+		return cli.Exit(fmt.Sprintf("Failed to serve: %v", err), 1)
+	}
+
+	// Serve returns as soon as the listener closes, before running calls
+	// finish; the engine is only closed once they have
+	<-shutdownDone
+	return nil
+}
+
+// shutdown lets running calls finish, then cancels whatever is still running
+// after timeout so long scans cannot hold the process open. It returns once
+// every call has returned.
+func shutdown(grpcServer *grpc.Server, timeout time.Duration) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		fmt.Println("Shutdown timeout reached, cancelling running calls")
+		grpcServer.Stop()
+		<-stopped
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/HaiderBassem/imaged/pkg/engine"
 )
@@ -24,13 +23,13 @@ func main() {
 	ctx := context.Background()
 	fmt.Println("Starting scan...")
 
-	start := time.Now()
-	err = eng.ScanFolder(ctx, "/home/cpluspluser/Pictures/Images", nil)
+	scanReport, err := eng.ScanFolder(ctx, "/home/cpluspluser/Pictures/Images", nil)
 	if err != nil {
 		log.Fatal("Scan failed:", err)
 	}
 
-	fmt.Printf("Scan completed in %v\n", time.Since(start))
+	fmt.Printf("Scan completed in %v: %d images indexed, %d skipped\n",
+		scanReport.ScanDuration, scanReport.ProcessedImages, scanReport.SkippedFiles)
 
 	// Find duplicates
 	fmt.Println("Finding duplicates...")
@@ -25,8 +25,7 @@ func main() {
 	// Scan directory first
 	ctx := context.Background()
 	fmt.Println("Scanning directory...")
-	err = eng.ScanFolder(ctx, "./photos", nil)
-	if err != nil {
+	if _, err := eng.ScanFolder(ctx, "./photos", nil); err != nil {
 		log.Fatal("Scan failed:", err)
 	}
 
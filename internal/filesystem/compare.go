@@ -0,0 +1,62 @@
+package filesystem
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// compareChunkSize is the buffer size FilesEqual reads from each file at a time
+const compareChunkSize = 64 * 1024
+
+// FilesEqual reports whether two files have identical contents. Files are
+// streamed in fixed-size chunks, so memory use does not grow with file size,
+// and the comparison stops at the first differing chunk.
+func FilesEqual(pathA, pathB string) (bool, error) {
+	fileA, err := os.Open(pathA)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer fileA.Close()
+
+	fileB, err := os.Open(pathB)
+	if err != nil {
+		return false, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer fileB.Close()
+
+	infoA, err := fileA.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	infoB, err := fileB.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+
+	bufA := make([]byte, compareChunkSize)
+	bufB := make([]byte, compareChunkSize)
+	for {
+		nA, errA := io.ReadFull(fileA, bufA)
+		nB, errB := io.ReadFull(fileB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+
+		doneA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		doneB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		if errA != nil && !doneA {
+			return false, fmt.Errorf("failed to read file: %w", errA)
+		}
+		if errB != nil && !doneB {
+			return false, fmt.Errorf("failed to read file: %w", errB)
+		}
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package filesystem
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Mover performs the low-level rename behind file moves, allowing tests to
+// simulate failures such as cross-device renames
+type Mover interface {
+	Rename(oldPath, newPath string) error
+}
+
+// OSMover renames files with os.Rename
+type OSMover struct{}
+
+// Rename renames oldPath to newPath
+func (OSMover) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+// IsCrossDevice reports whether err is a rename failure caused by source and
+// destination living on different filesystems (EXDEV)
+func IsCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
@@ -12,31 +12,43 @@ import (
 
 // Organizer handles safe file operations with conflict resolution
 type Organizer struct {
+	mover  Mover
+	safe   *SafeOperations
 	logger *logrus.Logger
 }
 
 // NewOrganizer creates a new file organizer
 func NewOrganizer() *Organizer {
 	return &Organizer{
+		mover:  OSMover{},
+		safe:   NewSafeOperations(),
 		logger: logrus.New(),
 	}
 }
 
+// SetMover replaces the mover used for renames
+func (o *Organizer) SetMover(m Mover) {
+	o.mover = m
+}
+
 // MoveFile safely moves a file with conflict resolution
 func (o *Organizer) MoveFile(sourcePath, destDir string) (string, error) {
+	return o.MoveFileTo(sourcePath, filepath.Join(destDir, filepath.Base(sourcePath)))
+}
+
+// MoveFileTo moves a file to destPath, picking a free name if destPath is taken.
+// It returns the path the file actually ended up at.
+func (o *Organizer) MoveFileTo(sourcePath, destPath string) (string, error) {
 	// Ensure destination directory exists
-	if err := os.MkdirAll(destDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 		return "", fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	filename := filepath.Base(sourcePath)
-	destPath := filepath.Join(destDir, filename)
-
 	// Resolve naming conflicts
 	destPath = o.resolveConflict(destPath)
 
 	// Perform the move operation
-	if err := os.Rename(sourcePath, destPath); err != nil {
+	if err := o.rename(sourcePath, destPath); err != nil {
 		return "", fmt.Errorf("failed to move file: %w", err)
 	}
 
@@ -44,6 +56,18 @@ func (o *Organizer) MoveFile(sourcePath, destDir string) (string, error) {
 	return destPath, nil
 }
 
+// rename moves a file, falling back to a verified copy and remove when the
+// destination is on a different filesystem
+func (o *Organizer) rename(sourcePath, destPath string) error {
+	err := o.mover.Rename(sourcePath, destPath)
+	if err == nil || !IsCrossDevice(err) {
+		return err
+	}
+
+	o.logger.Debugf("Rename across devices failed, copying instead: %s -> %s", sourcePath, destPath)
+	return o.safe.MoveAcrossDevices(sourcePath, destPath)
+}
+
 // CopyFile safely copies a file with conflict resolution
 func (o *Organizer) CopyFile(sourcePath, destDir string) (string, error) {
 	// Ensure destination directory exists
@@ -72,6 +96,27 @@ func (o *Organizer) CopyFile(sourcePath, destDir string) (string, error) {
 	return destPath, nil
 }
 
+// LinkFile creates a symbolic link to sourcePath in destDir with conflict resolution
+func (o *Organizer) LinkFile(sourcePath, destDir string) (string, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	// Link to an absolute path so the link works from any directory
+	target, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve source path: %w", err)
+	}
+
+	destPath := o.resolveConflict(filepath.Join(destDir, filepath.Base(sourcePath)))
+	if err := os.Symlink(target, destPath); err != nil {
+		return "", fmt.Errorf("failed to create symlink: %w", err)
+	}
+
+	o.logger.Debugf("Linked file: %s -> %s", destPath, target)
+	return destPath, nil
+}
+
 // DeleteFile safely deletes a file with backup option
 func (o *Organizer) DeleteFile(filePath string, backupDir string) error {
 	// Create backup if requested
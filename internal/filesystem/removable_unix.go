@@ -0,0 +1,31 @@
+//go:build !windows
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// accessWriteSearch asks access(2) for write and search permission
+const accessWriteSearch = 0x2 | 0x1
+
+// CheckRemovable reports why the file at path could not be moved out of or
+// deleted from its directory, or nil if nothing is known to stand in the way
+func CheckRemovable(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	dir := filepath.Dir(path)
+	if err := syscall.Access(dir, accessWriteSearch); err != nil {
+		return &os.PathError{Op: "access", Path: dir, Err: err}
+	}
+	return nil
+}
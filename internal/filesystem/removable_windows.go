@@ -0,0 +1,24 @@
+//go:build windows
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+)
+
+// CheckRemovable reports why the file at path could not be moved out of or
+// deleted from its directory, or nil if nothing is known to stand in the way
+func CheckRemovable(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf("%s is read-only", path)
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package filesystem
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -45,9 +48,14 @@ func (so *SafeOperations) SafeMove(source, destination string) error {
 		so.logger.Debugf("Created backup: %s", backupPath)
 	}
 
-	// Perform the move
+	// Perform the move, copying across filesystems when a rename is impossible
 	if err := os.Rename(source, destination); err != nil {
-		return fmt.Errorf("failed to move file: %w", err)
+		if !IsCrossDevice(err) {
+			return fmt.Errorf("failed to move file: %w", err)
+		}
+		if err := so.MoveAcrossDevices(source, destination); err != nil {
+			return err
+		}
 	}
 
 	// Verify move was successful
@@ -86,6 +94,70 @@ func (so *SafeOperations) SafeCopy(source, destination string) error {
 	return nil
 }
 
+// MoveAcrossDevices moves a file by copying it, verifying that the copy has the
+// same size and SHA256 as the source, and only then removing the source
+func (so *SafeOperations) MoveAcrossDevices(source, destination string) error {
+	if err := so.SafeCopy(source, destination); err != nil {
+		return err
+	}
+
+	if err := so.verifyCopy(source, destination); err != nil {
+		os.Remove(destination)
+		return fmt.Errorf("copy verification failed: %w", err)
+	}
+
+	if err := os.Remove(source); err != nil {
+		return fmt.Errorf("failed to remove source after copy: %w", err)
+	}
+
+	so.logger.Debugf("Moved across devices: %s -> %s", source, destination)
+	return nil
+}
+
+// verifyCopy checks that two files have identical size and SHA256
+func (so *SafeOperations) verifyCopy(source, destination string) error {
+	srcInfo, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	dstInfo, err := os.Stat(destination)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return fmt.Errorf("size mismatch: %d != %d", srcInfo.Size(), dstInfo.Size())
+	}
+
+	srcHash, err := fileSHA256(source)
+	if err != nil {
+		return err
+	}
+	dstHash, err := fileSHA256(destination)
+	if err != nil {
+		return err
+	}
+	if srcHash != dstHash {
+		return fmt.Errorf("SHA256 mismatch for %s", destination)
+	}
+
+	return nil
+}
+
+// fileSHA256 streams a file through SHA256 and returns the hex digest
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // SafeDelete safely deletes a file with optional backup
 func (so *SafeOperations) SafeDelete(path string, backupDir string) error {
 	// Verify file exists
@@ -139,14 +211,37 @@ func (so *SafeOperations) fileExists(path string) bool {
 	return !os.IsNotExist(err)
 }
 
-// copyFile performs a file copy operation
+// copyFile streams source into destination, keeping the source's permissions and modification time
 func (so *SafeOperations) copyFile(source, destination string) error {
-	data, err := os.ReadFile(source)
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(destination, data, 0644)
+	dst, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(destination, info.ModTime(), info.ModTime())
 }
 
 // generateBackupPath generates a backup path with timestamp
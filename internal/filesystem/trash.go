@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// TrashedFile records where a file was sent to the trash so it can be restored
+type TrashedFile struct {
+	OriginalPath string
+	TrashPath    string // empty when the platform trash cannot be restored from, as on Windows
+	infoPath     string // XDG .trashinfo describing the file
+}
+
+// MoveToTrash moves a file to the platform trash instead of deleting it permanently:
+// the XDG trash on Linux and other Unix systems, ~/.Trash on macOS and the Recycle Bin on Windows
+func MoveToTrash(path string) error {
+	_, err := NewOrganizer().TrashFile(path)
+	return err
+}
+
+// TrashFile moves a file to the platform trash, like MoveToTrash, renaming it
+// with the organizer's mover, and returns where it went
+func (o *Organizer) TrashFile(path string) (*TrashedFile, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	trashed, err := o.moveToTrash(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move %s to trash: %w", absPath, err)
+	}
+	return trashed, nil
+}
+
+// RestoreFromTrash moves a trashed file back to its original path. It fails
+// rather than overwrite a file that has since taken that path.
+func (o *Organizer) RestoreFromTrash(trashed *TrashedFile) error {
+	if trashed.TrashPath == "" {
+		return fmt.Errorf("failed to restore %s: the trash cannot be restored from", trashed.OriginalPath)
+	}
+	if _, err := os.Lstat(trashed.OriginalPath); err == nil {
+		return fmt.Errorf("failed to restore %s: path is taken", trashed.OriginalPath)
+	}
+
+	if err := o.mover.Rename(trashed.TrashPath, trashed.OriginalPath); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", trashed.OriginalPath, err)
+	}
+	if trashed.infoPath != "" {
+		if err := os.Remove(trashed.infoPath); err != nil {
+			o.logger.Warnf("Failed to remove trash info %s: %v", trashed.infoPath, err)
+		}
+	}
+	return nil
+}
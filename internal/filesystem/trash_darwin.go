@@ -0,0 +1,23 @@
+//go:build darwin
+
+package filesystem
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// moveToTrash moves the file into ~/.Trash, picking a free name on conflict
+func (o *Organizer) moveToTrash(absPath string) (*TrashedFile, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate home directory: %w", err)
+	}
+
+	trashPath, err := o.MoveFile(absPath, filepath.Join(home, ".Trash"))
+	if err != nil {
+		return nil, err
+	}
+	return &TrashedFile{OriginalPath: absPath, TrashPath: trashPath}, nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package filesystem
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// moveToTrash sends the file to the Recycle Bin through the VisualBasic FileSystem
+// API. The Recycle Bin does not say where the file went, so it cannot be restored.
+func (o *Organizer) moveToTrash(absPath string) (*TrashedFile, error) {
+	script := fmt.Sprintf(
+		"Add-Type -AssemblyName Microsoft.VisualBasic; "+
+			"[Microsoft.VisualBasic.FileIO.FileSystem]::DeleteFile('%s', 'OnlyErrorDialogs', 'SendToRecycleBin')",
+		strings.ReplaceAll(absPath, "'", "''"))
+
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return &TrashedFile{OriginalPath: absPath}, nil
+}
@@ -0,0 +1,87 @@
+//go:build !darwin && !windows
+
+package filesystem
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// trashDir returns the home trash directory defined by the XDG trash specification
+func trashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate home directory: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "Trash"), nil
+}
+
+// moveToTrash moves the file into Trash/files and writes the matching Trash/info/<name>.trashinfo
+func (o *Organizer) moveToTrash(absPath string) (*TrashedFile, error) {
+	trash, err := trashDir()
+	if err != nil {
+		return nil, err
+	}
+
+	filesDir := filepath.Join(trash, "files")
+	infoDir := filepath.Join(trash, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create trash directory: %w", err)
+		}
+	}
+
+	// Reserve a unique name by creating the info file exclusively
+	name, infoFile, err := reserveTrashName(infoDir, filepath.Base(absPath))
+	if err != nil {
+		return nil, err
+	}
+
+	info := fmt.Sprintf("[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		(&url.URL{Path: absPath}).EscapedPath(),
+		time.Now().Format("2006-01-02T15:04:05"))
+	if _, err := infoFile.WriteString(info); err != nil {
+		infoFile.Close()
+		os.Remove(infoFile.Name())
+		return nil, fmt.Errorf("failed to write trash info: %w", err)
+	}
+	if err := infoFile.Close(); err != nil {
+		os.Remove(infoFile.Name())
+		return nil, fmt.Errorf("failed to write trash info: %w", err)
+	}
+
+	trashPath := filepath.Join(filesDir, name)
+	if err := o.mover.Rename(absPath, trashPath); err != nil {
+		os.Remove(infoFile.Name())
+		return nil, err
+	}
+
+	return &TrashedFile{OriginalPath: absPath, TrashPath: trashPath, infoPath: infoFile.Name()}, nil
+}
+
+// reserveTrashName finds a free name in the trash and creates its .trashinfo file
+func reserveTrashName(infoDir, base string) (string, *os.File, error) {
+	ext := filepath.Ext(base)
+	stem := base[:len(base)-len(ext)]
+
+	name := base
+	for i := 1; i < 10000; i++ {
+		f, err := os.OpenFile(filepath.Join(infoDir, name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return name, f, nil
+		}
+		if !os.IsExist(err) {
+			return "", nil, fmt.Errorf("failed to create trash info: %w", err)
+		}
+		name = fmt.Sprintf("%s.%d%s", stem, i, ext)
+	}
+
+	return "", nil, fmt.Errorf("no free trash name for %s", base)
+}
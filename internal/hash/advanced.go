@@ -82,9 +82,10 @@ func (a *AdvancedHash) computeShapeFeatures(img image.Image) []float64 {
 	// Aspect ratio
 	aspectRatio := float64(bounds.Dx()) / float64(bounds.Dy())
 
-	// Centroid (simplified)
-	centroidX := float64(bounds.Dx()) / 2
-	centroidY := float64(bounds.Dy()) / 2
+	// Centroid (simplified), relative to the image size so it stays on the same
+	// scale as the other features
+	centroidX := 0.5
+	centroidY := 0.5
 
 	return []float64{aspectRatio, centroidX, centroidY}
 }
@@ -2,8 +2,9 @@ package perceptual
 
 import (
 	"image"
+	"math"
 
-	"github.com/disintegration/imaging"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 	"github.com/nfnt/resize"
 )
 
@@ -23,18 +24,16 @@ func NewAHash(size int) *AHash {
 func (a *AHash) Compute(img image.Image) (uint64, error) {
 	// Resize image to target size
 	resized := resize.Resize(uint(a.Size), uint(a.Size), img, resize.Lanczos3)
-	gray := imaging.Grayscale(resized)
 
 	// Calculate average pixel value
 	var sum uint64
 	var pixels []uint64
 
-	bounds := gray.Bounds()
+	bounds := resized.Bounds()
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, _ := gray.At(x, y).RGBA()
 			// Convert to grayscale luminance
-			luminance := (uint64(r) + uint64(g) + uint64(b)) / 3
+			luminance := uint64(math.Round(pkgimaging.Luminance(resized.At(x, y)) * 65535))
 			sum += luminance
 			pixels = append(pixels, luminance)
 		}
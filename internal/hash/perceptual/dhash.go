@@ -3,7 +3,7 @@ package perceptual
 import (
 	"image"
 
-	"github.com/disintegration/imaging"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 	"github.com/nfnt/resize"
 )
 
@@ -25,21 +25,16 @@ func NewDHash(width, height int) *DHash {
 func (d *DHash) Compute(img image.Image) (uint64, error) {
 	// Resize to width+1 x height to allow difference calculation
 	resized := resize.Resize(uint(d.Width+1), uint(d.Height), img, resize.Lanczos3)
-	gray := imaging.Grayscale(resized)
 
-	bounds := gray.Bounds()
+	bounds := resized.Bounds()
 	var hash uint64
 	bitPosition := 0
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X-1; x++ {
-			// Get current and next pixel
-			r1, g1, b1, _ := gray.At(x, y).RGBA()
-			r2, g2, b2, _ := gray.At(x+1, y).RGBA()
-
-			// Calculate luminance for both pixels
-			luminance1 := (uint64(r1) + uint64(g1) + uint64(b1)) / 3
-			luminance2 := (uint64(r2) + uint64(g2) + uint64(b2)) / 3
+			// Calculate luminance for the current and next pixel
+			luminance1 := pkgimaging.Luminance(resized.At(x, y))
+			luminance2 := pkgimaging.Luminance(resized.At(x+1, y))
 
 			// Set bit if next pixel is brighter
 			if luminance2 > luminance1 {
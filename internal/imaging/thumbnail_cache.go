@@ -0,0 +1,126 @@
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultThumbnailSize is the longest edge, in pixels, of report thumbnails
+const DefaultThumbnailSize = 256
+
+// ThumbnailCache stores JPEG thumbnails on disk keyed by image ID and size.
+// Each cached file carries its source's modification time, so an entry is
+// rebuilt as soon as the source file changes.
+type ThumbnailCache struct {
+	dir         string
+	quality     int
+	transformer *pkgimaging.Transformer
+	logger      *logrus.Logger
+}
+
+// NewThumbnailCache creates a thumbnail cache rooted at dir
+func NewThumbnailCache(dir string) (*ThumbnailCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail cache directory: %w", err)
+	}
+
+	return &ThumbnailCache{
+		dir:         dir,
+		quality:     80,
+		transformer: pkgimaging.NewTransformer(DefaultThumbnailSize),
+		logger:      logrus.New(),
+	}, nil
+}
+
+// GetOrCreateThumbnail returns the JPEG thumbnail of fp's image whose longest
+// edge is at most size pixels, generating it when missing or out of date
+func (c *ThumbnailCache) GetOrCreateThumbnail(fp api.ImageFingerprint, size int) ([]byte, error) {
+	if fp.ID == "" || strings.ContainsAny(string(fp.ID), `/\`) {
+		return nil, fmt.Errorf("invalid image ID for thumbnail: %q", fp.ID)
+	}
+	if size <= 0 {
+		size = DefaultThumbnailSize
+	}
+
+	source, err := os.Stat(fp.Metadata.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat source image: %w", err)
+	}
+
+	cachePath := c.thumbnailPath(fp.ID, size)
+	if cached, err := os.Stat(cachePath); err == nil && cached.ModTime().Equal(source.ModTime()) {
+		data, err := os.ReadFile(cachePath)
+		if err == nil {
+			return data, nil
+		}
+		c.logger.Debugf("Failed to read cached thumbnail %s: %v", cachePath, err)
+	}
+
+	data, err := c.createThumbnail(fp, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.store(cachePath, data, source); err != nil {
+		// The thumbnail is still usable even if it could not be cached
+		c.logger.Warnf("Failed to cache thumbnail for %s: %v", fp.ID, err)
+	}
+	return data, nil
+}
+
+// createThumbnail decodes the source image and encodes a resized JPEG
+func (c *ThumbnailCache) createThumbnail(fp api.ImageFingerprint, size int) ([]byte, error) {
+	file, err := os.Open(fp.Metadata.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source image: %w", err)
+	}
+	defer file.Close()
+
+	img, _, _, err := pkgimaging.DecodeRepresentativeFrame(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode source image: %w", err)
+	}
+
+	img = c.transformer.NormalizeOrientation(img, fp.Metadata.Orientation)
+	thumbnail := c.transformer.CreateThumbnail(img, size)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: c.quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// store writes a thumbnail atomically and stamps it with the source's modification time
+func (c *ThumbnailCache) store(cachePath string, data []byte, source os.FileInfo) error {
+	tmp, err := os.CreateTemp(c.dir, ".thumb-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), source.ModTime(), source.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath)
+}
+
+// thumbnailPath returns the cache file for an image ID and size
+func (c *ThumbnailCache) thumbnailPath(id api.ImageID, size int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s_%d.jpg", id, size))
+}
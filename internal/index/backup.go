@@ -0,0 +1,100 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// Backups of file-backed stores are copies of the database file; memory and
+// Postgres stores back up to a JSON document listing every fingerprint.
+
+// jsonBackup is the backup format of stores without a database file
+type jsonBackup struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Fingerprints  []api.ImageFingerprint `json:"fingerprints"`
+}
+
+// writeJSONBackup encodes fingerprints to w as a JSON backup
+func writeJSONBackup(w io.Writer, fingerprints []api.ImageFingerprint) error {
+	if fingerprints == nil {
+		fingerprints = []api.ImageFingerprint{}
+	}
+	backup := jsonBackup{SchemaVersion: CurrentSchemaVersion, Fingerprints: fingerprints}
+	if err := json.NewEncoder(w).Encode(backup); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
+}
+
+// readJSONBackup decodes a JSON backup, rejecting ones from a newer schema
+func readJSONBackup(r io.Reader) ([]api.ImageFingerprint, error) {
+	var backup jsonBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if backup.SchemaVersion > CurrentSchemaVersion {
+		return nil, schemaTooNew(backup.SchemaVersion)
+	}
+	return backup.Fingerprints, nil
+}
+
+// NewStoreFromBackup creates the store described by cfg from a backup taken
+// with Store.Backup on a store of the same type. File-backed stores are
+// written to cfg.Path, which must not exist yet; memory and Postgres stores
+// are filled through a writable connection whatever cfg.ReadOnly says.
+func NewStoreFromBackup(cfg Config, r io.Reader) (Store, error) {
+	switch cfg.Type {
+	case StoreTypeBoltDB, StoreTypeSQLite:
+		if err := restoreFile(cfg.Path, r); err != nil {
+			return nil, err
+		}
+		return NewStore(cfg)
+
+	case StoreTypeMemory, StoreTypePostgres:
+		fingerprints, err := readJSONBackup(r)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := NewStore(Config{Type: cfg.Type, Path: cfg.Path})
+		if err != nil {
+			return nil, err
+		}
+		if err := store.SaveFingerprints(fingerprints); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to restore fingerprints: %w", err)
+		}
+		return store, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported store type: %v", cfg.Type)
+	}
+}
+
+// restoreFile copies a database file backup to path, failing if path exists
+func restoreFile(path string, r io.Reader) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("cannot restore backup: %s already exists", path)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to create restore file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write restore file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write restore file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
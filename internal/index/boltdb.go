@@ -1,8 +1,11 @@
 package index
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
 	"github.com/HaiderBassem/imaged/pkg/api"
@@ -12,14 +15,35 @@ import (
 
 // BoltStore implements the index Store interface using BoltDB for persistent storage
 type BoltStore struct {
-	db     *bolt.DB
-	logger *logrus.Logger
+	db       *bolt.DB
+	path     string
+	readOnly bool
+	logger   *logrus.Logger
+}
+
+// boltBuckets lists every bucket of the index layout
+var boltBuckets = []string{
+	"fingerprints",
+	"sha256_index",
+	"ahash_index",
+	"phash_index",
+	"dhash_index",
+	"whash_index",
+	"path_index",
+	"metadata",
 }
 
 // NewBoltStore creates a new BoltDB-based index store
 func NewBoltStore(dbPath string) (*BoltStore, error) {
+	return openBoltStore(dbPath, false)
+}
+
+// openBoltStore opens a BoltDB index; a read-only store takes a shared lock
+// and requires the index to exist already
+func openBoltStore(dbPath string, readOnly bool) (*BoltStore, error) {
 	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
-		Timeout: 5 * time.Second,
+		Timeout:  5 * time.Second,
+		ReadOnly: readOnly,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -29,8 +53,18 @@ func NewBoltStore(dbPath string) (*BoltStore, error) {
 	logger.SetLevel(logrus.InfoLevel)
 
 	store := &BoltStore{
-		db:     db,
-		logger: logger,
+		db:       db,
+		path:     dbPath,
+		readOnly: readOnly,
+		logger:   logger,
+	}
+
+	if readOnly {
+		if err := store.checkBuckets(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open read-only index: %w", err)
+		}
+		return store, nil
 	}
 
 	// Initialize required buckets
@@ -42,72 +76,223 @@ func NewBoltStore(dbPath string) (*BoltStore, error) {
 	return store, nil
 }
 
-// initBuckets creates all necessary buckets if they don't exist
+// checkBuckets verifies that a read-only index has every bucket and a schema
+// version this build can read
+func (s *BoltStore) checkBuckets() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		for _, bucket := range boltBuckets {
+			if tx.Bucket([]byte(bucket)) == nil {
+				return fmt.Errorf("missing bucket %s", bucket)
+			}
+		}
+
+		version, err := readBoltSchemaVersion(tx)
+		if err != nil {
+			return err
+		}
+		if version > CurrentSchemaVersion {
+			return schemaTooNew(version)
+		}
+		return nil
+	})
+}
+
+// initBuckets creates all necessary buckets if they don't exist. A new index
+// is stamped with the current schema version; an existing one keeps its
+// version until Migrate is called, and one newer than this build is rejected.
 func (s *BoltStore) initBuckets() error {
 	return s.db.Update(func(tx *bolt.Tx) error {
-		buckets := []string{
-			"fingerprints",
-			"sha256_index",
-			"ahash_index",
-			"phash_index",
-			"dhash_index",
-			"whash_index",
-			"path_index",
-			"metadata",
-		}
-
-		for _, bucket := range buckets {
+		fresh := tx.Bucket([]byte("fingerprints")) == nil
+
+		if !fresh {
+			version, err := readBoltSchemaVersion(tx)
+			if err != nil {
+				return err
+			}
+			if version > CurrentSchemaVersion {
+				return schemaTooNew(version)
+			}
+		}
+
+		for _, bucket := range boltBuckets {
 			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
 				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
 			}
 		}
+
+		if fresh {
+			return writeBoltSchemaVersion(tx, CurrentSchemaVersion)
+		}
 		return nil
 	})
 }
 
+// SchemaVersion returns the index's schema version
+func (s *BoltStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		version, err = readBoltSchemaVersion(tx)
+		return err
+	})
+	return version, err
+}
+
+// Migrate upgrades the index to CurrentSchemaVersion one version at a time,
+// committing after each step so an interrupted upgrade resumes where it stopped
+func (s *BoltStore) Migrate() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	for {
+		version, err := s.SchemaVersion()
+		if err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if version > CurrentSchemaVersion {
+			return schemaTooNew(version)
+		}
+		if version == CurrentSchemaVersion {
+			return nil
+		}
+
+		err = s.db.Update(func(tx *bolt.Tx) error {
+			if err := boltMigrations[version-1](tx); err != nil {
+				return err
+			}
+			return writeBoltSchemaVersion(tx, version+1)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate index from version %d: %w", version, err)
+		}
+		s.logger.Infof("Migrated index schema from version %d to %d", version, version+1)
+	}
+}
+
 // SaveFingerprint stores an image fingerprint and updates all indices
 func (s *BoltStore) SaveFingerprint(fp api.ImageFingerprint) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	return s.db.Update(func(tx *bolt.Tx) error {
-		// Serialize fingerprint data
-		data, err := json.Marshal(fp)
-		if err != nil {
-			return fmt.Errorf("failed to marshal fingerprint: %w", err)
-		}
+		return s.saveFingerprintTx(tx, fp)
+	})
+}
 
-		// Store in main fingerprints bucket
-		fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
-		if err := fingerprintsBucket.Put([]byte(fp.ID), data); err != nil {
-			return fmt.Errorf("failed to store fingerprint: %w", err)
+// SaveFingerprints stores a batch of fingerprints in a single transaction
+func (s *BoltStore) SaveFingerprints(fps []api.ImageFingerprint) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, fp := range fps {
+			if err := s.saveFingerprintTx(tx, fp); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
 
-		// Update SHA256 index for exact duplicate detection
-		sha256Bucket := tx.Bucket([]byte("sha256_index"))
-		if err := sha256Bucket.Put([]byte(fp.Metadata.SHA256), []byte(fp.ID)); err != nil {
-			return fmt.Errorf("failed to update SHA256 index: %w", err)
+// saveFingerprintTx writes a fingerprint and its index entries within tx
+func (s *BoltStore) saveFingerprintTx(tx *bolt.Tx, fp api.ImageFingerprint) error {
+	// Serialize fingerprint data
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal fingerprint: %w", err)
+	}
+
+	// Drop index entries of the image's previous version
+	fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
+	if previous := fingerprintsBucket.Get([]byte(fp.ID)); previous != nil {
+		var old api.ImageFingerprint
+		if err := json.Unmarshal(previous, &old); err == nil {
+			if err := s.unindexTx(tx, old); err != nil {
+				return err
+			}
 		}
+	}
+
+	// Store in main fingerprints bucket
+	if err := fingerprintsBucket.Put([]byte(fp.ID), data); err != nil {
+		return fmt.Errorf("failed to store fingerprint: %w", err)
+	}
+
+	if err := s.indexMetadataTx(tx, fp); err != nil {
+		return err
+	}
+	if err := clearGroupsTx(tx); err != nil {
+		return err
+	}
+
+	// Update perceptual hash indices
+	if err := s.updateHashIndex(tx, "ahash_index", fp.PHashes.AHash, fp.ID); err != nil {
+		return err
+	}
+	if err := s.updateHashIndex(tx, "phash_index", fp.PHashes.PHash, fp.ID); err != nil {
+		return err
+	}
+	if err := s.updateHashIndex(tx, "dhash_index", fp.PHashes.DHash, fp.ID); err != nil {
+		return err
+	}
+	if err := s.updateHashIndex(tx, "whash_index", fp.PHashes.WHash, fp.ID); err != nil {
+		return err
+	}
+
+	s.logger.Debugf("Successfully indexed image: %s", fp.ID)
+	return nil
+}
+
+// indexMetadataTx adds fp to the SHA256 and path indices within tx
+func (s *BoltStore) indexMetadataTx(tx *bolt.Tx, fp api.ImageFingerprint) error {
+	// Update SHA256 index for exact duplicate detection; identical files share an entry
+	sha256Bucket := tx.Bucket([]byte("sha256_index"))
+	if err := addToIDList(sha256Bucket, []byte(fp.Metadata.SHA256), fp.ID); err != nil {
+		return fmt.Errorf("failed to update SHA256 index: %w", err)
+	}
+
+	// Update path index for quick path-based lookups
+	pathBucket := tx.Bucket([]byte("path_index"))
+	if err := pathBucket.Put([]byte(fp.Metadata.Path), []byte(fp.ID)); err != nil {
+		return fmt.Errorf("failed to update path index: %w", err)
+	}
+	return nil
+}
 
-		// Update path index for quick path-based lookups
-		pathBucket := tx.Bucket([]byte("path_index"))
-		if err := pathBucket.Put([]byte(fp.Metadata.Path), []byte(fp.ID)); err != nil {
-			return fmt.Errorf("failed to update path index: %w", err)
+// UpdateMetadata replaces a fingerprint's metadata and its SHA256 and path
+// index entries, leaving the perceptual hash indices untouched
+func (s *BoltStore) UpdateMetadata(imageID api.ImageID, meta api.ImageMetadata) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
+		data := fingerprintsBucket.Get([]byte(imageID))
+		if data == nil {
+			return api.ErrImageNotFound
+		}
+		var fp api.ImageFingerprint
+		if err := json.Unmarshal(data, &fp); err != nil {
+			return fmt.Errorf("failed to unmarshal fingerprint: %w", err)
 		}
 
-		// Update perceptual hash indices
-		if err := s.updateHashIndex(tx, "ahash_index", fp.PHashes.AHash, fp.ID); err != nil {
+		if err := s.unindexMetadataTx(tx, fp); err != nil {
 			return err
 		}
-		if err := s.updateHashIndex(tx, "phash_index", fp.PHashes.PHash, fp.ID); err != nil {
+		if err := clearGroupsTx(tx); err != nil {
 			return err
 		}
-		if err := s.updateHashIndex(tx, "dhash_index", fp.PHashes.DHash, fp.ID); err != nil {
-			return err
+
+		fp.Metadata = meta
+		data, err := json.Marshal(fp)
+		if err != nil {
+			return fmt.Errorf("failed to marshal fingerprint: %w", err)
 		}
-		if err := s.updateHashIndex(tx, "whash_index", fp.PHashes.WHash, fp.ID); err != nil {
-			return err
+		if err := fingerprintsBucket.Put([]byte(imageID), data); err != nil {
+			return fmt.Errorf("failed to store fingerprint: %w", err)
 		}
 
-		s.logger.Debugf("Successfully indexed image: %s", fp.ID)
-		return nil
+		return s.indexMetadataTx(tx, fp)
 	})
 }
 
@@ -119,38 +304,66 @@ func (s *BoltStore) updateHashIndex(tx *bolt.Tx, bucketName string, hash uint64,
 
 	bucket := tx.Bucket([]byte(bucketName))
 	key := fmt.Sprintf("%016x", hash)
+	if err := addToIDList(bucket, []byte(key), imageID); err != nil {
+		return fmt.Errorf("failed to update hash index: %w", err)
+	}
+	return nil
+}
 
-	// Get existing images for this hash
-	var images []api.ImageID
-	existingData := bucket.Get([]byte(key))
-	if existingData != nil {
-		if err := json.Unmarshal(existingData, &images); err != nil {
-			return fmt.Errorf("failed to unmarshal existing images: %w", err)
-		}
+// decodeIDList reads an index value holding a JSON array of image IDs.
+// Older SHA256 index entries hold a single raw ID instead.
+func decodeIDList(data []byte) []api.ImageID {
+	var ids []api.ImageID
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return []api.ImageID{api.ImageID(data)}
+	}
+	return ids
+}
+
+// addToIDList appends imageID to the ID list stored under key, if not already present
+func addToIDList(bucket *bolt.Bucket, key []byte, imageID api.ImageID) error {
+	var ids []api.ImageID
+	if existing := bucket.Get(key); existing != nil {
+		ids = decodeIDList(existing)
 	}
 
-	// Add new image ID if not already present
-	found := false
-	for _, id := range images {
+	for _, id := range ids {
 		if id == imageID {
-			found = true
-			break
+			return nil
 		}
 	}
 
-	if !found {
-		images = append(images, imageID)
-		newData, err := json.Marshal(images)
-		if err != nil {
-			return fmt.Errorf("failed to marshal image list: %w", err)
-		}
+	data, err := json.Marshal(append(ids, imageID))
+	if err != nil {
+		return fmt.Errorf("failed to marshal image list: %w", err)
+	}
+	return bucket.Put(key, data)
+}
 
-		if err := bucket.Put([]byte(key), newData); err != nil {
-			return fmt.Errorf("failed to update hash index: %w", err)
+// removeFromIDList drops imageID from the ID list stored under key,
+// deleting the key once the list is empty
+func removeFromIDList(bucket *bolt.Bucket, key []byte, imageID api.ImageID) error {
+	existing := bucket.Get(key)
+	if existing == nil {
+		return nil
+	}
+
+	var remaining []api.ImageID
+	for _, id := range decodeIDList(existing) {
+		if id != imageID {
+			remaining = append(remaining, id)
 		}
 	}
 
-	return nil
+	if len(remaining) == 0 {
+		return bucket.Delete(key)
+	}
+
+	data, err := json.Marshal(remaining)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated image list: %w", err)
+	}
+	return bucket.Put(key, data)
 }
 
 // GetFingerprint retrieves a fingerprint by image ID
@@ -178,6 +391,53 @@ func (s *BoltStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint,
 	return &fingerprint, nil
 }
 
+// GetFingerprintByPath retrieves the fingerprint indexed for a file path
+func (s *BoltStore) GetFingerprintByPath(path string) (*api.ImageFingerprint, error) {
+	var fingerprint api.ImageFingerprint
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		imageID := tx.Bucket([]byte("path_index")).Get([]byte(path))
+		if imageID == nil {
+			return api.ErrImageNotFound
+		}
+
+		data := tx.Bucket([]byte("fingerprints")).Get(imageID)
+		if data == nil {
+			return api.ErrImageNotFound
+		}
+
+		if err := json.Unmarshal(data, &fingerprint); err != nil {
+			return fmt.Errorf("failed to unmarshal fingerprint: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fingerprint, nil
+}
+
+// Exists reports whether a path is indexed using the path index bucket alone
+func (s *BoltStore) Exists(path string) (bool, api.ImageID, error) {
+	var imageID api.ImageID
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if id := tx.Bucket([]byte("path_index")).Get([]byte(path)); id != nil {
+			imageID = api.ImageID(id)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return false, "", err
+	}
+
+	return imageID != "", imageID, nil
+}
+
 // GetAllFingerprints retrieves all fingerprints from the index
 func (s *BoltStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
 	var fingerprints []api.ImageFingerprint
@@ -203,44 +463,135 @@ func (s *BoltStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
 	return fingerprints, nil
 }
 
+// IterateFingerprints streams every fingerprint to fn inside a read transaction
+func (s *BoltStore) IterateFingerprints(ctx context.Context, fn func(api.ImageFingerprint) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("fingerprints"))
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var fp api.ImageFingerprint
+			if err := json.Unmarshal(v, &fp); err != nil {
+				s.logger.Warnf("Failed to unmarshal fingerprint %s: %v", k, err)
+				return nil // Continue with next fingerprint
+			}
+			return fn(fp)
+		})
+	})
+}
+
+// ListFingerprints returns a page of fingerprints in key (image ID) order
+func (s *BoltStore) ListFingerprints(offset, limit int) ([]api.ImageFingerprint, error) {
+	fingerprints := []api.ImageFingerprint{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("fingerprints"))
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		k, v := cursor.First()
+		for skipped := 0; k != nil && skipped < offset; skipped++ {
+			k, v = cursor.Next()
+		}
+
+		for ; k != nil && (limit <= 0 || len(fingerprints) < limit); k, v = cursor.Next() {
+			var fp api.ImageFingerprint
+			if err := json.Unmarshal(v, &fp); err != nil {
+				return fmt.Errorf("failed to unmarshal fingerprint %s: %w", k, err)
+			}
+			fingerprints = append(fingerprints, fp)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+
+	return fingerprints, nil
+}
+
+// CountFingerprints returns the number of stored fingerprints
+func (s *BoltStore) CountFingerprints() (int64, error) {
+	var count int64
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket([]byte("fingerprints")); bucket != nil {
+			count = int64(bucket.Stats().KeyN)
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// FindByQualityRange returns fingerprints whose final score lies in [min, max]
+func (s *BoltStore) FindByQualityRange(min, max float64) ([]api.ImageFingerprint, error) {
+	matches, err := filterFingerprints(s.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return inQualityRange(fp, min, max)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quality range: %w", err)
+	}
+	return matches, nil
+}
+
+// FindByCaptureDate returns fingerprints captured within [from, to]
+func (s *BoltStore) FindByCaptureDate(from, to time.Time) ([]api.ImageFingerprint, error) {
+	matches, err := filterFingerprints(s.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return takenBetween(fp, from, to)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capture dates: %w", err)
+	}
+	return matches, nil
+}
+
+// FindNearLocation returns geotagged fingerprints within radiusKm of (lat, lon)
+func (s *BoltStore) FindNearLocation(lat, lon, radiusKm float64) ([]api.ImageFingerprint, error) {
+	matches, err := filterFingerprints(s.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return withinRadius(fp, lat, lon, radiusKm)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location: %w", err)
+	}
+	return matches, nil
+}
+
 // DeleteFingerprint removes a fingerprint and all its indices
 func (s *BoltStore) DeleteFingerprint(imageID api.ImageID) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	return s.db.Update(func(tx *bolt.Tx) error {
 		// Get the fingerprint first to update indices
-		fp, err := s.GetFingerprint(imageID)
-		if err != nil {
-			return err
+		fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
+		data := fingerprintsBucket.Get([]byte(imageID))
+		if data == nil {
+			return api.ErrImageNotFound
+		}
+		var fp api.ImageFingerprint
+		if err := json.Unmarshal(data, &fp); err != nil {
+			return fmt.Errorf("failed to unmarshal fingerprint: %w", err)
 		}
 
 		// Remove from main fingerprints bucket
-		fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
 		if err := fingerprintsBucket.Delete([]byte(imageID)); err != nil {
 			return fmt.Errorf("failed to delete fingerprint: %w", err)
 		}
 
-		// Remove from SHA256 index
-		sha256Bucket := tx.Bucket([]byte("sha256_index"))
-		if err := sha256Bucket.Delete([]byte(fp.Metadata.SHA256)); err != nil {
-			return fmt.Errorf("failed to remove SHA256 index: %w", err)
-		}
-
-		// Remove from path index
-		pathBucket := tx.Bucket([]byte("path_index"))
-		if err := pathBucket.Delete([]byte(fp.Metadata.Path)); err != nil {
-			return fmt.Errorf("failed to remove path index: %w", err)
-		}
-
-		// Remove from perceptual hash indices
-		if err := s.removeFromHashIndex(tx, "ahash_index", fp.PHashes.AHash, imageID); err != nil {
-			return err
-		}
-		if err := s.removeFromHashIndex(tx, "phash_index", fp.PHashes.PHash, imageID); err != nil {
+		if err := s.unindexTx(tx, fp); err != nil {
 			return err
 		}
-		if err := s.removeFromHashIndex(tx, "dhash_index", fp.PHashes.DHash, imageID); err != nil {
-			return err
-		}
-		if err := s.removeFromHashIndex(tx, "whash_index", fp.PHashes.WHash, imageID); err != nil {
+		if err := clearGroupsTx(tx); err != nil {
 			return err
 		}
 
@@ -249,6 +600,43 @@ func (s *BoltStore) DeleteFingerprint(imageID api.ImageID) error {
 	})
 }
 
+// unindexTx removes fp's entries from the SHA256, path and perceptual hash indices
+func (s *BoltStore) unindexTx(tx *bolt.Tx, fp api.ImageFingerprint) error {
+	if err := s.unindexMetadataTx(tx, fp); err != nil {
+		return err
+	}
+
+	// Remove from perceptual hash indices
+	if err := s.removeFromHashIndex(tx, "ahash_index", fp.PHashes.AHash, fp.ID); err != nil {
+		return err
+	}
+	if err := s.removeFromHashIndex(tx, "phash_index", fp.PHashes.PHash, fp.ID); err != nil {
+		return err
+	}
+	if err := s.removeFromHashIndex(tx, "dhash_index", fp.PHashes.DHash, fp.ID); err != nil {
+		return err
+	}
+	return s.removeFromHashIndex(tx, "whash_index", fp.PHashes.WHash, fp.ID)
+}
+
+// unindexMetadataTx removes fp's entries from the SHA256 and path indices
+func (s *BoltStore) unindexMetadataTx(tx *bolt.Tx, fp api.ImageFingerprint) error {
+	// Remove from SHA256 index, keeping other images with the same content
+	sha256Bucket := tx.Bucket([]byte("sha256_index"))
+	if err := removeFromIDList(sha256Bucket, []byte(fp.Metadata.SHA256), fp.ID); err != nil {
+		return fmt.Errorf("failed to remove SHA256 index: %w", err)
+	}
+
+	// Remove from path index unless the path now belongs to another image
+	pathBucket := tx.Bucket([]byte("path_index"))
+	if string(pathBucket.Get([]byte(fp.Metadata.Path))) == string(fp.ID) {
+		if err := pathBucket.Delete([]byte(fp.Metadata.Path)); err != nil {
+			return fmt.Errorf("failed to remove path index: %w", err)
+		}
+	}
+	return nil
+}
+
 // removeFromHashIndex removes an image from a specific hash index
 func (s *BoltStore) removeFromHashIndex(tx *bolt.Tx, bucketName string, hash uint64, imageID api.ImageID) error {
 	if hash == 0 {
@@ -257,37 +645,63 @@ func (s *BoltStore) removeFromHashIndex(tx *bolt.Tx, bucketName string, hash uin
 
 	bucket := tx.Bucket([]byte(bucketName))
 	key := fmt.Sprintf("%016x", hash)
-
-	existingData := bucket.Get([]byte(key))
-	if existingData == nil {
-		return nil // No entries for this hash
+	if err := removeFromIDList(bucket, []byte(key), imageID); err != nil {
+		return fmt.Errorf("failed to update hash index: %w", err)
 	}
+	return nil
+}
 
-	var images []api.ImageID
-	if err := json.Unmarshal(existingData, &images); err != nil {
-		return fmt.Errorf("failed to unmarshal image list: %w", err)
-	}
+// groupsKey is the key holding the saved duplicate groups in the groups bucket
+var groupsKey = []byte("groups")
 
-	// Filter out the image to remove
-	var newImages []api.ImageID
-	for _, id := range images {
-		if id != imageID {
-			newImages = append(newImages, id)
-		}
+// SaveGroups stores the result of a duplicate search as one JSON document
+func (s *BoltStore) SaveGroups(groups []api.DuplicateGroup) error {
+	if s.readOnly {
+		return ErrReadOnly
 	}
-
-	if len(newImages) == 0 {
-		// Remove the entire hash entry if no images left
-		return bucket.Delete([]byte(key))
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return fmt.Errorf("failed to marshal groups: %w", err)
 	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("groups"))
+		if err != nil {
+			return fmt.Errorf("failed to create groups bucket: %w", err)
+		}
+		return bucket.Put(groupsKey, data)
+	})
+}
 
-	// Update with remaining images
-	newData, err := json.Marshal(newImages)
+// GetGroups returns the saved duplicate groups, if any
+func (s *BoltStore) GetGroups() ([]api.DuplicateGroup, error) {
+	var groups []api.DuplicateGroup
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("groups"))
+		if bucket == nil {
+			return nil
+		}
+		data := bucket.Get(groupsKey)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &groups)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated image list: %w", err)
+		return nil, fmt.Errorf("failed to read groups: %w", err)
 	}
+	return groups, nil
+}
 
-	return bucket.Put([]byte(key), newData)
+// clearGroupsTx discards the saved duplicate groups within tx
+func clearGroupsTx(tx *bolt.Tx) error {
+	bucket := tx.Bucket([]byte("groups"))
+	if bucket == nil {
+		return nil
+	}
+	if err := bucket.Delete(groupsKey); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+	return nil
 }
 
 // FindBySHA256 finds all images with a specific SHA256 hash
@@ -301,15 +715,9 @@ func (s *BoltStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error) {
 			return nil // No images found, return empty slice
 		}
 
-		var imageIDs []api.ImageID
-		if err := json.Unmarshal(imageIDData, &imageIDs); err != nil {
-			// Handle single image ID (backward compatibility)
-			imageIDs = []api.ImageID{api.ImageID(imageIDData)}
-		}
-
 		// Retrieve full fingerprints for each image ID
 		fpBucket := tx.Bucket([]byte("fingerprints"))
-		for _, imageID := range imageIDs {
+		for _, imageID := range decodeIDList(imageIDData) {
 			data := fpBucket.Get([]byte(imageID))
 			if data != nil {
 				var fp api.ImageFingerprint
@@ -331,9 +739,18 @@ func (s *BoltStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error) {
 	return fingerprints, nil
 }
 
-// FindSimilarHashes finds images with similar perceptual hashes within maximum distance
+// FindSimilarHashes finds images with similar perceptual hashes within maximum
+// distance. Each image is returned once, even if several of its index entries match.
 func (s *BoltStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashType string) ([]api.ImageFingerprint, error) {
+	if err := checkHashType(hashType); err != nil {
+		return nil, err
+	}
+	if targetHash == 0 {
+		return nil, nil // a zero hash was never computed
+	}
+
 	var similarFingerprints []api.ImageFingerprint
+	seen := make(map[api.ImageID]bool)
 
 	err := s.db.View(func(tx *bolt.Tx) error {
 		bucketName := hashType + "_index"
@@ -359,6 +776,9 @@ func (s *BoltStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashTy
 				// Retrieve fingerprints for similar images
 				fpBucket := tx.Bucket([]byte("fingerprints"))
 				for _, imageID := range imageIDs {
+					if seen[imageID] {
+						continue
+					}
 					data := fpBucket.Get([]byte(imageID))
 					if data != nil {
 						var fp api.ImageFingerprint
@@ -366,6 +786,7 @@ func (s *BoltStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashTy
 							s.logger.Warnf("Failed to unmarshal fingerprint %s: %v", imageID, err)
 							continue
 						}
+						seen[imageID] = true
 						similarFingerprints = append(similarFingerprints, fp)
 					}
 				}
@@ -386,54 +807,50 @@ func (s *BoltStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashTy
 func (s *BoltStore) GetStats() (*Stats, error) {
 	stats := &Stats{}
 
-	err := s.db.View(func(tx *bolt.Tx) error {
-		fingerprintsBucket := tx.Bucket([]byte("fingerprints"))
-		if fingerprintsBucket == nil {
-			// No fingerprints bucket yet → empty stats
-			return nil
-		}
-
-		var totalSize int64
-		var totalQuality float64
-		var count int
-
-		err := fingerprintsBucket.ForEach(func(k, v []byte) error {
-			var fp api.ImageFingerprint
-			if err := json.Unmarshal(v, &fp); err != nil {
-				// Skip corrupted entries but don't stop the whole scan
-				s.logger.Warnf("Failed to unmarshal fingerprint %s: %v", k, err)
-				return nil
-			}
+	var totalQuality float64
+	duplicates := newDuplicateTally()
 
-			totalSize += fp.Metadata.SizeBytes
-			totalQuality += fp.Quality.FinalScore
-			count++
+	err := s.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		stats.TotalImages++
+		stats.TotalSizeBytes += fp.Metadata.SizeBytes
+		totalQuality += fp.Quality.FinalScore
+		duplicates.add(fp.Metadata.SHA256, fp.PHashes.PHash, fp.Metadata.SizeBytes)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect stats: %w", err)
+	}
 
+	if stats.TotalImages > 0 {
+		stats.AverageQuality = totalQuality / float64(stats.TotalImages)
+	}
+	stats.DuplicateGroups = duplicates.groups()
+	stats.ExactDuplicateBytes, stats.NearDuplicateBytes = duplicates.reclaimable()
+
+	// Report the actual allocated file on disk, falling back to the data size
+	if info, err := os.Stat(s.path); err == nil {
+		stats.IndexSizeBytes = info.Size()
+	} else {
+		err = s.db.View(func(tx *bolt.Tx) error {
+			stats.IndexSizeBytes = tx.Size()
 			return nil
 		})
 		if err != nil {
-			return err
-		}
-
-		stats.TotalImages = int64(count)
-		stats.TotalSizeBytes = totalSize
-		if count > 0 {
-			stats.AverageQuality = totalQuality / float64(count)
+			return nil, fmt.Errorf("failed to collect stats: %w", err)
 		}
+	}
 
-		// NOTE: IndexSizeBytes is left as 0 for now.
-		//  real on-disk size later
-		// -  BoltStore
-		// -  os.Stat(path).Size()
+	return stats, nil
+}
 
+// Backup streams a consistent copy of the database file to w
+func (s *BoltStore) Backup(w io.Writer) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if _, err := tx.WriteTo(w); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
 		return nil
 	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect stats: %w", err)
-	}
-
-	return stats, nil
 }
 
 // Close safely closes the database connection
@@ -444,6 +861,9 @@ func (s *BoltStore) Close() error {
 
 // Compact performs database compaction (BoltDB handles this automatically)
 func (s *BoltStore) Compact() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	// BoltDB doesn't require explicit compaction
 	return nil
 }
@@ -1,16 +1,59 @@
 package index
 
-import "github.com/HaiderBassem/imaged/pkg/api"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
 
 // Store defines the interface for index storage operations
 type Store interface {
 	SaveFingerprint(fp api.ImageFingerprint) error
+	// SaveFingerprints stores a batch atomically in one transaction
+	SaveFingerprints(fps []api.ImageFingerprint) error
 	GetFingerprint(id api.ImageID) (*api.ImageFingerprint, error)
+	// GetFingerprintByPath returns the fingerprint last indexed for path,
+	// or api.ErrImageNotFound when the path has not been indexed
+	GetFingerprintByPath(path string) (*api.ImageFingerprint, error)
+	// Exists reports whether path is indexed, and under which image ID,
+	// without loading the fingerprint
+	Exists(path string) (bool, api.ImageID, error)
 	GetAllFingerprints() ([]api.ImageFingerprint, error)
+	// IterateFingerprints calls fn for each stored fingerprint without loading
+	// them all at once; an error from fn or ctx stops the iteration and is returned
+	IterateFingerprints(ctx context.Context, fn func(api.ImageFingerprint) error) error
+	// ListFingerprints returns one page of fingerprints ordered by image ID;
+	// a limit of zero or less returns everything after offset
+	ListFingerprints(offset, limit int) ([]api.ImageFingerprint, error)
+	CountFingerprints() (int64, error)
+	// FindByQualityRange returns fingerprints whose final quality score lies in [min, max]
+	FindByQualityRange(min, max float64) ([]api.ImageFingerprint, error)
+	// FindByCaptureDate returns fingerprints whose EXIF capture time lies in
+	// [from, to]; images without a capture time never match
+	FindByCaptureDate(from, to time.Time) ([]api.ImageFingerprint, error)
+	// FindNearLocation returns geotagged fingerprints within radiusKm of (lat, lon)
+	FindNearLocation(lat, lon, radiusKm float64) ([]api.ImageFingerprint, error)
 	FindBySHA256(hash string) ([]api.ImageFingerprint, error)
 	FindSimilarHashes(targetHash uint64, maxDistance int, hashType string) ([]api.ImageFingerprint, error)
+	// UpdateMetadata replaces the metadata of a stored fingerprint and its
+	// SHA256 and path index entries, without rewriting the hash indices;
+	// it returns api.ErrImageNotFound for an unknown ID
+	UpdateMetadata(id api.ImageID, meta api.ImageMetadata) error
 	DeleteFingerprint(id api.ImageID) error
+	// SaveGroups replaces the stored result of a duplicate search. Saving,
+	// updating or deleting a fingerprint discards it, so GetGroups returns
+	// no groups until they are saved again.
+	SaveGroups(groups []api.DuplicateGroup) error
+	GetGroups() ([]api.DuplicateGroup, error)
 	GetStats() (*Stats, error)
+	// SchemaVersion reports the on-disk layout version; Migrate upgrades it to CurrentSchemaVersion
+	SchemaVersion() (int, error)
+	Migrate() error
+	// Backup writes a consistent snapshot of the index to w while it stays
+	// open; NewStoreFromBackup restores it into a store of the same type
+	Backup(w io.Writer) error
 	Close() error
 	Compact() error
 }
@@ -22,6 +65,12 @@ type Stats struct {
 	IndexSizeBytes  int64   `json:"index_size_bytes"`
 	AverageQuality  float64 `json:"average_quality"`
 	DuplicateGroups int     `json:"duplicate_groups"`
+
+	// ExactDuplicateBytes is the space freed by keeping one file per SHA256;
+	// NearDuplicateBytes is the further space freed by keeping one of the
+	// distinct files that share a pHash
+	ExactDuplicateBytes int64 `json:"exact_duplicate_bytes"`
+	NearDuplicateBytes  int64 `json:"near_duplicate_bytes"`
 }
 
 // BatchOperation represents a batch of index operations
@@ -0,0 +1,767 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/sirupsen/logrus"
+)
+
+// The Postgres store talks to the server through database/sql. The driver is
+// only linked in when building with the postgres tag (see postgres_driver.go),
+// so single-host builds do not pull it in.
+
+// ErrPostgresDriverUnavailable is returned when opening a Postgres index in a build without the postgres tag
+var ErrPostgresDriverUnavailable = errors.New("postgres index requires building with the postgres tag")
+
+// postgresDriver is the database/sql driver name registered by the postgres build tag
+const postgresDriver = "postgres"
+
+// PostgresStore implements the Store interface using PostgreSQL, for indexes
+// shared between machines. Metadata is kept as JSONB with a GIN index, and
+// perceptual hashes are stored as bigint so they can be compared in SQL.
+type PostgresStore struct {
+	db       *sql.DB
+	readOnly bool
+	logger   *logrus.Logger
+}
+
+// NewPostgresStore opens a Postgres-based index store; dsn is a libpq
+// connection string or URL
+func NewPostgresStore(dsn string) (Store, error) {
+	return openPostgresStore(dsn, false)
+}
+
+// openPostgresStore connects to Postgres and prepares the schema; a read-only
+// store leaves the schema untouched and rejects writes
+func openPostgresStore(dsn string, readOnly bool) (Store, error) {
+	if !postgresDriverRegistered() {
+		return nil, ErrPostgresDriverUnavailable
+	}
+
+	db, err := sql.Open(postgresDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+
+	store := &PostgresStore{
+		db:       db,
+		readOnly: readOnly,
+		logger:   logger,
+	}
+
+	if readOnly {
+		if err := store.checkSchema(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open read-only index: %w", err)
+		}
+		return store, nil
+	}
+
+	if err := store.initSchema(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	return store, nil
+}
+
+// postgresDriverRegistered reports whether a Postgres driver is linked in
+func postgresDriverRegistered() bool {
+	for _, name := range sql.Drivers() {
+		if name == postgresDriver {
+			return true
+		}
+	}
+	return false
+}
+
+// initSchema creates the tables and indexes if they don't exist. A new index
+// is stamped with the current schema version; a newer one is rejected.
+func (s *PostgresStore) initSchema() error {
+	exists, err := s.tableExists("fingerprints")
+	if err != nil {
+		return err
+	}
+	if exists {
+		version, err := s.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		if version > CurrentSchemaVersion {
+			return schemaTooNew(version)
+		}
+	}
+
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS fingerprints (
+            id TEXT PRIMARY KEY,
+            metadata JSONB NOT NULL,
+            phashes JSONB NOT NULL,
+            quality JSONB NOT NULL,
+            color_hist JSONB,
+            feature_vec JSONB,
+            palette JSONB,
+            created_at TIMESTAMPTZ DEFAULT now()
+        )`,
+		`CREATE TABLE IF NOT EXISTS sha256_index (
+            sha256 TEXT NOT NULL,
+            image_id TEXT NOT NULL REFERENCES fingerprints (id) ON DELETE CASCADE,
+            PRIMARY KEY (sha256, image_id)
+        )`,
+		`CREATE TABLE IF NOT EXISTS perceptual_index (
+            hash_type TEXT NOT NULL,
+            hash_value BIGINT NOT NULL,
+            image_id TEXT NOT NULL REFERENCES fingerprints (id) ON DELETE CASCADE,
+            PRIMARY KEY (hash_type, hash_value, image_id)
+        )`,
+		`CREATE TABLE IF NOT EXISTS path_index (
+            path TEXT PRIMARY KEY,
+            image_id TEXT NOT NULL REFERENCES fingerprints (id) ON DELETE CASCADE
+        )`,
+		`CREATE TABLE IF NOT EXISTS duplicate_groups (
+            seq INTEGER PRIMARY KEY,
+            data JSONB NOT NULL
+        )`,
+		`CREATE TABLE IF NOT EXISTS schema_info (version INTEGER NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS idx_fingerprints_metadata ON fingerprints USING GIN (metadata)`,
+		`CREATE INDEX IF NOT EXISTS idx_perceptual_image ON perceptual_index (image_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_path_image ON path_index (image_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_sha256_image ON sha256_index (image_id)`,
+	}
+
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+	}
+
+	if !exists {
+		return s.writeSchemaVersion(CurrentSchemaVersion)
+	}
+	return nil
+}
+
+// checkSchema verifies that a read-only index exists with a schema version this build can read
+func (s *PostgresStore) checkSchema() error {
+	exists, err := s.tableExists("fingerprints")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("missing fingerprints table")
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		return schemaTooNew(version)
+	}
+	return nil
+}
+
+// tableExists reports whether a table is visible on the connection's search path
+func (s *PostgresStore) tableExists(name string) (bool, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	return exists, nil
+}
+
+// SchemaVersion returns the index's schema version
+func (s *PostgresStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT version FROM schema_info`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return CurrentSchemaVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Migrate brings the index to CurrentSchemaVersion, replaying the steps
+// added since Postgres indexes were introduced at version 3
+func (s *PostgresStore) Migrate() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		return schemaTooNew(version)
+	}
+	if version == CurrentSchemaVersion {
+		return nil
+	}
+	for _, step := range postgresMigrations[max(version-3, 0):] {
+		if _, err := s.db.Exec(step); err != nil {
+			return fmt.Errorf("failed to migrate index from version %d: %w", version, err)
+		}
+	}
+	return s.writeSchemaVersion(CurrentSchemaVersion)
+}
+
+// writeSchemaVersion records the schema version in schema_info
+func (s *PostgresStore) writeSchemaVersion(version int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM schema_info`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_info (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SaveFingerprint stores an image fingerprint
+func (s *PostgresStore) SaveFingerprint(fp api.ImageFingerprint) error {
+	return s.SaveFingerprints([]api.ImageFingerprint{fp})
+}
+
+// SaveFingerprints stores a batch of fingerprints in a single transaction
+func (s *PostgresStore) SaveFingerprints(fps []api.ImageFingerprint) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, fp := range fps {
+		if err := s.saveFingerprintTx(tx, fp); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// saveFingerprintTx upserts a fingerprint and replaces its index entries within tx
+func (s *PostgresStore) saveFingerprintTx(tx *sql.Tx, fp api.ImageFingerprint) error {
+	metadataJSON, _ := json.Marshal(fp.Metadata)
+	phashesJSON, _ := json.Marshal(fp.PHashes)
+	qualityJSON, _ := json.Marshal(fp.Quality)
+
+	var colorHistJSON, featureVecJSON, paletteJSON sql.NullString
+	if fp.ColorHist != nil {
+		data, _ := json.Marshal(fp.ColorHist)
+		colorHistJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	if fp.FeatureVec != nil {
+		data, _ := json.Marshal(fp.FeatureVec)
+		featureVecJSON = sql.NullString{String: string(data), Valid: true}
+	}
+	if fp.Palette != nil {
+		data, _ := json.Marshal(fp.Palette)
+		paletteJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	_, err := tx.Exec(`
+        INSERT INTO fingerprints
+        (id, metadata, phashes, quality, color_hist, feature_vec, palette, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        ON CONFLICT (id) DO UPDATE SET
+            metadata = EXCLUDED.metadata,
+            phashes = EXCLUDED.phashes,
+            quality = EXCLUDED.quality,
+            color_hist = EXCLUDED.color_hist,
+            feature_vec = EXCLUDED.feature_vec,
+            palette = EXCLUDED.palette,
+            created_at = EXCLUDED.created_at
+    `, string(fp.ID), string(metadataJSON), string(phashesJSON),
+		string(qualityJSON), colorHistJSON, featureVecJSON, paletteJSON, fp.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert fingerprint: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+
+	// Drop entries from the fingerprint's previous version before re-adding them
+	for _, table := range []string{"sha256_index", "path_index", "perceptual_index"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE image_id = $1`, string(fp.ID)); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO sha256_index (sha256, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		fp.Metadata.SHA256, string(fp.ID))
+	if err != nil {
+		return fmt.Errorf("failed to update SHA256 index: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO path_index (path, image_id) VALUES ($1, $2)
+        ON CONFLICT (path) DO UPDATE SET image_id = EXCLUDED.image_id
+    `, fp.Metadata.Path, string(fp.ID))
+	if err != nil {
+		return fmt.Errorf("failed to update path index: %w", err)
+	}
+
+	hashes := map[string]uint64{
+		"ahash": fp.PHashes.AHash,
+		"phash": fp.PHashes.PHash,
+		"dhash": fp.PHashes.DHash,
+		"whash": fp.PHashes.WHash,
+	}
+	for hashType, hashValue := range hashes {
+		if hashValue == 0 {
+			continue
+		}
+		// bigint is signed; the bit pattern is kept as is
+		_, err := tx.Exec(`INSERT INTO perceptual_index (hash_type, hash_value, image_id) VALUES ($1, $2, $3)`,
+			hashType, int64(hashValue), string(fp.ID))
+		if err != nil {
+			return fmt.Errorf("failed to update perceptual index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateMetadata rewrites a fingerprint's metadata column and its SHA256 and
+// path index rows, leaving the perceptual index untouched
+func (s *PostgresStore) UpdateMetadata(imageID api.ImageID, meta api.ImageMetadata) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	metadataJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE fingerprints SET metadata = $1 WHERE id = $2`, string(metadataJSON), string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return api.ErrImageNotFound
+	}
+
+	for _, table := range []string{"sha256_index", "path_index"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE image_id = $1`, string(imageID)); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO sha256_index (sha256, image_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		meta.SHA256, string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update SHA256 index: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO path_index (path, image_id) VALUES ($1, $2)
+        ON CONFLICT (path) DO UPDATE SET image_id = EXCLUDED.image_id
+    `, meta.Path, string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update path index: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetFingerprint retrieves a fingerprint by ID
+func (s *PostgresStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint, error) {
+	fp, err := scanFingerprintRow(s.db.QueryRow(`
+        SELECT `+fingerprintColumns+`
+        FROM fingerprints WHERE id = $1
+    `, string(imageID)))
+
+	if err == sql.ErrNoRows {
+		return nil, api.ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &fp, nil
+}
+
+// GetFingerprintByPath retrieves the fingerprint indexed for a file path
+func (s *PostgresStore) GetFingerprintByPath(path string) (*api.ImageFingerprint, error) {
+	fp, err := scanFingerprintRow(s.db.QueryRow(`
+        SELECT f.id, f.metadata, f.phashes, f.quality, f.color_hist, f.feature_vec, f.palette, f.created_at
+        FROM fingerprints f
+        JOIN path_index p ON f.id = p.image_id
+        WHERE p.path = $1
+    `, path))
+
+	if err == sql.ErrNoRows {
+		return nil, api.ErrImageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &fp, nil
+}
+
+// Exists reports whether a path is indexed using the path_index table alone
+func (s *PostgresStore) Exists(path string) (bool, api.ImageID, error) {
+	var imageID string
+	err := s.db.QueryRow(`SELECT image_id FROM path_index WHERE path = $1`, path).Scan(&imageID)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to query path index: %w", err)
+	}
+
+	return true, api.ImageID(imageID), nil
+}
+
+// GetAllFingerprints retrieves all fingerprints
+func (s *PostgresStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
+	var fingerprints []api.ImageFingerprint
+
+	err := s.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		fingerprints = append(fingerprints, fp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fingerprints, nil
+}
+
+// IterateFingerprints streams every fingerprint row to fn
+func (s *PostgresStore) IterateFingerprints(ctx context.Context, fn func(api.ImageFingerprint) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+fingerprintColumns+` FROM fingerprints`)
+	if err != nil {
+		return fmt.Errorf("failed to query fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan fingerprint: %w", err)
+		}
+		if err := fn(fp); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ListFingerprints returns a page of fingerprints ordered by ID
+func (s *PostgresStore) ListFingerprints(offset, limit int) ([]api.ImageFingerprint, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	var limitArg interface{}
+	if limit > 0 {
+		limitArg = limit // a NULL limit is unbounded
+	}
+
+	return s.queryFingerprints("list fingerprints",
+		`SELECT `+fingerprintColumns+` FROM fingerprints ORDER BY id LIMIT $1 OFFSET $2`, limitArg, offset)
+}
+
+// CountFingerprints returns the number of stored fingerprints
+func (s *PostgresStore) CountFingerprints() (int64, error) {
+	var count int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM fingerprints`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count fingerprints: %w", err)
+	}
+	return count, nil
+}
+
+// FindByQualityRange returns fingerprints whose final score lies in [min, max]
+func (s *PostgresStore) FindByQualityRange(min, max float64) ([]api.ImageFingerprint, error) {
+	return s.queryFingerprints("query quality range", `
+        SELECT `+fingerprintColumns+`
+        FROM fingerprints
+        WHERE (quality->>'final_score')::float8 BETWEEN $1 AND $2
+        ORDER BY id
+    `, min, max)
+}
+
+// FindByCaptureDate returns fingerprints captured within [from, to]
+func (s *PostgresStore) FindByCaptureDate(from, to time.Time) ([]api.ImageFingerprint, error) {
+	// Go encodes a missing capture time as year 1, which is excluded explicitly
+	return s.queryFingerprints("query capture dates", `
+        SELECT `+fingerprintColumns+`
+        FROM fingerprints
+        WHERE (metadata->'exif'->>'taken_at')::timestamptz BETWEEN $1 AND $2
+          AND (metadata->'exif'->>'taken_at')::timestamptz > '0001-01-02T00:00:00Z'
+        ORDER BY id
+    `, from.UTC(), to.UTC())
+}
+
+// FindNearLocation returns geotagged fingerprints within radiusKm of (lat, lon).
+// The GIN index narrows the scan to geotagged rows; the distance filter runs in Go.
+func (s *PostgresStore) FindNearLocation(lat, lon, radiusKm float64) ([]api.ImageFingerprint, error) {
+	rows, err := s.db.Query(`
+        SELECT ` + fingerprintColumns + `
+        FROM fingerprints
+        WHERE metadata @> '{"exif": {"has_gps": true}}'
+        ORDER BY id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []api.ImageFingerprint{}
+	for rows.Next() {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query location: %w", err)
+		}
+		if withinRadius(fp, lat, lon, radiusKm) {
+			matches = append(matches, fp)
+		}
+	}
+
+	return matches, rows.Err()
+}
+
+// FindBySHA256 finds every fingerprint with the given content hash
+func (s *PostgresStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error) {
+	return s.queryFingerprints("query SHA256 index", `
+        SELECT f.id, f.metadata, f.phashes, f.quality, f.color_hist, f.feature_vec, f.palette, f.created_at
+        FROM fingerprints f
+        JOIN sha256_index s ON f.id = s.image_id
+        WHERE s.sha256 = $1
+        ORDER BY f.id
+    `, hash)
+}
+
+// FindSimilarHashes finds fingerprints whose hash of hashType lies within
+// maxDistance bits of targetHash, computing the Hamming distance in SQL
+func (s *PostgresStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashType string) ([]api.ImageFingerprint, error) {
+	if err := checkHashType(hashType); err != nil {
+		return nil, err
+	}
+	if targetHash == 0 {
+		return []api.ImageFingerprint{}, nil // a zero hash was never computed
+	}
+	return s.queryFingerprints("query similar hashes", `
+        SELECT f.id, f.metadata, f.phashes, f.quality, f.color_hist, f.feature_vec, f.palette, f.created_at
+        FROM fingerprints f
+        JOIN perceptual_index p ON f.id = p.image_id
+        WHERE p.hash_type = $1
+          AND length(replace(((p.hash_value # $2)::bit(64))::text, '0', '')) <= $3
+        ORDER BY f.id
+    `, hashType, int64(targetHash), maxDistance)
+}
+
+// queryFingerprints runs a query returning fingerprint rows; op describes it in errors
+func (s *PostgresStore) queryFingerprints(op, query string, args ...interface{}) ([]api.ImageFingerprint, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", op, err)
+	}
+	defer rows.Close()
+
+	fingerprints := []api.ImageFingerprint{}
+	for rows.Next() {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s: %w", op, err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+
+	return fingerprints, rows.Err()
+}
+
+// DeleteFingerprint removes a fingerprint; its index entries cascade
+func (s *PostgresStore) DeleteFingerprint(imageID api.ImageID) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM fingerprints WHERE id = $1`, string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to delete fingerprint: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return api.ErrImageNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SaveGroups replaces the stored duplicate groups, one row per group
+func (s *PostgresStore) SaveGroups(groups []api.DuplicateGroup) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+	for i, group := range groups {
+		data, err := json.Marshal(group)
+		if err != nil {
+			return fmt.Errorf("failed to marshal group: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO duplicate_groups (seq, data) VALUES ($1, $2)`, i, string(data)); err != nil {
+			return fmt.Errorf("failed to save group: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetGroups returns the stored duplicate groups in the order they were saved
+func (s *PostgresStore) GetGroups() ([]api.DuplicateGroup, error) {
+	return queryGroups(s.db)
+}
+
+// GetStats returns statistics about the Postgres index
+func (s *PostgresStore) GetStats() (*Stats, error) {
+	var stats Stats
+
+	err := s.db.QueryRow(`
+        SELECT
+            COUNT(*),
+            COALESCE(SUM((metadata->>'size_bytes')::bigint), 0),
+            COALESCE(AVG((quality->>'final_score')::float8), 0)
+        FROM fingerprints
+    `).Scan(&stats.TotalImages, &stats.TotalSizeBytes, &stats.AverageQuality)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stats: %w", err)
+	}
+
+	// Exact duplicate groups: SHA256 values shared by more than one image
+	err = s.db.QueryRow(`
+        SELECT COUNT(*) FROM (
+            SELECT metadata->>'sha256' AS sha
+            FROM fingerprints
+            WHERE COALESCE(metadata->>'sha256', '') != ''
+            GROUP BY sha
+            HAVING COUNT(*) > 1
+        ) groups
+    `).Scan(&stats.DuplicateGroups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate groups: %w", err)
+	}
+
+	// Reclaimable space, from each file's content hash, pHash and size
+	rows, err := s.db.Query(`
+        SELECT
+            COALESCE(f.metadata->>'sha256', ''),
+            COALESCE(p.hash_value, 0),
+            COALESCE((f.metadata->>'size_bytes')::bigint, 0)
+        FROM fingerprints f
+        LEFT JOIN perceptual_index p ON p.image_id = f.id AND p.hash_type = 'phash'
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate sizes: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := newDuplicateTally()
+	for rows.Next() {
+		var sha string
+		var pHash, size int64
+		if err := rows.Scan(&sha, &pHash, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate sizes: %w", err)
+		}
+		duplicates.add(sha, uint64(pHash), size)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query duplicate sizes: %w", err)
+	}
+	stats.ExactDuplicateBytes, stats.NearDuplicateBytes = duplicates.reclaimable()
+
+	err = s.db.QueryRow(`
+        SELECT COALESCE(SUM(pg_total_relation_size(to_regclass(t))), 0)
+        FROM unnest(ARRAY['fingerprints', 'sha256_index', 'perceptual_index', 'path_index']) AS t
+    `).Scan(&stats.IndexSizeBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query index size: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// Backup writes every fingerprint to w as a JSON backup
+func (s *PostgresStore) Backup(w io.Writer) error {
+	fingerprints, err := s.ListFingerprints(0, 0)
+	if err != nil {
+		return err
+	}
+	return writeJSONBackup(w, fingerprints)
+}
+
+// Close closes the connection pool
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// Compact reclaims space left by updates and deletes and refreshes planner statistics
+func (s *PostgresStore) Compact() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	for _, table := range []string{"fingerprints", "sha256_index", "perceptual_index", "path_index"} {
+		if _, err := s.db.Exec(`VACUUM ANALYZE ` + table); err != nil {
+			return fmt.Errorf("failed to vacuum %s: %w", table, err)
+		}
+	}
+	return nil
+}
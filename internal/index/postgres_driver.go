@@ -0,0 +1,7 @@
+//go:build postgres
+
+package index
+
+// Building with -tags postgres links the lib/pq driver used by PostgresStore
+
+import _ "github.com/lib/pq"
@@ -0,0 +1,150 @@
+package index
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/boltdb/bolt"
+)
+
+// CurrentSchemaVersion is the newest index layout this build reads and writes.
+//
+//	1: original unversioned layout
+//	2: schema version marker; Bolt path index rebuilt, SQLite capture-time index
+//	3: SHA256 index keeps every image with the same content, not just the last saved
+//	4: SQLite and Postgres palette column for dominant colors
+//	5: SQLite and Postgres duplicate_groups table caching the last duplicate search
+const CurrentSchemaVersion = 5
+
+// ErrSchemaTooNew is returned when opening an index written by a newer release
+var ErrSchemaTooNew = errors.New("index schema is newer than this version supports")
+
+// schemaTooNew builds the error for an index at version found
+func schemaTooNew(found int) error {
+	return fmt.Errorf("%w: found version %d, supported up to %d", ErrSchemaTooNew, found, CurrentSchemaVersion)
+}
+
+// schemaVersionKey is the Bolt metadata key holding the schema version
+var schemaVersionKey = []byte("schema_version")
+
+// boltMigrations[i] upgrades a Bolt index from version i+1 to i+2
+var boltMigrations = []func(tx *bolt.Tx) error{
+	rebuildBoltPathIndex,
+	rebuildBoltSHA256Index,
+	// Bolt keeps whole fingerprints as JSON, so palettes need no new layout
+	func(tx *bolt.Tx) error { return nil },
+	// The groups bucket is created when groups are first saved
+	func(tx *bolt.Tx) error { return nil },
+}
+
+// sqliteMigrations[i] upgrades a SQLite index from version i+1 to i+2
+var sqliteMigrations = []string{
+	`CREATE INDEX IF NOT EXISTS idx_taken_at ON fingerprints(` + takenAtExpr + `)`,
+	`CREATE TABLE sha256_index_v3 (
+        sha256 TEXT NOT NULL,
+        image_id TEXT NOT NULL,
+        PRIMARY KEY (sha256, image_id),
+        FOREIGN KEY (image_id) REFERENCES fingerprints (id)
+    );
+    INSERT OR IGNORE INTO sha256_index_v3 (sha256, image_id)
+        SELECT json_extract(metadata, '$.sha256'), id FROM fingerprints;
+    DROP TABLE sha256_index;
+    ALTER TABLE sha256_index_v3 RENAME TO sha256_index`,
+	`ALTER TABLE fingerprints ADD COLUMN palette TEXT`,
+	`CREATE TABLE duplicate_groups (
+        seq INTEGER PRIMARY KEY,
+        data TEXT NOT NULL
+    )`,
+}
+
+// postgresMigrations[i] upgrades a Postgres index from version i+3 to i+4;
+// Postgres indexes were introduced at version 3. Each step is idempotent, as
+// a new index already has the current tables.
+var postgresMigrations = []string{
+	`ALTER TABLE fingerprints ADD COLUMN IF NOT EXISTS palette JSONB`,
+	`CREATE TABLE IF NOT EXISTS duplicate_groups (
+        seq INTEGER PRIMARY KEY,
+        data JSONB NOT NULL
+    )`,
+}
+
+// readBoltSchemaVersion returns the stored version, treating a missing marker as version 1
+func readBoltSchemaVersion(tx *bolt.Tx) (int, error) {
+	bucket := tx.Bucket([]byte("metadata"))
+	if bucket == nil {
+		return 1, nil
+	}
+
+	data := bucket.Get(schemaVersionKey)
+	if data == nil {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q: %w", data, err)
+	}
+	return version, nil
+}
+
+// writeBoltSchemaVersion records the schema version in the metadata bucket
+func writeBoltSchemaVersion(tx *bolt.Tx, version int) error {
+	bucket, err := tx.CreateBucketIfNotExists([]byte("metadata"))
+	if err != nil {
+		return err
+	}
+	return bucket.Put(schemaVersionKey, []byte(strconv.Itoa(version)))
+}
+
+// rebuildBoltPathIndex drops path entries left behind when a fingerprint's
+// path changed, and re-adds every current path
+func rebuildBoltPathIndex(tx *bolt.Tx) error {
+	if err := tx.DeleteBucket([]byte("path_index")); err != nil && err != bolt.ErrBucketNotFound {
+		return fmt.Errorf("failed to drop path index: %w", err)
+	}
+	pathBucket, err := tx.CreateBucket([]byte("path_index"))
+	if err != nil {
+		return fmt.Errorf("failed to create path index: %w", err)
+	}
+
+	fingerprints := tx.Bucket([]byte("fingerprints"))
+	if fingerprints == nil {
+		return nil
+	}
+
+	return fingerprints.ForEach(func(k, v []byte) error {
+		var fp api.ImageFingerprint
+		if err := json.Unmarshal(v, &fp); err != nil || fp.Metadata.Path == "" {
+			return nil // Unreadable entries are left for the caller to report
+		}
+		return pathBucket.Put([]byte(fp.Metadata.Path), k)
+	})
+}
+
+// rebuildBoltSHA256Index re-adds every image to the SHA256 index as ID lists,
+// recovering exact duplicates that older versions overwrote
+func rebuildBoltSHA256Index(tx *bolt.Tx) error {
+	if err := tx.DeleteBucket([]byte("sha256_index")); err != nil && err != bolt.ErrBucketNotFound {
+		return fmt.Errorf("failed to drop SHA256 index: %w", err)
+	}
+	shaBucket, err := tx.CreateBucket([]byte("sha256_index"))
+	if err != nil {
+		return fmt.Errorf("failed to create SHA256 index: %w", err)
+	}
+
+	fingerprints := tx.Bucket([]byte("fingerprints"))
+	if fingerprints == nil {
+		return nil
+	}
+
+	return fingerprints.ForEach(func(k, v []byte) error {
+		var fp api.ImageFingerprint
+		if err := json.Unmarshal(v, &fp); err != nil {
+			return nil // Unreadable entries are left for the caller to report
+		}
+		return addToIDList(shaBucket, []byte(fp.Metadata.SHA256), api.ImageID(k))
+	})
+}
@@ -1,9 +1,13 @@
 package index
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/HaiderBassem/imaged/pkg/api"
@@ -13,13 +17,25 @@ import (
 
 // SQLiteStore implements the Store interface using SQLite
 type SQLiteStore struct {
-	db     *sql.DB
-	logger *logrus.Logger
+	db       *sql.DB
+	readOnly bool
+	logger   *logrus.Logger
 }
 
 // NewSQLiteStore creates a new SQLite-based index store
 func NewSQLiteStore(dbPath string) (Store, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	return openSQLiteStore(dbPath, false)
+}
+
+// openSQLiteStore opens a SQLite index; a read-only store opens the file with
+// mode=ro and requires the index to exist already
+func openSQLiteStore(dbPath string, readOnly bool) (Store, error) {
+	dsn := dbPath
+	if readOnly {
+		dsn = "file:" + dbPath + "?mode=ro"
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -28,8 +44,17 @@ func NewSQLiteStore(dbPath string) (Store, error) {
 	logger.SetLevel(logrus.InfoLevel)
 
 	store := &SQLiteStore{
-		db:     db,
-		logger: logger,
+		db:       db,
+		readOnly: readOnly,
+		logger:   logger,
+	}
+
+	if readOnly {
+		if err := store.checkSchema(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open read-only index: %w", err)
+		}
+		return store, nil
 	}
 
 	// Initialize database schema
@@ -49,7 +74,7 @@ func (s *SQLiteStore) GetStats() (*Stats, error) {
 	row := s.db.QueryRow(`
 		SELECT 
 			COUNT(*),
-			IFNULL(SUM(json_extract(metadata, '$.SizeBytes')), 0)
+			IFNULL(SUM(json_extract(metadata, '$.size_bytes')), 0)
 		FROM fingerprints
 	`)
 
@@ -63,7 +88,7 @@ func (s *SQLiteStore) GetStats() (*Stats, error) {
 	// Average quality
 	row = s.db.QueryRow(`
 		SELECT 
-			IFNULL(AVG(json_extract(quality, '$.FinalScore')), 0)
+			IFNULL(AVG(json_extract(quality, '$.final_score')), 0)
 		FROM fingerprints
 	`)
 
@@ -76,6 +101,49 @@ func (s *SQLiteStore) GetStats() (*Stats, error) {
 	stats.TotalSizeBytes = totalSize
 	stats.AverageQuality = avgQuality
 
+	// Exact duplicate groups: SHA256 values shared by more than one image
+	row = s.db.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT json_extract(metadata, '$.sha256') AS sha
+			FROM fingerprints
+			WHERE sha IS NOT NULL AND sha != ''
+			GROUP BY sha
+			HAVING COUNT(*) > 1
+		)
+	`)
+
+	if err := row.Scan(&stats.DuplicateGroups); err != nil {
+		return nil, fmt.Errorf("failed to query duplicate groups: %w", err)
+	}
+
+	// Reclaimable space, from each file's content hash, pHash and size
+	rows, err := s.db.Query(`
+		SELECT
+			IFNULL(json_extract(f.metadata, '$.sha256'), ''),
+			IFNULL(p.hash_value, 0),
+			IFNULL(json_extract(f.metadata, '$.size_bytes'), 0)
+		FROM fingerprints f
+		LEFT JOIN perceptual_index p ON p.image_id = f.id AND p.hash_type = 'phash'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate sizes: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := newDuplicateTally()
+	for rows.Next() {
+		var sha string
+		var pHash, size int64
+		if err := rows.Scan(&sha, &pHash, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate sizes: %w", err)
+		}
+		duplicates.add(sha, uint64(pHash), size)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query duplicate sizes: %w", err)
+	}
+	stats.ExactDuplicateBytes, stats.NearDuplicateBytes = duplicates.reclaimable()
+
 	// SQLite total database size (approximate)
 	var pageCount int64
 	var pageSize int64
@@ -94,7 +162,27 @@ func (s *SQLiteStore) GetStats() (*Stats, error) {
 }
 
 // initSchema creates the necessary database tables
+//
+// A new database is stamped with the current schema version (applying every
+// migration to the base tables); an existing one keeps its version until
+// Migrate is called, and one newer than this build is rejected.
 func (s *SQLiteStore) initSchema() error {
+	var existing int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'fingerprints'`).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	fresh := existing == 0
+
+	if !fresh {
+		version, err := s.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		if version > CurrentSchemaVersion {
+			return schemaTooNew(version)
+		}
+	}
+
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS fingerprints (
             id TEXT PRIMARY KEY,
@@ -106,8 +194,9 @@ func (s *SQLiteStore) initSchema() error {
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
         )`,
 		`CREATE TABLE IF NOT EXISTS sha256_index (
-            sha256 TEXT PRIMARY KEY,
+            sha256 TEXT NOT NULL,
             image_id TEXT NOT NULL,
+            PRIMARY KEY (sha256, image_id),
             FOREIGN KEY (image_id) REFERENCES fingerprints (id)
         )`,
 		`CREATE TABLE IF NOT EXISTS perceptual_index (
@@ -133,17 +222,130 @@ func (s *SQLiteStore) initSchema() error {
 			return fmt.Errorf("failed to execute query: %w", err)
 		}
 	}
+
+	if fresh {
+		return s.Migrate()
+	}
 	return nil
 }
 
+// checkSchema verifies that a read-only index exists with a schema version this build can read
+func (s *SQLiteStore) checkSchema() error {
+	var existing int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'fingerprints'`).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if existing == 0 {
+		return fmt.Errorf("missing fingerprints table")
+	}
+
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		return schemaTooNew(version)
+	}
+	return nil
+}
+
+// SchemaVersion returns the index's schema version; databases without a
+// schema_info table predate versioning and report version 1
+func (s *SQLiteStore) SchemaVersion() (int, error) {
+	var tables int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_info'`).Scan(&tables); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if tables == 0 {
+		return 1, nil
+	}
+
+	var version int
+	if err := s.db.QueryRow(`SELECT version FROM schema_info`).Scan(&version); err != nil {
+		if err == sql.ErrNoRows {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Migrate upgrades the index to CurrentSchemaVersion one version at a time,
+// each step in its own transaction
+func (s *SQLiteStore) Migrate() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	for {
+		version, err := s.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		if version > CurrentSchemaVersion {
+			return schemaTooNew(version)
+		}
+		if version == CurrentSchemaVersion {
+			return nil
+		}
+
+		if err := s.migrateStep(version); err != nil {
+			return fmt.Errorf("failed to migrate index from version %d: %w", version, err)
+		}
+		s.logger.Infof("Migrated index schema from version %d to %d", version, version+1)
+	}
+}
+
+// migrateStep applies the migration from version to version+1 and records it
+func (s *SQLiteStore) migrateStep(version int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(sqliteMigrations[version-1]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_info (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM schema_info`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_info (version) VALUES (?)`, version+1); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // SaveFingerprint stores an image fingerprint
 func (s *SQLiteStore) SaveFingerprint(fp api.ImageFingerprint) error {
+	return s.SaveFingerprints([]api.ImageFingerprint{fp})
+}
+
+// SaveFingerprints stores a batch of fingerprints in a single transaction
+func (s *SQLiteStore) SaveFingerprints(fps []api.ImageFingerprint) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	for _, fp := range fps {
+		if err := s.saveFingerprintTx(tx, fp); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// saveFingerprintTx writes a fingerprint and its index entries within tx
+func (s *SQLiteStore) saveFingerprintTx(tx *sql.Tx, fp api.ImageFingerprint) error {
 	metadataJSON, _ := json.Marshal(fp.Metadata)
 	phashesJSON, _ := json.Marshal(fp.PHashes)
 	qualityJSON, _ := json.Marshal(fp.Quality)
@@ -158,31 +360,96 @@ func (s *SQLiteStore) SaveFingerprint(fp api.ImageFingerprint) error {
 		featureVecJSON, _ = json.Marshal(fp.FeatureVec)
 	}
 
-	_, err = tx.Exec(`
+	var paletteJSON []byte
+	if fp.Palette != nil {
+		paletteJSON, _ = json.Marshal(fp.Palette)
+	}
+
+	_, err := tx.Exec(`
         INSERT OR REPLACE INTO fingerprints 
-        (id, metadata, phashes, quality, color_hist, feature_vec, created_at)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
+        (id, metadata, phashes, quality, color_hist, feature_vec, palette, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
     `, string(fp.ID), string(metadataJSON), string(phashesJSON),
-		string(qualityJSON), colorHistJSON, featureVecJSON, fp.CreatedAt)
+		string(qualityJSON), colorHistJSON, featureVecJSON, paletteJSON, fp.CreatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert fingerprint: %w", err)
 	}
 
+	// Identical files each keep a row; drop the row for this image's previous content
+	_, err = tx.Exec(`DELETE FROM sha256_index WHERE image_id = ?`, string(fp.ID))
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec(`INSERT OR REPLACE INTO sha256_index (sha256, image_id) VALUES (?, ?)`,
 		fp.Metadata.SHA256, string(fp.ID))
 	if err != nil {
 		return err
 	}
 
+	// A fingerprint saved under a new path no longer owns its old one
+	_, err = tx.Exec(`DELETE FROM path_index WHERE image_id = ?`, string(fp.ID))
+	if err != nil {
+		return err
+	}
 	_, err = tx.Exec(`INSERT OR REPLACE INTO path_index (path, image_id) VALUES (?, ?)`,
 		fp.Metadata.Path, string(fp.ID))
 	if err != nil {
 		return err
 	}
 
-	if err := s.updatePerceptualIndex(tx, fp); err != nil {
-		return err
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+
+	return s.updatePerceptualIndex(tx, fp)
+}
+
+// UpdateMetadata rewrites a fingerprint's metadata column and its SHA256 and
+// path index rows, leaving the perceptual index untouched
+func (s *SQLiteStore) UpdateMetadata(imageID api.ImageID, meta api.ImageMetadata) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	metadataJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE fingerprints SET metadata = ? WHERE id = ?`, string(metadataJSON), string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return api.ErrImageNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sha256_index WHERE image_id = ?`, string(imageID)); err != nil {
+		return fmt.Errorf("failed to update SHA256 index: %w", err)
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO sha256_index (sha256, image_id) VALUES (?, ?)`,
+		meta.SHA256, string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update SHA256 index: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM path_index WHERE image_id = ?`, string(imageID)); err != nil {
+		return fmt.Errorf("failed to update path index: %w", err)
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO path_index (path, image_id) VALUES (?, ?)`,
+		meta.Path, string(imageID))
+	if err != nil {
+		return fmt.Errorf("failed to update path index: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
 	}
 
 	return tx.Commit()
@@ -206,10 +473,12 @@ func (s *SQLiteStore) updatePerceptualIndex(tx *sql.Tx, fp api.ImageFingerprint)
 		if hashValue == 0 {
 			continue
 		}
+		// SQLite integers are signed; the driver rejects uint64 values with the
+		// top bit set, so the bit pattern is stored as an int64
 		_, err := tx.Exec(`
             INSERT INTO perceptual_index (hash_type, hash_value, image_id)
             VALUES (?, ?, ?)
-        `, hashType, hashValue, string(fp.ID))
+        `, hashType, int64(hashValue), string(fp.ID))
 		if err != nil {
 			return err
 		}
@@ -218,19 +487,50 @@ func (s *SQLiteStore) updatePerceptualIndex(tx *sql.Tx, fp api.ImageFingerprint)
 	return nil
 }
 
-// GetFingerprint retrieves a fingerprint by ID
-func (s *SQLiteStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint, error) {
+// fingerprintColumns lists the columns read by scanFingerprintRow, in order
+const fingerprintColumns = `id, metadata, phashes, quality, color_hist, feature_vec, palette, created_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFingerprintRow decodes one fingerprints row; color_hist, feature_vec and
+// palette may be NULL
+func scanFingerprintRow(row rowScanner) (api.ImageFingerprint, error) {
 	var fp api.ImageFingerprint
-	var metadataJSON, phashesJSON, qualityJSON, colorHistJSON, featureVecJSON string
+	var metadataJSON, phashesJSON, qualityJSON string
+	var colorHistJSON, featureVecJSON, paletteJSON sql.NullString
 	var createdAt time.Time
 
-	err := s.db.QueryRow(`
-        SELECT id, metadata, phashes, quality, color_hist, feature_vec, created_at
+	if err := row.Scan(&fp.ID, &metadataJSON, &phashesJSON, &qualityJSON, &colorHistJSON, &featureVecJSON, &paletteJSON, &createdAt); err != nil {
+		return fp, err
+	}
+
+	json.Unmarshal([]byte(metadataJSON), &fp.Metadata)
+	json.Unmarshal([]byte(phashesJSON), &fp.PHashes)
+	json.Unmarshal([]byte(qualityJSON), &fp.Quality)
+
+	if colorHistJSON.Valid && colorHistJSON.String != "" {
+		json.Unmarshal([]byte(colorHistJSON.String), &fp.ColorHist)
+	}
+	if featureVecJSON.Valid && featureVecJSON.String != "" {
+		json.Unmarshal([]byte(featureVecJSON.String), &fp.FeatureVec)
+	}
+	if paletteJSON.Valid && paletteJSON.String != "" {
+		json.Unmarshal([]byte(paletteJSON.String), &fp.Palette)
+	}
+
+	fp.CreatedAt = createdAt
+	return fp, nil
+}
+
+// GetFingerprint retrieves a fingerprint by ID
+func (s *SQLiteStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint, error) {
+	fp, err := scanFingerprintRow(s.db.QueryRow(`
+        SELECT `+fingerprintColumns+`
         FROM fingerprints WHERE id = ?
-    `, string(imageID)).Scan(
-		&fp.ID, &metadataJSON, &phashesJSON, &qualityJSON,
-		&colorHistJSON, &featureVecJSON, &createdAt,
-	)
+    `, string(imageID)))
 
 	if err == sql.ErrNoRows {
 		return nil, api.ErrImageNotFound
@@ -239,55 +539,190 @@ func (s *SQLiteStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint
 		return nil, err
 	}
 
-	json.Unmarshal([]byte(metadataJSON), &fp.Metadata)
-	json.Unmarshal([]byte(phashesJSON), &fp.PHashes)
-	json.Unmarshal([]byte(qualityJSON), &fp.Quality)
+	return &fp, nil
+}
 
-	if colorHistJSON != "" {
-		json.Unmarshal([]byte(colorHistJSON), &fp.ColorHist)
+// GetFingerprintByPath retrieves the fingerprint indexed for a file path
+func (s *SQLiteStore) GetFingerprintByPath(path string) (*api.ImageFingerprint, error) {
+	var imageID string
+	err := s.db.QueryRow(`SELECT image_id FROM path_index WHERE path = ?`, path).Scan(&imageID)
+	if err == sql.ErrNoRows {
+		return nil, api.ErrImageNotFound
 	}
-	if featureVecJSON != "" {
-		json.Unmarshal([]byte(featureVecJSON), &fp.FeatureVec)
+	if err != nil {
+		return nil, err
 	}
 
-	fp.CreatedAt = createdAt
-	return &fp, nil
+	return s.GetFingerprint(api.ImageID(imageID))
+}
+
+// Exists reports whether a path is indexed using the path_index table alone
+func (s *SQLiteStore) Exists(path string) (bool, api.ImageID, error) {
+	var imageID string
+	err := s.db.QueryRow(`SELECT image_id FROM path_index WHERE path = ?`, path).Scan(&imageID)
+	if err == sql.ErrNoRows {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to query path index: %w", err)
+	}
+
+	return true, api.ImageID(imageID), nil
 }
 
 // GetAllFingerprints retrieves all fingerprints
 func (s *SQLiteStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
-	rows, err := s.db.Query(`SELECT id, metadata, phashes, quality, color_hist, feature_vec, created_at FROM fingerprints`)
+	var fingerprints []api.ImageFingerprint
+
+	err := s.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		fingerprints = append(fingerprints, fp)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var fingerprints []api.ImageFingerprint
+	return fingerprints, nil
+}
 
-	for rows.Next() {
-		var fp api.ImageFingerprint
-		var metadataJSON, phashesJSON, qualityJSON, colorHistJSON, featureVecJSON string
-		var createdAt time.Time
+// IterateFingerprints streams every fingerprint row to fn
+func (s *SQLiteStore) IterateFingerprints(ctx context.Context, fn func(api.ImageFingerprint) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+fingerprintColumns+` FROM fingerprints`)
+	if err != nil {
+		return fmt.Errorf("failed to query fingerprints: %w", err)
+	}
+	defer rows.Close()
 
-		if err := rows.Scan(&fp.ID, &metadataJSON, &phashesJSON, &qualityJSON, &colorHistJSON, &featureVecJSON, &createdAt); err != nil {
-			continue
+	for rows.Next() {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan fingerprint: %w", err)
 		}
+		if err := fn(fp); err != nil {
+			return err
+		}
+	}
 
-		json.Unmarshal([]byte(metadataJSON), &fp.Metadata)
-		json.Unmarshal([]byte(phashesJSON), &fp.PHashes)
-		json.Unmarshal([]byte(qualityJSON), &fp.Quality)
+	return rows.Err()
+}
 
-		fp.CreatedAt = createdAt
-		fingerprints = append(fingerprints, fp)
+// ListFingerprints returns a page of fingerprints ordered by ID
+func (s *SQLiteStore) ListFingerprints(offset, limit int) ([]api.ImageFingerprint, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	rows, err := s.db.Query(`SELECT `+fingerprintColumns+` FROM fingerprints ORDER BY id LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+	defer rows.Close()
+
+	fingerprints, err := s.scanFingerprints(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fingerprints: %w", err)
+	}
+	if fingerprints == nil {
+		fingerprints = []api.ImageFingerprint{}
+	}
+	return fingerprints, nil
+}
+
+// CountFingerprints returns the number of stored fingerprints
+func (s *SQLiteStore) CountFingerprints() (int64, error) {
+	var count int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM fingerprints`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count fingerprints: %w", err)
+	}
+	return count, nil
+}
+
+// FindByQualityRange returns fingerprints whose final score lies in [min, max]
+func (s *SQLiteStore) FindByQualityRange(min, max float64) ([]api.ImageFingerprint, error) {
+	rows, err := s.db.Query(`
+        SELECT `+fingerprintColumns+`
+        FROM fingerprints
+        WHERE json_extract(quality, '$.final_score') BETWEEN ? AND ?
+        ORDER BY id
+    `, min, max)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quality range: %w", err)
+	}
+	defer rows.Close()
+
+	fingerprints, err := s.scanFingerprints(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quality range: %w", err)
+	}
+	if fingerprints == nil {
+		fingerprints = []api.ImageFingerprint{}
+	}
+	return fingerprints, nil
+}
+
+// takenAtExpr is the indexed expression for a fingerprint's EXIF capture time as a Julian day
+const takenAtExpr = `julianday(json_extract(metadata, '$.exif.taken_at'))`
+
+// FindByCaptureDate returns fingerprints captured within [from, to]
+func (s *SQLiteStore) FindByCaptureDate(from, to time.Time) ([]api.ImageFingerprint, error) {
+	// Go encodes a missing capture time as year 1, which is excluded explicitly
+	rows, err := s.db.Query(`
+        SELECT `+fingerprintColumns+`
+        FROM fingerprints
+        WHERE `+takenAtExpr+` BETWEEN julianday(?) AND julianday(?)
+          AND `+takenAtExpr+` > julianday('0001-01-02')
+        ORDER BY id
+    `, from.UTC().Format(time.RFC3339Nano), to.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capture dates: %w", err)
 	}
+	defer rows.Close()
 
+	fingerprints, err := s.scanFingerprints(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query capture dates: %w", err)
+	}
+	if fingerprints == nil {
+		fingerprints = []api.ImageFingerprint{}
+	}
 	return fingerprints, nil
 }
 
+// FindNearLocation returns geotagged fingerprints within radiusKm of (lat, lon).
+// SQL narrows the scan to geotagged rows; the distance filter runs in Go.
+func (s *SQLiteStore) FindNearLocation(lat, lon, radiusKm float64) ([]api.ImageFingerprint, error) {
+	rows, err := s.db.Query(`
+        SELECT ` + fingerprintColumns + `
+        FROM fingerprints
+        WHERE json_extract(metadata, '$.exif.has_gps') = 1
+        ORDER BY id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query location: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []api.ImageFingerprint{}
+	for rows.Next() {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query location: %w", err)
+		}
+		if withinRadius(fp, lat, lon, radiusKm) {
+			matches = append(matches, fp)
+		}
+	}
+
+	return matches, rows.Err()
+}
+
 // FindBySHA256 finds fingerprints by hash
 func (s *SQLiteStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error) {
 	rows, err := s.db.Query(`
-        SELECT f.id, f.metadata, f.phashes, f.quality, f.color_hist, f.feature_vec, f.created_at
+        SELECT f.id, f.metadata, f.phashes, f.quality, f.color_hist, f.feature_vec, f.palette, f.created_at
         FROM fingerprints f
         JOIN sha256_index s ON f.id = s.image_id
         WHERE s.sha256 = ?
@@ -302,6 +737,13 @@ func (s *SQLiteStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error)
 
 // FindSimilarHashes finds similar perceptual hashes
 func (s *SQLiteStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashType string) ([]api.ImageFingerprint, error) {
+	if err := checkHashType(hashType); err != nil {
+		return nil, err
+	}
+	if targetHash == 0 {
+		return nil, nil // a zero hash was never computed
+	}
+
 	all, err := s.GetAllFingerprints()
 	if err != nil {
 		return nil, err
@@ -336,23 +778,40 @@ func (s *SQLiteStore) scanFingerprints(rows *sql.Rows) ([]api.ImageFingerprint,
 	var fingerprints []api.ImageFingerprint
 
 	for rows.Next() {
-		var fp api.ImageFingerprint
-		var metadataJSON, phashesJSON, qualityJSON, colorHistJSON, featureVecJSON string
-		var createdAt time.Time
-
-		if err := rows.Scan(&fp.ID, &metadataJSON, &phashesJSON, &qualityJSON, &colorHistJSON, &featureVecJSON, &createdAt); err != nil {
+		fp, err := scanFingerprintRow(rows)
+		if err != nil {
 			return nil, err
 		}
+		fingerprints = append(fingerprints, fp)
+	}
 
-		json.Unmarshal([]byte(metadataJSON), &fp.Metadata)
-		json.Unmarshal([]byte(phashesJSON), &fp.PHashes)
-		json.Unmarshal([]byte(qualityJSON), &fp.Quality)
+	return fingerprints, rows.Err()
+}
 
-		fp.CreatedAt = createdAt
-		fingerprints = append(fingerprints, fp)
+// Backup writes a consistent copy of the database to w, using VACUUM INTO
+// a temporary file so the live database stays open
+func (s *SQLiteStore) Backup(w io.Writer) error {
+	dir, err := os.MkdirTemp("", "imaged-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
+	defer os.RemoveAll(dir)
 
-	return fingerprints, nil
+	snapshot := filepath.Join(dir, "index.sqlite")
+	if _, err := s.db.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+		return fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	file, err := os.Open(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(w, file); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return nil
 }
 
 // Close closes database
@@ -362,6 +821,9 @@ func (s *SQLiteStore) Close() error {
 
 // Compact vacuum database
 func (s *SQLiteStore) Compact() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	_, err := s.db.Exec("VACUUM")
 	return err
 }
@@ -379,6 +841,9 @@ func (s *SQLiteStore) Compact() error {
 
 // DeleteFingerprint removes a fingerprint from SQLite
 func (s *SQLiteStore) DeleteFingerprint(imageID api.ImageID) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -398,13 +863,13 @@ func (s *SQLiteStore) DeleteFingerprint(imageID api.ImageID) error {
 	}
 
 	// Delete from sha256 index
-	_, err = tx.Exec(`DELETE FROM sha256_index WHERE sha256 = ?`, fp.Metadata.SHA256)
+	_, err = tx.Exec(`DELETE FROM sha256_index WHERE image_id = ?`, string(imageID))
 	if err != nil {
 		return fmt.Errorf("failed to delete sha256 index: %w", err)
 	}
 
 	// Delete from path index
-	_, err = tx.Exec(`DELETE FROM path_index WHERE path = ?`, fp.Metadata.Path)
+	_, err = tx.Exec(`DELETE FROM path_index WHERE path = ? AND image_id = ?`, fp.Metadata.Path, string(imageID))
 	if err != nil {
 		return fmt.Errorf("failed to delete path index: %w", err)
 	}
@@ -415,5 +880,64 @@ func (s *SQLiteStore) DeleteFingerprint(imageID api.ImageID) error {
 		return fmt.Errorf("failed to delete perceptual index: %w", err)
 	}
 
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+
 	return tx.Commit()
 }
+
+// SaveGroups replaces the stored duplicate groups, one row per group
+func (s *SQLiteStore) SaveGroups(groups []api.DuplicateGroup) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM duplicate_groups`); err != nil {
+		return fmt.Errorf("failed to clear groups: %w", err)
+	}
+	for i, group := range groups {
+		data, err := json.Marshal(group)
+		if err != nil {
+			return fmt.Errorf("failed to marshal group: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO duplicate_groups (seq, data) VALUES (?, ?)`, i, string(data)); err != nil {
+			return fmt.Errorf("failed to save group: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetGroups returns the stored duplicate groups in the order they were saved
+func (s *SQLiteStore) GetGroups() ([]api.DuplicateGroup, error) {
+	return queryGroups(s.db)
+}
+
+// queryGroups reads the duplicate_groups table shared by the SQL stores
+func queryGroups(db *sql.DB) ([]api.DuplicateGroup, error) {
+	rows, err := db.Query(`SELECT data FROM duplicate_groups ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []api.DuplicateGroup
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		var group api.DuplicateGroup
+		if err := json.Unmarshal([]byte(data), &group); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,84 @@
+package index
+
+import "fmt"
+
+// duplicateTally accumulates the files of an index by content and by pHash
+// to work out how many bytes removing their duplicates would reclaim
+type duplicateTally struct {
+	bySHA   map[string]*sizeGroup
+	byPHash map[uint64]map[string]int64 // distinct contents sharing a pHash, by SHA256
+	unnamed int
+}
+
+// sizeGroup sums the sizes of a group's files and remembers the largest
+type sizeGroup struct {
+	count   int
+	total   int64
+	largest int64
+}
+
+// newDuplicateTally creates an empty tally
+func newDuplicateTally() *duplicateTally {
+	return &duplicateTally{
+		bySHA:   make(map[string]*sizeGroup),
+		byPHash: make(map[uint64]map[string]int64),
+	}
+}
+
+// add records one indexed file. An empty sha or a zero pHash leaves the file
+// out of the exact or near groups respectively.
+func (d *duplicateTally) add(sha string, pHash uint64, size int64) {
+	if sha != "" {
+		group, ok := d.bySHA[sha]
+		if !ok {
+			group = &sizeGroup{}
+			d.bySHA[sha] = group
+		}
+		group.count++
+		group.total += size
+		group.largest = max(group.largest, size)
+	}
+
+	if pHash != 0 {
+		contents, ok := d.byPHash[pHash]
+		if !ok {
+			contents = make(map[string]int64)
+			d.byPHash[pHash] = contents
+		}
+		// Without a content hash a file can only be told apart by itself
+		if sha == "" {
+			d.unnamed++
+			sha = fmt.Sprintf("unnamed_%d", d.unnamed)
+		}
+		contents[sha] = max(contents[sha], size)
+	}
+}
+
+// groups counts the SHA256 values shared by more than one file
+func (d *duplicateTally) groups() int {
+	groups := 0
+	for _, group := range d.bySHA {
+		if group.count > 1 {
+			groups++
+		}
+	}
+	return groups
+}
+
+// reclaimable returns the bytes freed by keeping the largest file of each
+// exact duplicate group, and the further bytes freed by then keeping only the
+// largest of the distinct contents that share a pHash
+func (d *duplicateTally) reclaimable() (exact, near int64) {
+	for _, group := range d.bySHA {
+		exact += group.total - group.largest
+	}
+	for _, contents := range d.byPHash {
+		var total, largest int64
+		for _, size := range contents {
+			total += size
+			largest = max(largest, size)
+		}
+		near += total - largest
+	}
+	return exact, near
+}
@@ -1,8 +1,15 @@
 package index
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/HaiderBassem/imaged/internal/metadata"
 	"github.com/HaiderBassem/imaged/pkg/api"
 )
 
@@ -13,55 +20,190 @@ const (
 	StoreTypeBoltDB StoreType = iota
 	StoreTypeSQLite
 	StoreTypeMemory
+	// StoreTypePostgres takes a connection string in Config.Path and needs the postgres build tag
+	StoreTypePostgres
 )
 
-// Config defines index storage configuration
+// Config defines index storage configuration. A ReadOnly store opens an
+// existing index without locking out other readers, and its write methods
+// return ErrReadOnly.
 type Config struct {
 	Type     StoreType
 	Path     string
 	ReadOnly bool
 }
 
+// ErrReadOnly is returned by write methods of a store opened read-only
+var ErrReadOnly = errors.New("index is opened read-only")
+
+// ErrUnknownHashType is returned by FindSimilarHashes for a hash type other
+// than "ahash", "phash", "dhash" or "whash"
+var ErrUnknownHashType = errors.New("unknown hash type")
+
+// checkHashType validates the hashType argument of FindSimilarHashes
+func checkHashType(hashType string) error {
+	switch hashType {
+	case "ahash", "phash", "dhash", "whash":
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownHashType, hashType)
+	}
+}
+
 // NewStore creates a new index store based on configuration
 func NewStore(cfg Config) (Store, error) {
 	switch cfg.Type {
 	case StoreTypeBoltDB:
-		return NewBoltStore(cfg.Path)
+		return openBoltStore(cfg.Path, cfg.ReadOnly)
 	case StoreTypeSQLite:
-		return NewSQLiteStore(cfg.Path)
+		return openSQLiteStore(cfg.Path, cfg.ReadOnly)
 	case StoreTypeMemory:
 		return NewMemoryStore()
+	case StoreTypePostgres:
+		return openPostgresStore(cfg.Path, cfg.ReadOnly)
 	default:
 		return nil, fmt.Errorf("unsupported store type: %v", cfg.Type)
 	}
 }
 
-// MemoryStore is an in-memory implementation for testing
+// filterFingerprints streams a store through iterate and keeps the fingerprints matching keep
+func filterFingerprints(iterate func(context.Context, func(api.ImageFingerprint) error) error, keep func(api.ImageFingerprint) bool) ([]api.ImageFingerprint, error) {
+	matches := []api.ImageFingerprint{}
+
+	err := iterate(context.Background(), func(fp api.ImageFingerprint) error {
+		if keep(fp) {
+			matches = append(matches, fp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// inQualityRange reports whether fp's final score lies in [min, max]
+func inQualityRange(fp api.ImageFingerprint, min, max float64) bool {
+	return fp.Quality.FinalScore >= min && fp.Quality.FinalScore <= max
+}
+
+// takenBetween reports whether fp has an EXIF capture time within [from, to]
+func takenBetween(fp api.ImageFingerprint, from, to time.Time) bool {
+	if fp.Metadata.EXIF == nil || fp.Metadata.EXIF.TakenAt.IsZero() {
+		return false
+	}
+	takenAt := fp.Metadata.EXIF.TakenAt
+	return !takenAt.Before(from) && !takenAt.After(to)
+}
+
+// withinRadius reports whether fp is geotagged within radiusKm of (lat, lon)
+func withinRadius(fp api.ImageFingerprint, lat, lon, radiusKm float64) bool {
+	exif := fp.Metadata.EXIF
+	if exif == nil || !exif.HasGPS {
+		return false
+	}
+	return metadata.HaversineKm(lat, lon, exif.GPSLat, exif.GPSLon) <= radiusKm
+}
+
+// MemoryStore is an in-memory implementation for testing. It is safe for
+// concurrent use.
 type MemoryStore struct {
+	mu           sync.RWMutex
 	fingerprints map[api.ImageID]api.ImageFingerprint
-	sha256Index  map[string]api.ImageID
+	sha256Index  map[string][]api.ImageID
 	pathIndex    map[string]api.ImageID
+	groups       []api.DuplicateGroup
 }
 
 // NewMemoryStore creates a new in-memory store
 func NewMemoryStore() (*MemoryStore, error) {
 	return &MemoryStore{
 		fingerprints: make(map[api.ImageID]api.ImageFingerprint),
-		sha256Index:  make(map[string]api.ImageID),
+		sha256Index:  make(map[string][]api.ImageID),
 		pathIndex:    make(map[string]api.ImageID),
 	}, nil
 }
 
 // SaveFingerprint stores a fingerprint in memory
 func (m *MemoryStore) SaveFingerprint(fp api.ImageFingerprint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saveLocked(fp)
+	return nil
+}
+
+// SaveFingerprints stores a batch of fingerprints in memory
+func (m *MemoryStore) SaveFingerprints(fps []api.ImageFingerprint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, fp := range fps {
+		m.saveLocked(fp)
+	}
+	return nil
+}
+
+// saveLocked stores a fingerprint; the caller holds the write lock
+func (m *MemoryStore) saveLocked(fp api.ImageFingerprint) {
+	if previous, exists := m.fingerprints[fp.ID]; exists {
+		m.unindexSHA256(previous.Metadata.SHA256, fp.ID)
+		if m.pathIndex[previous.Metadata.Path] == fp.ID {
+			delete(m.pathIndex, previous.Metadata.Path)
+		}
+	}
+
 	m.fingerprints[fp.ID] = fp
-	m.sha256Index[fp.Metadata.SHA256] = fp.ID
+	m.sha256Index[fp.Metadata.SHA256] = append(m.sha256Index[fp.Metadata.SHA256], fp.ID)
 	m.pathIndex[fp.Metadata.Path] = fp.ID
+	m.groups = nil
+}
+
+// UpdateMetadata replaces a fingerprint's metadata and its SHA256 and path entries
+func (m *MemoryStore) UpdateMetadata(imageID api.ImageID, meta api.ImageMetadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fp, exists := m.fingerprints[imageID]
+	if !exists {
+		return api.ErrImageNotFound
+	}
+
+	m.unindexSHA256(fp.Metadata.SHA256, imageID)
+	if m.pathIndex[fp.Metadata.Path] == imageID {
+		delete(m.pathIndex, fp.Metadata.Path)
+	}
+
+	fp.Metadata = meta
+	m.fingerprints[imageID] = fp
+	m.sha256Index[meta.SHA256] = append(m.sha256Index[meta.SHA256], imageID)
+	m.pathIndex[meta.Path] = imageID
+	m.groups = nil
 	return nil
 }
 
+// unindexSHA256 drops imageID from the images sharing hash; the caller holds the write lock
+func (m *MemoryStore) unindexSHA256(hash string, imageID api.ImageID) {
+	var remaining []api.ImageID
+	for _, id := range m.sha256Index[hash] {
+		if id != imageID {
+			remaining = append(remaining, id)
+		}
+	}
+
+	if len(remaining) == 0 {
+		delete(m.sha256Index, hash)
+		return
+	}
+	m.sha256Index[hash] = remaining
+}
+
 // GetFingerprint retrieves a fingerprint from memory
 func (m *MemoryStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	fp, exists := m.fingerprints[imageID]
 	if !exists {
 		return nil, api.ErrImageNotFound
@@ -69,8 +211,32 @@ func (m *MemoryStore) GetFingerprint(imageID api.ImageID) (*api.ImageFingerprint
 	return &fp, nil
 }
 
+// GetFingerprintByPath retrieves a fingerprint from memory by file path
+func (m *MemoryStore) GetFingerprintByPath(path string) (*api.ImageFingerprint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fp, exists := m.fingerprints[m.pathIndex[path]]
+	if !exists {
+		return nil, api.ErrImageNotFound
+	}
+	return &fp, nil
+}
+
+// Exists reports whether a path is indexed in memory
+func (m *MemoryStore) Exists(path string) (bool, api.ImageID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, exists := m.pathIndex[path]
+	return exists, id, nil
+}
+
 // GetAllFingerprints returns all fingerprints from memory
 func (m *MemoryStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	fingerprints := make([]api.ImageFingerprint, 0, len(m.fingerprints))
 	for _, fp := range m.fingerprints {
 		fingerprints = append(fingerprints, fp)
@@ -78,23 +244,110 @@ func (m *MemoryStore) GetAllFingerprints() ([]api.ImageFingerprint, error) {
 	return fingerprints, nil
 }
 
+// IterateFingerprints calls fn for each fingerprint in memory. It walks a
+// snapshot, so fn may write to the store.
+func (m *MemoryStore) IterateFingerprints(ctx context.Context, fn func(api.ImageFingerprint) error) error {
+	fingerprints, err := m.GetAllFingerprints()
+	if err != nil {
+		return err
+	}
+
+	for _, fp := range fingerprints {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(fp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFingerprints returns a page of fingerprints ordered by ID
+func (m *MemoryStore) ListFingerprints(offset, limit int) ([]api.ImageFingerprint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.fingerprints))
+	for id := range m.fingerprints {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(ids) {
+		offset = len(ids)
+	}
+	end := len(ids)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	fingerprints := make([]api.ImageFingerprint, 0, end-offset)
+	for _, id := range ids[offset:end] {
+		fingerprints = append(fingerprints, m.fingerprints[api.ImageID(id)])
+	}
+	return fingerprints, nil
+}
+
+// CountFingerprints returns the number of fingerprints in memory
+func (m *MemoryStore) CountFingerprints() (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return int64(len(m.fingerprints)), nil
+}
+
+// FindByQualityRange returns fingerprints whose final score lies in [min, max]
+func (m *MemoryStore) FindByQualityRange(min, max float64) ([]api.ImageFingerprint, error) {
+	return filterFingerprints(m.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return inQualityRange(fp, min, max)
+	})
+}
+
+// FindByCaptureDate returns fingerprints captured within [from, to]
+func (m *MemoryStore) FindByCaptureDate(from, to time.Time) ([]api.ImageFingerprint, error) {
+	return filterFingerprints(m.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return takenBetween(fp, from, to)
+	})
+}
+
+// FindNearLocation returns geotagged fingerprints within radiusKm of (lat, lon)
+func (m *MemoryStore) FindNearLocation(lat, lon, radiusKm float64) ([]api.ImageFingerprint, error) {
+	return filterFingerprints(m.IterateFingerprints, func(fp api.ImageFingerprint) bool {
+		return withinRadius(fp, lat, lon, radiusKm)
+	})
+}
+
 // FindBySHA256 finds image by SHA256 hash in memory
 func (m *MemoryStore) FindBySHA256(hash string) ([]api.ImageFingerprint, error) {
-	imageID, exists := m.sha256Index[hash]
-	if !exists {
-		return []api.ImageFingerprint{}, nil
-	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	fp, exists := m.fingerprints[imageID]
-	if !exists {
-		return []api.ImageFingerprint{}, nil
+	matches := []api.ImageFingerprint{}
+	for _, imageID := range m.sha256Index[hash] {
+		if fp, exists := m.fingerprints[imageID]; exists {
+			matches = append(matches, fp)
+		}
 	}
 
-	return []api.ImageFingerprint{fp}, nil
+	return matches, nil
 }
 
 // FindSimilarHashes placeholder for memory store
 func (m *MemoryStore) FindSimilarHashes(targetHash uint64, maxDistance int, hashType string) ([]api.ImageFingerprint, error) {
+	if err := checkHashType(hashType); err != nil {
+		return nil, err
+	}
+	if targetHash == 0 {
+		return nil, nil // a zero hash was never computed
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	// Simple implementation that checks all fingerprints
 	var similar []api.ImageFingerprint
 	for _, fp := range m.fingerprints {
@@ -126,26 +379,54 @@ func (m *MemoryStore) FindSimilarHashes(targetHash uint64, maxDistance int, hash
 
 // DeleteFingerprint removes a fingerprint from memory
 func (m *MemoryStore) DeleteFingerprint(imageID api.ImageID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	fp, exists := m.fingerprints[imageID]
 	if !exists {
 		return api.ErrImageNotFound
 	}
 
 	delete(m.fingerprints, imageID)
-	delete(m.sha256Index, fp.Metadata.SHA256)
-	delete(m.pathIndex, fp.Metadata.Path)
+	m.unindexSHA256(fp.Metadata.SHA256, imageID)
+	if m.pathIndex[fp.Metadata.Path] == imageID {
+		delete(m.pathIndex, fp.Metadata.Path)
+	}
+	m.groups = nil
 
 	return nil
 }
 
+// SaveGroups keeps a copy of the duplicate groups in memory
+func (m *MemoryStore) SaveGroups(groups []api.DuplicateGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.groups = append([]api.DuplicateGroup(nil), groups...)
+	return nil
+}
+
+// GetGroups returns the duplicate groups saved in memory
+func (m *MemoryStore) GetGroups() ([]api.DuplicateGroup, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]api.DuplicateGroup(nil), m.groups...), nil
+}
+
 // GetStats returns memory store statistics
 func (m *MemoryStore) GetStats() (*Stats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var totalSize int64
 	var totalQuality float64
+	duplicates := newDuplicateTally()
 
 	for _, fp := range m.fingerprints {
 		totalSize += fp.Metadata.SizeBytes
 		totalQuality += fp.Quality.FinalScore
+		duplicates.add(fp.Metadata.SHA256, fp.PHashes.PHash, fp.Metadata.SizeBytes)
 	}
 
 	count := int64(len(m.fingerprints))
@@ -154,21 +435,47 @@ func (m *MemoryStore) GetStats() (*Stats, error) {
 		avgQuality = totalQuality / float64(count)
 	}
 
+	exactBytes, nearBytes := duplicates.reclaimable()
 	return &Stats{
-		TotalImages:    count,
-		TotalSizeBytes: totalSize,
-		AverageQuality: avgQuality,
+		TotalImages:         count,
+		TotalSizeBytes:      totalSize,
+		AverageQuality:      avgQuality,
+		DuplicateGroups:     duplicates.groups(),
+		ExactDuplicateBytes: exactBytes,
+		NearDuplicateBytes:  nearBytes,
 	}, nil
 }
 
+// Backup writes every fingerprint in memory to w as a JSON backup
+func (m *MemoryStore) Backup(w io.Writer) error {
+	fingerprints, err := m.ListFingerprints(0, 0)
+	if err != nil {
+		return err
+	}
+	return writeJSONBackup(w, fingerprints)
+}
+
 // Close cleans up memory store
 func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.fingerprints = nil
 	m.sha256Index = nil
 	m.pathIndex = nil
 	return nil
 }
 
+// SchemaVersion always reports the current version for memory stores
+func (m *MemoryStore) SchemaVersion() (int, error) {
+	return CurrentSchemaVersion, nil
+}
+
+// Migrate is a no-op for memory store
+func (m *MemoryStore) Migrate() error {
+	return nil
+}
+
 // Compact is a no-op for memory store
 func (m *MemoryStore) Compact() error {
 	return nil
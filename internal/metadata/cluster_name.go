@@ -0,0 +1,83 @@
+package metadata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// ReverseGeocoder turns coordinates in decimal degrees into a place name
+// for cluster labels, such as "Paris"
+type ReverseGeocoder interface {
+	PlaceName(lat, lon float64) (string, error)
+}
+
+// CoordinateGeocoder names a place by its coordinates rounded to about a
+// kilometer, for use without a geocoding service
+type CoordinateGeocoder struct{}
+
+// PlaceName formats lat and lon with two decimals
+func (CoordinateGeocoder) PlaceName(lat, lon float64) (string, error) {
+	return fmt.Sprintf("%.2f,%.2f", lat, lon), nil
+}
+
+// ClusterNamer labels clusters of photos from their members' EXIF data
+type ClusterNamer struct {
+	geocoder ReverseGeocoder
+}
+
+// NewClusterNamer creates a namer that names places with geocoder; a nil
+// geocoder uses CoordinateGeocoder
+func NewClusterNamer(geocoder ReverseGeocoder) *ClusterNamer {
+	if geocoder == nil {
+		geocoder = CoordinateGeocoder{}
+	}
+	return &ClusterNamer{geocoder: geocoder}
+}
+
+// Name returns a label such as "2023-07 Paris": the month most members were
+// captured in, ties going to the earliest, followed by the place at the
+// mean position of the geotagged members. Either part is left out when no
+// member has the data, so a cluster without EXIF gets an empty name.
+func (n *ClusterNamer) Name(members []api.ImageFingerprint) string {
+	months := make(map[string]int)
+	var lat, lon float64
+	var geotagged int
+	for _, fp := range members {
+		exif := fp.Metadata.EXIF
+		if exif == nil {
+			continue
+		}
+		if !exif.TakenAt.IsZero() {
+			months[exif.TakenAt.Format("2006-01")]++
+		}
+		if exif.HasGPS {
+			lat += exif.GPSLat
+			lon += exif.GPSLon
+			geotagged++
+		}
+	}
+
+	var parts []string
+	var month string
+	for candidate, count := range months {
+		if month == "" || count > months[month] || (count == months[month] && candidate < month) {
+			month = candidate
+		}
+	}
+	if month != "" {
+		parts = append(parts, month)
+	}
+
+	if geotagged > 0 {
+		lat, lon = lat/float64(geotagged), lon/float64(geotagged)
+		place, err := n.geocoder.PlaceName(lat, lon)
+		if err != nil || place == "" {
+			place, _ = CoordinateGeocoder{}.PlaceName(lat, lon)
+		}
+		parts = append(parts, place)
+	}
+
+	return strings.Join(parts, " ")
+}
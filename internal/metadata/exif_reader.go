@@ -1,14 +1,18 @@
 package metadata
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/rwcarlsen/goexif/exif"
 	"github.com/rwcarlsen/goexif/mknote"
+	"github.com/rwcarlsen/goexif/tiff"
 	"github.com/sirupsen/logrus"
 )
 
@@ -35,8 +39,14 @@ func (e *EXIFReader) ExtractEXIF(filePath string) (*api.EXIFInfo, error) {
 	}
 	defer file.Close()
 
+	return e.ExtractEXIFFrom(file, filePath)
+}
+
+// ExtractEXIFFrom extracts EXIF metadata from the image data in r; name
+// identifies the image in log messages
+func (e *EXIFReader) ExtractEXIFFrom(r io.Reader, name string) (*api.EXIFInfo, error) {
 	// Decode EXIF data
-	x, err := exif.Decode(file)
+	x, err := exif.Decode(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode EXIF: %w", err)
 	}
@@ -73,7 +83,7 @@ func (e *EXIFReader) ExtractEXIF(filePath string) (*api.EXIFInfo, error) {
 
 	if exposure, err := x.Get(exif.ExposureTime); err == nil {
 		if num, denom, err := exposure.Rat2(0); err == nil {
-			if denom != 0 {
+			if num != 0 && denom != 0 {
 				exifInfo.Exposure = fmt.Sprintf("1/%d", denom/num)
 			}
 		}
@@ -95,13 +105,9 @@ func (e *EXIFReader) ExtractEXIF(filePath string) (*api.EXIFInfo, error) {
 		}
 	}
 
-	// Extract date/time
-	if dateTime, err := x.Get(exif.DateTime); err == nil {
-		if dateTimeStr, err := dateTime.StringVal(); err == nil {
-			if takenAt, err := time.Parse("2006:01:02 15:04:05", dateTimeStr); err == nil {
-				exifInfo.TakenAt = takenAt
-			}
-		}
+	// Extract capture date/time (DateTimeOriginal, falling back to DateTime)
+	if takenAt, err := captureTime(x); err == nil {
+		exifInfo.TakenAt = takenAt
 	}
 
 	// Extract GPS coordinates
@@ -111,10 +117,146 @@ func (e *EXIFReader) ExtractEXIF(filePath string) (*api.EXIFInfo, error) {
 		exifInfo.HasGPS = true
 	}
 
-	e.logger.Debugf("Extracted EXIF metadata from %s: Camera=%s", filePath, exifInfo.CameraModel)
+	e.logger.Debugf("Extracted EXIF metadata from %s: Camera=%s", name, exifInfo.CameraModel)
 	return exifInfo, nil
 }
 
+// The EXIF 2.31 time zone tags, which goexif does not map
+const (
+	offsetTime         exif.FieldName = "OffsetTime"
+	offsetTimeOriginal exif.FieldName = "OffsetTimeOriginal"
+)
+
+var offsetFields = map[uint16]exif.FieldName{
+	0x9010: offsetTime,
+	0x9011: offsetTimeOriginal,
+}
+
+// captureTime returns the EXIF capture time including the sub-second digits
+// and UTC offset the camera recorded alongside it. goexif's DateTime only has
+// whole seconds, which is too coarse to tell burst frames apart.
+func captureTime(x *exif.Exif) (time.Time, error) {
+	takenAt, err := x.DateTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Each timestamp tag has its own sub-second and offset companions
+	subSecField, offsetField := exif.SubSecTimeOriginal, offsetTimeOriginal
+	if _, err := x.Get(exif.DateTimeOriginal); err != nil {
+		subSecField, offsetField = exif.SubSecTime, offsetTime
+	}
+
+	nanos := 0
+	if digits, ok := stringField(x, subSecField); ok {
+		nanos = parseSubSec(digits)
+	}
+
+	loc := takenAt.Location()
+	loadOffsetTags(x)
+	if offset, ok := stringField(x, offsetField); ok {
+		if parsed, err := time.Parse("-07:00", offset); err == nil {
+			_, seconds := parsed.Zone()
+			loc = time.FixedZone("", seconds)
+		}
+	}
+
+	return time.Date(takenAt.Year(), takenAt.Month(), takenAt.Day(),
+		takenAt.Hour(), takenAt.Minute(), takenAt.Second(), nanos, loc), nil
+}
+
+// loadOffsetTags loads the time zone tags from the Exif sub-IFD
+func loadOffsetTags(x *exif.Exif) {
+	pointer, err := x.Get(exif.ExifIFDPointer)
+	if err != nil || x.Tiff == nil {
+		return
+	}
+	offset, err := pointer.Int64(0)
+	if err != nil {
+		return
+	}
+
+	r := bytes.NewReader(x.Raw)
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	dir, _, err := tiff.DecodeDir(r, x.Tiff.Order)
+	if err != nil {
+		return
+	}
+	x.LoadTags(dir, offsetFields, false)
+}
+
+// stringField returns the trimmed value of an ASCII tag
+func stringField(x *exif.Exif, name exif.FieldName) (string, bool) {
+	tag, err := x.Get(name)
+	if err != nil {
+		return "", false
+	}
+	value, err := tag.StringVal()
+	if err != nil {
+		return "", false
+	}
+	value = strings.Trim(value, " \x00")
+	return value, value != ""
+}
+
+// parseSubSec converts SubSecTime digits ("123" for .123s) to nanoseconds
+func parseSubSec(digits string) int {
+	if len(digits) > 9 {
+		digits = digits[:9]
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 0 {
+		return 0
+	}
+	for i := len(digits); i < 9; i++ {
+		n *= 10
+	}
+	return n
+}
+
+// ReadOrientation returns the EXIF orientation tag (1-8), or 1 when the file has none
+func (e *EXIFReader) ReadOrientation(filePath string) (int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 1, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return e.ReadOrientationFrom(file), nil
+}
+
+// ReadOrientationFrom returns the EXIF orientation tag (1-8) of the image
+// data in r, or 1 when it has none
+func (e *EXIFReader) ReadOrientationFrom(r io.Reader) int {
+	x, err := exif.Decode(r)
+	if err != nil {
+		return 1
+	}
+
+	tag, err := x.Get(exif.Orientation)
+	if err != nil {
+		return 1
+	}
+
+	orientation, err := tag.Int(0)
+	if err != nil || orientation < 1 || orientation > 8 {
+		return 1
+	}
+
+	return orientation
+}
+
+// DisplayDimensions returns the dimensions after applying an EXIF orientation;
+// orientations 5-8 rotate by 90 degrees and swap width and height
+func DisplayDimensions(width, height, orientation int) (int, int) {
+	if orientation >= 5 && orientation <= 8 {
+		return height, width
+	}
+	return width, height
+}
+
 // HasEXIFData checks if a file contains EXIF metadata
 func (e *EXIFReader) HasEXIFData(filePath string) (bool, error) {
 	file, err := os.Open(filePath)
@@ -5,13 +5,17 @@ import (
 	"math"
 
 	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 	"github.com/sirupsen/logrus"
 )
 
 // Analyzer performs comprehensive image quality assessment
 type Analyzer struct {
-	config Config
-	logger *logrus.Logger
+	config      Config
+	compression *CompressionAnalyzer
+	sharpness   *SharpnessAnalyzer
+	colorCast   *ColorCastAnalyzer
+	logger      *logrus.Logger
 }
 
 // Config defines quality analysis parameters and thresholds
@@ -22,9 +26,40 @@ type Config struct {
 	MinExposure        float64
 	MaxExposure        float64
 	MinContrast        float64
-	CompressionQuality float64
+	CompressionQuality float64 // compression artifacts above 1-CompressionQuality are reported
+	MaxColorCast       float64
+	TrimBorders        bool // measure contrast without near-constant border rows and columns
+	ExposureMethod     ExposureMethod
+	SharpnessSubject   SharpnessSubject
+	SubjectWeight      float64 // share of the sharpness score given to the subject region
 }
 
+// SharpnessSubject selects the region whose sharpness stands for the subject,
+// so a sharp subject against an intentionally blurred background scores well
+type SharpnessSubject int
+
+const (
+	// SubjectWholeFrame averages sharpness over the whole frame
+	SubjectWholeFrame SharpnessSubject = iota
+	// SubjectCenter treats the central half of the frame as the subject
+	SubjectCenter
+	// SubjectSharpest treats the sharpest of the center and corner regions as the subject
+	SubjectSharpest
+)
+
+// ExposureMethod selects how exposure is measured from the luminance histogram
+type ExposureMethod int
+
+const (
+	// ExposureMean averages luminance, pushed further out when more than 30%
+	// of the pixels are darker than 0.1 or brighter than 0.9
+	ExposureMean ExposureMethod = iota
+	// ExposurePercentile takes the median luminance, pulled towards 0.5 by the
+	// interquartile spread, so high-contrast scenes such as night shots or
+	// snow are not scored as badly exposed for their dark or bright areas
+	ExposurePercentile
+)
+
 // DefaultConfig returns sensible default quality analysis configuration
 func DefaultConfig() Config {
 	return Config{
@@ -35,6 +70,8 @@ func DefaultConfig() Config {
 		MaxExposure:        0.9,
 		MinContrast:        0.2,
 		CompressionQuality: 0.8,
+		MaxColorCast:       0.3,
+		SubjectWeight:      0.7,
 	}
 }
 
@@ -44,8 +81,11 @@ func NewAnalyzer(cfg Config) *Analyzer {
 	logger.SetLevel(logrus.InfoLevel)
 
 	return &Analyzer{
-		config: cfg,
-		logger: logger,
+		config:      cfg,
+		compression: NewCompressionAnalyzer(),
+		sharpness:   NewSharpnessAnalyzer(),
+		colorCast:   NewColorCastAnalyzer(),
+		logger:      logger,
 	}
 }
 
@@ -87,6 +127,7 @@ func (a *Analyzer) Analyze(img image.Image) (*api.ImageQuality, error) {
 	if err != nil {
 		a.logger.Warnf("Color cast analysis failed: %v", err)
 	}
+	quality.ColorTemperature, quality.ColorTemperatureScore = a.colorCast.DetectColorTemperature(img)
 
 	// Calculate final composite score
 	quality.FinalScore = a.calculateFinalScore(quality)
@@ -97,17 +138,9 @@ func (a *Analyzer) Analyze(img image.Image) (*api.ImageQuality, error) {
 	return quality, nil
 }
 
+// toGray converts img to luma for the sharpness, noise, exposure and contrast analyses
 func toGray(img image.Image) *image.Gray {
-	bounds := img.Bounds()
-	gray := image.NewGray(bounds)
-
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			gray.Set(x, y, img.At(x, y))
-		}
-	}
-
-	return gray
+	return pkgimaging.Grayscale(img)
 }
 
 // analyzeSharpness calculates image sharpness using Laplacian variance method
@@ -146,9 +179,32 @@ func (a *Analyzer) analyzeSharpness(gray *image.Gray) (float64, error) {
 	variance := sum / float64(count)
 	normalized := math.Min(variance/100.0, 1.0) // Assuming max variance around 100
 
+	if a.config.SharpnessSubject != SubjectWholeFrame {
+		normalized = a.weightSubjectSharpness(gray, normalized)
+	}
+
 	return normalized, nil
 }
 
+// weightSubjectSharpness blends the sharpness of the configured subject region
+// into the whole-frame sharpness, giving the subject SubjectWeight of the score
+func (a *Analyzer) weightSubjectSharpness(gray *image.Gray, frame float64) float64 {
+	regions, err := a.sharpness.AnalyzeFocusRegion(gray)
+	if err != nil {
+		return frame
+	}
+
+	subject := regions["center"]
+	if a.config.SharpnessSubject == SubjectSharpest {
+		for _, sharpness := range regions {
+			subject = math.Max(subject, sharpness)
+		}
+	}
+
+	weight := math.Max(0, math.Min(1, a.config.SubjectWeight))
+	return (1-weight)*frame + weight*subject
+}
+
 // analyzeNoise estimates image noise level
 func (a *Analyzer) analyzeNoise(gray *image.Gray) (float64, error) {
 	bounds := gray.Bounds()
@@ -216,6 +272,9 @@ func (a *Analyzer) analyzeExposure(gray *image.Gray) (float64, error) {
 	if totalPixels == 0 {
 		return 0.5, nil // Default neutral exposure
 	}
+	if a.config.ExposureMethod == ExposurePercentile {
+		return percentileExposure(gray), nil
+	}
 
 	var sum float64
 	var darkPixels, brightPixels int
@@ -255,8 +314,45 @@ func (a *Analyzer) analyzeExposure(gray *image.Gray) (float64, error) {
 	return exposure, nil
 }
 
+// percentileExposure measures exposure as the median luminance, scaled towards
+// 0.5 by one minus the interquartile range. A narrow histogram keeps its
+// median; one spread across the whole range reads as balanced.
+func percentileExposure(gray *image.Gray) float64 {
+	var histogram [256]int
+	bounds := gray.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			histogram[gray.GrayAt(x, y).Y]++
+		}
+	}
+	total := bounds.Dx() * bounds.Dy()
+
+	median := histogramPercentile(histogram, total, 0.5)
+	spread := histogramPercentile(histogram, total, 0.75) - histogramPercentile(histogram, total, 0.25)
+
+	exposure := 0.5 + (median-0.5)*(1-spread)
+	return math.Max(0, math.Min(1, exposure))
+}
+
+// histogramPercentile returns the luminance, between 0 and 1, below which
+// fraction p of the total pixels in histogram fall
+func histogramPercentile(histogram [256]int, total int, p float64) float64 {
+	target := int(math.Ceil(p * float64(total)))
+	cumulative := 0
+	for level, count := range histogram {
+		cumulative += count
+		if cumulative >= target {
+			return float64(level) / 255.0
+		}
+	}
+	return 1
+}
+
 // analyzeContrast measures image contrast using standard deviation
 func (a *Analyzer) analyzeContrast(gray *image.Gray) (float64, error) {
+	if a.config.TrimBorders {
+		gray = trimBorders(gray)
+	}
 	bounds := gray.Bounds()
 	totalPixels := bounds.Dx() * bounds.Dy()
 
@@ -285,20 +381,56 @@ func (a *Analyzer) analyzeContrast(gray *image.Gray) (float64, error) {
 	return contrast, nil
 }
 
-// analyzeCompression detects compression artifacts (simplified)
-func (a *Analyzer) analyzeCompression(img image.Image) (float64, error) {
-	// Simplified compression artifact detection
-	// In a real implementation, this would analyze blocking artifacts
-	// common in JPEG compression
+// borderTolerance is the widest range of gray levels a row or column may
+// span and still count as part of a uniform border
+const borderTolerance = 8
 
-	bounds := img.Bounds()
-	if bounds.Dx() < 100 || bounds.Dy() < 100 {
-		return 0.1, nil // Small images have less noticeable compression
+// trimBorders returns gray without the near-constant rows and columns along
+// its edges, such as letterbox bars or the white margin of a scanned print.
+// An image that is uniform throughout is returned whole.
+func trimBorders(gray *image.Gray) *image.Gray {
+	bounds := gray.Bounds()
+	uniform := func(x0, y0, x1, y1 int) bool {
+		lo, hi := uint8(255), uint8(0)
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				v := gray.GrayAt(x, y).Y
+				if v < lo {
+					lo = v
+				}
+				if v > hi {
+					hi = v
+				}
+			}
+		}
+		return int(hi)-int(lo) <= borderTolerance
+	}
+
+	top, bottom := bounds.Min.Y, bounds.Max.Y
+	for top < bottom && uniform(bounds.Min.X, top, bounds.Max.X, top+1) {
+		top++
+	}
+	for bottom > top && uniform(bounds.Min.X, bottom-1, bounds.Max.X, bottom) {
+		bottom--
 	}
+	if top == bottom {
+		return gray
+	}
+
+	left, right := bounds.Min.X, bounds.Max.X
+	for left < right && uniform(left, top, left+1, bottom) {
+		left++
+	}
+	for right > left && uniform(right-1, top, right, bottom) {
+		right--
+	}
+
+	return gray.SubImage(image.Rect(left, top, right, bottom)).(*image.Gray)
+}
 
-	// Placeholder: assume moderate compression for most images
-	// Real implementation would analyze high-frequency noise patterns
-	return 0.3, nil
+// analyzeCompression measures blocking, ringing and noise artifacts left by lossy compression
+func (a *Analyzer) analyzeCompression(img image.Image) (float64, error) {
+	return a.compression.AnalyzeCompression(img)
 }
 
 // analyzeColorCast detects color balance issues
@@ -348,6 +480,11 @@ func (a *Analyzer) analyzeColorCast(img image.Image) (float64, error) {
 
 // calculateFinalScore computes a composite quality score from individual metrics
 func (a *Analyzer) calculateFinalScore(quality *api.ImageQuality) float64 {
+	return a.CompositeScore(*quality)
+}
+
+// CompositeScore weights the individual quality metrics into a 0-100 score
+func (a *Analyzer) CompositeScore(quality api.ImageQuality) float64 {
 	// Weighted combination of quality factors
 	weights := map[string]float64{
 		"sharpness":   0.3,  // Sharpness is most important
@@ -358,13 +495,10 @@ func (a *Analyzer) calculateFinalScore(quality *api.ImageQuality) float64 {
 		"color_cast":  0.05, // Color balance
 	}
 
-	// Adjust exposure score to penalize both under and overexposure
-	exposureScore := 1.0 - math.Abs(quality.Exposure-0.5)*2
-
 	// Calculate weighted score
 	score := quality.Sharpness*weights["sharpness"] +
 		(1-quality.Noise)*weights["noise"] + // Invert noise (lower is better)
-		exposureScore*weights["exposure"] +
+		ExposureScore(quality.Exposure)*weights["exposure"] +
 		quality.Contrast*weights["contrast"] +
 		(1-quality.Compression)*weights["compression"] + // Invert compression
 		(1-quality.ColorCast)*weights["color_cast"] // Invert color cast
@@ -376,6 +510,12 @@ func (a *Analyzer) calculateFinalScore(quality *api.ImageQuality) float64 {
 	return math.Max(0, math.Min(100, finalScore))
 }
 
+// ExposureScore rates an exposure level from 0 to 1, penalizing both under
+// and overexposure around the ideal of 0.5
+func ExposureScore(exposure float64) float64 {
+	return 1.0 - math.Abs(exposure-0.5)*2
+}
+
 // IsBlurry determines if an image is blurry based on sharpness threshold
 func (a *Analyzer) IsBlurry(quality api.ImageQuality) bool {
 	return quality.Sharpness < a.config.SharpnessThreshold
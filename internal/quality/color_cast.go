@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"image"
 	"math"
+
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 )
 
 // ColorCastAnalyzer analyzes color balance and cast in images
@@ -108,7 +110,8 @@ func (c *ColorCastAnalyzer) AnalyzeColorDistribution(img image.Image) map[string
 	fmt.Print(totalPixels)
 	for y := bounds.Min.Y; y < bounds.Max.Y; y += 4 {
 		for x := bounds.Min.X; x < bounds.Max.X; x += 4 {
-			r, g, b, _ := img.At(x, y).RGBA()
+			c := img.At(x, y)
+			r, g, b, _ := c.RGBA()
 
 			rNorm := float64(r) / 65535.0
 			gNorm := float64(g) / 65535.0
@@ -123,7 +126,7 @@ func (c *ColorCastAnalyzer) AnalyzeColorDistribution(img image.Image) map[string
 			}
 
 			// Check brightness
-			brightness := (rNorm + gNorm + bNorm) / 3
+			brightness := pkgimaging.Luminance(c)
 
 			if saturation > 0.7 {
 				saturatedPixels++
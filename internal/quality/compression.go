@@ -36,40 +36,51 @@ func (c *CompressionAnalyzer) AnalyzeCompression(img image.Image) (float64, erro
 	return math.Min(compressionScore, 1.0), nil
 }
 
-// analyzeBlockiness detects block artifacts from JPEG compression
+// analyzeBlockiness detects block artifacts from JPEG compression by comparing
+// the luminance steps across 8x8 block boundaries with the steps inside blocks.
+// Quantization smooths each block independently, so the boundary steps grow
+// relative to the inner ones as compression gets heavier, while real edges
+// fall on boundary and inner positions alike.
 func (c *CompressionAnalyzer) analyzeBlockiness(img image.Image) float64 {
-	bounds := img.Bounds()
-	gray := imaging.Grayscale(img)
-
-	var blockArtifacts float64
+	gray := toGray(img)
+	bounds := gray.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
 	blockSize := 8 // JPEG uses 8x8 blocks
 
-	// Check for discontinuities at block boundaries
-	for y := blockSize; y < bounds.Dy()-blockSize; y += blockSize {
-		for x := blockSize; x < bounds.Dx()-blockSize; x += blockSize {
-			// Check horizontal block boundaries
-			lr, _, _, _ := gray.At(x-1, y).RGBA()
-			rr, _, _, _ := gray.At(x, y).RGBA()
-			horizontalDiff := math.Abs(float64(lr) - float64(rr))
-
-			// Check vertical block boundaries
-			tr, _, _, _ := gray.At(x, y-1).RGBA()
-			br, _, _, _ := gray.At(x, y).RGBA()
-			verticalDiff := math.Abs(float64(tr) - float64(br))
+	var boundarySum, innerSum float64
+	var boundaryCount, innerCount int
+	step := func(a, b uint8, atBoundary bool) {
+		diff := math.Abs(float64(a) - float64(b))
+		if atBoundary {
+			boundarySum += diff
+			boundaryCount++
+		} else {
+			innerSum += diff
+			innerCount++
+		}
+	}
 
-			// High differences at block boundaries indicate compression artifacts
-			if horizontalDiff > 10000 || verticalDiff > 10000 {
-				blockArtifacts += 1.0
-			}
+	for y := 0; y < height; y++ {
+		row := gray.Pix[y*gray.Stride:]
+		for x := 1; x < width; x++ {
+			step(row[x-1], row[x], x%blockSize == 0)
+		}
+	}
+	for y := 1; y < height; y++ {
+		above, row := gray.Pix[(y-1)*gray.Stride:], gray.Pix[y*gray.Stride:]
+		for x := 0; x < width; x++ {
+			step(above[x], row[x], y%blockSize == 0)
 		}
 	}
 
-	totalBlocks := ((bounds.Dx() / blockSize) - 1) * ((bounds.Dy() / blockSize) - 1)
-	if totalBlocks == 0 {
+	if boundaryCount == 0 || innerCount == 0 {
 		return 0.0
 	}
 
-	return math.Min(blockArtifacts/float64(totalBlocks), 1.0)
+	// A ratio of 1 means no blocking; a ratio of 3 or more is saturated.
+	// The +1 keeps flat images, whose steps are all near zero, from scoring high.
+	ratio := (boundarySum/float64(boundaryCount) + 1) / (innerSum/float64(innerCount) + 1)
+	return math.Max(0, math.Min((ratio-1)/2, 1.0))
 }
 
 // analyzeRingingArtifacts detects ringing artifacts near edges
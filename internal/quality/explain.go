@@ -0,0 +1,86 @@
+package quality
+
+import (
+	"fmt"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// Explain lists the metrics of quality that fail the analyzer's thresholds,
+// in the order sharpness, noise, exposure, contrast, compression, color cast.
+// An image without issues yields an empty list.
+func (a *Analyzer) Explain(quality api.ImageQuality) []api.QualityIssue {
+	issues := []api.QualityIssue{}
+
+	if a.IsBlurry(quality) {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "sharpness",
+			Severity:        issueSeverity(quality.Sharpness, a.config.SharpnessThreshold, 0),
+			Message:         fmt.Sprintf("Image is blurry (sharpness %.2f, below %.2f)", quality.Sharpness, a.config.SharpnessThreshold),
+			SuggestedAction: "Keep a sharper copy, or retake with a faster shutter or better focus",
+		})
+	}
+
+	if quality.Noise > a.config.NoiseThreshold {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "noise",
+			Severity:        issueSeverity(quality.Noise, a.config.NoiseThreshold, 1),
+			Message:         fmt.Sprintf("High noise level (%.2f, above %.2f)", quality.Noise, a.config.NoiseThreshold),
+			SuggestedAction: "Apply noise reduction, or shoot at a lower ISO",
+		})
+	}
+
+	if a.IsUnderexposed(quality) {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "exposure",
+			Severity:        issueSeverity(quality.Exposure, a.config.MinExposure, 0),
+			Message:         fmt.Sprintf("Image is underexposed (exposure %.2f, below %.2f)", quality.Exposure, a.config.MinExposure),
+			SuggestedAction: "Brighten the image or raise the exposure when shooting",
+		})
+	} else if a.IsOverexposed(quality) {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "exposure",
+			Severity:        issueSeverity(quality.Exposure, a.config.MaxExposure, 1),
+			Message:         fmt.Sprintf("Image is overexposed (exposure %.2f, above %.2f)", quality.Exposure, a.config.MaxExposure),
+			SuggestedAction: "Darken the image or lower the exposure when shooting",
+		})
+	}
+
+	if quality.Contrast < a.config.MinContrast {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "contrast",
+			Severity:        issueSeverity(quality.Contrast, a.config.MinContrast, 0),
+			Message:         fmt.Sprintf("Low contrast (%.2f, below %.2f)", quality.Contrast, a.config.MinContrast),
+			SuggestedAction: "Increase contrast or apply a levels adjustment",
+		})
+	}
+
+	if maxArtifacts := 1 - a.config.CompressionQuality; quality.Compression > maxArtifacts {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "compression",
+			Severity:        issueSeverity(quality.Compression, maxArtifacts, 1),
+			Message:         fmt.Sprintf("Visible compression artifacts (%.2f, above %.2f)", quality.Compression, maxArtifacts),
+			SuggestedAction: "Keep a less compressed copy, or save at a higher quality setting",
+		})
+	}
+
+	if quality.ColorCast > a.config.MaxColorCast {
+		issues = append(issues, api.QualityIssue{
+			Metric:          "color_cast",
+			Severity:        issueSeverity(quality.ColorCast, a.config.MaxColorCast, 1),
+			Message:         fmt.Sprintf("Noticeable color cast (%.2f, above %.2f)", quality.ColorCast, a.config.MaxColorCast),
+			SuggestedAction: "Correct the white balance",
+		})
+	}
+
+	return issues
+}
+
+// issueSeverity grades value, already past threshold, by how far it has gone
+// towards worst, the metric's worst possible value
+func issueSeverity(value, threshold, worst float64) api.IssueSeverity {
+	if abs(value-threshold) >= abs(worst-threshold)/2 {
+		return api.SeverityMajor
+	}
+	return api.SeverityMinor
+}
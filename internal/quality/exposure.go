@@ -4,7 +4,7 @@ import (
 	"image"
 	"math"
 
-	"github.com/disintegration/imaging"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 )
 
 // ExposureAnalyzer analyzes image exposure levels
@@ -17,8 +17,7 @@ func NewExposureAnalyzer() *ExposureAnalyzer {
 
 // AnalyzeExposure assesses image exposure level
 func (e *ExposureAnalyzer) AnalyzeExposure(img image.Image) (float64, error) {
-	gray := imaging.Grayscale(img)
-	bounds := gray.Bounds()
+	bounds := img.Bounds()
 	totalPixels := bounds.Dx() * bounds.Dy()
 
 	if totalPixels == 0 {
@@ -31,8 +30,7 @@ func (e *ExposureAnalyzer) AnalyzeExposure(img image.Image) (float64, error) {
 	// Analyze histogram for exposure characteristics
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, _, _, _ := gray.At(x, y).RGBA()
-			luminance := float64(r) / 65535.0
+			luminance := pkgimaging.Luminance(img.At(x, y))
 			sum += luminance
 
 			if luminance < 0.1 {
@@ -84,15 +82,13 @@ func (e *ExposureAnalyzer) IsUnderexposed(img image.Image, threshold float64) (b
 
 // GetExposureHistogram returns the luminance histogram
 func (e *ExposureAnalyzer) GetExposureHistogram(img image.Image, bins int) ([]float64, error) {
-	gray := imaging.Grayscale(img)
-	bounds := gray.Bounds()
+	bounds := img.Bounds()
 	histogram := make([]float64, bins)
 	totalPixels := float64(bounds.Dx() * bounds.Dy())
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, _, _, _ := gray.At(x, y).RGBA()
-			brightness := float64(r) / 65535.0
+			brightness := pkgimaging.Luminance(img.At(x, y))
 			bin := int(brightness * float64(bins-1))
 			if bin >= 0 && bin < bins {
 				histogram[bin]++
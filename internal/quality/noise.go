@@ -4,7 +4,7 @@ import (
 	"image"
 	"math"
 
-	"github.com/disintegration/imaging"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 )
 
 // NoiseAnalyzer estimates image noise levels
@@ -17,13 +17,12 @@ func NewNoiseAnalyzer() *NoiseAnalyzer {
 
 // helper: convert pixel to grayscale value
 func grayValue(img image.Image, x, y int) float64 {
-	r, g, b, _ := img.At(x, y).RGBA()
-	return float64(r+g+b) / 3.0
+	return pkgimaging.Luminance(img.At(x, y)) * 65535.0
 }
 
 // AnalyzeNoise estimates image noise level
 func (n *NoiseAnalyzer) AnalyzeNoise(img image.Image) (float64, error) {
-	gray := imaging.Grayscale(img)
+	gray := pkgimaging.Grayscale(img)
 	bounds := gray.Bounds()
 
 	if bounds.Dx() < 3 || bounds.Dy() < 3 {
@@ -81,7 +80,7 @@ func (n *NoiseAnalyzer) AnalyzeNoise(img image.Image) (float64, error) {
 // DetectNoisePattern identifies specific noise patterns
 func (n *NoiseAnalyzer) DetectNoisePattern(img image.Image) map[string]float64 {
 	patterns := make(map[string]float64)
-	gray := imaging.Grayscale(img)
+	gray := pkgimaging.Grayscale(img)
 
 	patterns["high_frequency"] = n.analyzeHighFrequencyNoise(gray)
 	patterns["low_frequency"] = n.analyzeLowFrequencyNoise(gray)
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/sirupsen/logrus"
+)
+
+// CSVReportGenerator generates a CSV report with one row per duplicate group member
+type CSVReportGenerator struct {
+	fingerprints map[api.ImageID]*api.ImageFingerprint
+	logger       *logrus.Logger
+}
+
+// NewCSVReportGenerator creates a new CSV report generator
+func NewCSVReportGenerator() *CSVReportGenerator {
+	return &CSVReportGenerator{
+		fingerprints: make(map[api.ImageID]*api.ImageFingerprint),
+		logger:       logrus.New(),
+	}
+}
+
+// SetFingerprints provides the fingerprints used to resolve group members to files
+func (c *CSVReportGenerator) SetFingerprints(fingerprints []api.ImageFingerprint) {
+	c.fingerprints = make(map[api.ImageID]*api.ImageFingerprint, len(fingerprints))
+	for i := range fingerprints {
+		c.fingerprints[fingerprints[i].ID] = &fingerprints[i]
+	}
+}
+
+// Generate writes every duplicate group member as a CSV row
+func (c *CSVReportGenerator) Generate(scanReport *api.ScanReport, outputPath string) error {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"group_id", "reason", "confidence", "image_id", "is_main", "path", "size_bytes", "quality"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for _, group := range scanReport.Groups {
+		ids := append([]api.ImageID{group.MainImage}, group.DuplicateIDs...)
+		for i, id := range ids {
+			if err := writer.Write(c.row(group, id, i == 0)); err != nil {
+				return fmt.Errorf("failed to write CSV row: %w", err)
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+
+	c.logger.Infof("CSV report saved to: %s", outputPath)
+	return nil
+}
+
+// row formats one group member, leaving file columns empty when no fingerprint is known
+func (c *CSVReportGenerator) row(group api.DuplicateGroup, id api.ImageID, isMain bool) []string {
+	row := []string{
+		group.GroupID,
+		group.Reason,
+		strconv.FormatFloat(group.Confidence, 'f', 4, 64),
+		string(id),
+		strconv.FormatBool(isMain),
+		"", "", "",
+	}
+
+	if fp, ok := c.fingerprints[id]; ok {
+		row[5] = fp.Metadata.Path
+		row[6] = strconv.FormatInt(fp.Metadata.SizeBytes, 10)
+		row[7] = strconv.FormatFloat(fp.Quality.FinalScore, 'f', 1, 64)
+	}
+	return row
+}
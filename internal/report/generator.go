@@ -4,12 +4,45 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/sirupsen/logrus"
 )
 
+// ReportGenerator writes a scan report to a file
+type ReportGenerator interface {
+	Generate(scanReport *api.ScanReport, outputPath string) error
+}
+
+// FingerprintSetter is implemented by generators that resolve image IDs
+// against indexed fingerprints
+type FingerprintSetter interface {
+	SetFingerprints(fingerprints []api.ImageFingerprint)
+}
+
+// ReportFormats lists the formats accepted by NewReportGenerator
+var ReportFormats = []string{"html", "json", "text", "csv", "interactive"}
+
+// NewReportGenerator creates the report generator for format
+func NewReportGenerator(format string) (ReportGenerator, error) {
+	switch strings.ToLower(format) {
+	case "html":
+		return NewHTMLReportGenerator(), nil
+	case "json":
+		return NewJSONReportGenerator(), nil
+	case "text":
+		return NewTextReportGenerator(), nil
+	case "csv":
+		return NewCSVReportGenerator(), nil
+	case "interactive":
+		return NewInteractiveReportGenerator(DefaultInteractiveReportConfig()), nil
+	default:
+		return nil, fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
 // Generator creates various types of reports from scan results
 type Generator struct {
 	logger *logrus.Logger
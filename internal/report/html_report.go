@@ -6,6 +6,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/HaiderBassem/imaged/internal/imaging"
 	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
@@ -13,23 +14,43 @@ import (
 
 // HTMLReportGenerator generates HTML format reports
 type HTMLReportGenerator struct {
-	logger *logrus.Logger
+	fingerprints map[api.ImageID]*api.ImageFingerprint
+	thumbnails   *thumbnailer
+	logger       *logrus.Logger
 }
 
 // NewHTMLReportGenerator creates a new HTML report generator
 func NewHTMLReportGenerator() *HTMLReportGenerator {
 	return &HTMLReportGenerator{
-		logger: logrus.New(),
+		fingerprints: make(map[api.ImageID]*api.ImageFingerprint),
+		thumbnails:   newThumbnailer(),
+		logger:       logrus.New(),
 	}
 }
 
-// Generate generates a comprehensive HTML report
+// SetFingerprints provides the indexed fingerprints; they resolve group members
+// to files and thumbnails and are the basis of the report's statistics
+func (h *HTMLReportGenerator) SetFingerprints(fingerprints []api.ImageFingerprint) {
+	h.fingerprints = make(map[api.ImageID]*api.ImageFingerprint, len(fingerprints))
+	for i := range fingerprints {
+		h.fingerprints[fingerprints[i].ID] = &fingerprints[i]
+	}
+}
+
+// SetThumbnailCache reuses thumbnails from cache instead of decoding every image
+func (h *HTMLReportGenerator) SetThumbnailCache(cache *imaging.ThumbnailCache) {
+	h.thumbnails.cache = cache
+}
+
+// Generate generates a comprehensive HTML report. Thumbnails are inlined as
+// data URIs so the file stays self-contained.
 func (h *HTMLReportGenerator) Generate(scanReport *api.ScanReport, outputPath string) error {
 	// Prepare data for template
 	data := HTMLReportData{
 		ScanReport:  scanReport,
 		GeneratedAt: time.Now(),
 		Statistics:  h.calculateStatistics(scanReport),
+		Groups:      h.buildGroups(scanReport.Groups),
 	}
 
 	// Parse and execute template
@@ -59,6 +80,54 @@ type HTMLReportData struct {
 	*api.ScanReport
 	GeneratedAt time.Time
 	Statistics  *HTMLStatistics
+	Groups      []HTMLGroup // shadows ScanReport.Groups with resolved images
+}
+
+// HTMLGroup is a duplicate group with its members resolved for display
+type HTMLGroup struct {
+	api.DuplicateGroup
+	Images []HTMLImage
+}
+
+// HTMLImage is one group member as rendered in the report
+type HTMLImage struct {
+	ID        api.ImageID
+	Path      string
+	Thumbnail template.URL // data URI, empty when unavailable
+	IsMain    bool
+}
+
+// buildGroups resolves every group member to its path and thumbnail
+func (h *HTMLReportGenerator) buildGroups(groups []api.DuplicateGroup) []HTMLGroup {
+	result := make([]HTMLGroup, 0, len(groups))
+	for _, group := range groups {
+		hg := HTMLGroup{DuplicateGroup: group}
+		hg.Images = append(hg.Images, h.htmlImage(group.MainImage, true))
+		for _, id := range group.DuplicateIDs {
+			hg.Images = append(hg.Images, h.htmlImage(id, false))
+		}
+		result = append(result, hg)
+	}
+	return result
+}
+
+// htmlImage builds a report image, falling back to the bare ID when no fingerprint is known
+func (h *HTMLReportGenerator) htmlImage(id api.ImageID, isMain bool) HTMLImage {
+	img := HTMLImage{ID: id, Path: string(id), IsMain: isMain}
+
+	fp, ok := h.fingerprints[id]
+	if !ok {
+		return img
+	}
+	img.Path = fp.Metadata.Path
+
+	thumbnail, err := h.thumbnails.dataURI(fp)
+	if err != nil {
+		h.logger.Debugf("Failed to create thumbnail for %s: %v", fp.Metadata.Path, err)
+		return img
+	}
+	img.Thumbnail = template.URL(thumbnail)
+	return img
 }
 
 // HTMLStatistics contains statistics for HTML report
@@ -69,15 +138,26 @@ type HTMLStatistics struct {
 	SpaceSavingsMB    float64
 }
 
-// calculateStatistics computes statistics for HTML report
+// calculateStatistics computes statistics for HTML report from the known fingerprints
 func (h *HTMLReportGenerator) calculateStatistics(scanReport *api.ScanReport) *HTMLStatistics {
-	// These would be calculated from actual data in a real implementation
-	return &HTMLStatistics{
-		TotalSizeMB:       0,                                           // Would be calculated
-		AverageFileSizeMB: 0,                                           // Would be calculated
-		AverageQuality:    0,                                           // Would be calculated
-		SpaceSavingsMB:    float64(scanReport.ExactDuplicateCount) * 5, // Estimate
+	stats := &HTMLStatistics{}
+	if len(h.fingerprints) == 0 {
+		return stats
+	}
+
+	var totalBytes int64
+	var totalQuality float64
+	for _, fp := range h.fingerprints {
+		totalBytes += fp.Metadata.SizeBytes
+		totalQuality += fp.Quality.FinalScore
 	}
+
+	count := float64(len(h.fingerprints))
+	stats.TotalSizeMB = float64(totalBytes) / bytesPerMB
+	stats.AverageFileSizeMB = stats.TotalSizeMB / count
+	stats.AverageQuality = totalQuality / count
+	stats.SpaceSavingsMB = float64(reclaimableBytes(scanReport.Groups, h.fingerprints)) / bytesPerMB
+	return stats
 }
 
 // Template functions
@@ -239,6 +319,33 @@ const htmlTemplate = `<!DOCTYPE html>
             color: #666;
         }
         
+        .thumbnails {
+            display: flex;
+            flex-wrap: wrap;
+            gap: 1rem;
+            margin-top: 0.5rem;
+        }
+        
+        .thumbnail {
+            width: 200px;
+            background: white;
+            padding: 0.5rem;
+            border: 1px solid var(--border-color);
+            border-radius: 4px;
+            font-size: 0.8rem;
+            word-break: break-all;
+        }
+        
+        .thumbnail.main {
+            border-color: var(--success-color);
+        }
+        
+        .thumbnail img {
+            max-width: 100%;
+            display: block;
+            margin-bottom: 0.25rem;
+        }
+        
         .file-list {
             max-height: 200px;
             overflow-y: auto;
@@ -334,6 +441,25 @@ const htmlTemplate = `<!DOCTYPE html>
             </div>
         </div>
 
+        <div class="stats-grid">
+            <div class="stat-card">
+                <div class="stat-number">{{printf "%.2f" .Statistics.TotalSizeMB}} MB</div>
+                <div class="stat-label">Total Size</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-number">{{printf "%.2f" .Statistics.AverageFileSizeMB}} MB</div>
+                <div class="stat-label">Average File Size</div>
+            </div>
+            <div class="stat-card">
+                <div class="stat-number">{{printf "%.1f" .Statistics.AverageQuality}}</div>
+                <div class="stat-label">Average Quality</div>
+            </div>
+            <div class="stat-card highlight">
+                <div class="stat-number">{{printf "%.2f" .Statistics.SpaceSavingsMB}} MB</div>
+                <div class="stat-label">Reclaimable Space</div>
+            </div>
+        </div>
+
         {{if .Groups}}
         <div class="section">
             <h2 class="section-title">🔍 Duplicate Analysis</h2>
@@ -343,22 +469,20 @@ const htmlTemplate = `<!DOCTYPE html>
                     <span class="group-type {{if eq .Reason "exact"}}type-exact{{else}}type-near{{end}}">
                         {{.Reason}} duplicate
                     </span>
-                    <span class="confidence">Confidence: {{printf "%.0f" (mul .Confidence 100)}}%}}%}}%</span>
+                    <span class="confidence">Confidence: {{printf "%.0f" (mul .Confidence 100)}}%</span>
                 </div>
                 <div class="group-info">
                     <strong>Main Image:</strong> {{.MainImage}}<br>
                     <strong>Duplicates Found:</strong> {{len .DuplicateIDs}} files
                 </div>
-                {{if .DuplicateIDs}}
-                <details style="margin-top: 0.5rem;">
-                    <summary>Show duplicate files</summary>
-                    <div class="file-list">
-                        {{range .DuplicateIDs}}
-                        <div>{{.}}</div>
-                        {{end}}
+                <div class="thumbnails">
+                    {{range .Images}}
+                    <div class="thumbnail {{if .IsMain}}main{{end}}">
+                        {{if .Thumbnail}}<img src="{{.Thumbnail}}" alt="{{.Path}}">{{end}}
+                        {{if .IsMain}}<strong>Main:</strong> {{end}}{{.Path}}
                     </div>
-                </details>
-                {{end}}
+                    {{end}}
+                </div>
             </div>
             {{end}}
         </div>
@@ -0,0 +1,283 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/imaging"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+)
+
+// InteractiveReportConfig configures the interactive duplicate-review page
+type InteractiveReportConfig struct {
+	Title               string
+	PreselectDuplicates bool // tick every non-main image by default
+}
+
+// DefaultInteractiveReportConfig returns the default interactive report configuration
+func DefaultInteractiveReportConfig() InteractiveReportConfig {
+	return InteractiveReportConfig{
+		Title:               "Duplicate Review",
+		PreselectDuplicates: true,
+	}
+}
+
+// InteractiveReportGenerator generates a self-contained HTML page for reviewing
+// duplicate groups and exporting the decisions without a server
+type InteractiveReportGenerator struct {
+	config       InteractiveReportConfig
+	fingerprints map[api.ImageID]*api.ImageFingerprint
+	thumbnails   *thumbnailer
+	logger       *logrus.Logger
+}
+
+// NewInteractiveReportGenerator creates a new interactive report generator
+func NewInteractiveReportGenerator(cfg InteractiveReportConfig) *InteractiveReportGenerator {
+	if cfg.Title == "" {
+		cfg.Title = DefaultInteractiveReportConfig().Title
+	}
+
+	return &InteractiveReportGenerator{
+		config:       cfg,
+		fingerprints: make(map[api.ImageID]*api.ImageFingerprint),
+		thumbnails:   newThumbnailer(),
+		logger:       logrus.New(),
+	}
+}
+
+// SetFingerprints provides the fingerprints used to resolve group members to file paths
+func (g *InteractiveReportGenerator) SetFingerprints(fingerprints []api.ImageFingerprint) {
+	g.fingerprints = make(map[api.ImageID]*api.ImageFingerprint, len(fingerprints))
+	for i := range fingerprints {
+		g.fingerprints[fingerprints[i].ID] = &fingerprints[i]
+	}
+}
+
+// SetThumbnailCache reuses thumbnails from cache instead of decoding every image
+func (g *InteractiveReportGenerator) SetThumbnailCache(cache *imaging.ThumbnailCache) {
+	g.thumbnails.cache = cache
+}
+
+// ReviewData is the JSON blob inlined into the interactive page
+type ReviewData struct {
+	ScanID      string        `json:"scan_id"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Preselect   bool          `json:"preselect"`
+	Groups      []ReviewGroup `json:"groups"`
+}
+
+// ReviewGroup is a duplicate group as presented on the review page
+type ReviewGroup struct {
+	GroupID    string       `json:"group_id"`
+	Reason     string       `json:"reason"`
+	Confidence float64      `json:"confidence"`
+	Images     []ReviewItem `json:"images"`
+}
+
+// ReviewItem is a single image within a review group
+type ReviewItem struct {
+	ID        api.ImageID `json:"id"`
+	Path      string      `json:"path"`
+	SizeBytes int64       `json:"size_bytes"`
+	Width     int         `json:"width"`
+	Height    int         `json:"height"`
+	Quality   float64     `json:"quality"`
+	Thumbnail string      `json:"thumbnail,omitempty"` // data URI, empty when unavailable
+	IsMain    bool        `json:"is_main"`
+}
+
+// Generate writes the interactive review page to outputPath
+func (g *InteractiveReportGenerator) Generate(scanReport *api.ScanReport, outputPath string) error {
+	data := g.buildReviewData(scanReport)
+
+	tmpl, err := template.New("interactive").Funcs(template.FuncMap{
+		"formatBytes": func(b int64) string { return humanize.Bytes(uint64(b)) },
+		"formatTime":  formatTime,
+		"mul":         func(a, b float64) float64 { return a * b },
+		"dataURI":     func(s string) template.URL { return template.URL(s) },
+	}).Parse(interactiveTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse interactive template: %w", err)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create interactive report: %w", err)
+	}
+	defer file.Close()
+
+	if err := tmpl.Execute(file, struct {
+		Title string
+		Data  *ReviewData
+	}{
+		Title: g.config.Title,
+		Data:  data,
+	}); err != nil {
+		return fmt.Errorf("failed to execute interactive template: %w", err)
+	}
+
+	g.logger.Infof("Interactive report saved to: %s", outputPath)
+	return nil
+}
+
+// buildReviewData resolves each group member against the known fingerprints
+func (g *InteractiveReportGenerator) buildReviewData(scanReport *api.ScanReport) *ReviewData {
+	data := &ReviewData{
+		ScanID:      scanReport.ScanID,
+		GeneratedAt: time.Now(),
+		Preselect:   g.config.PreselectDuplicates,
+		Groups:      make([]ReviewGroup, 0, len(scanReport.Groups)),
+	}
+
+	for _, group := range scanReport.Groups {
+		rg := ReviewGroup{
+			GroupID:    group.GroupID,
+			Reason:     group.Reason,
+			Confidence: group.Confidence,
+		}
+
+		rg.Images = append(rg.Images, g.reviewItem(group.MainImage, true))
+		for _, id := range group.DuplicateIDs {
+			rg.Images = append(rg.Images, g.reviewItem(id, false))
+		}
+
+		data.Groups = append(data.Groups, rg)
+	}
+
+	return data
+}
+
+// reviewItem builds a review item, falling back to the bare ID when no fingerprint is known
+func (g *InteractiveReportGenerator) reviewItem(id api.ImageID, isMain bool) ReviewItem {
+	item := ReviewItem{ID: id, Path: string(id), IsMain: isMain}
+
+	fp, ok := g.fingerprints[id]
+	if !ok {
+		return item
+	}
+	item.Path = fp.Metadata.Path
+	item.SizeBytes = fp.Metadata.SizeBytes
+	item.Width = fp.Metadata.Width
+	item.Height = fp.Metadata.Height
+	item.Quality = fp.Quality.FinalScore
+
+	thumbnail, err := g.thumbnails.dataURI(fp)
+	if err != nil {
+		g.logger.Debugf("Failed to create thumbnail for %s: %v", fp.Metadata.Path, err)
+		return item
+	}
+	item.Thumbnail = thumbnail
+	return item
+}
+
+// Interactive HTML template; the script reads the inlined review-data blob
+const interactiveTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <style>
+        body { font-family: 'Segoe UI', Tahoma, Geneva, Verdana, sans-serif; background: #f5f5f5; color: #333; margin: 0; }
+        .container { max-width: 1200px; margin: 0 auto; padding: 20px; }
+        .toolbar { position: sticky; top: 0; background: #2c3e50; color: white; padding: 1rem; border-radius: 8px; display: flex; gap: 1rem; align-items: center; }
+        .toolbar button { background: #3498db; color: white; border: none; padding: 0.5rem 1rem; border-radius: 4px; cursor: pointer; }
+        .group { background: white; margin: 1rem 0; padding: 1rem; border-radius: 8px; border-left: 4px solid #f39c12; }
+        .group.exact { border-left-color: #e74c3c; }
+        .images { display: flex; flex-wrap: wrap; gap: 1rem; }
+        .image { width: 220px; padding: 0.5rem; border: 1px solid #dee2e6; border-radius: 6px; font-size: 0.85rem; word-break: break-all; }
+        .image.main { border-color: #27ae60; }
+        .image img { max-width: 100%; display: block; margin-bottom: 0.5rem; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="toolbar">
+            <strong>{{.Title}}</strong>
+            <span>Scan {{.Data.ScanID}} | {{len .Data.Groups}} groups | {{.Data.GeneratedAt | formatTime}}</span>
+            <span id="selected-count"></span>
+            <button type="button" onclick="downloadScript()">Download cleanup script</button>
+            <button type="button" onclick="downloadDecisions()">Download decisions (JSON)</button>
+        </div>
+
+        {{range $g := .Data.Groups}}
+        <div class="group {{if eq $g.Reason "exact"}}exact{{end}}" data-group-id="{{$g.GroupID}}">
+            <h3>{{$g.GroupID}} &middot; {{$g.Reason}} &middot; {{printf "%.0f" (mul $g.Confidence 100)}}%</h3>
+            <div class="images">
+                {{range $g.Images}}
+                <label class="image {{if .IsMain}}main{{end}}">
+                    {{if .Thumbnail}}<img src="{{dataURI .Thumbnail}}" alt="">{{end}}
+                    <input type="checkbox" class="delete-toggle" data-group-id="{{$g.GroupID}}" data-image-id="{{.ID}}"{{if and (not .IsMain) $.Data.Preselect}} checked{{end}}>
+                    delete{{if .IsMain}} (main){{end}}<br>
+                    {{.Path}}<br>
+                    {{.Width}}x{{.Height}} &middot; {{formatBytes .SizeBytes}} &middot; Q {{printf "%.1f" .Quality}}
+                </label>
+                {{end}}
+            </div>
+        </div>
+        {{end}}
+    </div>
+
+    <script id="review-data" type="application/json">{{.Data}}</script>
+    <script>
+        const reviewData = JSON.parse(document.getElementById('review-data').textContent);
+
+        function decisions() {
+            const selected = new Set();
+            document.querySelectorAll('.delete-toggle:checked').forEach(function (el) {
+                selected.add(el.dataset.groupId + '\u0000' + el.dataset.imageId);
+            });
+            return reviewData.groups.map(function (group) {
+                const keep = [], remove = [];
+                group.images.forEach(function (img) {
+                    (selected.has(group.group_id + '\u0000' + img.id) ? remove : keep).push(img.path);
+                });
+                return { group_id: group.group_id, keep: keep, delete: remove };
+            });
+        }
+
+        function shellQuote(s) {
+            return "'" + s.replace(/'/g, "'\\''") + "'";
+        }
+
+        function download(name, type, content) {
+            const link = document.createElement('a');
+            link.href = URL.createObjectURL(new Blob([content], { type: type }));
+            link.download = name;
+            link.click();
+            URL.revokeObjectURL(link.href);
+        }
+
+        function downloadScript() {
+            const lines = ['#!/bin/sh', '# Generated from imaged scan ' + reviewData.scan_id, 'set -e'];
+            decisions().forEach(function (d) {
+                if (d.keep.length === 0) {
+                    lines.push('# skipped ' + d.group_id + ': every image was selected for deletion');
+                    return;
+                }
+                d.delete.forEach(function (p) { lines.push('rm -- ' + shellQuote(p)); });
+            });
+            download('imaged_cleanup.sh', 'text/x-shellscript', lines.join('\n') + '\n');
+        }
+
+        function downloadDecisions() {
+            const payload = { scan_id: reviewData.scan_id, generated_at: new Date().toISOString(), groups: decisions() };
+            download('imaged_decisions.json', 'application/json', JSON.stringify(payload, null, 2));
+        }
+
+        function updateCount() {
+            const n = document.querySelectorAll('.delete-toggle:checked').length;
+            document.getElementById('selected-count').textContent = n + ' selected for deletion';
+        }
+
+        document.querySelectorAll('.delete-toggle').forEach(function (el) {
+            el.addEventListener('change', updateCount);
+        });
+        updateCount();
+    </script>
+</body>
+</html>`
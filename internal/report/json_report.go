@@ -12,7 +12,8 @@ import (
 
 // JSONReportGenerator generates JSON format reports
 type JSONReportGenerator struct {
-	logger *logrus.Logger
+	fingerprints []api.ImageFingerprint
+	logger       *logrus.Logger
 }
 
 // NewJSONReportGenerator creates a new JSON report generator
@@ -22,10 +23,15 @@ func NewJSONReportGenerator() *JSONReportGenerator {
 	}
 }
 
+// SetFingerprints provides the indexed fingerprints the report statistics are computed from
+func (j *JSONReportGenerator) SetFingerprints(fingerprints []api.ImageFingerprint) {
+	j.fingerprints = fingerprints
+}
+
 // Generate generates a comprehensive JSON report
 func (j *JSONReportGenerator) Generate(scanReport *api.ScanReport, outputPath string) error {
 	// Create enhanced report structure
-	enhancedReport := j.enhanceReport(scanReport)
+	enhancedReport := j.enhanceReport(scanReport, j.fingerprints)
 
 	data, err := json.MarshalIndent(enhancedReport, "", "  ")
 	if err != nil {
@@ -41,7 +47,7 @@ func (j *JSONReportGenerator) Generate(scanReport *api.ScanReport, outputPath st
 }
 
 // enhanceReport adds additional information to the basic scan report
-func (j *JSONReportGenerator) enhanceReport(scanReport *api.ScanReport) *EnhancedReport {
+func (j *JSONReportGenerator) enhanceReport(scanReport *api.ScanReport, fingerprints []api.ImageFingerprint) *EnhancedReport {
 	enhanced := &EnhancedReport{
 		ScanReport:  scanReport,
 		GeneratedAt: time.Now(),
@@ -49,7 +55,7 @@ func (j *JSONReportGenerator) enhanceReport(scanReport *api.ScanReport) *Enhance
 	}
 
 	// Calculate additional statistics
-	enhanced.Statistics = j.calculateStatistics(scanReport)
+	enhanced.Statistics = j.calculateStatistics(fingerprints)
 
 	// Add recommendations
 	enhanced.Recommendations = j.generateRecommendations(scanReport)
@@ -86,23 +92,69 @@ type Recommendation struct {
 	Confidence  float64 `json:"confidence"`
 }
 
-// calculateStatistics computes detailed statistics from the scan report
-func (j *JSONReportGenerator) calculateStatistics(scanReport *api.ScanReport) *ReportStatistics {
+// calculateStatistics computes detailed statistics from the indexed fingerprints
+func (j *JSONReportGenerator) calculateStatistics(fingerprints []api.ImageFingerprint) *ReportStatistics {
 	stats := &ReportStatistics{
 		QualityDistribution: make(map[string]int),
 		FormatDistribution:  make(map[string]int),
 		SizeDistribution:    make(map[string]int),
 	}
+	if len(fingerprints) == 0 {
+		return stats
+	}
+
+	var totalBytes int64
+	var totalQuality float64
+	for _, fp := range fingerprints {
+		totalBytes += fp.Metadata.SizeBytes
+		totalQuality += fp.Quality.FinalScore
+
+		stats.QualityDistribution[qualityBand(fp.Quality.FinalScore)]++
+		stats.SizeDistribution[sizeBand(fp.Metadata.SizeBytes)]++
+
+		format := fp.Metadata.Format
+		if format == "" {
+			format = "unknown"
+		}
+		stats.FormatDistribution[format]++
+	}
 
-	// This would be populated from actual fingerprint data
-	// For now, we'll use placeholder values
-	stats.TotalSizeMB = 0 // Would be calculated from actual data
-	stats.AverageFileSizeMB = 0
-	stats.AverageQuality = 0
+	count := float64(len(fingerprints))
+	stats.TotalSizeMB = float64(totalBytes) / bytesPerMB
+	stats.AverageFileSizeMB = stats.TotalSizeMB / count
+	stats.AverageQuality = totalQuality / count
 
 	return stats
 }
 
+// qualityBand returns the 20-point band a quality score falls into; 100 belongs to the top band
+func qualityBand(score float64) string {
+	switch {
+	case score < 20:
+		return "0-20"
+	case score < 40:
+		return "20-40"
+	case score < 60:
+		return "40-60"
+	case score < 80:
+		return "60-80"
+	default:
+		return "80-100"
+	}
+}
+
+// sizeBand returns the file size bucket for a byte count
+func sizeBand(sizeBytes int64) string {
+	switch {
+	case sizeBytes < bytesPerMB:
+		return "<1MB"
+	case sizeBytes <= 5*bytesPerMB:
+		return "1-5MB"
+	default:
+		return ">5MB"
+	}
+}
+
 // generateRecommendations generates actionable recommendations
 func (j *JSONReportGenerator) generateRecommendations(scanReport *api.ScanReport) []*Recommendation {
 	var recommendations []*Recommendation
@@ -0,0 +1,25 @@
+package report
+
+import "github.com/HaiderBassem/imaged/pkg/api"
+
+// bytesPerMB converts byte counts to the MB figures shown in reports
+const bytesPerMB = 1 << 20
+
+// reclaimableBytes sums the file sizes of every duplicate in groups, counting an
+// image once even when it appears in both an exact and a near group
+func reclaimableBytes(groups []api.DuplicateGroup, fingerprints map[api.ImageID]*api.ImageFingerprint) int64 {
+	var total int64
+	counted := make(map[api.ImageID]bool)
+	for _, group := range groups {
+		for _, id := range group.DuplicateIDs {
+			if counted[id] {
+				continue
+			}
+			counted[id] = true
+			if fp, ok := fingerprints[id]; ok {
+				total += fp.Metadata.SizeBytes
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/jpeg"
+	"os"
+
+	"github.com/HaiderBassem/imaged/internal/imaging"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+)
+
+// thumbnailer renders the thumbnails inlined into HTML reports
+type thumbnailer struct {
+	cache       *imaging.ThumbnailCache
+	transformer *pkgimaging.Transformer
+	size        int
+}
+
+// newThumbnailer creates a thumbnailer producing thumbnails of the default size
+func newThumbnailer() *thumbnailer {
+	return &thumbnailer{
+		transformer: pkgimaging.NewTransformer(imaging.DefaultThumbnailSize),
+		size:        imaging.DefaultThumbnailSize,
+	}
+}
+
+// dataURI returns fp's thumbnail as a JPEG data URI
+func (t *thumbnailer) dataURI(fp *api.ImageFingerprint) (string, error) {
+	thumbnail, err := t.thumbnail(fp)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(thumbnail), nil
+}
+
+// thumbnail returns JPEG thumbnail bytes, from the cache when one is set
+func (t *thumbnailer) thumbnail(fp *api.ImageFingerprint) ([]byte, error) {
+	if t.cache != nil {
+		return t.cache.GetOrCreateThumbnail(*fp, t.size)
+	}
+
+	file, err := os.Open(fp.Metadata.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	src, _, _, err := pkgimaging.DecodeRepresentativeFrame(file)
+	if err != nil {
+		return nil, err
+	}
+	src = t.transformer.NormalizeOrientation(src, fp.Metadata.Orientation)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, t.transformer.CreateThumbnail(src, t.size), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
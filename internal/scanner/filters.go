@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -10,6 +11,8 @@ type Filter struct {
 	includeExtensions map[string]bool
 	excludeExtensions map[string]bool
 	excludeDirs       map[string]bool
+	includeGlobs      []string
+	excludeGlobs      []string
 	minFileSize       int64
 	maxFileSize       int64
 }
@@ -52,6 +55,20 @@ func (f *Filter) AddExcludeDir(dirs ...string) {
 	}
 }
 
+// AddIncludeGlob restricts scanning to files matching at least one of the
+// patterns. A pattern containing "/" is matched against the slash-separated
+// path relative to the scan root, any other pattern against the base name.
+// Malformed patterns match nothing.
+func (f *Filter) AddIncludeGlob(patterns ...string) {
+	f.includeGlobs = append(f.includeGlobs, patterns...)
+}
+
+// AddExcludeGlob skips files and whole directories matching any of the
+// patterns, which are interpreted as for AddIncludeGlob
+func (f *Filter) AddExcludeGlob(patterns ...string) {
+	f.excludeGlobs = append(f.excludeGlobs, patterns...)
+}
+
 // SetSizeLimits sets minimum and maximum file size limits
 func (f *Filter) SetSizeLimits(minSize, maxSize int64) {
 	f.minFileSize = minSize
@@ -79,6 +96,33 @@ func (f *Filter) ShouldIncludeDir(dirPath string) bool {
 	return !f.excludeDirs[strings.ToLower(dirName)]
 }
 
+// ShouldIncludeRelPath checks a path relative to the scan root against the
+// glob patterns; include patterns only apply to files
+func (f *Filter) ShouldIncludeRelPath(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	if matchesAnyGlob(f.excludeGlobs, relPath) {
+		return false
+	}
+	if !isDir && len(f.includeGlobs) > 0 {
+		return matchesAnyGlob(f.includeGlobs, relPath)
+	}
+	return true
+}
+
+// matchesAnyGlob reports whether any pattern matches relPath
+func matchesAnyGlob(patterns []string, relPath string) bool {
+	for _, pattern := range patterns {
+		name := relPath
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relPath)
+		}
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
 // isExtensionAllowed checks if file extension is allowed
 func (f *Filter) isExtensionAllowed(filePath string) bool {
 	ext := strings.ToLower(filepath.Ext(filePath))
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// ScanFS walks root in fsys, such as a zip archive or embedded files, and
+// returns the names of the image files that pass the scanner's filters, in
+// lexical order. The extension, size, directory and glob filters apply as
+// for ScanFolder; the minimum dimensions are not checked. Discovery stops
+// with api.ErrScanLimitReached and the names found so far at MaxImages.
+func (s *Scanner) ScanFS(ctx context.Context, fsys fs.FS, root string) ([]string, error) {
+	s.logger.Infof("Starting scan of file system at: %s", root)
+
+	var names []string
+	err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if name == root {
+				return err
+			}
+			s.logger.Warnf("Scan error: %v", err)
+			return nil
+		}
+
+		rel := strings.TrimPrefix(name, root+"/")
+		if root == "." {
+			rel = name
+		}
+
+		if d.IsDir() {
+			if name != root && (!s.filter.ShouldIncludeDir(name) || !s.filter.ShouldIncludeRelPath(rel, true)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if !s.filter.isExtensionAllowed(name) || !s.filter.ShouldIncludeRelPath(rel, false) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			s.logger.Debugf("Failed to get file info for %s: %v", name, err)
+			return nil
+		}
+		if !s.filter.ShouldIncludeFile(name, info.Size()) {
+			return nil
+		}
+
+		if s.config.MaxImages > 0 && len(names) >= s.config.MaxImages {
+			return api.ErrScanLimitReached
+		}
+		names = append(names, name)
+		return nil
+	})
+
+	if err == api.ErrScanLimitReached {
+		s.logger.Warnf("Stopped discovery after %d images", s.config.MaxImages)
+		return names, err
+	}
+	if err != nil {
+		return names, fmt.Errorf("failed to scan file system: %w", err)
+	}
+
+	s.logger.Infof("Scan completed. Found %d images", len(names))
+	return names, nil
+}
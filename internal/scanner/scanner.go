@@ -2,19 +2,31 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"sync"
 
+	"github.com/HaiderBassem/imaged/pkg/api"
+	// Registers the GIF, WebP, BMP, TIFF and HEIC config decoders
+	"github.com/HaiderBassem/imaged/pkg/imaging"
 	"github.com/sirupsen/logrus"
 )
 
 // Scanner handles recursive directory scanning and image file discovery
 type Scanner struct {
 	config Config
+	filter *Filter
 	logger *logrus.Logger
+
+	// configs caches the image headers read for the dimension filter
+	configs   map[string]image.Config
+	configsMu sync.RWMutex
 }
 
 // Config defines scanner behavior and supported formats
@@ -24,13 +36,27 @@ type Config struct {
 	ExcludeDirs      []string
 	MaxFileSize      int64
 	FollowSymlinks   bool
+
+	// MinWidth and MinHeight skip images smaller than either bound, read
+	// from the image header without a full decode; zero disables the check
+	MinWidth  int
+	MinHeight int
+
+	// IncludeGlobs and ExcludeGlobs select files by path relative to the scan
+	// root, e.g. "IMG_*.jpg" or "*/cache/*"; see Filter.AddIncludeGlob
+	IncludeGlobs []string
+	ExcludeGlobs []string
+
+	// MaxImages stops discovery once that many images are found, guarding
+	// against scans of huge trees such as /; zero means no limit
+	MaxImages int
 }
 
 // DefaultConfig returns sensible default scanner configuration
 func DefaultConfig() Config {
 	return Config{
 		NumWorkers:       4,
-		SupportedFormats: []string{".jpg", ".jpeg", ".png", ".webp", ".tiff", ".bmp", ".gif"},
+		SupportedFormats: []string{".jpg", ".jpeg", ".png", ".webp", ".tiff", ".bmp", ".gif", ".heic", ".heif"},
 		ExcludeDirs:      []string{".git", ".svn", ".hg", "node_modules", "__pycache__"},
 		MaxFileSize:      500 * 1024 * 1024, // 500MB
 		FollowSymlinks:   false,
@@ -47,28 +73,89 @@ func NewScanner(cfg Config) *Scanner {
 	logger.SetLevel(logrus.InfoLevel)
 
 	return &Scanner{
-		config: cfg,
-		logger: logger,
+		config:  cfg,
+		filter:  filterFromConfig(cfg),
+		logger:  logger,
+		configs: make(map[string]image.Config),
+	}
+}
+
+// filterFromConfig builds the filter equivalent to a scanner configuration
+func filterFromConfig(cfg Config) *Filter {
+	filter := NewFilter()
+	filter.AddIncludeExtension(cfg.SupportedFormats...)
+	filter.AddExcludeDir(cfg.ExcludeDirs...)
+	filter.AddIncludeGlob(cfg.IncludeGlobs...)
+	filter.AddExcludeGlob(cfg.ExcludeGlobs...)
+	filter.SetSizeLimits(0, cfg.MaxFileSize)
+	return filter
+}
+
+// SetFilter replaces the filter deciding which files and directories are
+// scanned; nil restores the one derived from the scanner's Config
+func (s *Scanner) SetFilter(filter *Filter) {
+	if filter == nil {
+		filter = filterFromConfig(s.config)
 	}
+	s.filter = filter
 }
 
 // ScanResult represents the outcome of a scanning operation
 type ScanResult struct {
 	ImagePaths []string
+	// Errors holds a *FileError for every file or directory that could not be accessed
 	Errors     []error
 	TotalFiles int
 }
 
+// FileError records a file or directory the scanner could not access
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
 // scanDirectory is a compatibility wrapper for worker pool
 func (s *Scanner) scanDirectory(path string) ([]string, error) {
 	var files []string
 	var mu sync.Mutex
-	err := s.processDirectory(path, &files, &mu)
+	err := s.processDirectory(context.Background(), path, path, &files, &mu, nil, nil)
 	return files, err
 }
 
 // ScanFolder recursively scans a directory for image files
 func (s *Scanner) ScanFolder(ctx context.Context, rootPath string) ([]string, error) {
+	return s.ScanFolderProgress(ctx, rootPath, nil)
+}
+
+// ScanFolderProgress scans like ScanFolder and also sends each image path on
+// discovered as soon as it is found, so callers can start on it before the
+// walk completes. Sends block until received or ctx is done; discovered is
+// not closed. The full list is still returned.
+func (s *Scanner) ScanFolderProgress(ctx context.Context, rootPath string, discovered chan<- string) ([]string, error) {
+	result, err := s.ScanFolderWithResult(ctx, rootPath, discovered)
+	if err != nil && !errors.Is(err, api.ErrScanLimitReached) {
+		return nil, err
+	}
+
+	if len(result.Errors) > 0 && len(result.ImagePaths) == 0 {
+		return nil, fmt.Errorf("scan failed with %d errors: %v", len(result.Errors), result.Errors[0])
+	}
+	return result.ImagePaths, err
+}
+
+// ScanFolderWithResult scans like ScanFolderProgress but also returns every
+// file and directory that could not be accessed. An error is returned when
+// rootPath itself cannot be scanned, or api.ErrScanLimitReached together
+// with the partial result when discovery stopped at Config.MaxImages.
+func (s *Scanner) ScanFolderWithResult(ctx context.Context, rootPath string, discovered chan<- string) (*ScanResult, error) {
 	absPath, err := filepath.Abs(rootPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
@@ -85,6 +172,14 @@ func (s *Scanner) ScanFolder(ctx context.Context, rootPath string) ([]string, er
 		return nil, fmt.Errorf("path is not a directory: %s", absPath)
 	}
 
+	s.configsMu.Lock()
+	s.configs = make(map[string]image.Config)
+	s.configsMu.Unlock()
+
+	// Reaching MaxImages cancels the rest of the walk
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var imagePaths []string
 	var mu sync.Mutex
 	var wg sync.WaitGroup
@@ -96,11 +191,16 @@ func (s *Scanner) ScanFolder(ctx context.Context, rootPath string) ([]string, er
 	// Start worker goroutines
 	for i := 0; i < s.config.NumWorkers; i++ {
 		wg.Add(1)
-		go s.worker(ctx, i, jobs, &imagePaths, &mu, &wg, errors)
+		go s.worker(ctx, i, absPath, jobs, &imagePaths, &mu, &wg, errors, discovered)
 	}
 
-	// Start directory walker
-	go s.walkDirectories(ctx, absPath, jobs, errors)
+	// Start directory walker; it may still report errors after cancelled
+	// workers exit, so it also holds the wait group
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.walkDirectories(ctx, absPath, jobs, errors)
+	}()
 
 	// Wait for completion and collect errors
 	go func() {
@@ -110,7 +210,13 @@ func (s *Scanner) ScanFolder(ctx context.Context, rootPath string) ([]string, er
 
 	// Process errors
 	var scanErrors []error
+	limitReached := false
 	for err := range errors {
+		if err == api.ErrScanLimitReached {
+			limitReached = true
+			cancel()
+			continue
+		}
 		if err != nil {
 			scanErrors = append(scanErrors, err)
 			s.logger.Warnf("Scan error: %v", err)
@@ -119,15 +225,20 @@ func (s *Scanner) ScanFolder(ctx context.Context, rootPath string) ([]string, er
 
 	s.logger.Infof("Scan completed. Found %d images, %d errors", len(imagePaths), len(scanErrors))
 
-	if len(scanErrors) > 0 && len(imagePaths) == 0 {
-		return nil, fmt.Errorf("scan failed with %d errors: %v", len(scanErrors), scanErrors[0])
+	result := &ScanResult{
+		ImagePaths: imagePaths,
+		Errors:     scanErrors,
+		TotalFiles: len(imagePaths),
 	}
-
-	return imagePaths, nil
+	if limitReached {
+		s.logger.Warnf("Stopped discovery after %d images", s.config.MaxImages)
+		return result, api.ErrScanLimitReached
+	}
+	return result, nil
 }
 
 // worker processes directories from the jobs channel
-func (s *Scanner) worker(ctx context.Context, id int, jobs <-chan string, imagePaths *[]string, mu *sync.Mutex, wg *sync.WaitGroup, errors chan<- error) {
+func (s *Scanner) worker(ctx context.Context, id int, root string, jobs <-chan string, imagePaths *[]string, mu *sync.Mutex, wg *sync.WaitGroup, errors chan<- error, discovered chan<- string) {
 	defer wg.Done()
 
 	for dir := range jobs {
@@ -136,8 +247,8 @@ func (s *Scanner) worker(ctx context.Context, id int, jobs <-chan string, imageP
 			s.logger.Debugf("Worker %d stopping due to context cancellation", id)
 			return
 		default:
-			if err := s.processDirectory(dir, imagePaths, mu); err != nil {
-				errors <- fmt.Errorf("worker %d: %w", id, err)
+			if err := s.processDirectory(ctx, root, dir, imagePaths, mu, discovered, errors); err != nil {
+				errors <- err
 			}
 		}
 	}
@@ -149,7 +260,10 @@ func (s *Scanner) walkDirectories(ctx context.Context, root string, jobs chan<-
 
 	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			errors <- fmt.Errorf("access error at %s: %w", path, err)
+			// Unreadable directories are reported by the worker that reads them
+			if info == nil || !info.IsDir() {
+				errors <- &FileError{Path: path, Err: err}
+			}
 			return nil // Continue walking
 		}
 
@@ -162,28 +276,35 @@ func (s *Scanner) walkDirectories(ctx context.Context, root string, jobs chan<-
 
 		if info.IsDir() {
 			// Skip excluded directories
-			if s.isExcludedDirectory(path) {
+			if path != root && !s.shouldIncludeDir(root, path) {
 				s.logger.Debugf("Skipping excluded directory: %s", path)
 				return filepath.SkipDir
 			}
 
-			// Send directory to workers for processing
-			jobs <- path
+			// Send directory to workers for processing; the workers stop
+			// reading once ctx is done, so the send must not block forever
+			select {
+			case jobs <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 
 		return nil
 	})
 
-	if err != nil && err != context.Canceled {
+	if err != nil && ctx.Err() == nil {
 		errors <- fmt.Errorf("directory walk error: %w", err)
 	}
 }
 
-// processDirectory scans a single directory for image files
-func (s *Scanner) processDirectory(dir string, imagePaths *[]string, mu *sync.Mutex) error {
+// processDirectory scans a single directory under root for image files and
+// sends each one on discovered when it is non-nil. Files that cannot be
+// accessed are sent on errors, or only logged when errors is nil.
+func (s *Scanner) processDirectory(ctx context.Context, root, dir string, imagePaths *[]string, mu *sync.Mutex, discovered chan<- string, errors chan<- error) error {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+		return &FileError{Path: dir, Err: fmt.Errorf("failed to read directory: %w", err)}
 	}
 
 	var dirImagePaths []string
@@ -195,67 +316,136 @@ func (s *Scanner) processDirectory(dir string, imagePaths *[]string, mu *sync.Mu
 
 		filePath := filepath.Join(dir, entry.Name())
 
-		// Check if file is a supported image format
-		if s.isImageFile(filePath) {
-			// Check file size if configured
-			if s.config.MaxFileSize > 0 {
-				info, err := entry.Info()
-				if err != nil {
-					s.logger.Debugf("Failed to get file info for %s: %v", filePath, err)
-					continue
-				}
-
-				if info.Size() > s.config.MaxFileSize {
-					s.logger.Debugf("Skipping large file: %s (%d bytes)", filePath, info.Size())
-					continue
-				}
-			}
+		// Check the extension and patterns first so non-images are never stat'ed
+		if !s.filter.isExtensionAllowed(filePath) {
+			continue
+		}
+		if rel, err := filepath.Rel(root, filePath); err == nil && !s.filter.ShouldIncludeRelPath(rel, false) {
+			continue
+		}
 
-			dirImagePaths = append(dirImagePaths, filePath)
+		// Symlinked images are sized by their target, and broken links are reported
+		var info os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err = os.Stat(filePath)
+		} else {
+			info, err = entry.Info()
+		}
+		if err != nil {
+			s.logger.Debugf("Failed to get file info for %s: %v", filePath, err)
+			if errors != nil {
+				errors <- &FileError{Path: filePath, Err: err}
+			}
+			continue
+		}
+		if !s.filter.ShouldIncludeFile(filePath, info.Size()) {
+			s.logger.Debugf("Skipping file outside size limits: %s (%d bytes)", filePath, info.Size())
+			continue
+		}
+		if !s.meetsMinDimensions(filePath) {
+			continue
 		}
+
+		dirImagePaths = append(dirImagePaths, filePath)
 	}
 
-	// Add discovered images to the main list
+	// Add discovered images to the main list, up to MaxImages in total
+	limitReached := false
 	if len(dirImagePaths) > 0 {
 		mu.Lock()
+		if s.config.MaxImages > 0 {
+			remaining := s.config.MaxImages - len(*imagePaths)
+			if remaining <= len(dirImagePaths) {
+				dirImagePaths = dirImagePaths[:max(remaining, 0)]
+				limitReached = true
+			}
+		}
 		*imagePaths = append(*imagePaths, dirImagePaths...)
 		mu.Unlock()
 
 		s.logger.Debugf("Found %d images in %s", len(dirImagePaths), dir)
 	}
 
-	return nil
-}
+	var limitErr error
+	if limitReached {
+		limitErr = api.ErrScanLimitReached
+	}
 
-// isImageFile checks if a file has a supported image extension
-func (s *Scanner) isImageFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	for _, supportedExt := range s.config.SupportedFormats {
-		if ext == supportedExt {
-			return true
+	if discovered != nil {
+		for _, path := range dirImagePaths {
+			select {
+			case discovered <- path:
+			case <-ctx.Done():
+				return limitErr
+			}
 		}
 	}
-	return false
+
+	return limitErr
+}
+
+// shouldIncludeDir checks a directory below root against the excluded
+// names and glob patterns
+func (s *Scanner) shouldIncludeDir(root, dir string) bool {
+	if !s.filter.ShouldIncludeDir(dir) {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	return err != nil || s.filter.ShouldIncludeRelPath(rel, true)
 }
 
-// isExcludedDirectory checks if a directory should be excluded from scanning
-func (s *Scanner) isExcludedDirectory(path string) bool {
-	dirName := filepath.Base(path)
-	for _, excluded := range s.config.ExcludeDirs {
-		if strings.EqualFold(dirName, excluded) {
-			return true
-		}
+// meetsMinDimensions reports whether an image is at least MinWidth x MinHeight.
+// Files whose header cannot be read are kept so the engine can report them.
+func (s *Scanner) meetsMinDimensions(path string) bool {
+	if s.config.MinWidth <= 0 && s.config.MinHeight <= 0 {
+		return true
 	}
-	return false
+
+	file, err := os.Open(path)
+	if err != nil {
+		s.logger.Debugf("Failed to open %s for dimension check: %v", path, err)
+		return true
+	}
+	defer file.Close()
+
+	cfg, _, err := imaging.DecodeConfig(file)
+	if err != nil {
+		s.logger.Debugf("Failed to read image header of %s: %v", path, err)
+		return true
+	}
+
+	s.configsMu.Lock()
+	s.configs[path] = cfg
+	s.configsMu.Unlock()
+
+	if cfg.Width < s.config.MinWidth || cfg.Height < s.config.MinHeight {
+		s.logger.Debugf("Skipping small image: %s (%dx%d)", path, cfg.Width, cfg.Height)
+		return false
+	}
+	return true
+}
+
+// ImageConfig returns the image header read for path during the last scan,
+// if the dimension filter read one
+func (s *Scanner) ImageConfig(path string) (image.Config, bool) {
+	s.configsMu.RLock()
+	defer s.configsMu.RUnlock()
+	cfg, ok := s.configs[path]
+	return cfg, ok
 }
 
-// GetSupportedFormats returns the list of supported image formats
+// GetSupportedFormats returns the sorted list of extensions the filter includes
 func (s *Scanner) GetSupportedFormats() []string {
-	return s.config.SupportedFormats
+	formats := s.filter.GetSupportedExtensions()
+	sort.Strings(formats)
+	return formats
 }
 
-// SetSupportedFormats updates the list of supported image formats
+// SetSupportedFormats updates the list of supported image formats, keeping
+// the filter's other settings
 func (s *Scanner) SetSupportedFormats(formats []string) {
 	s.config.SupportedFormats = formats
+	s.filter.includeExtensions = make(map[string]bool)
+	s.filter.AddIncludeExtension(formats...)
 	s.logger.Infof("Updated supported formats: %v", formats)
 }
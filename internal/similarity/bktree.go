@@ -0,0 +1,139 @@
+package similarity
+
+import (
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// BKTree indexes perceptual hashes by Hamming distance so that all hashes
+// within a radius can be found without comparing against every entry. A tree
+// holds either 64-bit hashes or wide hashes of a single size, never both.
+type BKTree struct {
+	root *bkNode
+	size int
+}
+
+// bkNode holds every ID sharing one hash and its children indexed by distance
+type bkNode struct {
+	hash     []uint64
+	ids      []api.ImageID
+	children []*bkNode
+}
+
+// NewBKTree creates an empty BK-tree
+func NewBKTree() *BKTree {
+	return &BKTree{}
+}
+
+// Insert adds an image hash to the tree
+func (t *BKTree) Insert(id api.ImageID, hash uint64) {
+	t.insert(id, []uint64{hash})
+}
+
+// InsertWide adds an image's wide hash to the tree
+func (t *BKTree) InsertWide(id api.ImageID, hash []byte) {
+	t.insert(id, wideHashWords(hash))
+}
+
+// insert adds a hash, split into 64-bit words, to the tree
+func (t *BKTree) insert(id api.ImageID, hash []uint64) {
+	t.size++
+
+	if t.root == nil {
+		t.root = &bkNode{hash: hash, ids: []api.ImageID{id}}
+		return
+	}
+
+	node := t.root
+	for {
+		distance := wordsDistance(node.hash, hash)
+		if distance == 0 {
+			node.ids = append(node.ids, id)
+			return
+		}
+
+		if distance >= len(node.children) {
+			grown := make([]*bkNode, distance+1)
+			copy(grown, node.children)
+			node.children = grown
+		}
+		if node.children[distance] == nil {
+			node.children[distance] = &bkNode{hash: hash, ids: []api.ImageID{id}}
+			return
+		}
+		node = node.children[distance]
+	}
+}
+
+// Query returns the IDs of all hashes within maxDistance of hash
+func (t *BKTree) Query(hash uint64, maxDistance int) []api.ImageID {
+	return t.query([]uint64{hash}, maxDistance)
+}
+
+// QueryWide returns the IDs of all wide hashes within maxDistance of hash
+func (t *BKTree) QueryWide(hash []byte, maxDistance int) []api.ImageID {
+	return t.query(wideHashWords(hash), maxDistance)
+}
+
+// query returns the IDs of all hashes within maxDistance of hash
+func (t *BKTree) query(hash []uint64, maxDistance int) []api.ImageID {
+	if t.root == nil {
+		return nil
+	}
+
+	var results []api.ImageID
+	stack := []*bkNode{t.root}
+
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		distance := wordsDistance(node.hash, hash)
+		if distance <= maxDistance {
+			results = append(results, node.ids...)
+		}
+
+		// By the triangle inequality only children in [d-r, d+r] can match
+		lo, hi := distance-maxDistance, distance+maxDistance
+		if lo < 1 {
+			lo = 1
+		}
+		if hi >= len(node.children) {
+			hi = len(node.children) - 1
+		}
+		for d := lo; d <= hi; d++ {
+			if child := node.children[d]; child != nil {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	return results
+}
+
+// Len returns the number of inserted entries
+func (t *BKTree) Len() int {
+	return t.size
+}
+
+// wideHashWords packs a wide hash into 64-bit words, zero-padding the last
+func wideHashWords(hash []byte) []uint64 {
+	words := make([]uint64, (len(hash)+7)/8)
+	for i := range words {
+		var word [8]byte
+		copy(word[:], hash[i*8:])
+		words[i] = binary.BigEndian.Uint64(word[:])
+	}
+	return words
+}
+
+// wordsDistance returns the Hamming distance between two equally sized hashes
+func wordsDistance(a, b []uint64) int {
+	distance := 0
+	for i := range a {
+		distance += bits.OnesCount64(a[i] ^ b[i])
+	}
+	return distance
+}
@@ -1,48 +1,138 @@
 package similarity
 
 import (
+	"math"
+	"math/rand"
+	"time"
+
 	"github.com/HaiderBassem/imaged/pkg/api"
 )
 
 // Clusterer performs advanced image clustering
 type Clusterer struct {
-	comparator *Comparator
+	comparator   *Comparator
+	fingerprints map[api.ImageID]api.ImageFingerprint
+	rng          *rand.Rand
 }
 
-// NewClusterer creates a new image clusterer
-func NewClusterer(comparator *Comparator) *Clusterer {
+// NewClusterer creates a new image clusterer over the given fingerprint set
+func NewClusterer(comparator *Comparator, fingerprints []api.ImageFingerprint) *Clusterer {
+	byID := make(map[api.ImageID]api.ImageFingerprint, len(fingerprints))
+	for _, fp := range fingerprints {
+		byID[fp.ID] = fp
+	}
+
 	return &Clusterer{
-		comparator: comparator,
+		comparator:   comparator,
+		fingerprints: byID,
+		rng:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetRandSource replaces the randomness used for k-means seeding, making results reproducible
+func (c *Clusterer) SetRandSource(src rand.Source) {
+	c.rng = rand.New(src)
+}
+
+// LinkageMethod chooses how hierarchical clustering scores the similarity
+// of two clusters from the similarities of their members
+type LinkageMethod int
+
+const (
+	// LinkageAverage uses the mean similarity over all cross-cluster pairs (UPGMA)
+	LinkageAverage LinkageMethod = iota
+	// LinkageSingle uses the most similar pair, so clusters chain through
+	// neighbours even when their ends look nothing alike
+	LinkageSingle
+	// LinkageComplete uses the least similar pair, so every member of a
+	// cluster is within the threshold of every other
+	LinkageComplete
+)
+
+// clusterLink is the linkage between two clusters: the summed similarity
+// (average linkage) or the extreme one (single and complete), over the
+// member pairs that could be compared
+type clusterLink struct {
+	value float64
+	pairs int
+}
+
+// score returns the linkage similarity, zero when no pair could be compared
+func (l clusterLink) score(method LinkageMethod) float64 {
+	if l.pairs == 0 {
+		return 0
+	}
+	if method == LinkageAverage {
+		return l.value / float64(l.pairs)
+	}
+	return l.value
+}
+
+// merge combines the links of two clusters to a third into the link of
+// their union to it
+func (l clusterLink) merge(other clusterLink, method LinkageMethod) clusterLink {
+	switch {
+	case l.pairs == 0:
+		return other
+	case other.pairs == 0:
+		return l
 	}
+
+	merged := clusterLink{pairs: l.pairs + other.pairs}
+	switch method {
+	case LinkageSingle:
+		merged.value = math.Max(l.value, other.value)
+	case LinkageComplete:
+		merged.value = math.Min(l.value, other.value)
+	default:
+		merged.value = l.value + other.value
+	}
+	return merged
 }
 
-// ClusterBySimilarity performs hierarchical clustering
-func (c *Clusterer) ClusterBySimilarity(fingerprints []api.ImageFingerprint, threshold float64) []api.Cluster {
+// ClusterBySimilarity performs agglomerative hierarchical clustering,
+// repeatedly merging the two clusters with the highest linkage similarity
+// until none reaches threshold. Every pair of images is compared once; the
+// links of a merged cluster are derived from those of its two parts.
+func (c *Clusterer) ClusterBySimilarity(fingerprints []api.ImageFingerprint, threshold float64, linkage LinkageMethod) []api.Cluster {
 	if len(fingerprints) == 0 {
 		return []api.Cluster{}
 	}
 
 	// Start with each image in its own cluster
 	clusters := c.initializeClusters(fingerprints)
+	links := c.initialLinks(fingerprints)
+	active := make([]bool, len(clusters))
+	for i := range active {
+		active[i] = true
+	}
 
 	// Perform hierarchical clustering
 	for {
-		if len(clusters) <= 1 {
-			break
-		}
-
-		// Find closest clusters
-		closestI, closestJ, maxSimilarity := c.findClosestClusters(clusters)
-
-		if maxSimilarity < threshold {
+		closestI, closestJ, maxSimilarity := findClosestClusters(links, active, linkage)
+		if closestI < 0 || maxSimilarity < threshold {
 			break // No more clusters to merge
 		}
 
-		// Merge clusters
-		clusters = c.mergeClusters(clusters, closestI, closestJ)
+		// Merge cluster j into cluster i
+		clusters[closestI].Images = append(clusters[closestI].Images, clusters[closestJ].Images...)
+		active[closestJ] = false
+		for k := range clusters {
+			if !active[k] || k == closestI {
+				continue
+			}
+			merged := links[closestI][k].merge(links[closestJ][k], linkage)
+			links[closestI][k], links[k][closestI] = merged, merged
+		}
 	}
 
-	return clusters
+	result := make([]api.Cluster, 0, len(clusters))
+	for i, cluster := range clusters {
+		if active[i] {
+			result = append(result, cluster)
+		}
+	}
+	return result
 }
 
 // initializeClusters creates initial single-element clusters
@@ -57,67 +147,58 @@ func (c *Clusterer) initializeClusters(fingerprints []api.ImageFingerprint) []ap
 	return clusters
 }
 
-// findClosestClusters finds the two most similar clusters
-func (c *Clusterer) findClosestClusters(clusters []api.Cluster) (int, int, float64) {
-	var maxSimilarity float64
-	var closestI, closestJ int
+// initialLinks compares every pair of images once; pairs that fail to
+// compare are left out of the linkage
+func (c *Clusterer) initialLinks(fingerprints []api.ImageFingerprint) [][]clusterLink {
+	links := make([][]clusterLink, len(fingerprints))
+	for i := range links {
+		links[i] = make([]clusterLink, len(fingerprints))
+	}
 
-	for i := 0; i < len(clusters); i++ {
-		for j := i + 1; j < len(clusters); j++ {
-			similarity := c.clusterSimilarity(clusters[i], clusters[j])
-			if similarity > maxSimilarity {
-				maxSimilarity = similarity
-				closestI = i
-				closestJ = j
+	for i := range fingerprints {
+		for j := i + 1; j < len(fingerprints); j++ {
+			similarity, err := c.comparator.CompareFingerprints(fingerprints[i], fingerprints[j])
+			if err != nil {
+				continue
 			}
+			link := clusterLink{value: similarity, pairs: 1}
+			links[i][j], links[j][i] = link, link
 		}
 	}
-
-	return closestI, closestJ, maxSimilarity
+	return links
 }
 
-// clusterSimilarity calculates similarity between two clusters
-func (c *Clusterer) clusterSimilarity(cluster1, cluster2 api.Cluster) float64 {
-	var totalSimilarity float64
-	var comparisons int
-
-	// Use average linkage (average similarity between all pairs)
-	for _, img1 := range cluster1.Images {
-		for _, img2 := range cluster2.Images {
-			// In a real implementation, you would compare the actual fingerprints
-			// For now, we'll use a simplified approach
-			fp1 := c.findFingerprint(img1)
-			fp2 := c.findFingerprint(img2)
-
-			if fp1 != nil && fp2 != nil {
-				similarity, err := c.comparator.CompareFingerprints(*fp1, *fp2)
-				if err == nil {
-					totalSimilarity += similarity
-					comparisons++
-				}
+// findClosestClusters finds the two active clusters with the highest
+// linkage similarity, returning -1 when fewer than two remain
+func findClosestClusters(links [][]clusterLink, active []bool, linkage LinkageMethod) (int, int, float64) {
+	var maxSimilarity float64
+	closestI, closestJ := -1, -1
+
+	for i := range links {
+		if !active[i] {
+			continue
+		}
+		for j := i + 1; j < len(links); j++ {
+			if !active[j] {
+				continue
+			}
+			similarity := links[i][j].score(linkage)
+			if closestI < 0 || similarity > maxSimilarity {
+				maxSimilarity = similarity
+				closestI = i
+				closestJ = j
 			}
 		}
 	}
 
-	if comparisons == 0 {
-		return 0.0
-	}
-
-	return totalSimilarity / float64(comparisons)
-}
-
-// mergeClusters merges two clusters
-func (c *Clusterer) mergeClusters(clusters []api.Cluster, i, j int) []api.Cluster {
-	// Merge cluster j into cluster i
-	clusters[i].Images = append(clusters[i].Images, clusters[j].Images...)
-
-	// Remove cluster j
-	return append(clusters[:j], clusters[j+1:]...)
+	return closestI, closestJ, maxSimilarity
 }
 
-// DBSCANClustering performs density-based clustering
+// DBSCANClustering performs density-based clustering; points that are not
+// density-reachable from any core point are treated as noise and omitted
 func (c *Clusterer) DBSCANClustering(fingerprints []api.ImageFingerprint, eps float64, minPts int) []api.Cluster {
 	visited := make(map[api.ImageID]bool)
+	clustered := make(map[api.ImageID]bool)
 	clusters := []api.Cluster{}
 	clusterID := 0
 
@@ -130,7 +211,7 @@ func (c *Clusterer) DBSCANClustering(fingerprints []api.ImageFingerprint, eps fl
 		neighbors := c.rangeQuery(fingerprints, fp, eps)
 
 		if len(neighbors) < minPts {
-			// Noise point (will be handled later)
+			// Noise for now; may still join a cluster later as a border point
 			continue
 		}
 
@@ -139,9 +220,10 @@ func (c *Clusterer) DBSCANClustering(fingerprints []api.ImageFingerprint, eps fl
 			ClusterID: generateClusterID(clusterID),
 			Images:    []api.ImageID{fp.ID},
 		}
+		clustered[fp.ID] = true
 		clusterID++
 
-		neighbors = c.expandCluster(fingerprints, neighbors, cluster, eps, minPts, visited)
+		c.expandCluster(fingerprints, neighbors, &cluster, eps, minPts, visited, clustered)
 		clusters = append(clusters, cluster)
 	}
 
@@ -162,14 +244,18 @@ func (c *Clusterer) rangeQuery(fingerprints []api.ImageFingerprint, point api.Im
 	return neighbors
 }
 
-// expandCluster expands a cluster based on density
-func (c *Clusterer) expandCluster(fingerprints, seeds []api.ImageFingerprint, cluster api.Cluster, eps float64, minPts int, visited map[api.ImageID]bool) []api.ImageFingerprint {
+// expandCluster adds every point density-reachable from seeds to cluster
+func (c *Clusterer) expandCluster(fingerprints, seeds []api.ImageFingerprint, cluster *api.Cluster, eps float64, minPts int, visited, clustered map[api.ImageID]bool) {
 	for i := 0; i < len(seeds); i++ {
 		point := seeds[i]
 
+		if !clustered[point.ID] {
+			clustered[point.ID] = true
+			cluster.Images = append(cluster.Images, point.ID)
+		}
+
 		if !visited[point.ID] {
 			visited[point.ID] = true
-			cluster.Images = append(cluster.Images, point.ID)
 
 			pointNeighbors := c.rangeQuery(fingerprints, point, eps)
 			if len(pointNeighbors) >= minPts {
@@ -177,8 +263,6 @@ func (c *Clusterer) expandCluster(fingerprints, seeds []api.ImageFingerprint, cl
 			}
 		}
 	}
-
-	return seeds
 }
 
 // KMeansClustering performs K-means clustering (simplified)
@@ -209,7 +293,7 @@ func (c *Clusterer) KMeansClustering(fingerprints []api.ImageFingerprint, k int,
 		}
 
 		// Update centroids
-		newCentroids := c.updateCentroids(clusters, fingerprints)
+		newCentroids := c.updateCentroids(clusters, centroids)
 
 		// Check for convergence
 		if c.centroidsConverged(centroids, newCentroids) {
@@ -222,13 +306,51 @@ func (c *Clusterer) KMeansClustering(fingerprints []api.ImageFingerprint, k int,
 	return clusters
 }
 
-// initializeCentroids initializes K centroids randomly
+// initializeCentroids picks K centroids with k-means++ seeding: the first at
+// random, each next one with probability proportional to its squared distance
+// from the nearest centroid chosen so far
 func (c *Clusterer) initializeCentroids(fingerprints []api.ImageFingerprint, k int) []api.ImageFingerprint {
-	centroids := make([]api.ImageFingerprint, k)
+	centroids := make([]api.ImageFingerprint, 0, k)
+	centroids = append(centroids, fingerprints[c.rng.Intn(len(fingerprints))])
 
-	// Simple random selection (in practice, use better initialization like k-means++)
-	for i := 0; i < k; i++ {
-		centroids[i] = fingerprints[i%len(fingerprints)]
+	// Squared distance from each point to its nearest centroid
+	weights := make([]float64, len(fingerprints))
+	for i := range weights {
+		weights[i] = -1
+	}
+
+	for len(centroids) < k {
+		latest := centroids[len(centroids)-1]
+
+		var total float64
+		for i, fp := range fingerprints {
+			similarity, err := c.comparator.CompareFingerprints(fp, latest)
+			if err != nil {
+				similarity = 0
+			}
+			distance := (1 - similarity) * (1 - similarity)
+			if weights[i] < 0 || distance < weights[i] {
+				weights[i] = distance
+			}
+			total += weights[i]
+		}
+
+		// Every point coincides with a centroid; fall back to a uniform pick
+		if total == 0 {
+			centroids = append(centroids, fingerprints[c.rng.Intn(len(fingerprints))])
+			continue
+		}
+
+		target := c.rng.Float64() * total
+		chosen := len(fingerprints) - 1
+		for i, weight := range weights {
+			target -= weight
+			if target < 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, fingerprints[chosen])
 	}
 
 	return centroids
@@ -256,27 +378,34 @@ func (c *Clusterer) findNearestCentroid(point api.ImageFingerprint, centroids []
 }
 
 // updateCentroids updates cluster centroids
-func (c *Clusterer) updateCentroids(clusters []api.Cluster, fingerprints []api.ImageFingerprint) []api.ImageFingerprint {
+func (c *Clusterer) updateCentroids(clusters []api.Cluster, current []api.ImageFingerprint) []api.ImageFingerprint {
 	centroids := make([]api.ImageFingerprint, len(clusters))
 
 	for i, cluster := range clusters {
 		if len(cluster.Images) == 0 {
 			// Keep current centroid if cluster is empty
+			centroids[i] = current[i]
 			continue
 		}
 
 		// Find the most central point in the cluster
-		centroids[i] = c.findMedoid(cluster, fingerprints)
+		centroids[i] = c.findMedoid(cluster)
 	}
 
 	return centroids
 }
 
 // findMedoid finds the most central point in a cluster
-func (c *Clusterer) findMedoid(cluster api.Cluster, fingerprints []api.ImageFingerprint) api.ImageFingerprint {
+func (c *Clusterer) findMedoid(cluster api.Cluster) api.ImageFingerprint {
 	var bestFingerprint api.ImageFingerprint
 	var minTotalDistance float64 = -1
 
+	if len(cluster.Images) == 1 {
+		if fp := c.findFingerprint(cluster.Images[0]); fp != nil {
+			return *fp
+		}
+	}
+
 	for _, imgID := range cluster.Images {
 		fp := c.findFingerprint(imgID)
 		if fp == nil {
@@ -329,10 +458,13 @@ func (c *Clusterer) centroidsConverged(oldCentroids, newCentroids []api.ImageFin
 	return true
 }
 
-// findFingerprint helper function (would be connected to index in real implementation)
+// findFingerprint looks up a fingerprint from the clusterer's set
 func (c *Clusterer) findFingerprint(imageID api.ImageID) *api.ImageFingerprint {
-	// This would query the index in a real implementation
-	return nil
+	fp, ok := c.fingerprints[imageID]
+	if !ok {
+		return nil
+	}
+	return &fp
 }
 
 // Utility functions
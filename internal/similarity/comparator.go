@@ -2,13 +2,16 @@ package similarity
 
 import (
 	"math"
+	"math/bits"
 
+	"github.com/HaiderBassem/imaged/internal/hash"
 	"github.com/HaiderBassem/imaged/pkg/api"
 )
 
 // Comparator handles image similarity comparison using multiple algorithms
 type Comparator struct {
 	config ComparatorConfig
+	colors *hash.ColorSignature
 }
 
 // ComparatorConfig defines similarity comparison parameters
@@ -19,6 +22,26 @@ type ComparatorConfig struct {
 	PHashWeight   float64
 	DHashWeight   float64
 	WHashWeight   float64
+
+	// ColorHistWeight blends in color histogram similarity, measured with
+	// ColorHistMetric, when both fingerprints carry a ColorHist (0 ignores color)
+	ColorHistWeight float64
+	ColorHistMetric hash.HistogramMetric
+
+	// MaxAspectRatioDiff is the largest relative difference between the display
+	// aspect ratios of two images that may still match (0 disables the guard)
+	MaxAspectRatioDiff float64
+
+	// RotationInvariant also scores each pair with either image's hashes
+	// replaced by its stored Rotations and keeps the best score. The
+	// aspect-ratio guard then matches an image against a copy rotated by 90
+	// degrees, whose ratio is the inverse.
+	RotationInvariant bool
+
+	// FlipInvariant also scores each pair with the second image's hashes
+	// mirrored horizontally and keeps the better score, so mirrored copies
+	// match. It roughly doubles the cost of every comparison.
+	FlipInvariant bool
 }
 
 // NewComparator creates a new similarity comparator
@@ -33,29 +56,80 @@ func NewComparator(cfg ComparatorConfig) *Comparator {
 
 	return &Comparator{
 		config: cfg,
+		colors: hash.NewColorSignature(16),
 	}
 }
 
-// CompareFingerprints calculates similarity between two image fingerprints
+// CompareFingerprints calculates similarity between two image fingerprints,
+// keeping the best score over the orientations the config allows
 func (c *Comparator) CompareFingerprints(fp1, fp2 api.ImageFingerprint) (float64, error) {
+	var best float64
+	for _, pair := range c.orientations(fp1, fp2) {
+		similarity, err := c.compareFingerprints(pair[0], pair[1])
+		if err != nil {
+			return 0, err
+		}
+		best = math.Max(best, similarity)
+	}
+	return best, nil
+}
+
+// orientations returns the pairs a comparison scores: fp1 and fp2 as stored,
+// fp2 mirrored under FlipInvariant, and under RotationInvariant each stored
+// rotation of one image against the other image
+func (c *Comparator) orientations(fp1, fp2 api.ImageFingerprint) [][2]api.ImageFingerprint {
+	pairs := [][2]api.ImageFingerprint{{fp1, fp2}}
+	if c.config.FlipInvariant {
+		mirrored := fp2
+		mirrored.PHashes = MirrorHashes(fp2.PHashes)
+		pairs = append(pairs, [2]api.ImageFingerprint{fp1, mirrored})
+	}
+	if c.config.RotationInvariant {
+		for _, rotated := range rotations(fp2) {
+			pairs = append(pairs, [2]api.ImageFingerprint{fp1, rotated})
+		}
+		for _, rotated := range rotations(fp1) {
+			pairs = append(pairs, [2]api.ImageFingerprint{rotated, fp2})
+		}
+	}
+	return pairs
+}
+
+// rotations returns a copy of fp carrying the hashes of each of its Rotations
+func rotations(fp api.ImageFingerprint) []api.ImageFingerprint {
+	out := make([]api.ImageFingerprint, 0, len(fp.PHashes.Rotations))
+	for _, hashes := range fp.PHashes.Rotations {
+		rotated := fp
+		rotated.PHashes = hashes
+		out = append(out, rotated)
+	}
+	return out
+}
+
+// compareFingerprints scores two fingerprints in their stored orientation
+func (c *Comparator) compareFingerprints(fp1, fp2 api.ImageFingerprint) (float64, error) {
+	if !c.aspectRatioCompatible(fp1.Metadata, fp2.Metadata) {
+		return 0.0, nil
+	}
+
 	var totalSimilarity float64
 	var totalWeight float64
 
 	// Compare each type of hash with its respective weight
 	if c.config.AHashWeight > 0 && fp1.PHashes.AHash != 0 && fp2.PHashes.AHash != 0 {
-		similarity := c.compareAHash(fp1.PHashes.AHash, fp2.PHashes.AHash)
+		similarity := c.aHashSimilarity(fp1.PHashes, fp2.PHashes)
 		totalSimilarity += similarity * c.config.AHashWeight
 		totalWeight += c.config.AHashWeight
 	}
 
 	if c.config.PHashWeight > 0 && fp1.PHashes.PHash != 0 && fp2.PHashes.PHash != 0 {
-		similarity := c.comparePHash(fp1.PHashes.PHash, fp2.PHashes.PHash)
+		similarity := c.pHashSimilarity(fp1.PHashes, fp2.PHashes)
 		totalSimilarity += similarity * c.config.PHashWeight
 		totalWeight += c.config.PHashWeight
 	}
 
 	if c.config.DHashWeight > 0 && fp1.PHashes.DHash != 0 && fp2.PHashes.DHash != 0 {
-		similarity := c.compareDHash(fp1.PHashes.DHash, fp2.PHashes.DHash)
+		similarity := c.dHashSimilarity(fp1.PHashes, fp2.PHashes)
 		totalSimilarity += similarity * c.config.DHashWeight
 		totalWeight += c.config.DHashWeight
 	}
@@ -66,6 +140,12 @@ func (c *Comparator) CompareFingerprints(fp1, fp2 api.ImageFingerprint) (float64
 		totalWeight += c.config.WHashWeight
 	}
 
+	if c.config.ColorHistWeight > 0 && len(fp1.ColorHist) > 0 && len(fp2.ColorHist) > 0 {
+		similarity := c.colors.CompareHistogramsWith(c.config.ColorHistMetric, fp1.ColorHist, fp2.ColorHist)
+		totalSimilarity += similarity * c.config.ColorHistWeight
+		totalWeight += c.config.ColorHistWeight
+	}
+
 	if totalWeight == 0 {
 		return 0.0, nil
 	}
@@ -74,6 +154,104 @@ func (c *Comparator) CompareFingerprints(fp1, fp2 api.ImageFingerprint) (float64
 	return math.Max(0.0, math.Min(1.0, finalSimilarity)), nil
 }
 
+// MeetsHashMinimums reports whether every per-hash minimum set in opts is met.
+// A minimum on a hash that either fingerprint lacks is never met. Meeting them
+// in any orientation the config allows is enough.
+func (c *Comparator) MeetsHashMinimums(fp1, fp2 api.ImageFingerprint, opts api.NearDuplicateOptions) bool {
+	for _, pair := range c.orientations(fp1, fp2) {
+		if c.meetsHashMinimums(pair[0], pair[1], opts) {
+			return true
+		}
+	}
+	return false
+}
+
+// meetsHashMinimums checks the per-hash minimums in the stored orientation
+func (c *Comparator) meetsHashMinimums(fp1, fp2 api.ImageFingerprint, opts api.NearDuplicateOptions) bool {
+	compareWHash := func(h1, h2 api.PerceptualHashes) float64 { return c.compareWHash(h1.WHash, h2.WHash) }
+	floors := []struct {
+		min          float64
+		hash1, hash2 uint64
+		compare      func(api.PerceptualHashes, api.PerceptualHashes) float64
+	}{
+		{opts.MinAHash, fp1.PHashes.AHash, fp2.PHashes.AHash, c.aHashSimilarity},
+		{opts.MinPHash, fp1.PHashes.PHash, fp2.PHashes.PHash, c.pHashSimilarity},
+		{opts.MinDHash, fp1.PHashes.DHash, fp2.PHashes.DHash, c.dHashSimilarity},
+		{opts.MinWHash, fp1.PHashes.WHash, fp2.PHashes.WHash, compareWHash},
+	}
+
+	for _, floor := range floors {
+		if floor.min <= 0 {
+			continue
+		}
+		if floor.hash1 == 0 || floor.hash2 == 0 {
+			return false
+		}
+		if floor.compare(fp1.PHashes, fp2.PHashes) < floor.min {
+			return false
+		}
+	}
+	return true
+}
+
+// aspectRatioCompatible reports whether two images pass the aspect-ratio guard.
+// Display dimensions are used so that EXIF-rotated copies are not rejected.
+func (c *Comparator) aspectRatioCompatible(m1, m2 api.ImageMetadata) bool {
+	if c.config.MaxAspectRatioDiff <= 0 {
+		return true
+	}
+
+	r1, r2 := m1.AspectRatio(), m2.AspectRatio()
+	if r1 == 0 || r2 == 0 {
+		return true // unknown dimensions, nothing to guard against
+	}
+
+	if c.config.RotationInvariant && math.Abs(r1-1/r2)/math.Max(r1, 1/r2) <= c.config.MaxAspectRatioDiff {
+		return true
+	}
+	return math.Abs(r1-r2)/math.Max(r1, r2) <= c.config.MaxAspectRatioDiff
+}
+
+// aHashSimilarity compares the aHashes of two fingerprints, using the wide
+// hashes when both have them
+func (c *Comparator) aHashSimilarity(h1, h2 api.PerceptualHashes) float64 {
+	if similarity, ok := wideHashSimilarity(h1.AHashWide, h2.AHashWide); ok {
+		return similarity
+	}
+	return c.compareAHash(h1.AHash, h2.AHash)
+}
+
+// pHashSimilarity compares the pHashes of two fingerprints, using the wide
+// hashes when both have them
+func (c *Comparator) pHashSimilarity(h1, h2 api.PerceptualHashes) float64 {
+	if similarity, ok := wideHashSimilarity(h1.PHashWide, h2.PHashWide); ok {
+		return pHashPenalty(similarity)
+	}
+	return c.comparePHash(h1.PHash, h2.PHash)
+}
+
+// dHashSimilarity compares the dHashes of two fingerprints, using the wide
+// hashes when both have them
+func (c *Comparator) dHashSimilarity(h1, h2 api.PerceptualHashes) float64 {
+	if similarity, ok := wideHashSimilarity(h1.DHashWide, h2.DHashWide); ok {
+		return similarity
+	}
+	return c.compareDHash(h1.DHash, h2.DHash)
+}
+
+// wideHashSimilarity returns one minus the normalized Hamming distance of two
+// wide hashes; ok is false unless both are present and the same size
+func wideHashSimilarity(hash1, hash2 []byte) (similarity float64, ok bool) {
+	if len(hash1) == 0 || len(hash1) != len(hash2) {
+		return 0, false
+	}
+	distance := 0
+	for i := range hash1 {
+		distance += bits.OnesCount8(hash1[i] ^ hash2[i])
+	}
+	return 1.0 - float64(distance)/float64(8*len(hash1)), true
+}
+
 // compareAHash compares two Average Hashes
 func (c *Comparator) compareAHash(hash1, hash2 uint64) float64 {
 	distance := hammingDistance(hash1, hash2)
@@ -84,10 +262,17 @@ func (c *Comparator) compareAHash(hash1, hash2 uint64) float64 {
 
 // comparePHash compares two Perception Hashes
 func (c *Comparator) comparePHash(hash1, hash2 uint64) float64 {
-	distance := hammingDistance(hash1, hash2)
+	return pHashSimilarity(hammingDistance(hash1, hash2))
+}
+
+// pHashSimilarity converts a pHash Hamming distance into a similarity score
+func pHashSimilarity(distance int) float64 {
 	maxDistance := 64.0
-	similarity := 1.0 - (float64(distance) / maxDistance)
+	return pHashPenalty(1.0 - (float64(distance) / maxDistance))
+}
 
+// pHashPenalty scales down medium and low pHash similarities
+func pHashPenalty(similarity float64) float64 {
 	// pHash is more sensitive to small differences
 	if similarity > 0.9 {
 		return similarity // High confidence for very similar images
@@ -98,6 +283,35 @@ func (c *Comparator) comparePHash(hash1, hash2 uint64) float64 {
 	}
 }
 
+// MaxPHashDistance returns the largest pHash Hamming distance at which two
+// fingerprints could still reach threshold, assuming every other hash matches
+// perfectly. Pairs further apart can be skipped without changing results.
+func (c *Comparator) MaxPHashDistance(threshold float64) int {
+	return c.MaxWidePHashDistance(threshold, 64)
+}
+
+// MaxWidePHashDistance is MaxPHashDistance for wide pHashes of hashBits bits,
+// which pHashSimilarity scores in place of the 64-bit pHash when both
+// fingerprints have one of the same size
+func (c *Comparator) MaxWidePHashDistance(threshold float64, hashBits int) int {
+	totalWeight := c.config.AHashWeight + c.config.PHashWeight + c.config.DHashWeight + c.config.WHashWeight + c.config.ColorHistWeight
+	if c.config.PHashWeight <= 0 || totalWeight <= 0 {
+		return hashBits
+	}
+
+	// Minimum pHash similarity needed when the other hashes contribute their full weight
+	// (with a small tolerance so rounding never prunes a pair sitting exactly on the threshold)
+	required := (threshold*totalWeight-(totalWeight-c.config.PHashWeight))/c.config.PHashWeight - 1e-9
+
+	maxDistance := -1
+	for distance := 0; distance <= hashBits; distance++ {
+		if pHashPenalty(1.0-float64(distance)/float64(hashBits)) >= required {
+			maxDistance = distance
+		}
+	}
+	return maxDistance
+}
+
 // compareDHash compares two Difference Hashes
 func (c *Comparator) compareDHash(hash1, hash2 uint64) float64 {
 	distance := hammingDistance(hash1, hash2)
@@ -121,13 +335,7 @@ func (c *Comparator) compareWHash(hash1, hash2 uint64) float64 {
 
 // hammingDistance calculates the Hamming distance between two 64-bit integers
 func hammingDistance(a, b uint64) int {
-	xor := a ^ b
-	distance := 0
-	for xor != 0 {
-		distance++
-		xor &= xor - 1
-	}
-	return distance
+	return bits.OnesCount64(a ^ b)
 }
 
 // FindSimilarImages finds all images similar to a target fingerprint
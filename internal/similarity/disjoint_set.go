@@ -0,0 +1,72 @@
+package similarity
+
+// DisjointSet is a union-find structure over the indices 0..n-1, used to merge
+// pairwise matches into connected components
+type DisjointSet struct {
+	parent []int
+	rank   []int
+}
+
+// NewDisjointSet creates n singleton sets
+func NewDisjointSet(n int) *DisjointSet {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return &DisjointSet{parent: parent, rank: make([]int, n)}
+}
+
+// Find returns the representative of the set containing i
+func (d *DisjointSet) Find(i int) int {
+	root := i
+	for d.parent[root] != root {
+		root = d.parent[root]
+	}
+
+	// Compress the path so later lookups are near constant time
+	for d.parent[i] != root {
+		d.parent[i], i = root, d.parent[i]
+	}
+	return root
+}
+
+// Union merges the sets containing i and j
+func (d *DisjointSet) Union(i, j int) {
+	ri, rj := d.Find(i), d.Find(j)
+	if ri == rj {
+		return
+	}
+
+	switch {
+	case d.rank[ri] < d.rank[rj]:
+		d.parent[ri] = rj
+	case d.rank[ri] > d.rank[rj]:
+		d.parent[rj] = ri
+	default:
+		d.parent[rj] = ri
+		d.rank[ri]++
+	}
+}
+
+// Connected reports whether i and j are in the same set
+func (d *DisjointSet) Connected(i, j int) bool {
+	return d.Find(i) == d.Find(j)
+}
+
+// Components returns every set as a list of indices. Members are in ascending
+// order and components are ordered by their smallest member.
+func (d *DisjointSet) Components() [][]int {
+	var components [][]int
+	slot := make(map[int]int)
+	for i := range d.parent {
+		root := d.Find(i)
+		k, ok := slot[root]
+		if !ok {
+			k = len(components)
+			slot[root] = k
+			components = append(components, nil)
+		}
+		components[k] = append(components[k], i)
+	}
+	return components
+}
@@ -1,25 +1,33 @@
 package similarity
 
 import (
-	"fmt"
 	"hash/fnv"
 	"math/rand"
 )
 
-// LSH implements Locality Sensitive Hashing for efficient similarity search
+// LSH implements Locality Sensitive Hashing for efficient similarity search.
+// Each hash function is a signed random projection contributing one bit, and
+// each table keys vectors by numHashes such bits: more bits per table make
+// buckets more selective (fewer false candidates), more tables raise the
+// chance that a true neighbor shares at least one bucket (better recall).
 type LSH struct {
 	numTables     int
 	numHashes     int
 	hashTables    []map[uint32][]string
 	hashFunctions []func([]float64) uint32
+	rng           *rand.Rand
 }
 
+// lshSeed fixes the projections so an index built twice buckets vectors identically
+const lshSeed = 1
+
 // NewLSH creates a new LSH index
 func NewLSH(numTables, numHashes int) *LSH {
 	lsh := &LSH{
 		numTables:  numTables,
 		numHashes:  numHashes,
 		hashTables: make([]map[uint32][]string, numTables),
+		rng:        rand.New(rand.NewSource(lshSeed)),
 	}
 
 	// Initialize hash tables
@@ -39,32 +47,31 @@ func (l *LSH) generateHashFunctions() {
 
 	for i := 0; i < l.numTables*l.numHashes; i++ {
 		// Create a random projection vector
-		l.hashFunctions[i] = l.createRandomProjection()
+		l.hashFunctions[i] = l.createRandomProjection(l.rng.Int63())
 	}
 }
 
-// createRandomProjection creates a random projection hash function
-func (l *LSH) createRandomProjection() func([]float64) uint32 {
-	// Generate random weights for the projection
+// createRandomProjection creates a hash function returning the sign of the
+// vector's projection onto a fixed random direction. The direction is drawn
+// lazily from its own seed, so it does not depend on the vector length up front.
+func (l *LSH) createRandomProjection(seed int64) func([]float64) uint32 {
+	source := rand.New(rand.NewSource(seed))
+	var weights []float64
+
 	return func(vector []float64) uint32 {
-		var hash uint32
-		fmt.Print(hash)
-		hasher := fnv.New32a()
+		for len(weights) < len(vector) {
+			weights = append(weights, source.NormFloat64())
+		}
 
+		var projected float64
 		for i, value := range vector {
-			// Simple random projection (in practice, use better random values)
-			weight := rand.Float64()*2 - 1 // Random between -1 and 1
-			projected := value * weight
-
-			// Threshold to get binary value
-			if projected > 0 {
-				hasher.Write([]byte{byte(i), 1})
-			} else {
-				hasher.Write([]byte{byte(i), 0})
-			}
+			projected += value * weights[i]
 		}
 
-		return hasher.Sum32()
+		if projected > 0 {
+			return 1
+		}
+		return 0
 	}
 }
 
@@ -85,13 +92,7 @@ func (l *LSH) computeTableHash(vector []float64, table int) uint32 {
 
 	for hash := 0; hash < l.numHashes; hash++ {
 		funcIndex := table*l.numHashes + hash
-		hashValue := l.hashFunctions[funcIndex](vector)
-		hasher.Write([]byte{
-			byte(hashValue >> 24),
-			byte(hashValue >> 16),
-			byte(hashValue >> 8),
-			byte(hashValue),
-		})
+		hasher.Write([]byte{byte(l.hashFunctions[funcIndex](vector))})
 	}
 
 	return hasher.Sum32()
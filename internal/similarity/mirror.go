@@ -0,0 +1,76 @@
+package similarity
+
+import (
+	"math"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// MirrorHashes returns the hashes the engine would compute for the
+// horizontally mirrored image, by reordering bits instead of re-hashing.
+// The aHash and wHash are 8x8 grids, so each row is reversed. The pHash
+// folds a 32x32 grid onto 64 bits, two rows per 64 bits, so each 32-bit half
+// is reversed. The dHash compares each pixel with its right neighbour, so
+// rows are reversed and inverted; this is exact except where neighbours are
+// equal, which only costs a little similarity on flat regions. Wide hashes
+// are mirrored the same way on their HashSize grids.
+func MirrorHashes(h api.PerceptualHashes) api.PerceptualHashes {
+	return api.PerceptualHashes{
+		AHash:     mirrorRows(h.AHash, 8),
+		PHash:     mirrorRows(h.PHash, 32),
+		DHash:     invertNonZero(mirrorRows(h.DHash, 8)),
+		WHash:     mirrorRows(h.WHash, 8),
+		AHashWide: mirrorWideRows(h.AHashWide, 1),
+		PHashWide: mirrorWideRows(h.PHashWide, 4),
+		DHashWide: invertWide(mirrorWideRows(h.DHashWide, 1)),
+	}
+}
+
+// mirrorRows reverses the bit order within each width-bit row of a 64-bit hash
+func mirrorRows(hash uint64, width int) uint64 {
+	var mirrored uint64
+	for bit := 0; bit < 64; bit++ {
+		if hash&(1<<uint(bit)) == 0 {
+			continue
+		}
+		row, col := bit/width, bit%width
+		mirrored |= 1 << uint(row*width+width-1-col)
+	}
+	return mirrored
+}
+
+// invertNonZero flips every bit of a hash that was computed; zero marks a
+// hash that is absent and stays zero
+func invertNonZero(hash uint64) uint64 {
+	if hash == 0 {
+		return 0
+	}
+	return ^hash
+}
+
+// mirrorWideRows reverses the bit order within each row of a square wide
+// hash, whose rows are scale times the hash's side
+func mirrorWideRows(hash []byte, scale int) []byte {
+	if len(hash) == 0 {
+		return nil
+	}
+	width := scale * int(math.Round(math.Sqrt(float64(8*len(hash)))))
+	mirrored := make([]byte, len(hash))
+	for bit := 0; bit < 8*len(hash); bit++ {
+		if hash[bit/8]&(1<<uint(bit%8)) == 0 {
+			continue
+		}
+		row, col := bit/width, bit%width
+		target := row*width + width - 1 - col
+		mirrored[target/8] |= 1 << uint(target%8)
+	}
+	return mirrored
+}
+
+// invertWide flips every bit of a wide hash
+func invertWide(hash []byte) []byte {
+	for i := range hash {
+		hash[i] = ^hash[i]
+	}
+	return hash
+}
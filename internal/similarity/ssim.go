@@ -0,0 +1,72 @@
+package similarity
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+)
+
+// SSIMSize is the edge length, in pixels, of the grayscale downscales SSIM compares
+const SSIMSize = 64
+
+const (
+	ssimWindow = 8 // window edge length
+	ssimStride = 4 // windows overlap by half
+
+	// Stabilizing constants for 8-bit luminance: (0.01*255)^2 and (0.03*255)^2
+	ssimC1 = 6.5025
+	ssimC2 = 58.5225
+)
+
+// SSIMImage downscales img to the SSIMSize x SSIMSize grayscale image SSIM compares
+func SSIMImage(img image.Image) *image.Gray {
+	resized := imaging.Resize(img, SSIMSize, SSIMSize, imaging.Lanczos)
+	gray := image.NewGray(image.Rect(0, 0, SSIMSize, SSIMSize))
+	draw.Draw(gray, gray.Bounds(), resized, resized.Bounds().Min, draw.Src)
+	return gray
+}
+
+// SSIM computes the mean Structural Similarity Index of two equally sized
+// grayscale images over overlapping windows. The result is 1 for identical
+// images and falls towards 0 (or below) as luminance, contrast and structure diverge.
+func SSIM(a, b *image.Gray) float64 {
+	bounds := a.Bounds()
+	if bounds.Dx() != b.Bounds().Dx() || bounds.Dy() != b.Bounds().Dy() ||
+		bounds.Dx() < ssimWindow || bounds.Dy() < ssimWindow {
+		return 0
+	}
+
+	const n = ssimWindow * ssimWindow
+	offset := b.Bounds().Min.Sub(bounds.Min)
+
+	var total float64
+	var windows int
+	for y := bounds.Min.Y; y+ssimWindow <= bounds.Max.Y; y += ssimStride {
+		for x := bounds.Min.X; x+ssimWindow <= bounds.Max.X; x += ssimStride {
+			var sumA, sumB, sumAA, sumBB, sumAB float64
+			for wy := y; wy < y+ssimWindow; wy++ {
+				for wx := x; wx < x+ssimWindow; wx++ {
+					pa := float64(a.GrayAt(wx, wy).Y)
+					pb := float64(b.GrayAt(wx+offset.X, wy+offset.Y).Y)
+					sumA += pa
+					sumB += pb
+					sumAA += pa * pa
+					sumBB += pb * pb
+					sumAB += pa * pb
+				}
+			}
+
+			meanA, meanB := sumA/n, sumB/n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			cov := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + ssimC1) * (2*cov + ssimC2)) /
+				((meanA*meanA + meanB*meanB + ssimC1) * (varA + varB + ssimC2))
+			windows++
+		}
+	}
+
+	return total / float64(windows)
+}
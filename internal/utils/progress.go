@@ -88,3 +88,74 @@ func (p *ProgressTracker) ProgressBar(width int) string {
 
 	return fmt.Sprintf("%s %.1f%%", bar, progress*100)
 }
+
+// RateEstimator estimates throughput over a sliding time window, so the ETA
+// follows the current pace rather than the average since the start
+type RateEstimator struct {
+	window  time.Duration
+	samples []rateSample
+}
+
+// rateSample is the completed count observed at one moment
+type rateSample struct {
+	at    time.Time
+	count int
+}
+
+// NewRateEstimator creates an estimator averaging over the given window
+func NewRateEstimator(window time.Duration) *RateEstimator {
+	return &RateEstimator{window: window}
+}
+
+// Observe records that count items were complete at time at
+func (r *RateEstimator) Observe(at time.Time, count int) {
+	r.samples = append(r.samples, rateSample{at: at, count: count})
+
+	// Keep one sample at or beyond the window's start so the span covers it
+	drop := 0
+	for drop+1 < len(r.samples) && at.Sub(r.samples[drop+1].at) >= r.window {
+		drop++
+	}
+	r.samples = r.samples[drop:]
+}
+
+// Rate returns items per second over the window, or zero until two samples
+// at different times have been observed
+func (r *RateEstimator) Rate() float64 {
+	if len(r.samples) < 2 {
+		return 0
+	}
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.count-first.count) / elapsed
+}
+
+// ETA returns the time left to reach total at the current rate; ok is false
+// while the rate is unknown
+func (r *RateEstimator) ETA(total int) (eta time.Duration, ok bool) {
+	rate := r.Rate()
+	if rate <= 0 {
+		return 0, false
+	}
+	remaining := total - r.samples[len(r.samples)-1].count
+	if remaining <= 0 {
+		return 0, true
+	}
+	return time.Duration(float64(remaining) / rate * float64(time.Second)), true
+}
+
+// FormatETA formats a remaining duration compactly, such as 45s, 1m08s or 2h05m
+func FormatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
@@ -34,11 +34,29 @@ const (
 	FormatGIF  = "gif"
 
 	// Duplicate reasons
-	ReasonExact      = "exact"
-	ReasonNear       = "near"
-	ReasonResized    = "resized"
-	ReasonCompressed = "compressed"
-	ReasonCropped    = "cropped"
+	ReasonExact        = "exact"
+	ReasonNear         = "near"
+	ReasonResized      = "resized"
+	ReasonCompressed   = "compressed"
+	ReasonCropped      = "cropped"
+	ReasonRecompressed = "recompressed" // same picture saved with a different codec
+	ReasonBurst        = "burst"        // frames shot in quick succession
+
+	// Clean actions
+	ActionMove   = "move"
+	ActionDelete = "delete"
+	ActionTrash  = "trash"
+
+	// Index events reported by watch mode
+	IndexEventAdded   = "added"
+	IndexEventUpdated = "updated"
+	IndexEventRemoved = "removed"
+	IndexEventFailed  = "failed"
+
+	// FSPathPrefix starts the path of every image indexed from an fs.FS by
+	// ScanFS or ScanFSWithLabel, followed by the label it was scanned under,
+	// a colon and its name in that file system
+	FSPathPrefix = "fs:"
 
 	// Performance constants
 	MaxBatchSize     = 1000
@@ -13,4 +13,6 @@ var (
 	ErrImageDecodeFailed  = errors.New("failed to decode image data")
 	ErrIndexCorrupted     = errors.New("image index is corrupted")
 	ErrInsufficientMemory = errors.New("insufficient memory for operation")
+	ErrScanLimitReached   = errors.New("scan stopped at the maximum number of images")
+	ErrEngineClosed       = errors.New("engine is closed")
 )
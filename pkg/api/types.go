@@ -1,6 +1,8 @@
 package api
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -9,14 +11,37 @@ type ImageID string
 
 // ImageMetadata contains comprehensive metadata about an image file
 type ImageMetadata struct {
-	Path       string    `json:"path"`
-	SizeBytes  int64     `json:"size_bytes"`
-	Format     string    `json:"format"`
-	Width      int       `json:"width"`
-	Height     int       `json:"height"`
-	ModifiedAt time.Time `json:"modified_at"`
-	EXIF       *EXIFInfo `json:"exif,omitempty"`
-	SHA256     string    `json:"sha256"`
+	Path               string    `json:"path"`
+	SizeBytes          int64     `json:"size_bytes"`
+	Format             string    `json:"format"`
+	Width              int       `json:"width"`                         // raw decoded width
+	Height             int       `json:"height"`                        // raw decoded height
+	DisplayWidth       int       `json:"display_width,omitempty"`       // width after EXIF orientation
+	DisplayHeight      int       `json:"display_height,omitempty"`      // height after EXIF orientation
+	Orientation        int       `json:"orientation,omitempty"`         // EXIF orientation tag (1-8)
+	AppliedOrientation int       `json:"applied_orientation,omitempty"` // orientation undone before hashing and rating
+	FrameCount         int       `json:"frame_count,omitempty"`         // frames in an animation, 1 for stills
+	ModifiedAt         time.Time `json:"modified_at"`
+	EXIF               *EXIFInfo `json:"exif,omitempty"`
+	SHA256             string    `json:"sha256"`
+}
+
+// DisplaySize returns the dimensions as shown by viewers, falling back to the
+// raw dimensions for metadata indexed before display sizes were recorded
+func (m ImageMetadata) DisplaySize() (int, int) {
+	if m.DisplayWidth == 0 || m.DisplayHeight == 0 {
+		return m.Width, m.Height
+	}
+	return m.DisplayWidth, m.DisplayHeight
+}
+
+// AspectRatio returns the display width divided by the display height
+func (m ImageMetadata) AspectRatio() float64 {
+	w, h := m.DisplaySize()
+	if h == 0 {
+		return 0
+	}
+	return float64(w) / float64(h)
 }
 
 // EXIFInfo contains EXIF metadata extracted from images
@@ -39,6 +64,21 @@ type PerceptualHashes struct {
 	PHash uint64 `json:"p_hash"` // Perception Hash - resistant to scaling and minor modifications
 	DHash uint64 `json:"d_hash"` // Difference Hash - good for similar images
 	WHash uint64 `json:"w_hash"` // Wavelet Hash - excellent for cropped/scaled images
+
+	// With a HashSize above 8, the aHash, pHash and dHash are also kept at
+	// HashSize x HashSize bits, packed least significant bit first in row-major
+	// order. Comparisons use them when both fingerprints have them; the 64-bit
+	// hashes above are always computed and remain what the indexes bucket on.
+	AHashWide []byte `json:"a_hash_wide,omitempty"`
+	PHashWide []byte `json:"p_hash_wide,omitempty"`
+	DHashWide []byte `json:"d_hash_wide,omitempty"`
+
+	// Rotations holds, when the engine is RotationInvariant, the 64-bit
+	// hashes of the image turned by 90, 180 and 270 degrees
+	Rotations []PerceptualHashes `json:"rotations,omitempty"`
+
+	// Custom holds the hashes of hashers registered on the engine, keyed by name
+	Custom map[string]uint64 `json:"custom,omitempty"`
 }
 
 // ImageQuality represents comprehensive quality analysis results
@@ -50,6 +90,36 @@ type ImageQuality struct {
 	Compression float64 `json:"compression"` // 0..1 (1 = most artifacts)
 	ColorCast   float64 `json:"color_cast"`  // 0..1 (1 = strongest color cast)
 	FinalScore  float64 `json:"final_score"` // 0..100 overall quality score
+
+	// ColorTemperature is the white-balance mood, "warm", "cool" or
+	// "neutral"; ColorTemperatureScore is how strongly it leans (0..1)
+	ColorTemperature      string  `json:"color_temperature,omitempty"`
+	ColorTemperatureScore float64 `json:"color_temperature_score,omitempty"`
+}
+
+// IssueSeverity grades how far a quality metric is past its threshold
+type IssueSeverity string
+
+const (
+	SeverityMinor IssueSeverity = "minor" // past the threshold, less than halfway to the worst value
+	SeverityMajor IssueSeverity = "major" // at least halfway from the threshold to the worst value
+)
+
+// QualityIssue describes one quality metric that fails its configured threshold
+type QualityIssue struct {
+	Metric          string        `json:"metric"` // ImageQuality field, by its JSON name
+	Severity        IssueSeverity `json:"severity"`
+	Message         string        `json:"message"`
+	SuggestedAction string        `json:"suggested_action"`
+}
+
+// PaletteColor is one of an image's dominant colors, with the fraction of the
+// image it covers
+type PaletteColor struct {
+	R        uint8   `json:"r"`
+	G        uint8   `json:"g"`
+	B        uint8   `json:"b"`
+	Coverage float64 `json:"coverage"`
 }
 
 // ImageFingerprint represents a complete digital fingerprint of an image
@@ -59,6 +129,7 @@ type ImageFingerprint struct {
 	PHashes    PerceptualHashes `json:"perceptual_hashes"`
 	Quality    ImageQuality     `json:"quality"`
 	ColorHist  []float64        `json:"color_histogram,omitempty"`
+	Palette    []PaletteColor   `json:"palette,omitempty"`
 	FeatureVec []float32        `json:"feature_vector,omitempty"`
 	CreatedAt  time.Time        `json:"created_at"`
 }
@@ -70,6 +141,26 @@ type DuplicateGroup struct {
 	DuplicateIDs []ImageID `json:"duplicate_ids"`
 	Reason       string    `json:"reason"` // exact, near, resized, etc.
 	Confidence   float64   `json:"confidence"`
+	Formats      []string  `json:"formats,omitempty"`    // codecs involved in a recompressed group
+	Threshold    float64   `json:"threshold,omitempty"`  // similarity threshold of the search that found it
+	SearchKey    string    `json:"search_key,omitempty"` // digest of the settings of the search that found it
+}
+
+// ComparisonResult describes how two image files compare
+type ComparisonResult struct {
+	PathA      string `json:"path_a"`
+	PathB      string `json:"path_b"`
+	ExactMatch bool   `json:"exact_match"` // identical SHA256
+
+	// HammingDistances holds the differing bits of each computed hash, keyed ahash, phash, dhash,
+	// whash or the name of a custom hash both images have
+	HammingDistances map[string]int `json:"hamming_distances"`
+	Similarity       float64        `json:"similarity"` // combined similarity (0..1)
+	Threshold        float64        `json:"threshold"`
+	IsNearDuplicate  bool           `json:"is_near_duplicate"`
+
+	QualityA ImageQuality `json:"quality_a"`
+	QualityB ImageQuality `json:"quality_b"`
 }
 
 // Cluster represents a group of similar images based on content analysis
@@ -93,6 +184,9 @@ type ScanReport struct {
 	TotalFiles          int              `json:"total_files"`
 	ProcessedImages     int              `json:"processed_images"`
 	SkippedFiles        int              `json:"skipped_files"`
+	UnchangedFiles      int              `json:"unchanged_files"`
+	RenamedFiles        int              `json:"renamed_files"`
+	Errors              []ScanError      `json:"errors,omitempty"`
 	ExactDuplicateCount int              `json:"exact_duplicate_count"`
 	NearDuplicateCount  int              `json:"near_duplicate_count"`
 	Groups              []DuplicateGroup `json:"duplicate_groups"`
@@ -103,22 +197,151 @@ type ScanReport struct {
 	GeneratedAt         time.Time        `json:"generated_at"`
 }
 
+// ScanError explains why a file was skipped during a scan
+type ScanError struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
 // CleanOptions configures the behavior of duplicate cleaning operations
 type CleanOptions struct {
 	DryRun                 bool            `json:"dry_run"`
 	SelectionPolicy        SelectionPolicy `json:"selection_policy"`
 	MinQualityScore        float64         `json:"min_quality_score"`
 	MaxSimilarityThreshold float64         `json:"max_similarity_threshold"`
-	MoveDuplicates         bool            `json:"move_duplicates"`
+	MoveDuplicates         bool            `json:"move_duplicates"`  // move removed files under OutputDir
+	TrashDuplicates        bool            `json:"trash_duplicates"` // send removed files to the OS trash; with neither set they are deleted
 	OutputDir              string          `json:"output_dir"`
+
+	// QualityMargin leaves a near-duplicate and its group's main image in place, flagged
+	// for review, when their quality scores differ by less than this (0 disables the check)
+	QualityMargin float64 `json:"quality_margin"`
+}
+
+// NearDuplicateOptions sets how similar two images must be to count as near
+// duplicates. MinCombined applies to the weighted blend of all hashes; each
+// non-zero per-hash minimum must also be met by that hash on its own.
+type NearDuplicateOptions struct {
+	MinAHash    float64 `json:"min_ahash"`
+	MinPHash    float64 `json:"min_phash"`
+	MinDHash    float64 `json:"min_dhash"`
+	MinWHash    float64 `json:"min_whash"`
+	MinCombined float64 `json:"min_combined"`
 }
 
 // CleanReport provides results of a cleaning operation
 type CleanReport struct {
-	TotalProcessed int   `json:"total_processed"`
-	MovedFiles     int   `json:"moved_files"`
-	FreedSpace     int64 `json:"freed_space_bytes"`
-	Errors         int   `json:"errors"`
+	TotalProcessed int    `json:"total_processed"`
+	MovedFiles     int    `json:"moved_files"`
+	FreedSpace     int64  `json:"freed_space_bytes"`
+	Errors         int    `json:"errors"`
+	ManifestPath   string `json:"manifest_path,omitempty"` // record of moves, usable for undo
+
+	// Actions lists what cleaning does with each duplicate; dry runs plan the same actions without performing them
+	Actions []PlannedAction `json:"actions"`
+
+	// NeedsReview lists files left in place because no copy was clearly better
+	NeedsReview []string `json:"needs_review,omitempty"`
+
+	// Groups records the outcome of each group cleaned; a failed group has its moves rolled back
+	Groups []CleanGroupResult `json:"groups,omitempty"`
+}
+
+// CleanGroupResult reports whether every action planned for a duplicate group
+// was carried out and, when one failed, whether the group was rolled back
+type CleanGroupResult struct {
+	GroupID    string `json:"group_id"`
+	Succeeded  bool   `json:"succeeded"`
+	RolledBack bool   `json:"rolled_back"` // a failed group was left as it was found
+	Error      string `json:"error,omitempty"`
+
+	// NotRestored lists the files a failed group left moved, trashed or
+	// deleted because they could not be put back
+	NotRestored []string `json:"not_restored,omitempty"`
+}
+
+// VerifyOptions controls how the index is checked against the files on disk
+type VerifyOptions struct {
+	Rehash bool `json:"rehash"` // also compare file contents with the stored SHA256
+	Fix    bool `json:"fix"`    // remove missing entries and reprocess changed ones
+}
+
+// IndexEvent reports a change made to the index while watching a folder
+type IndexEvent struct {
+	Type    string  `json:"type"` // added, updated, removed or failed
+	Path    string  `json:"path"`
+	ImageID ImageID `json:"image_id,omitempty"`
+	Error   string  `json:"error,omitempty"` // why a failed file could not be indexed
+}
+
+// VerifyReport summarizes a check of the index against the files on disk
+type VerifyReport struct {
+	Checked      int         `json:"checked"`
+	OK           int         `json:"ok"`
+	Missing      int         `json:"missing"`
+	Changed      int         `json:"changed"`
+	MissingPaths []string    `json:"missing_paths,omitempty"`
+	ChangedPaths []string    `json:"changed_paths,omitempty"`
+	Pruned       int         `json:"pruned"`  // missing entries removed with Fix
+	Updated      int         `json:"updated"` // changed entries reprocessed with Fix
+	Errors       []ScanError `json:"errors,omitempty"`
+}
+
+// PlannedAction describes what cleaning does with one duplicate file
+type PlannedAction struct {
+	Source    string `json:"source"`
+	Dest      string `json:"dest,omitempty"` // empty unless Action is move
+	Action    string `json:"action"`         // move, delete or trash
+	GroupID   string `json:"group_id"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// OrganizeMode selects how files are placed into organized folders
+type OrganizeMode int
+
+const (
+	OrganizeMove OrganizeMode = iota
+	OrganizeCopy
+	OrganizeSymlink
+)
+
+// OrganizeOptions controls how images are sorted into folders
+type OrganizeOptions struct {
+	Mode   OrganizeMode `json:"mode"`
+	DryRun bool         `json:"dry_run"`
+}
+
+// OrganizeReport provides results of an organize operation
+type OrganizeReport struct {
+	Placed int `json:"placed"`
+	Errors int `json:"errors"`
+
+	// Files lists where each image goes; dry runs list the planned destinations
+	Files []OrganizedFile `json:"files"`
+}
+
+// OrganizedFile records where one image was placed by an organize operation
+type OrganizedFile struct {
+	ImageID ImageID `json:"image_id"`
+	Source  string  `json:"source"`
+	Dest    string  `json:"dest"`
+	Folder  string  `json:"folder"`
+}
+
+// CleanManifest records every file moved by a clean operation so it can be undone
+type CleanManifest struct {
+	CreatedAt time.Time            `json:"created_at"`
+	OutputDir string               `json:"output_dir"`
+	Entries   []CleanManifestEntry `json:"entries"`
+}
+
+// CleanManifestEntry maps a moved file's original location to its new one
+type CleanManifestEntry struct {
+	ImageID      ImageID   `json:"image_id"`
+	GroupID      string    `json:"group_id"`
+	OriginalPath string    `json:"original_path"`
+	NewPath      string    `json:"new_path"`
+	MovedAt      time.Time `json:"moved_at"`
 }
 
 // SelectionPolicy defines the strategy for selecting the best image from duplicates
@@ -130,4 +353,32 @@ const (
 	PolicyBestExposure
 	PolicyOldest
 	PolicyNewest
+	PolicyCompositeQuality // quality analyzer's weighting of the stored metrics
+	PolicyLargestFileSize  // keep the highest-bitrate original
+	PolicySmallestFileSize // reclaim the most space
 )
+
+// selectionPolicyNames lists the policies ParseSelectionPolicy accepts, by name
+var selectionPolicyNames = []struct {
+	name   string
+	policy SelectionPolicy
+}{
+	{"quality", PolicyHighestQuality},
+	{"resolution", PolicyHighestResolution},
+	{"exposure", PolicyBestExposure},
+	{"oldest", PolicyOldest},
+	{"newest", PolicyNewest},
+}
+
+// ParseSelectionPolicy returns the policy named quality, resolution,
+// exposure, oldest or newest, as accepted by the CLI's --policy flag
+func ParseSelectionPolicy(name string) (SelectionPolicy, error) {
+	names := make([]string, len(selectionPolicyNames))
+	for i, p := range selectionPolicyNames {
+		if p.name == strings.ToLower(name) {
+			return p.policy, nil
+		}
+		names[i] = p.name
+	}
+	return 0, fmt.Errorf("unknown selection policy %q (choose one of: %s)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// burstMaxPHashDistance is the most pHash bits consecutive frames of a burst
+// may differ by
+const burstMaxPHashDistance = 6
+
+// FindBursts groups images shot in quick succession: ordered by EXIF capture
+// time, consecutive frames taken at most maxGapMillis apart whose pHashes
+// are near-identical form a burst. Bursts with fewer than minSize frames, and
+// images without a capture time, are left out. The best-quality frame of
+// each burst is its main image.
+func (e *Engine) FindBursts(maxGapMillis int64, minSize int) ([]api.DuplicateGroup, error) {
+	if minSize < 2 {
+		minSize = 2
+	}
+	maxGap := time.Duration(maxGapMillis) * time.Millisecond
+
+	fingerprints, err := e.index.GetAllFingerprints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprints: %w", err)
+	}
+
+	var frames []api.ImageFingerprint
+	for _, fp := range fingerprints {
+		if fp.Metadata.EXIF != nil && !fp.Metadata.EXIF.TakenAt.IsZero() {
+			frames = append(frames, fp)
+		}
+	}
+	sort.Slice(frames, func(i, j int) bool {
+		ti, tj := frames[i].Metadata.EXIF.TakenAt, frames[j].Metadata.EXIF.TakenAt
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return frames[i].ID < frames[j].ID
+	})
+
+	var groups []api.DuplicateGroup
+	addBurst := func(burst []api.ImageFingerprint) {
+		if len(burst) < minSize {
+			return
+		}
+		ids := make([]api.ImageID, len(burst))
+		for i, fp := range burst {
+			ids[i] = fp.ID
+		}
+		mainImage := e.selectBestImage(ids, burst, api.PolicyHighestQuality)
+		groups = append(groups, api.DuplicateGroup{
+			GroupID:      contentGroupID("burst", ids),
+			MainImage:    mainImage,
+			DuplicateIDs: e.removeElement(ids, mainImage),
+			Reason:       api.ReasonBurst,
+			Confidence:   e.calculateGroupConfidence(ids, burst),
+		})
+	}
+
+	var burst []api.ImageFingerprint
+	for _, fp := range frames {
+		if len(burst) > 0 {
+			prev := burst[len(burst)-1]
+			gap := fp.Metadata.EXIF.TakenAt.Sub(prev.Metadata.EXIF.TakenAt)
+			if gap > maxGap || bits.OnesCount64(fp.PHashes.PHash^prev.PHashes.PHash) > burstMaxPHashDistance {
+				addBurst(burst)
+				burst = nil
+			}
+		}
+		burst = append(burst, fp)
+	}
+	addBurst(burst)
+
+	e.logger.Infof("Found %d bursts", len(groups))
+	return groups, nil
+}
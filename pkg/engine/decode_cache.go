@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+)
+
+// decodeKey identifies one version of a file; a changed file gets a new key
+type decodeKey struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// decodedImage is a decoded file as returned by DecodeRepresentativeFrame
+type decodedImage struct {
+	key    decodeKey
+	img    image.Image
+	format string
+	frames int
+	bytes  int64
+}
+
+// decodeCache keeps recently decoded images so analysis passes over the same
+// files, such as the searches and checks of one clean run, decode each once.
+// Entries are evicted least recently used first once their estimated size
+// exceeds capacity.
+type decodeCache struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	entries  map[decodeKey]*list.Element
+	order    *list.List // front is most recently used
+}
+
+// newDecodeCache creates a cache holding up to capacity bytes; zero disables it
+func newDecodeCache(capacity int64) *decodeCache {
+	return &decodeCache{
+		capacity: capacity,
+		entries:  make(map[decodeKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached decode for key, marking it recently used
+func (c *decodeCache) get(key decodeKey) (*decodedImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*decodedImage), true
+}
+
+// put caches a decode, evicting older ones to stay within capacity. Images
+// larger than the whole cache are not kept.
+func (c *decodeCache) put(entry *decodedImage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry.bytes > c.capacity {
+		return
+	}
+	if elem, ok := c.entries[entry.key]; ok {
+		c.size -= elem.Value.(*decodedImage).bytes
+		c.order.Remove(elem)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	c.size += entry.bytes
+
+	for c.size > c.capacity {
+		oldest := c.order.Back()
+		evicted := c.order.Remove(oldest).(*decodedImage)
+		delete(c.entries, evicted.key)
+		c.size -= evicted.bytes
+	}
+}
+
+// decodedSize estimates the memory held by a decoded image at four bytes per pixel
+func decodedSize(img image.Image) int64 {
+	bounds := img.Bounds()
+	return int64(bounds.Dx()) * int64(bounds.Dy()) * 4
+}
+
+// decodeImage decodes the file at path, whose current info is given, reusing
+// the cached decode if the file has not changed since. The decode holds
+// reservation until it returns, even when it is abandoned after DecodeTimeout.
+func (e *Engine) decodeImage(path string, info os.FileInfo, reservation *decodeReservation) (*decodedImage, error) {
+	key := decodeKey{path: path, modTime: info.ModTime(), size: info.Size()}
+	if cached, ok := e.decoded.get(key); ok {
+		return cached, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer file.Close()
+
+	// Animations yield their middle frame
+	var img image.Image
+	var format string
+	var frames int
+	err = pkgimaging.DecodeWithLimitsNotify(context.Background(), file, e.decodeLimits(), func(r io.Reader) error {
+		var err error
+		img, format, frames, err = pkgimaging.DecodeRepresentativeFrame(r)
+		return err
+	}, reservation.hold())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	e.decodes.Add(1)
+
+	entry := &decodedImage{key: key, img: img, format: format, frames: frames, bytes: decodedSize(img)}
+	e.decoded.put(entry)
+	return entry, nil
+}
+
+// decodeLimits returns the pixel budget and timeout every decode runs under
+func (e *Engine) decodeLimits() pkgimaging.DecodeLimits {
+	return pkgimaging.DecodeLimits{MaxPixels: e.config.MaxDecodePixels, Timeout: e.config.DecodeTimeout}
+}
@@ -1,25 +1,35 @@
 package engine
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
 	"math"
+	"math/bits"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/HaiderBassem/imaged/internal/filesystem"
+	"github.com/HaiderBassem/imaged/internal/hash"
 	"github.com/HaiderBassem/imaged/internal/index"
+	imgmeta "github.com/HaiderBassem/imaged/internal/metadata"
 	"github.com/HaiderBassem/imaged/internal/quality"
 	"github.com/HaiderBassem/imaged/internal/scanner"
 	"github.com/HaiderBassem/imaged/internal/similarity"
 	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
 	"github.com/disintegration/imaging"
 	"github.com/sirupsen/logrus"
 )
@@ -31,18 +41,122 @@ type Engine struct {
 	scanner    *scanner.Scanner
 	quality    *quality.Analyzer
 	similarity *similarity.Comparator
+	exif       *imgmeta.EXIFReader
+	features   *hash.AdvancedHash
+	colors     *hash.ColorSignature
+	organizer  *filesystem.Organizer
+	transform  *pkgimaging.Transformer
+	colorSpace *pkgimaging.ColorSpace
+	namer      *imgmeta.ClusterNamer
+	hashers    []Hasher // custom hashes added with RegisterHasher
 	logger     *logrus.Logger
+
+	// decoded caches decoded images across passes; decodes counts cache misses
+	decoded *decodeCache
+	decodes atomic.Int64
+
+	// memory bounds the images decoded and analyzed at once by MaxMemoryMB
+	memory *memoryLimiter
+
+	// active tracks running scans so Close waits for their final flush;
+	// closed, guarded by closeMu, stops new ones once Close has begun
+	active  sync.WaitGroup
+	closeMu sync.Mutex
+	closed  bool
 }
 
 // EngineConfig defines the configuration for the image processing engine
 type EngineConfig struct {
-	IndexPath     string
-	NumWorkers    int
-	UseGPU        bool
-	LogLevel      string
-	MaxMemoryMB   int
-	HashConfig    HashConfig
-	QualityConfig quality.Config
+	IndexPath   string
+	NumWorkers  int
+	UseGPU      bool
+	LogLevel    string
+	MaxMemoryMB int // bounds images decoded at once; a quarter of it caches decodes for later passes
+	HashConfig  HashConfig
+
+	// IndexType selects the index backend for IndexPath; the zero value is BoltDB
+	IndexType index.StoreType
+
+	// MaxAspectRatioDiff rejects near-duplicate pairs whose display aspect
+	// ratios differ by more than this fraction (0 disables the guard)
+	MaxAspectRatioDiff float64
+	QualityConfig      quality.Config
+
+	// ColorHistWeight weights color histogram similarity against the perceptual
+	// hashes; ColorHistMetric picks chi-squared (the default) or Earth Mover's Distance
+	ColorHistWeight float64
+	ColorHistMetric hash.HistogramMetric
+
+	// UseFeatureVec computes a feature vector per image and uses an LSH index
+	// over those vectors, instead of the pHash BK-tree, to choose which pairs
+	// FindNearDuplicates compares. LSH is approximate: pairs that never share
+	// a bucket are not compared, so recall drops slightly in exchange for far
+	// fewer comparisons. More tables raise recall; more hashes per table make
+	// buckets more selective but lower recall.
+	UseFeatureVec     bool
+	LSHTables         int
+	LSHHashesPerTable int
+
+	// SelectionPolicy picks the image kept as the main one in each near-duplicate group
+	SelectionPolicy api.SelectionPolicy
+
+	// DetectRecompressed tags near-duplicate groups whose confidence reaches
+	// RecompressedMinConfidence and whose files use different formats as
+	// "recompressed", e.g. a JPEG original next to its AVIF re-encode
+	DetectRecompressed        bool
+	RecompressedMinConfidence float64
+
+	// VerifySSIM re-checks every candidate near-duplicate pair with the
+	// structural similarity of grayscale downscales, rejecting pairs below
+	// SSIMThreshold. It weeds out hash collisions such as two different
+	// photos of clear sky, at the cost of decoding each candidate image once.
+	VerifySSIM    bool
+	SSIMThreshold float64
+
+	// ForceRescan makes ScanFolder reprocess every file, including ones
+	// already indexed whose size and modification time have not changed
+	ForceRescan bool
+
+	// MinWidth and MinHeight make ScanFolder ignore smaller images such as
+	// icons and sprites; zero disables the check
+	MinWidth  int
+	MinHeight int
+
+	// RotationInvariant also stores the hashes of each image rotated by 90,
+	// 180 and 270 degrees, computed on a 256px thumbnail, so rotated copies
+	// such as misoriented scans match: a pair is scored in every orientation
+	// and keeps the best score. The aspect-ratio guard also accepts a pair
+	// whose ratios are inverted.
+	RotationInvariant bool
+
+	// FlipInvariant lets near-duplicate matching pair horizontally mirrored
+	// copies. Each candidate pair is scored in both orientations and the
+	// pHash index is queried twice, so the search takes roughly twice as long.
+	FlipInvariant bool
+
+	// MaxImages caps how many images one ScanFolder call discovers; zero means no limit
+	MaxImages int
+
+	// ClusterLinkage picks how ClusterImages scores two clusters: average
+	// (the default), single or complete linkage
+	ClusterLinkage similarity.LinkageMethod
+
+	// WatchDebounce is how long Watch waits after the last file system
+	// notification before rescanning (500ms when unset). WatchPolling makes
+	// Watch poll every WatchInterval (2s when unset) instead, for network
+	// filesystems that do not deliver notifications; Watch also falls back
+	// to polling when notifications are unavailable.
+	WatchDebounce time.Duration
+	WatchPolling  bool
+	WatchInterval time.Duration
+
+	// MaxDecodePixels rejects images whose header declares more pixels, before
+	// they are decoded, and DecodeTimeout abandons decodes that run longer;
+	// together they guard against decompression bombs. Zero disables either.
+	// A timed-out decode is abandoned, not cancelled: it stops at its next read
+	// of the file, and keeps its share of MaxMemoryMB until it does.
+	MaxDecodePixels int64
+	DecodeTimeout   time.Duration
 }
 
 // HashConfig defines which perceptual hash algorithms to compute
@@ -51,7 +165,20 @@ type HashConfig struct {
 	ComputePHash bool
 	ComputeDHash bool
 	ComputeWHash bool
-	HashSize     int
+
+	// HashSize is the side of the grid the aHash, pHash and dHash are sampled
+	// on. Sizes above 8 add wider hashes next to the 64-bit ones (see
+	// api.PerceptualHashes); it is rounded up to a multiple of 8, at most 32.
+	HashSize int
+
+	// ComputeColorHist stores an RGB histogram with ColorHistBins bins per
+	// channel (16 when unset) in each fingerprint
+	ComputeColorHist bool
+	ColorHistBins    int
+
+	// PaletteSize stores up to this many dominant colors, found by k-means
+	// in LAB space, in each fingerprint (0 stores no palette)
+	PaletteSize int
 }
 
 // ScanProgress represents real-time scan progress state
@@ -72,24 +199,52 @@ func NewEngine(cfg EngineConfig) (*Engine, error) {
 	logger.SetLevel(level)
 
 	// Initialize the index storage backend
-	store, err := index.NewBoltStore(cfg.IndexPath)
+	store, err := index.NewStore(index.Config{Type: cfg.IndexType, Path: cfg.IndexPath})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create index store: %w", err)
 	}
 
+	// Bring indexes written by older releases up to date
+	if err := store.Migrate(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to migrate index store: %w", err)
+	}
+
 	// Initialize the folder scanner
 	scanner := scanner.NewScanner(scanner.Config{
 		NumWorkers:       cfg.NumWorkers,
-		SupportedFormats: []string{".jpg", ".jpeg", ".png", ".webp", ".tiff", ".bmp"},
+		SupportedFormats: scanner.DefaultConfig().SupportedFormats,
+		MinWidth:         cfg.MinWidth,
+		MinHeight:        cfg.MinHeight,
+		MaxImages:        cfg.MaxImages,
 	})
 
 	// Initialize the quality analyzer
 	qualityAnalyzer := quality.NewAnalyzer(cfg.QualityConfig)
 
+	// Wide hashes are packed in whole bytes per row
+	if hashSize := normalizeHashSize(cfg.HashConfig.HashSize); hashSize != cfg.HashConfig.HashSize {
+		if cfg.HashConfig.HashSize != 0 {
+			logger.Warnf("Hash size %d is not supported, using %d", cfg.HashConfig.HashSize, hashSize)
+		}
+		cfg.HashConfig.HashSize = hashSize
+	}
+
+	// Histograms use 16 bins per channel unless configured otherwise
+	colorHistBins := cfg.HashConfig.ColorHistBins
+	if colorHistBins <= 0 {
+		colorHistBins = 16
+	}
+
 	// Initialize the similarity comparator
 	comparator := similarity.NewComparator(similarity.ComparatorConfig{
-		MinSimilarity: 0.8,
-		UseFeatureVec: false,
+		MinSimilarity:      0.8,
+		UseFeatureVec:      cfg.UseFeatureVec,
+		MaxAspectRatioDiff: cfg.MaxAspectRatioDiff,
+		ColorHistWeight:    cfg.ColorHistWeight,
+		ColorHistMetric:    cfg.ColorHistMetric,
+		RotationInvariant:  cfg.RotationInvariant,
+		FlipInvariant:      cfg.FlipInvariant,
 	})
 
 	return &Engine{
@@ -98,64 +253,422 @@ func NewEngine(cfg EngineConfig) (*Engine, error) {
 		scanner:    scanner,
 		quality:    qualityAnalyzer,
 		similarity: comparator,
+		exif:       imgmeta.NewEXIFReader(),
+		features:   hash.NewAdvancedHash(),
+		colors:     hash.NewColorSignature(colorHistBins),
+		organizer:  filesystem.NewOrganizer(),
+		transform:  pkgimaging.NewTransformer(0),
+		colorSpace: pkgimaging.NewColorSpace(),
+		namer:      imgmeta.NewClusterNamer(nil),
 		logger:     logger,
+		decoded:    newDecodeCache(int64(cfg.MaxMemoryMB) * 1024 * 1024 / 4),
+		memory:     newMemoryLimiter(int64(cfg.MaxMemoryMB) * 1024 * 1024),
 	}, nil
 }
 
-// ScanFolder recursively scans a folder and indexes all discovered images
-func (e *Engine) ScanFolder(ctx context.Context, folderPath string, progress chan<- api.ScanProgress) error {
+// ScanFolder recursively scans a folder, indexes all discovered images and
+// returns a report of the scan. If ctx is cancelled, the partial report is
+// returned together with ctx.Err(); if discovery stopped at MaxImages, the
+// images found are indexed and api.ErrScanLimitReached is returned with the report.
+func (e *Engine) ScanFolder(ctx context.Context, folderPath string, progress chan<- api.ScanProgress) (*api.ScanReport, error) {
+	return e.ScanFolderWithWorkers(ctx, folderPath, e.config.NumWorkers, progress)
+}
+
+// ScanFolderWithWorkers is ScanFolder with the images processed by the given
+// number of workers; a count below 1 uses the configured NumWorkers
+func (e *Engine) ScanFolderWithWorkers(ctx context.Context, folderPath string, workers int, progress chan<- api.ScanProgress) (*api.ScanReport, error) {
+	if workers < 1 {
+		workers = e.config.NumWorkers
+	}
+
+	if err := e.begin(); err != nil {
+		return nil, err
+	}
+	defer e.active.Done()
+
 	e.logger.Infof("Starting scan of folder: %s", folderPath)
 
 	startTime := time.Now()
+	report := &api.ScanReport{
+		ScanID:    "scan_" + startTime.Format("20060102_150405"),
+		StartedAt: startTime,
+	}
 
 	// Perform the initial folder scan to discover image files
-	imagePaths, err := e.scanner.ScanFolder(ctx, folderPath)
-	if err != nil {
-		return fmt.Errorf("failed to scan folder: %w", err)
+	scanResult, err := e.scanner.ScanFolderWithResult(ctx, folderPath, nil)
+	limitReached := errors.Is(err, api.ErrScanLimitReached)
+	if err != nil && !limitReached {
+		return nil, fmt.Errorf("failed to scan folder: %w", err)
+	}
+	imagePaths := scanResult.ImagePaths
+
+	// Files and directories the scanner could not access count as skipped
+	for _, scanErr := range scanResult.Errors {
+		report.Errors = append(report.Errors, newScanError(scanErr))
+	}
+	report.TotalFiles = len(imagePaths) + len(scanResult.Errors)
+
+	// Skip files indexed by an earlier scan that have not changed since;
+	// changed files replace their stale entries once reprocessed
+	stale := make(map[string]api.ImageID)
+	if !e.config.ForceRescan {
+		imagePaths, stale = e.filterUnchanged(imagePaths)
+		report.UnchangedFiles = report.TotalFiles - len(imagePaths)
 	}
 
 	total := len(imagePaths)
-	e.logger.Infof("Found %d images to process", total)
-
-	// Process each image file with progress reporting
-	processed := 0
-	for _, path := range imagePaths {
-		select {
-		case <-ctx.Done():
-			e.logger.Info("Scan operation cancelled by user")
-			return ctx.Err()
-		default:
-			fingerprint, err := e.processImage(path)
+	e.logger.Infof("Found %d images to process (%d unchanged)", total, report.UnchangedFiles)
+
+	// Decode, hash and analyze images concurrently; index writes stay on this goroutine
+	// and are batched so each transaction commits many fingerprints.
+	// The stream is drained even after cancellation so computed fingerprints are not lost.
+	processor := NewProcessor(e, workers)
+	batch := make([]api.ImageFingerprint, 0, saveBatchSize)
+	processed, skipped := 0, len(scanResult.Errors)
+	for result := range processor.ProcessStream(ctx, imagePaths) {
+		if result.Err != nil {
+			e.logger.Warnf("Failed to process image %s: %v", result.Path, result.Err)
+			report.Errors = append(report.Errors, api.ScanError{Path: result.Path, Reason: result.Err.Error()})
+			skipped++
+			continue
+		}
+
+		fp := result.Fingerprint
+		if id, ok := stale[result.Path]; ok {
+			if err := e.index.DeleteFingerprint(id); err != nil {
+				e.logger.Warnf("Failed to remove stale fingerprint for %s: %v", result.Path, err)
+			}
+		} else if moved := e.findMovedFingerprint(fp); moved != nil {
+			// The file was renamed or moved since it was indexed: keep its
+			// record under the new path rather than adding a second one
+			if err := e.index.DeleteFingerprint(moved.ID); err != nil {
+				e.logger.Warnf("Failed to remove old entry for moved file %s: %v", result.Path, err)
+			} else {
+				e.logger.Debugf("Detected move of %s to %s", moved.Metadata.Path, result.Path)
+				fp.ID = moved.ID
+				fp.CreatedAt = moved.CreatedAt
+				report.RenamedFiles++
+			}
+		}
+
+		batch = append(batch, fp)
+		if len(batch) == saveBatchSize {
+			saved := e.saveBatch(batch)
+			processed += saved
+			skipped += len(batch) - saved
+			batch = batch[:0]
+		}
+
+		// Report progress to the caller if channel is provided
+		if progress != nil {
+			done := processed + len(batch)
+			progress <- api.ScanProgress{
+				Current:     done,
+				Total:       total,
+				CurrentFile: result.Path,
+				Percentage:  float64(done) / float64(total) * 100,
+			}
+		}
+	}
+	saved := e.saveBatch(batch)
+	processed += saved
+	skipped += len(batch) - saved
+
+	report.ProcessedImages = processed
+	report.SkippedFiles = skipped
+	report.CompletedAt = time.Now()
+	report.GeneratedAt = report.CompletedAt
+	report.ScanDuration = report.CompletedAt.Sub(startTime)
+
+	if ctx.Err() != nil {
+		e.logger.Infof("Scan operation cancelled by user after saving %d images", processed)
+		return report, ctx.Err()
+	}
+
+	e.logger.Infof("Scan completed. Processed %d images (%d skipped) in %v", processed, skipped, report.ScanDuration)
+	if limitReached {
+		return report, api.ErrScanLimitReached
+	}
+	return report, nil
+}
+
+// newScanError converts a scanner error into a report entry
+func newScanError(err error) api.ScanError {
+	var fileErr *scanner.FileError
+	if errors.As(err, &fileErr) {
+		return api.ScanError{Path: fileErr.Path, Reason: fileErr.Err.Error()}
+	}
+	return api.ScanError{Reason: err.Error()}
+}
+
+// filterUnchanged drops paths whose indexed fingerprint matches the file's
+// current size and modification time, and returns the IDs of indexed
+// entries that are out of date
+func (e *Engine) filterUnchanged(paths []string) ([]string, map[string]api.ImageID) {
+	changed := make([]string, 0, len(paths))
+	stale := make(map[string]api.ImageID)
+	for _, path := range paths {
+		indexed, id, err := e.index.Exists(path)
+		if err != nil || !indexed {
+			changed = append(changed, path)
+			continue
+		}
+
+		fp, err := e.index.GetFingerprint(id)
+		if err != nil {
+			changed = append(changed, path)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err == nil && info.Size() == fp.Metadata.SizeBytes && info.ModTime().Equal(fp.Metadata.ModifiedAt) {
+			continue
+		}
+
+		changed = append(changed, path)
+		stale[path] = fp.ID
+	}
+	return changed, stale
+}
+
+// findMovedFingerprint returns an indexed fingerprint with the same content
+// as fp whose file no longer exists, or nil when there is none
+func (e *Engine) findMovedFingerprint(fp api.ImageFingerprint) *api.ImageFingerprint {
+	if fp.Metadata.SHA256 == "" {
+		return nil
+	}
+
+	matches, err := e.index.FindBySHA256(fp.Metadata.SHA256)
+	if err != nil {
+		e.logger.Debugf("Failed to look up %s by content hash: %v", fp.Metadata.Path, err)
+		return nil
+	}
+
+	for i := range matches {
+		if matches[i].Metadata.Path == fp.Metadata.Path {
+			continue
+		}
+		if _, err := os.Stat(matches[i].Metadata.Path); os.IsNotExist(err) {
+			return &matches[i]
+		}
+	}
+	return nil
+}
+
+// PruneMissing removes fingerprints whose files no longer exist on disk and
+// returns how many were removed
+func (e *Engine) PruneMissing() (int, error) {
+	var missing []api.ImageID
+	err := e.index.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		if isFSPath(fp.Metadata.Path) {
+			return nil // indexed from a file system that is not mounted
+		}
+		if _, err := os.Stat(fp.Metadata.Path); os.IsNotExist(err) {
+			missing = append(missing, fp.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to iterate fingerprints: %w", err)
+	}
+
+	pruned := 0
+	for _, id := range missing {
+		if err := e.index.DeleteFingerprint(id); err != nil {
+			e.logger.Warnf("Failed to prune fingerprint %s: %v", id, err)
+			continue
+		}
+		pruned++
+	}
+
+	e.logger.Infof("Pruned %d fingerprints of missing files", pruned)
+	return pruned, nil
+}
+
+// VerifyIndex checks every indexed file against the disk. An entry is missing
+// when its file no longer exists and changed when the file's size or
+// modification time differ from the index; with opts.Rehash, files whose
+// size and time still match are also re-hashed, and a file whose time alone
+// changed counts as unchanged if its content did not. With opts.Fix, missing
+// entries are removed and changed files are reprocessed in place.
+func (e *Engine) VerifyIndex(opts api.VerifyOptions) (*api.VerifyReport, error) {
+	report := &api.VerifyReport{}
+	var missing, changed []api.ImageFingerprint
+
+	err := e.index.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		// Images indexed from an fs.FS cannot be checked without it
+		if isFSPath(fp.Metadata.Path) {
+			return nil
+		}
+
+		report.Checked++
+		path := fp.Metadata.Path
+
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			missing = append(missing, fp)
+			return nil
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, api.ScanError{Path: path, Reason: err.Error()})
+			return nil
+		}
+
+		same := info.Size() == fp.Metadata.SizeBytes && info.ModTime().Equal(fp.Metadata.ModifiedAt)
+		if opts.Rehash && info.Size() == fp.Metadata.SizeBytes {
+			sha, err := e.computeFileHash(path)
 			if err != nil {
-				e.logger.Warnf("Failed to process image %s: %v", path, err)
+				report.Errors = append(report.Errors, api.ScanError{Path: path, Reason: err.Error()})
+				return nil
+			}
+			same = sha == fp.Metadata.SHA256
+		}
+
+		if same {
+			report.OK++
+		} else {
+			changed = append(changed, fp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate fingerprints: %w", err)
+	}
+
+	report.Missing, report.Changed = len(missing), len(changed)
+	for _, fp := range missing {
+		report.MissingPaths = append(report.MissingPaths, fp.Metadata.Path)
+	}
+	for _, fp := range changed {
+		report.ChangedPaths = append(report.ChangedPaths, fp.Metadata.Path)
+	}
+
+	if opts.Fix {
+		for _, fp := range missing {
+			if err := e.index.DeleteFingerprint(fp.ID); err != nil {
+				e.logger.Warnf("Failed to prune fingerprint %s: %v", fp.ID, err)
 				continue
 			}
+			report.Pruned++
+		}
 
-			// Persist the computed fingerprint to the index
-			if err := e.index.SaveFingerprint(fingerprint); err != nil {
-				e.logger.Warnf("Failed to save fingerprint for %s: %v", path, err)
+		// Reprocessed files keep their ID, so references to them stay valid
+		for _, fp := range changed {
+			updated, err := e.processImage(fp.Metadata.Path)
+			if err != nil {
+				report.Errors = append(report.Errors, api.ScanError{Path: fp.Metadata.Path, Reason: err.Error()})
+				continue
+			}
+			updated.ID = fp.ID
+			updated.CreatedAt = fp.CreatedAt
+			if err := e.index.SaveFingerprint(updated); err != nil {
+				e.logger.Warnf("Failed to update fingerprint %s: %v", fp.ID, err)
 				continue
 			}
+			report.Updated++
+		}
+	}
+
+	e.logger.Infof("Verified %d index entries: %d ok, %d missing, %d changed",
+		report.Checked, report.OK, report.Missing, report.Changed)
+	return report, nil
+}
 
-			processed++
+// ExportCatalog writes every fingerprint in the index to w as newline-delimited
+// JSON, one ImageFingerprint per line, streaming from the index as it goes
+func (e *Engine) ExportCatalog(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	exported := 0
+	err := e.index.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		if err := encoder.Encode(fp); err != nil {
+			return fmt.Errorf("failed to write catalog entry: %w", err)
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export catalog: %w", err)
+	}
 
-			// Report progress to the caller if channel is provided
-			if progress != nil {
-				progress <- api.ScanProgress{
-					Current:     processed,
-					Total:       total,
-					CurrentFile: path,
-					Percentage:  float64(processed) / float64(total) * 100,
-				}
+	e.logger.Infof("Exported %d fingerprints", exported)
+	return nil
+}
+
+// ImportCatalog reads a catalog written by ExportCatalog and saves its
+// fingerprints in batches, rebuilding the index's secondary indices.
+// Entries already in the index with the same ID are replaced.
+func (e *Engine) ImportCatalog(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	batch := make([]api.ImageFingerprint, 0, saveBatchSize)
+	imported := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := e.index.SaveFingerprints(batch); err != nil {
+			return fmt.Errorf("failed to save catalog entries: %w", err)
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for line := 1; ; line++ {
+		var fp api.ImageFingerprint
+		err := decoder.Decode(&fp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read catalog entry %d: %w", line, err)
+		}
+		if fp.ID == "" {
+			return fmt.Errorf("catalog entry %d has no image ID", line)
+		}
+
+		batch = append(batch, fp)
+		if len(batch) == saveBatchSize {
+			if err := flush(); err != nil {
+				return err
 			}
 		}
 	}
+	if err := flush(); err != nil {
+		return err
+	}
 
-	duration := time.Since(startTime)
-	e.logger.Infof("Scan completed. Processed %d images in %v", processed, duration)
+	e.logger.Infof("Imported %d fingerprints", imported)
 	return nil
 }
 
+// saveBatchSize is the number of fingerprints ScanFolder commits per index transaction
+const saveBatchSize = 256
+
+// saveBatch persists fingerprints in one transaction, falling back to
+// individual saves if the batch fails, and returns how many were saved
+func (e *Engine) saveBatch(batch []api.ImageFingerprint) int {
+	if len(batch) == 0 {
+		return 0
+	}
+
+	err := e.index.SaveFingerprints(batch)
+	if err == nil {
+		return len(batch)
+	}
+	e.logger.Warnf("Failed to save batch of %d fingerprints, retrying individually: %v", len(batch), err)
+
+	saved := 0
+	for _, fp := range batch {
+		if err := e.index.SaveFingerprint(fp); err != nil {
+			e.logger.Warnf("Failed to save fingerprint for %s: %v", fp.Metadata.Path, err)
+			continue
+		}
+		saved++
+	}
+	return saved
+}
+
 // processImage performs comprehensive analysis on a single image file
 func (e *Engine) processImage(path string) (api.ImageFingerprint, error) {
 	var fingerprint api.ImageFingerprint
@@ -164,41 +677,54 @@ func (e *Engine) processImage(path string) (api.ImageFingerprint, error) {
 	fingerprint.ID = api.ImageID(generateImageID(path))
 	fingerprint.CreatedAt = time.Now()
 
+	// Wait until the decoded image fits within MaxMemoryMB; the reservation
+	// lasts until the analysis of the image is done
+	reservation := e.reserveDecode(path)
+	defer reservation.release()
+
 	// Load and decode the image with metadata
-	img, metadata, err := e.loadImage(path)
+	img, metadata, err := e.loadImage(path, reservation)
 	if err != nil {
 		return fingerprint, fmt.Errorf("failed to load image %s: %w", path, err)
 	}
 
 	fingerprint.Metadata = metadata
+	e.analyzeImage(&fingerprint, img)
+	return fingerprint, nil
+}
+
+// analyzeImage fills in the hashes, feature vector, color data and quality
+// of a fingerprint whose metadata is already loaded
+func (e *Engine) analyzeImage(fingerprint *api.ImageFingerprint, img image.Image) {
+	path := fingerprint.Metadata.Path
+	var err error
 
 	// Compute perceptual hashes based on configuration
-	if e.config.HashConfig.ComputeAHash {
-		fingerprint.PHashes.AHash, err = e.computeAHash(img)
-		if err != nil {
-			e.logger.Warnf("Failed to compute AHash for %s: %v", path, err)
-		}
+	if e.config.RotationInvariant {
+		fingerprint.PHashes = e.computeRotationInvariantHashes(img, path)
+	} else {
+		fingerprint.PHashes = e.computeHashes(img, path)
 	}
 
-	if e.config.HashConfig.ComputePHash {
-		fingerprint.PHashes.PHash, err = e.computePHash(img)
+	// Compute the feature vector used for LSH candidate search
+	if e.config.UseFeatureVec {
+		fingerprint.FeatureVec, err = e.computeFeatureVector(img)
 		if err != nil {
-			e.logger.Warnf("Failed to compute PHash for %s: %v", path, err)
+			e.logger.Warnf("Failed to compute feature vector for %s: %v", path, err)
 		}
 	}
 
-	if e.config.HashConfig.ComputeDHash {
-		fingerprint.PHashes.DHash, err = e.computeDHash(img)
+	// Compute the color histogram used for color-aware comparison
+	if e.config.HashConfig.ComputeColorHist {
+		fingerprint.ColorHist, err = e.colors.ComputeColorHistogram(imaging.Fit(img, 128, 128, imaging.Box))
 		if err != nil {
-			e.logger.Warnf("Failed to compute DHash for %s: %v", path, err)
+			e.logger.Warnf("Failed to compute color histogram for %s: %v", path, err)
 		}
 	}
 
-	if e.config.HashConfig.ComputeWHash {
-		fingerprint.PHashes.WHash, err = e.computeWHash(img)
-		if err != nil {
-			e.logger.Warnf("Failed to compute WHash for %s: %v", path, err)
-		}
+	// Extract the dominant colors used for palette search
+	if e.config.HashConfig.PaletteSize > 0 {
+		fingerprint.Palette = e.computePalette(img)
 	}
 
 	// Analyze image quality
@@ -215,12 +741,78 @@ func (e *Engine) processImage(path string) (api.ImageFingerprint, error) {
 
 	e.logger.Debugf("Processed image %s: Quality=%.1f, Hashes=[A:%016x P:%016x]",
 		path, fingerprint.Quality.FinalScore, fingerprint.PHashes.AHash, fingerprint.PHashes.PHash)
+}
 
-	return fingerprint, nil
+// computeHashes computes the perceptual hashes enabled in HashConfig
+func (e *Engine) computeHashes(img image.Image, path string) api.PerceptualHashes {
+	var hashes api.PerceptualHashes
+
+	for _, h := range e.defaultHashers() {
+		if !h.enabled {
+			continue
+		}
+		value, err := h.Compute(img)
+		if err != nil {
+			e.logger.Warnf("Failed to compute %s for %s: %v", h.Name(), path, err)
+		}
+		*h.field(&hashes) = value
+	}
+
+	for _, h := range e.hashers {
+		value, err := h.Compute(img)
+		if err != nil {
+			e.logger.Warnf("Failed to compute %s for %s: %v", h.Name(), path, err)
+			continue
+		}
+		if hashes.Custom == nil {
+			hashes.Custom = make(map[string]uint64, len(e.hashers))
+		}
+		hashes.Custom[h.Name()] = value
+	}
+
+	if e.config.HashConfig.HashSize > api.DefaultHashSize {
+		e.computeWideHashes(img, &hashes)
+	}
+
+	return hashes
+}
+
+// computeRotationInvariantHashes computes the usual hashes of img plus the
+// 64-bit hashes of a thumbnail of it turned by 90, 180 and 270 degrees
+func (e *Engine) computeRotationInvariantHashes(img image.Image, path string) api.PerceptualHashes {
+	hashes := e.computeHashes(img, path)
+
+	thumbnail := imaging.Fit(img, 256, 256, imaging.Box)
+	for _, rotated := range []image.Image{imaging.Rotate90(thumbnail), imaging.Rotate180(thumbnail), imaging.Rotate270(thumbnail)} {
+		h := e.computeHashes(rotated, path)
+		hashes.Rotations = append(hashes.Rotations, api.PerceptualHashes{
+			AHash: h.AHash,
+			PHash: h.PHash,
+			DHash: h.DHash,
+			WHash: h.WHash,
+		})
+	}
+	return hashes
+}
+
+// computeFeatureVector computes the feature vector on a thumbnail, since the
+// features are normalized and do not need full resolution
+func (e *Engine) computeFeatureVector(img image.Image) ([]float32, error) {
+	vector, err := e.features.ComputeFeatureVector(imaging.Fit(img, 128, 128, imaging.Box))
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]float32, len(vector))
+	for i, value := range vector {
+		features[i] = float32(value)
+	}
+	return features, nil
 }
 
-// loadImage handles image loading, decoding, and basic metadata extraction
-func (e *Engine) loadImage(path string) (image.Image, api.ImageMetadata, error) {
+// loadImage handles image loading, decoding, and basic metadata extraction;
+// the decode holds reservation until it returns
+func (e *Engine) loadImage(path string, reservation *decodeReservation) (image.Image, api.ImageMetadata, error) {
 	var metadata api.ImageMetadata
 	metadata.Path = path
 
@@ -240,31 +832,25 @@ func (e *Engine) loadImage(path string) (image.Image, api.ImageMetadata, error)
 	}
 	metadata.SHA256 = sha256Hash
 
-	// Open and decode the image file
-	file, err := os.Open(path)
+	// Decode image to get format and dimensions, unless a decode of this
+	// version of the file is still cached
+	decoded, err := e.decodeImage(path, fileInfo, reservation)
 	if err != nil {
-		return nil, metadata, fmt.Errorf("failed to open image file: %w", err)
+		return nil, metadata, err
 	}
-	defer file.Close()
+	metadata.Format = decoded.format
+	metadata.FrameCount = decoded.frames
 
-	// Decode image to get format and dimensions
-	img, format, err := image.Decode(file)
+	orientation, err := e.exif.ReadOrientation(path)
 	if err != nil {
-		return nil, metadata, fmt.Errorf("failed to decode image: %w", err)
+		e.logger.Debugf("Failed to read EXIF orientation from %s: %v", path, err)
 	}
+	img := e.orientImage(decoded.img, orientation, &metadata)
 
-	metadata.Format = format
-	bounds := img.Bounds()
-	metadata.Width = bounds.Dx()
-	metadata.Height = bounds.Dy()
-
-	// Reset file for EXIF extraction (if needed)
-	file.Seek(0, 0)
-
-	// Extract EXIF metadata (simplified - would use proper EXIF library)
-	exifInfo, err := e.extractEXIFMetadata(file, path)
+	// Extract EXIF metadata; files without EXIF keep a nil EXIF field
+	exifInfo, err := e.exif.ExtractEXIF(path)
 	if err != nil {
-		e.logger.Debugf("Failed to extract EXIF metadata from %s: %v", path, err)
+		e.logger.Debugf("No EXIF metadata extracted from %s: %v", path, err)
 	} else {
 		metadata.EXIF = exifInfo
 	}
@@ -272,6 +858,35 @@ func (e *Engine) loadImage(path string) (image.Image, api.ImageMetadata, error)
 	return img, metadata, nil
 }
 
+// orientImage records the dimensions of img, raw and as viewers display
+// them once the EXIF orientation is applied, and returns img in that
+// display orientation. Images are hashed and rated the way viewers display
+// them, so a tagged photo matches its already-rotated re-export; the cached
+// decode stays raw.
+func (e *Engine) orientImage(img image.Image, orientation int, metadata *api.ImageMetadata) image.Image {
+	bounds := img.Bounds()
+	metadata.Width = bounds.Dx()
+	metadata.Height = bounds.Dy()
+	metadata.Orientation = orientation
+	metadata.DisplayWidth, metadata.DisplayHeight = imgmeta.DisplayDimensions(metadata.Width, metadata.Height, orientation)
+
+	if orientation > 1 && orientation <= 8 {
+		img = e.transform.NormalizeOrientation(img, orientation)
+		metadata.AppliedOrientation = orientation
+	}
+	return img
+}
+
+// computePalette returns the PaletteSize dominant colors of img
+func (e *Engine) computePalette(img image.Image) []api.PaletteColor {
+	dominant := e.colorSpace.DominantColors(img, e.config.HashConfig.PaletteSize)
+	palette := make([]api.PaletteColor, len(dominant))
+	for i, d := range dominant {
+		palette[i] = api.PaletteColor{R: d.Color.R, G: d.Color.G, B: d.Color.B, Coverage: d.Coverage}
+	}
+	return palette
+}
+
 // computeFileHash calculates the SHA256 hash of a file
 func (e *Engine) computeFileHash(path string) (string, error) {
 	file, err := os.Open(path)
@@ -288,43 +903,19 @@ func (e *Engine) computeFileHash(path string) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// extractEXIFMetadata extracts EXIF metadata from an image file
-func (e *Engine) extractEXIFMetadata(file *os.File, path string) (*api.EXIFInfo, error) {
-	// This is a simplified implementation
-	// In a real implementation, you would use a proper EXIF library like goexif
-	exifInfo := &api.EXIFInfo{
-		HasGPS: false,
-	}
-
-	// Placeholder for actual EXIF extraction
-	// For now, we'll just set some basic info based on file extension
-	ext := filepath.Ext(path)
-	switch ext {
-	case ".jpg", ".jpeg":
-		exifInfo.CameraModel = "Unknown JPEG Camera"
-	case ".png":
-		exifInfo.CameraModel = "Unknown PNG Source"
-	}
-
-	return exifInfo, nil
-}
-
 // computeAHash calculates the Average Hash for an image
 func (e *Engine) computeAHash(img image.Image) (uint64, error) {
 	// Resize image to 8x8 for hash computation
 	resized := imaging.Resize(img, 8, 8, imaging.Lanczos)
-	gray := imaging.Grayscale(resized)
 
-	bounds := gray.Bounds()
+	bounds := resized.Bounds()
 	var sum uint64
 	var pixels []uint64
 
 	// Calculate average pixel value
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, _ := gray.At(x, y).RGBA()
-			// Convert to grayscale value (simplified)
-			luminance := (uint64(r) + uint64(g) + uint64(b)) / 3
+			luminance := uint64(math.Round(pkgimaging.Luminance(resized.At(x, y)) * 65535))
 			sum += luminance
 			pixels = append(pixels, luminance)
 		}
@@ -347,9 +938,8 @@ func (e *Engine) computeAHash(img image.Image) (uint64, error) {
 func (e *Engine) computePHash(img image.Image) (uint64, error) {
 	// Resize to 32x32 for better frequency analysis
 	resized := imaging.Resize(img, 32, 32, imaging.Lanczos)
-	gray := imaging.Grayscale(resized)
 
-	bounds := gray.Bounds()
+	bounds := resized.Bounds()
 	if bounds.Dx() != 32 || bounds.Dy() != 32 {
 		return 0, fmt.Errorf("unexpected image dimensions for pHash")
 	}
@@ -358,9 +948,7 @@ func (e *Engine) computePHash(img image.Image) (uint64, error) {
 	var matrix [32][32]float64
 	for y := 0; y < 32; y++ {
 		for x := 0; x < 32; x++ {
-			r, g, b, _ := gray.At(x, y).RGBA()
-			luminance := (float64(r) + float64(g) + float64(b)) / 3.0 / 65535.0
-			matrix[y][x] = luminance
+			matrix[y][x] = pkgimaging.Luminance(resized.At(x, y))
 		}
 	}
 
@@ -392,20 +980,16 @@ func (e *Engine) computePHash(img image.Image) (uint64, error) {
 func (e *Engine) computeDHash(img image.Image) (uint64, error) {
 	// Resize to 9x8 for difference calculation (8x8 differences)
 	resized := imaging.Resize(img, 9, 8, imaging.Lanczos)
-	gray := imaging.Grayscale(resized)
 
-	bounds := gray.Bounds()
+	bounds := resized.Bounds()
 	var hash uint64
 	bitPosition := 0
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X-1; x++ {
-			// Get current and next pixel
-			r1, g1, b1, _ := gray.At(x, y).RGBA()
-			r2, g2, b2, _ := gray.At(x+1, y).RGBA()
-
-			luminance1 := (uint64(r1) + uint64(g1) + uint64(b1)) / 3
-			luminance2 := (uint64(r2) + uint64(g2) + uint64(b2)) / 3
+			// Compare the luminance of the current and next pixel
+			luminance1 := pkgimaging.Luminance(resized.At(x, y))
+			luminance2 := pkgimaging.Luminance(resized.At(x+1, y))
 
 			// Set bit if next pixel is brighter
 			if luminance2 > luminance1 {
@@ -437,43 +1021,171 @@ func (e *Engine) computeWHash(img image.Image) (uint64, error) {
 func (e *Engine) FindExactDuplicates() ([]api.DuplicateGroup, error) {
 	e.logger.Info("Searching for exact duplicates using SHA256 hashes")
 
-	// Retrieve all fingerprints from the index
-	fingerprints, err := e.index.GetAllFingerprints()
+	// Stream the index, keeping only IDs grouped by their SHA256 hash
+	hashGroups := make(map[string][]api.ImageID)
+	var hashOrder []string
+	err := e.index.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+		sha := fp.Metadata.SHA256
+		if sha == "" {
+			return nil
+		}
+		if _, seen := hashGroups[sha]; !seen {
+			hashOrder = append(hashOrder, sha)
+		}
+		hashGroups[sha] = append(hashGroups[sha], fp.ID)
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve fingerprints: %w", err)
 	}
 
-	// Group images by their SHA256 hash
-	hashGroups := make(map[string][]api.ImageID)
-	for _, fp := range fingerprints {
-		hashGroups[fp.Metadata.SHA256] = append(hashGroups[fp.Metadata.SHA256], fp.ID)
-	}
-
 	// Create duplicate groups for hashes with multiple images
 	var groups []api.DuplicateGroup
-	groupCounter := 0
 
-	for _, imageIDs := range hashGroups {
-		if len(imageIDs) > 1 {
-			mainImage := e.selectBestImage(imageIDs, fingerprints, api.PolicyHighestQuality)
+	for _, sha := range hashOrder {
+		imageIDs := hashGroups[sha]
+		if len(imageIDs) < 2 {
+			continue
+		}
 
-			groups = append(groups, api.DuplicateGroup{
-				GroupID:      fmt.Sprintf("exact_%d", groupCounter),
-				MainImage:    mainImage,
-				DuplicateIDs: e.removeElement(imageIDs, mainImage),
-				Reason:       "exact",
-				Confidence:   1.0,
-			})
-			groupCounter++
+		// Only the members of a group are loaded to choose its main image
+		members := make([]api.ImageFingerprint, 0, len(imageIDs))
+		for _, id := range imageIDs {
+			fp, err := e.index.GetFingerprint(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load fingerprint %s: %w", id, err)
+			}
+			members = append(members, *fp)
 		}
+		mainImage := e.selectBestImage(imageIDs, members, api.PolicyHighestQuality)
+
+		groups = append(groups, api.DuplicateGroup{
+			GroupID:      contentGroupID("exact", imageIDs),
+			MainImage:    mainImage,
+			DuplicateIDs: e.removeElement(imageIDs, mainImage),
+			Reason:       "exact",
+			Confidence:   1.0,
+		})
 	}
 
 	e.logger.Infof("Found %d exact duplicate groups", len(groups))
 	return groups, nil
 }
 
+// FindDuplicates returns the exact and near-duplicate groups at threshold.
+// The groups of the last search are saved in the index and served again
+// while no fingerprint has changed and the threshold and the settings that
+// affect matching are the same; recompute forces a fresh search. An index
+// without duplicates is searched each time.
+func (e *Engine) FindDuplicates(threshold float64, recompute bool) (exact, near []api.DuplicateGroup, err error) {
+	return e.FindDuplicatesCtx(context.Background(), threshold, recompute)
+}
+
+// FindDuplicatesCtx is FindDuplicates with cancellation: a near-duplicate
+// search stops, returning ctx.Err(), once ctx is done
+func (e *Engine) FindDuplicatesCtx(ctx context.Context, threshold float64, recompute bool) (exact, near []api.DuplicateGroup, err error) {
+	if err := e.begin(); err != nil {
+		return nil, nil, err
+	}
+	defer e.active.Done()
+
+	searchKey := e.duplicateSearchKey(api.NearDuplicateOptions{MinCombined: threshold})
+	if !recompute {
+		cached, err := e.index.GetGroups()
+		if err != nil {
+			e.logger.Warnf("Failed to read saved duplicate groups: %v", err)
+		}
+		if len(cached) > 0 && cached[0].Threshold == threshold && cached[0].SearchKey == searchKey {
+			for _, group := range cached {
+				if group.Reason == api.ReasonExact {
+					exact = append(exact, group)
+				} else {
+					near = append(near, group)
+				}
+			}
+			e.logger.Infof("Using %d saved duplicate groups", len(cached))
+			return exact, near, nil
+		}
+	}
+
+	exact, err = e.FindExactDuplicates()
+	if err != nil {
+		return nil, nil, err
+	}
+	near, err = e.FindNearDuplicatesCtx(ctx, threshold, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range exact {
+		exact[i].Threshold = threshold
+		exact[i].SearchKey = searchKey
+	}
+	for i := range near {
+		near[i].Threshold = threshold
+		near[i].SearchKey = searchKey
+	}
+	groups := append(append([]api.DuplicateGroup(nil), exact...), near...)
+	if err := e.index.SaveGroups(groups); err != nil {
+		e.logger.Warnf("Failed to save duplicate groups: %v", err)
+	}
+
+	return exact, near, nil
+}
+
+// duplicateSearchKey digests opts and every setting that changes which
+// duplicate groups a search finds or which image leads them, so that groups
+// saved under other settings are not served
+func (e *Engine) duplicateSearchKey(opts api.NearDuplicateOptions) string {
+	hashers := make([]string, len(e.hashers))
+	for i, h := range e.hashers {
+		hashers[i] = h.Name()
+	}
+
+	settings := fmt.Sprintf("%+v", struct {
+		Options                   api.NearDuplicateOptions
+		Hashes                    HashConfig
+		Hashers                   []string
+		MaxAspectRatioDiff        float64
+		ColorHistWeight           float64
+		ColorHistMetric           hash.HistogramMetric
+		UseFeatureVec             bool
+		LSHTables                 int
+		LSHHashesPerTable         int
+		SelectionPolicy           api.SelectionPolicy
+		DetectRecompressed        bool
+		RecompressedMinConfidence float64
+		VerifySSIM                bool
+		SSIMThreshold             float64
+		RotationInvariant         bool
+		FlipInvariant             bool
+	}{
+		opts, e.config.HashConfig, hashers,
+		e.config.MaxAspectRatioDiff, e.config.ColorHistWeight, e.config.ColorHistMetric,
+		e.config.UseFeatureVec, e.config.LSHTables, e.config.LSHHashesPerTable,
+		e.config.SelectionPolicy, e.config.DetectRecompressed, e.config.RecompressedMinConfidence,
+		e.config.VerifySSIM, e.config.SSIMThreshold,
+		e.config.RotationInvariant, e.config.FlipInvariant,
+	})
+	sum := sha256.Sum256([]byte(settings))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
 // FindNearDuplicates identifies visually similar images using perceptual hashing
 func (e *Engine) FindNearDuplicates(threshold float64) ([]api.DuplicateGroup, error) {
+	return e.FindNearDuplicatesCtx(context.Background(), threshold, nil)
+}
+
+// FindNearDuplicatesCtx is FindNearDuplicates with cancellation and progress
+// reporting. Progress counts fingerprints visited by the grouping loop.
+func (e *Engine) FindNearDuplicatesCtx(ctx context.Context, threshold float64, progress chan<- api.ScanProgress) ([]api.DuplicateGroup, error) {
+	return e.FindNearDuplicatesWithOptions(ctx, api.NearDuplicateOptions{MinCombined: threshold}, progress)
+}
+
+// FindNearDuplicatesWithOptions groups images whose combined similarity reaches
+// opts.MinCombined and whose individual hashes meet any per-hash minimums
+func (e *Engine) FindNearDuplicatesWithOptions(ctx context.Context, opts api.NearDuplicateOptions, progress chan<- api.ScanProgress) ([]api.DuplicateGroup, error) {
+	threshold := opts.MinCombined
 	e.logger.Infof("Searching for near duplicates with similarity threshold: %.2f", threshold)
 
 	fingerprints, err := e.index.GetAllFingerprints()
@@ -481,60 +1193,335 @@ func (e *Engine) FindNearDuplicates(threshold float64) ([]api.DuplicateGroup, er
 		return nil, fmt.Errorf("failed to retrieve fingerprints: %w", err)
 	}
 
-	var groups []api.DuplicateGroup
-	processed := make(map[api.ImageID]bool)
-	groupCounter := 0
+	// Sort by ID so components, group numbering and tie-breaks do not depend on store order
+	sort.Slice(fingerprints, func(i, j int) bool {
+		return fingerprints[i].ID < fingerprints[j].ID
+	})
 
+	// Index the fingerprints once so each image only compares against its neighborhood
+	neighbors := e.nearDuplicateIndex(fingerprints, threshold)
+	position := make(map[api.ImageID]int, len(fingerprints))
+	for i, fp := range fingerprints {
+		position[fp.ID] = i
+	}
+
+	// Downscales for SSIM verification are decoded on first use and shared across pairs
+	structure := newSSIMCache()
+
+	// Union every pair above the threshold so chains (A~B, B~C) end up in one group
+	components := similarity.NewDisjointSet(len(fingerprints))
 	for i, fp1 := range fingerprints {
-		if processed[fp1.ID] {
-			continue
+		if err := ctx.Err(); err != nil {
+			e.logger.Infof("Near-duplicate search cancelled after %d of %d images", i, len(fingerprints))
+			return nil, err
 		}
 
-		var similarImages []api.ImageID
-		similarImages = append(similarImages, fp1.ID)
-		processed[fp1.ID] = true
+		// Report progress to the caller if channel is provided
+		if progress != nil {
+			select {
+			case progress <- api.ScanProgress{
+				Current:     i + 1,
+				Total:       len(fingerprints),
+				CurrentFile: fp1.Metadata.Path,
+				Percentage:  float64(i+1) / float64(len(fingerprints)) * 100,
+			}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
 
-		for j := i + 1; j < len(fingerprints); j++ {
-			fp2 := fingerprints[j]
-			if processed[fp2.ID] {
+		for _, j := range nearDuplicateCandidates(neighbors, fingerprints, i, position) {
+			// Already linked through another match; comparing again cannot change the result
+			if components.Connected(i, j) {
 				continue
 			}
 
+			fp2 := fingerprints[j]
 			similarity, err := e.similarity.CompareFingerprints(fp1, fp2)
 			if err != nil {
 				e.logger.Warnf("Failed to compare %s and %s: %v", fp1.ID, fp2.ID, err)
 				continue
 			}
 
-			if similarity >= threshold {
-				similarImages = append(similarImages, fp2.ID)
-				processed[fp2.ID] = true
+			if similarity >= threshold && e.similarity.MeetsHashMinimums(fp1, fp2, opts) &&
+				e.structurallySimilar(structure, fp1, fp2) {
+				components.Union(i, j)
 			}
 		}
+	}
 
-		if len(similarImages) > 1 {
-			mainImage := e.selectBestImage(similarImages, fingerprints, api.PolicyHighestQuality)
+	var groups []api.DuplicateGroup
+	for _, component := range components.Components() {
+		if len(component) < 2 {
+			continue
+		}
 
-			groups = append(groups, api.DuplicateGroup{
-				GroupID:      fmt.Sprintf("near_%d", groupCounter),
-				MainImage:    mainImage,
-				DuplicateIDs: e.removeElement(similarImages, mainImage),
-				Reason:       "near",
-				Confidence:   e.calculateGroupConfidence(similarImages, fingerprints),
-			})
-			groupCounter++
+		members := make([]api.ImageFingerprint, len(component))
+		similarImages := make([]api.ImageID, len(component))
+		for k, idx := range component {
+			members[k] = fingerprints[idx]
+			similarImages[k] = fingerprints[idx].ID
 		}
+
+		mainImage := e.selectBestImage(similarImages, members, e.config.SelectionPolicy)
+		group := api.DuplicateGroup{
+			GroupID:      contentGroupID("near", similarImages),
+			MainImage:    mainImage,
+			DuplicateIDs: e.removeElement(similarImages, mainImage),
+			Reason:       api.ReasonNear,
+			Confidence:   e.calculateGroupConfidence(similarImages, members),
+		}
+
+		if formats := groupFormats(members); e.config.DetectRecompressed &&
+			len(formats) > 1 && group.Confidence >= e.config.RecompressedMinConfidence {
+			group.Reason = api.ReasonRecompressed
+			group.Formats = formats
+		}
+
+		groups = append(groups, group)
 	}
 
 	e.logger.Infof("Found %d near-duplicate groups", len(groups))
 	return groups, nil
 }
 
+// ssimCache holds the SSIM downscales decoded during one near-duplicate search
+type ssimCache struct {
+	images map[api.ImageID]*image.Gray
+	failed map[api.ImageID]bool
+}
+
+// newSSIMCache creates an empty SSIM downscale cache
+func newSSIMCache() *ssimCache {
+	return &ssimCache{
+		images: make(map[api.ImageID]*image.Gray),
+		failed: make(map[api.ImageID]bool),
+	}
+}
+
+// structurallySimilar re-verifies a candidate pair with SSIM when VerifySSIM is
+// set. Pairs whose images cannot be decoded are rejected rather than grouped
+// unverified, except images indexed by ScanFS: their file system is not around
+// to re-read, so pairs involving them keep the verdict of their hashes.
+func (e *Engine) structurallySimilar(cache *ssimCache, fp1, fp2 api.ImageFingerprint) bool {
+	if !e.config.VerifySSIM || isFSPath(fp1.Metadata.Path) || isFSPath(fp2.Metadata.Path) {
+		return true
+	}
+
+	img1, ok1 := e.ssimImage(cache, fp1)
+	img2, ok2 := e.ssimImage(cache, fp2)
+	if !ok1 || !ok2 {
+		return false
+	}
+
+	score := similarity.SSIM(img1, img2)
+	e.logger.Debugf("SSIM of %s and %s: %.3f", fp1.Metadata.Path, fp2.Metadata.Path, score)
+	return score >= e.config.SSIMThreshold
+}
+
+// ssimImage returns the cached SSIM downscale of fp's image, decoding it on first use
+func (e *Engine) ssimImage(cache *ssimCache, fp api.ImageFingerprint) (*image.Gray, bool) {
+	if img, ok := cache.images[fp.ID]; ok {
+		return img, true
+	}
+	if cache.failed[fp.ID] {
+		return nil, false
+	}
+
+	info, err := os.Stat(fp.Metadata.Path)
+	if err != nil {
+		e.logger.Warnf("Failed to open %s for SSIM verification: %v", fp.Metadata.Path, err)
+		cache.failed[fp.ID] = true
+		return nil, false
+	}
+
+	reservation := e.reserveDecode(fp.Metadata.Path)
+	defer reservation.release()
+	decoded, err := e.decodeImage(fp.Metadata.Path, info, reservation)
+	if err != nil {
+		e.logger.Warnf("Failed to decode %s for SSIM verification: %v", fp.Metadata.Path, err)
+		cache.failed[fp.ID] = true
+		return nil, false
+	}
+
+	gray := similarity.SSIMImage(e.transform.NormalizeOrientation(decoded.img, fp.Metadata.AppliedOrientation))
+	cache.images[fp.ID] = gray
+	return gray, true
+}
+
+// groupFormats returns the distinct formats of a group's images in sorted order
+func groupFormats(members []api.ImageFingerprint) []string {
+	seen := make(map[string]bool)
+	var formats []string
+	for _, fp := range members {
+		format := strings.ToLower(fp.Metadata.Format)
+		if format == "" || seen[format] {
+			continue
+		}
+		seen[format] = true
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// neighborFunc returns the IDs that may be similar to fp, or false when fp
+// is not indexed and must be compared against everything
+type neighborFunc func(fp api.ImageFingerprint) ([]api.ImageID, bool)
+
+// nearDuplicateIndex builds the candidate index for FindNearDuplicates: an LSH
+// index over feature vectors when UseFeatureVec is set, otherwise BK-trees
+// over pHashes. Pairs that both have wide pHashes of one size are scored on
+// those, so they are also indexed in a BK-tree per wide hash size with a
+// radius scaled to its bits. The BK-tree radii are lossless; LSH trades some
+// recall for speed.
+func (e *Engine) nearDuplicateIndex(fingerprints []api.ImageFingerprint, threshold float64) neighborFunc {
+	var unindexed []api.ImageID
+
+	if e.config.UseFeatureVec {
+		tables, hashesPerTable := e.config.LSHTables, e.config.LSHHashesPerTable
+		if tables <= 0 || hashesPerTable <= 0 {
+			tables, hashesPerTable = 8, 16
+		}
+		lsh := similarity.NewLSH(tables, hashesPerTable)
+		for _, fp := range fingerprints {
+			if len(fp.FeatureVec) == 0 {
+				unindexed = append(unindexed, fp.ID)
+				continue
+			}
+			lsh.IndexVector(float64Vector(fp.FeatureVec), string(fp.ID))
+		}
+
+		return func(fp api.ImageFingerprint) ([]api.ImageID, bool) {
+			if len(fp.FeatureVec) == 0 {
+				return nil, false
+			}
+			ids := append([]api.ImageID(nil), unindexed...)
+			for _, id := range lsh.Query(float64Vector(fp.FeatureVec), len(fingerprints)) {
+				ids = append(ids, api.ImageID(id))
+			}
+			return ids, true
+		}
+	}
+
+	tree := similarity.NewBKTree()
+	wideTrees := make(map[int]*similarity.BKTree)
+	for _, fp := range fingerprints {
+		if fp.PHashes.PHash == 0 {
+			unindexed = append(unindexed, fp.ID)
+			continue
+		}
+		tree.Insert(fp.ID, fp.PHashes.PHash)
+
+		if size := len(fp.PHashes.PHashWide); size > 0 {
+			if wideTrees[size] == nil {
+				wideTrees[size] = similarity.NewBKTree()
+			}
+			wideTrees[size].InsertWide(fp.ID, fp.PHashes.PHashWide)
+		}
+	}
+	maxDistance := e.similarity.MaxPHashDistance(threshold)
+
+	return func(fp api.ImageFingerprint) ([]api.ImageID, bool) {
+		if fp.PHashes.PHash == 0 {
+			return nil, false
+		}
+		lookups := []api.PerceptualHashes{fp.PHashes}
+		if e.config.FlipInvariant {
+			lookups = append(lookups, similarity.MirrorHashes(fp.PHashes))
+		}
+		if e.config.RotationInvariant {
+			lookups = append(lookups, fp.PHashes.Rotations...)
+		}
+
+		ids := append([]api.ImageID(nil), unindexed...)
+		for _, hashes := range lookups {
+			ids = append(ids, tree.Query(hashes.PHash, maxDistance)...)
+			if wide, ok := wideTrees[len(hashes.PHashWide)]; ok {
+				wideDistance := e.similarity.MaxWidePHashDistance(threshold, 8*len(hashes.PHashWide))
+				ids = append(ids, wide.QueryWide(hashes.PHashWide, wideDistance)...)
+			}
+		}
+		return ids, true
+	}
+}
+
+// nearDuplicateCandidates returns, in index order, the positions after i that
+// may be similar to fingerprints[i]
+func nearDuplicateCandidates(neighbors neighborFunc, fingerprints []api.ImageFingerprint, i int, position map[api.ImageID]int) []int {
+	var candidates []int
+
+	ids, indexed := neighbors(fingerprints[i])
+	if !indexed {
+		for j := i + 1; j < len(fingerprints); j++ {
+			candidates = append(candidates, j)
+		}
+		return candidates
+	}
+
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if j, ok := position[id]; ok && j > i && !seen[j] {
+			seen[j] = true
+			candidates = append(candidates, j)
+		}
+	}
+
+	sort.Ints(candidates)
+	return candidates
+}
+
+// float64Vector widens a stored feature vector for the similarity package
+func float64Vector(vector []float32) []float64 {
+	widened := make([]float64, len(vector))
+	for i, value := range vector {
+		widened[i] = float64(value)
+	}
+	return widened
+}
+
+// ClusterImages groups all indexed images by hierarchical clustering with
+// the configured ClusterLinkage. Each cluster is named after its members'
+// capture month and location, such as "2023-07 Paris", when their EXIF
+// data has them.
+func (e *Engine) ClusterImages(threshold float64) ([]api.Cluster, error) {
+	fingerprints, err := e.index.GetAllFingerprints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve fingerprints: %w", err)
+	}
+
+	clusterer := similarity.NewClusterer(e.similarity, fingerprints)
+	clusters := clusterer.ClusterBySimilarity(fingerprints, threshold, e.config.ClusterLinkage)
+	e.nameClusters(clusters, fingerprints)
+
+	e.logger.Infof("Grouped %d images into %d clusters", len(fingerprints), len(clusters))
+	return clusters, nil
+}
+
+// nameClusters labels each cluster from the EXIF data of its members
+func (e *Engine) nameClusters(clusters []api.Cluster, fingerprints []api.ImageFingerprint) {
+	byID := make(map[api.ImageID]api.ImageFingerprint, len(fingerprints))
+	for _, fp := range fingerprints {
+		byID[fp.ID] = fp
+	}
+
+	for i := range clusters {
+		members := make([]api.ImageFingerprint, 0, len(clusters[i].Images))
+		for _, id := range clusters[i].Images {
+			members = append(members, byID[id])
+		}
+		clusters[i].Name = e.namer.Name(members)
+	}
+}
+
 // RateImageQuality analyzes and rates the quality of a specific image
 func (e *Engine) RateImageQuality(imagePath string) (*api.ImageQuality, error) {
 	e.logger.Debugf("Analyzing image quality: %s", imagePath)
 
-	img, _, err := e.loadImage(imagePath)
+	reservation := e.reserveDecode(imagePath)
+	defer reservation.release()
+
+	img, _, err := e.loadImage(imagePath, reservation)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load image for quality analysis: %w", err)
 	}
@@ -548,8 +1535,170 @@ func (e *Engine) RateImageQuality(imagePath string) (*api.ImageQuality, error) {
 	return quality, nil
 }
 
+// ExplainQuality lists the quality metrics that fail the configured
+// thresholds, with a message and a suggested fix for each
+func (e *Engine) ExplainQuality(quality api.ImageQuality) []api.QualityIssue {
+	return e.quality.Explain(quality)
+}
+
+// CompareFiles fingerprints two image files and compares them without touching
+// the index. Files count as near duplicates when their combined similarity
+// reaches api.DefaultSimilarityThreshold and, with VerifySSIM, they pass SSIM.
+func (e *Engine) CompareFiles(pathA, pathB string) (*api.ComparisonResult, error) {
+	fpA, err := e.processImage(pathA)
+	if err != nil {
+		return nil, err
+	}
+	fpB, err := e.processImage(pathB)
+	if err != nil {
+		return nil, err
+	}
+
+	score, err := e.similarity.CompareFingerprints(fpA, fpB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare images: %w", err)
+	}
+
+	result := &api.ComparisonResult{
+		PathA:            pathA,
+		PathB:            pathB,
+		ExactMatch:       fpA.Metadata.SHA256 == fpB.Metadata.SHA256,
+		HammingDistances: make(map[string]int),
+		Similarity:       score,
+		Threshold:        api.DefaultSimilarityThreshold,
+		QualityA:         fpA.Quality,
+		QualityB:         fpB.Quality,
+	}
+
+	hashes := []struct {
+		name     string
+		computed bool
+		a, b     uint64
+	}{
+		{"ahash", e.config.HashConfig.ComputeAHash, fpA.PHashes.AHash, fpB.PHashes.AHash},
+		{"phash", e.config.HashConfig.ComputePHash, fpA.PHashes.PHash, fpB.PHashes.PHash},
+		{"dhash", e.config.HashConfig.ComputeDHash, fpA.PHashes.DHash, fpB.PHashes.DHash},
+		{"whash", e.config.HashConfig.ComputeWHash, fpA.PHashes.WHash, fpB.PHashes.WHash},
+	}
+	for _, h := range hashes {
+		if h.computed {
+			result.HammingDistances[h.name] = bits.OnesCount64(h.a ^ h.b)
+		}
+	}
+	for name, a := range fpA.PHashes.Custom {
+		if b, ok := fpB.PHashes.Custom[name]; ok {
+			result.HammingDistances[name] = bits.OnesCount64(a ^ b)
+		}
+	}
+
+	result.IsNearDuplicate = result.ExactMatch ||
+		(score >= result.Threshold && e.structurallySimilar(newSSIMCache(), fpA, fpB))
+
+	return result, nil
+}
+
+// FindSimilarTo fingerprints the image at imagePath without indexing it and
+// returns the indexed images that match it: byte-identical files first, then
+// images whose combined similarity reaches threshold, best first. Each match
+// is its own group, with MainImage set to the indexed image and Confidence to
+// its similarity to the query. The query file itself is left out if indexed.
+func (e *Engine) FindSimilarTo(imagePath string, threshold float64) ([]api.DuplicateGroup, error) {
+	query, err := e.processImage(imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	exact, err := e.index.FindBySHA256(query.Metadata.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find exact matches: %w", err)
+	}
+
+	// Near matches are looked up on the pHash, whose lossless search radius is
+	// known. Without one, or when wide pHashes may be scored instead, every
+	// indexed aHash is a candidate.
+	hashType, hashValue, maxDistance := "phash", query.PHashes.PHash, e.similarity.MaxPHashDistance(threshold)
+	if hashValue == 0 || len(query.PHashes.PHashWide) > 0 {
+		hashType, hashValue, maxDistance = "ahash", query.PHashes.AHash, 64
+	}
+	lookups := []uint64{hashValue}
+	if e.config.FlipInvariant && hashType == "phash" {
+		lookups = append(lookups, similarity.MirrorHashes(query.PHashes).PHash)
+	}
+	if e.config.RotationInvariant && hashType == "phash" {
+		for _, rotated := range query.PHashes.Rotations {
+			lookups = append(lookups, rotated.PHash)
+		}
+	}
+	var candidates []api.ImageFingerprint
+	for _, value := range lookups {
+		if value == 0 {
+			continue
+		}
+		found, err := e.index.FindSimilarHashes(value, maxDistance, hashType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find similar images: %w", err)
+		}
+		candidates = append(candidates, found...)
+	}
+
+	queryPath := filepath.Clean(imagePath)
+	seen := make(map[api.ImageID]bool)
+	var groups []api.DuplicateGroup
+	for _, fp := range exact {
+		if seen[fp.ID] || filepath.Clean(fp.Metadata.Path) == queryPath {
+			continue
+		}
+		seen[fp.ID] = true
+		groups = append(groups, api.DuplicateGroup{MainImage: fp.ID, Reason: api.ReasonExact, Confidence: 1.0})
+	}
+	for _, fp := range candidates {
+		if seen[fp.ID] || filepath.Clean(fp.Metadata.Path) == queryPath {
+			continue
+		}
+		seen[fp.ID] = true
+
+		score, err := e.similarity.CompareFingerprints(query, fp)
+		if err != nil {
+			e.logger.Warnf("Failed to compare %s and %s: %v", imagePath, fp.ID, err)
+			continue
+		}
+		if score >= threshold {
+			groups = append(groups, api.DuplicateGroup{MainImage: fp.ID, Reason: api.ReasonNear, Confidence: score})
+		}
+	}
+
+	// Exact matches keep their lead; ties are ordered by ID for stable output
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Reason != groups[j].Reason {
+			return groups[i].Reason == api.ReasonExact
+		}
+		if groups[i].Confidence != groups[j].Confidence {
+			return groups[i].Confidence > groups[j].Confidence
+		}
+		return groups[i].MainImage < groups[j].MainImage
+	})
+	for i := range groups {
+		groups[i].GroupID = fmt.Sprintf("query_%d", i)
+	}
+
+	e.logger.Infof("Found %d images similar to %s", len(groups), imagePath)
+	return groups, nil
+}
+
 // CleanDuplicates performs duplicate cleaning based on the provided options
 func (e *Engine) CleanDuplicates(options api.CleanOptions) (*api.CleanReport, error) {
+	return e.CleanDuplicatesCtx(context.Background(), options)
+}
+
+// CleanDuplicatesCtx is CleanDuplicates with cancellation. Once ctx is done no
+// further group is touched; groups already cleaned stay in the manifest and the
+// partial report is returned together with ctx.Err().
+func (e *Engine) CleanDuplicatesCtx(ctx context.Context, options api.CleanOptions) (*api.CleanReport, error) {
+	if err := e.begin(); err != nil {
+		return nil, err
+	}
+	defer e.active.Done()
+
 	e.logger.Info("Starting duplicate cleaning process")
 
 	report := &api.CleanReport{}
@@ -562,20 +1711,89 @@ func (e *Engine) CleanDuplicates(options api.CleanOptions) (*api.CleanReport, er
 	}
 
 	// 2) Find near duplicates (cropped, resized, zoomed...)
-	nearGroups, err := e.FindNearDuplicates(options.MaxSimilarityThreshold)
+	nearGroups, err := e.FindNearDuplicatesCtx(ctx, options.MaxSimilarityThreshold, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	report.TotalProcessed = len(exactGroups) + len(nearGroups)
 
-	movedExact, freedExact := e.processExactGroups(exactGroups, options)
+	// Keep the copy the clean options prefer in every group
+	e.reselectMainImages(exactGroups, options.SelectionPolicy)
+	e.reselectMainImages(nearGroups, options.SelectionPolicy)
+
+	run := newCleanRun(options.OutputDir)
+
+	// Plan every action up front so dry runs report exactly what a real run does
+	e.planExactGroups(exactGroups, options, run)
+
+	// plan near-duplicate groups the same way
+	e.planNearGroups(nearGroups, options, run)
+
+	report.NeedsReview = run.review
+
+	for _, step := range run.steps {
+		report.Actions = append(report.Actions, step.action)
+		if options.DryRun {
+			e.logger.Infof("DRY RUN: would %s", describeCleanAction(step.action))
+		}
+	}
+
+	// Each group is cleaned as a unit so a failure never leaves one half done
+	var cancelErr error
+	for start := 0; !options.DryRun && start < len(run.steps); {
+		if cancelErr = ctx.Err(); cancelErr != nil {
+			e.logger.Warnf("Clean cancelled before group %s: %v", run.steps[start].action.GroupID, cancelErr)
+			break
+		}
+
+		end := start + 1
+		for end < len(run.steps) && run.steps[end].action.GroupID == run.steps[start].action.GroupID {
+			end++
+		}
+
+		result := api.CleanGroupResult{GroupID: run.steps[start].action.GroupID, Succeeded: true}
+		done := run.steps[start:end]
+		left, err := e.performCleanGroup(done, run)
+		if err != nil {
+			e.logger.Warnf("Failed to clean group %s: %v", result.GroupID, err)
+			result.Succeeded = false
+			result.RolledBack = len(left) == 0
+			result.Error = err.Error()
+			report.Errors++
+
+			// Files that could not be put back are still gone from their folders
+			done = left
+			for _, step := range left {
+				result.NotRestored = append(result.NotRestored, step.action.Source)
+			}
+		} else {
+			for i := start; i < end; i++ {
+				// A move may land under a different name if the planned destination was taken
+				if run.steps[i].action.Action == api.ActionMove {
+					report.Actions[i].Dest = run.steps[i].fp.Metadata.Path
+				}
+			}
+		}
+		for _, step := range done {
+			report.MovedFiles++
+			report.FreedSpace += step.action.SizeBytes
+		}
+		report.Groups = append(report.Groups, result)
 
-	// process near-duplicate groups the same way
-	movedNear, freedNear := e.processNearGroups(nearGroups, options)
+		start = end
+	}
 
-	report.MovedFiles = movedExact + movedNear
-	report.FreedSpace = freedExact + freedNear
+	// Record what moved where so the operation can be undone
+	if !options.DryRun && len(run.manifest.Entries) > 0 {
+		manifestPath, err := e.writeCleanManifest(run.manifest)
+		if err != nil {
+			e.logger.Warnf("Failed to write clean manifest: %v", err)
+			report.Errors++
+		} else {
+			report.ManifestPath = manifestPath
+		}
+	}
 
 	e.logger.Infof("Clean completed: %d files moved, %s freed in %v",
 		report.MovedFiles,
@@ -583,18 +1801,29 @@ func (e *Engine) CleanDuplicates(options api.CleanOptions) (*api.CleanReport, er
 		time.Since(startTime),
 	)
 
-	return report, nil
+	return report, cancelErr
 }
 
-// processNearGroups handles the movement/deletion of near-duplicate files in a group
-func (e *Engine) processNearGroups(groups []api.DuplicateGroup, options api.CleanOptions) (int, int64) {
-	var moved int
-	var freed int64
-
+// planNearGroups plans the removal of near-duplicate files in each group
+func (e *Engine) planNearGroups(groups []api.DuplicateGroup, options api.CleanOptions, run *cleanRun) {
 	for _, group := range groups {
+		// The keeper already goes out with an exact group; keep the rest in place
+		if run.planned[group.MainImage] {
+			continue
+		}
+
+		mainFP, err := e.index.GetFingerprint(group.MainImage)
+		if err != nil {
+			continue
+		}
+
 		for _, dupID := range group.DuplicateIDs {
+			if run.planned[dupID] {
+				continue
+			}
+
 			fp, err := e.index.GetFingerprint(dupID)
-			if err != nil {
+			if err != nil || isFSPath(fp.Metadata.Path) {
 				continue
 			}
 
@@ -605,63 +1834,55 @@ func (e *Engine) processNearGroups(groups []api.DuplicateGroup, options api.Clea
 				continue
 			}
 
-			src := fp.Metadata.Path
-			dstDir := filepath.Join(options.OutputDir, group.GroupID)
-			_ = os.MkdirAll(dstDir, 0755)
-			dst := filepath.Join(dstDir, filepath.Base(src))
-
-			if options.DryRun {
-				e.logger.Infof("DRY RUN: would move near-duplicate %s -> %s", src, dst)
+			// Neither copy is clearly better, so do not pick one silently
+			if math.Abs(mainFP.Quality.FinalScore-fp.Quality.FinalScore) < options.QualityMargin {
+				e.logger.Debugf("Quality of %s and %s is too close to choose (%.1f vs %.1f)",
+					mainFP.Metadata.Path, fp.Metadata.Path, mainFP.Quality.FinalScore, fp.Quality.FinalScore)
+				run.flagForReview(mainFP.Metadata.Path)
+				run.flagForReview(fp.Metadata.Path)
 				continue
 			}
 
-			if err := os.Rename(src, dst); err != nil {
-				e.logger.Warnf("Failed to move near-duplicate %s: %v", src, err)
-				continue
-			}
-
-			moved++
-			freed += fp.Metadata.SizeBytes
+			run.plan(fp, group.GroupID, options)
 		}
 	}
-
-	return moved, freed
 }
 
+// verifyRealBinaryMatch confirms that every duplicate is byte-identical to the main image.
+// Stored sizes and SHA256s rule out mismatches cheaply; files that agree are then
+// streamed and compared in case they changed on disk since they were indexed.
+// Images indexed by ScanFS cannot be re-read, so their stored SHA256 has to do.
 func (e *Engine) verifyRealBinaryMatch(main api.ImageID, duplicates []api.ImageID) (bool, error) {
 	mainFP, err := e.index.GetFingerprint(main)
 	if err != nil {
 		return false, err
 	}
 
-	mainBytes, err := os.ReadFile(mainFP.Metadata.Path)
-	if err != nil {
-		return false, err
-	}
-
 	for _, id := range duplicates {
 		fp, err := e.index.GetFingerprint(id)
 		if err != nil {
 			return false, err
 		}
 
-		dupBytes, err := os.ReadFile(fp.Metadata.Path)
-		if err != nil {
-			return false, err
+		if fp.Metadata.SizeBytes != mainFP.Metadata.SizeBytes ||
+			fp.Metadata.SHA256 == "" || fp.Metadata.SHA256 != mainFP.Metadata.SHA256 {
+			return false, nil
+		}
+		if isFSPath(mainFP.Metadata.Path) || isFSPath(fp.Metadata.Path) {
+			continue
 		}
 
-		if !bytes.Equal(mainBytes, dupBytes) {
-			return false, nil
+		equal, err := filesystem.FilesEqual(mainFP.Metadata.Path, fp.Metadata.Path)
+		if err != nil || !equal {
+			return false, err
 		}
 	}
 
 	return true, nil
 }
 
-func (e *Engine) processExactGroups(groups []api.DuplicateGroup, options api.CleanOptions) (int, int64) {
-	var moved int
-	var freed int64
-
+// planExactGroups plans the removal of every duplicate in groups whose files are byte-identical
+func (e *Engine) planExactGroups(groups []api.DuplicateGroup, options api.CleanOptions, run *cleanRun) {
 	for _, group := range groups {
 
 		ok, err := e.verifyRealBinaryMatch(group.MainImage, group.DuplicateIDs)
@@ -671,31 +1892,266 @@ func (e *Engine) processExactGroups(groups []api.DuplicateGroup, options api.Cle
 
 		for _, dupID := range group.DuplicateIDs {
 			fp, err := e.index.GetFingerprint(dupID)
-			if err != nil {
+			if err != nil || isFSPath(fp.Metadata.Path) {
 				continue
 			}
 
-			src := fp.Metadata.Path
-			dstDir := filepath.Join(options.OutputDir, group.GroupID)
-			_ = os.MkdirAll(dstDir, 0755)
-			dst := filepath.Join(dstDir, filepath.Base(src))
+			run.plan(fp, group.GroupID, options)
+		}
+	}
+}
 
-			if options.DryRun {
-				e.logger.Infof("DRY RUN: would move %s -> %s", src, dst)
-				continue
+// cleanRun tracks the actions planned and the moves made during a single CleanDuplicates call
+type cleanRun struct {
+	manifest api.CleanManifest
+	steps    []cleanStep
+	planned  map[api.ImageID]bool
+	review   []string
+	reviewed map[string]bool
+}
+
+// cleanStep pairs a planned action with the fingerprint it applies to
+type cleanStep struct {
+	action  api.PlannedAction
+	fp      *api.ImageFingerprint
+	trashed *filesystem.TrashedFile // where a trash action put the file
+}
+
+// newCleanRun starts an empty clean run for the given output directory
+func newCleanRun(outputDir string) *cleanRun {
+	return &cleanRun{
+		manifest: api.CleanManifest{CreatedAt: time.Now(), OutputDir: outputDir},
+		planned:  make(map[api.ImageID]bool),
+		reviewed: make(map[string]bool),
+	}
+}
+
+// flagForReview records a file that must be left for the user to decide on
+func (r *cleanRun) flagForReview(path string) {
+	if !r.reviewed[path] {
+		r.reviewed[path] = true
+		r.review = append(r.review, path)
+	}
+}
+
+// plan records what cleaning will do with a duplicate: it is sent to the trash when
+// TrashDuplicates is set, moved into the group's folder under OutputDir when
+// MoveDuplicates is set, and deleted otherwise
+func (r *cleanRun) plan(fp *api.ImageFingerprint, groupID string, options api.CleanOptions) {
+	action := api.PlannedAction{
+		Source:    fp.Metadata.Path,
+		Action:    api.ActionDelete,
+		GroupID:   groupID,
+		SizeBytes: fp.Metadata.SizeBytes,
+	}
+	switch {
+	case options.TrashDuplicates:
+		action.Action = api.ActionTrash
+	case options.MoveDuplicates:
+		action.Action = api.ActionMove
+		action.Dest = filepath.Join(options.OutputDir, groupID, filepath.Base(fp.Metadata.Path))
+	}
+
+	r.planned[fp.ID] = true
+	r.steps = append(r.steps, cleanStep{action: action, fp: fp})
+}
+
+// performCleanAction carries out a planned action
+func (e *Engine) performCleanAction(step *cleanStep, run *cleanRun) error {
+	switch step.action.Action {
+	case api.ActionTrash:
+		trashed, err := e.organizer.TrashFile(step.fp.Metadata.Path)
+		if err != nil {
+			return err
+		}
+		step.trashed = trashed
+		e.forgetDeletedDuplicate(step.fp)
+		return nil
+	case api.ActionDelete:
+		return e.deleteDuplicate(step.fp, false)
+	default:
+		return e.moveCleanedFile(step.fp, step.action.Dest, step.action.GroupID, run)
+	}
+}
+
+// performCleanGroup carries out the planned actions of one group. Every file
+// is checked first, so a group whose files cannot all be removed is left
+// untouched. If an action still fails, the files already moved or trashed
+// for the group are put back where they were and their index entries
+// restored. Deleted files cannot be brought back; they and any file that
+// could not be restored are returned as left, and stay in the manifest.
+func (e *Engine) performCleanGroup(steps []cleanStep, run *cleanRun) (left []cleanStep, err error) {
+	for _, step := range steps {
+		if err := filesystem.CheckRemovable(step.action.Source); err != nil {
+			return nil, fmt.Errorf("cannot %s duplicate %s: %w", step.action.Action, step.action.Source, err)
+		}
+	}
+
+	recorded := len(run.manifest.Entries)
+	for i := range steps {
+		err := e.performCleanAction(&steps[i], run)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("failed to %s duplicate %s: %w", steps[i].action.Action, steps[i].action.Source, err)
+
+		left = e.rollbackCleanSteps(steps[:i])
+		kept := make(map[api.ImageID]bool, len(left))
+		for _, step := range left {
+			kept[step.fp.ID] = true
+		}
+		entries := run.manifest.Entries[:recorded]
+		for _, entry := range run.manifest.Entries[recorded:] {
+			if kept[entry.ImageID] {
+				entries = append(entries, entry)
 			}
+		}
+		run.manifest.Entries = entries
 
-			if err := os.Rename(src, dst); err != nil {
-				e.logger.Warnf("Failed to move %s: %v", src, err)
+		if len(left) > 0 {
+			return left, fmt.Errorf("%w; rollback incomplete", err)
+		}
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// rollbackCleanSteps undoes completed steps, most recent first: moved files
+// are moved back and trashed files restored, and the index points at them
+// again. It returns the steps that could not be undone.
+func (e *Engine) rollbackCleanSteps(done []cleanStep) []cleanStep {
+	var left []cleanStep
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		switch step.action.Action {
+		case api.ActionMove:
+			restored, err := e.organizer.MoveFileTo(step.fp.Metadata.Path, step.action.Source)
+			if err != nil {
+				e.logger.Warnf("Failed to roll back move of %s: %v", step.action.Source, err)
+				left = append(left, step)
+				continue
+			}
+			step.fp.Metadata.Path = restored
+			if err := e.index.UpdateMetadata(step.fp.ID, step.fp.Metadata); err != nil {
+				e.logger.Warnf("Failed to update fingerprint after rollback: %v", err)
+			}
+		case api.ActionTrash:
+			if err := e.organizer.RestoreFromTrash(step.trashed); err != nil {
+				e.logger.Warnf("Failed to roll back trashing of %s: %v", step.action.Source, err)
+				left = append(left, step)
 				continue
 			}
+			if err := e.index.SaveFingerprint(*step.fp); err != nil {
+				e.logger.Warnf("Failed to restore fingerprint after rollback: %v", err)
+			}
+		default:
+			left = append(left, step)
+			continue
+		}
+		e.logger.Debugf("Rolled back %s of %s", step.action.Action, step.action.Source)
+	}
+	return left
+}
+
+// describeCleanAction describes a planned action, for dry runs
+func describeCleanAction(action api.PlannedAction) string {
+	if action.Dest != "" {
+		return fmt.Sprintf("%s %s -> %s", action.Action, action.Source, action.Dest)
+	}
+	return fmt.Sprintf("%s %s", action.Action, action.Source)
+}
+
+// moveCleanedFile moves a duplicate to dst without overwriting anything already there,
+// updates its indexed path and records the move in the run's manifest
+func (e *Engine) moveCleanedFile(fp *api.ImageFingerprint, dst, groupID string, run *cleanRun) error {
+	src := fp.Metadata.Path
+
+	finalPath, err := e.organizer.MoveFileTo(src, dst)
+	if err != nil {
+		return err
+	}
+
+	run.manifest.Entries = append(run.manifest.Entries, api.CleanManifestEntry{
+		ImageID:      fp.ID,
+		GroupID:      groupID,
+		OriginalPath: src,
+		NewPath:      finalPath,
+		MovedAt:      time.Now(),
+	})
+
+	fp.Metadata.Path = finalPath
+	if err := e.index.UpdateMetadata(fp.ID, fp.Metadata); err != nil {
+		e.logger.Warnf("Failed to update fingerprint after move: %v", err)
+	}
+
+	return nil
+}
+
+// writeCleanManifest saves the manifest as JSON inside the output directory
+func (e *Engine) writeCleanManifest(manifest api.CleanManifest) (string, error) {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal clean manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(manifest.OutputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	name := fmt.Sprintf("clean_manifest_%s.json", manifest.CreatedAt.Format("20060102_150405"))
+	manifestPath := filepath.Join(manifest.OutputDir, name)
+	if err := os.WriteFile(manifestPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write clean manifest: %w", err)
+	}
+
+	e.logger.Infof("Clean manifest saved to: %s", manifestPath)
+	return manifestPath, nil
+}
+
+// UndoClean reverses every move recorded in a clean manifest, restoring files to
+// their original locations and pointing the index back at them. If an original
+// path has since been re-occupied, the file is restored next to it under a free name.
+func (e *Engine) UndoClean(manifestPath string) (*api.CleanReport, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clean manifest: %w", err)
+	}
+
+	var manifest api.CleanManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse clean manifest: %w", err)
+	}
+
+	report := &api.CleanReport{TotalProcessed: len(manifest.Entries)}
+
+	// Undo in reverse order so chained moves unwind correctly
+	for i := len(manifest.Entries) - 1; i >= 0; i-- {
+		entry := manifest.Entries[i]
+
+		restoredPath, err := e.organizer.MoveFileTo(entry.NewPath, entry.OriginalPath)
+		if err != nil {
+			e.logger.Warnf("Failed to restore %s: %v", entry.OriginalPath, err)
+			report.Errors++
+			continue
+		}
+
+		if restoredPath != entry.OriginalPath {
+			e.logger.Warnf("Original path %s is occupied, restored to %s", entry.OriginalPath, restoredPath)
+		}
 
-			moved++
-			freed += fp.Metadata.SizeBytes
+		if fp, err := e.index.GetFingerprint(entry.ImageID); err == nil {
+			fp.Metadata.Path = restoredPath
+			if err := e.index.UpdateMetadata(fp.ID, fp.Metadata); err != nil {
+				e.logger.Warnf("Failed to update fingerprint after restore: %v", err)
+			}
 		}
+
+		report.MovedFiles++
 	}
 
-	return moved, freed
+	e.logger.Infof("Undo completed: %d of %d files restored", report.MovedFiles, report.TotalProcessed)
+	return report, nil
 }
 
 // processDuplicateGroup handles the movement/deletion of duplicate files in a group
@@ -721,7 +2177,7 @@ func (e *Engine) ProcessDuplicateGroup(group api.DuplicateGroup, options api.Cle
 				return moved, fmt.Errorf("failed to move duplicate %s: %w", duplicateID, err)
 			}
 		} else {
-			err := e.deleteDuplicate(fingerprint)
+			err := e.deleteDuplicate(fingerprint, options.TrashDuplicates)
 			if err != nil {
 				return moved, fmt.Errorf("failed to delete duplicate %s: %w", duplicateID, err)
 			}
@@ -737,21 +2193,16 @@ func (e *Engine) ProcessDuplicateGroup(group api.DuplicateGroup, options api.Cle
 func (e *Engine) MoveDuplicate(fp *api.ImageFingerprint, outputDir string, groupID string) error {
 	sourcePath := fp.Metadata.Path
 	filename := filepath.Base(sourcePath)
-	destPath := filepath.Join(outputDir, groupID, filename)
 
-	// Create destination directory
-	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Move the file
-	if err := os.Rename(sourcePath, destPath); err != nil {
-		return fmt.Errorf("failed to move file: %w", err)
+	// Move the file, picking a free name and copying across filesystems if needed
+	destPath, err := e.organizer.MoveFileTo(sourcePath, filepath.Join(outputDir, groupID, filename))
+	if err != nil {
+		return err
 	}
 
 	// Update the fingerprint path
 	fp.Metadata.Path = destPath
-	if err := e.index.SaveFingerprint(*fp); err != nil {
+	if err := e.index.UpdateMetadata(fp.ID, fp.Metadata); err != nil {
 		e.logger.Warnf("Failed to update fingerprint after move: %v", err)
 	}
 
@@ -759,19 +2210,26 @@ func (e *Engine) MoveDuplicate(fp *api.ImageFingerprint, outputDir string, group
 	return nil
 }
 
-// deleteDuplicate permanently deletes a duplicate file
-func (e *Engine) deleteDuplicate(fp *api.ImageFingerprint) error {
-	if err := os.Remove(fp.Metadata.Path); err != nil {
+// deleteDuplicate deletes a duplicate file, sending it to the OS trash when trash is set
+func (e *Engine) deleteDuplicate(fp *api.ImageFingerprint, trash bool) error {
+	if trash {
+		if _, err := e.organizer.TrashFile(fp.Metadata.Path); err != nil {
+			return err
+		}
+	} else if err := os.Remove(fp.Metadata.Path); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
-	// Remove from index
+	e.forgetDeletedDuplicate(fp)
+	return nil
+}
+
+// forgetDeletedDuplicate removes a deleted or trashed duplicate from the index
+func (e *Engine) forgetDeletedDuplicate(fp *api.ImageFingerprint) {
 	if err := e.index.DeleteFingerprint(fp.ID); err != nil {
 		e.logger.Warnf("Failed to remove fingerprint after deletion: %v", err)
 	}
-
 	e.logger.Debugf("Deleted duplicate: %s", fp.Metadata.Path)
-	return nil
 }
 
 // selectBestImage chooses the best image from a set based on selection policy
@@ -804,20 +2262,61 @@ func (e *Engine) selectBestImage(images []api.ImageID, fingerprints []api.ImageF
 	return bestImage
 }
 
+// reselectMainImages picks the main image of each group again with policy.
+// Ties keep the current main image; groups with a member that cannot be
+// loaded are left as they are.
+func (e *Engine) reselectMainImages(groups []api.DuplicateGroup, policy api.SelectionPolicy) {
+next:
+	for i, group := range groups {
+		ids := append([]api.ImageID{group.MainImage}, group.DuplicateIDs...)
+		members := make([]api.ImageFingerprint, 0, len(ids))
+		for _, id := range ids {
+			fp, err := e.index.GetFingerprint(id)
+			if err != nil {
+				continue next
+			}
+			members = append(members, *fp)
+		}
+
+		// Only a file on disk can be kept in place of the others, so images
+		// indexed by ScanFS are passed over while the group has one
+		candidates := make([]api.ImageID, 0, len(members))
+		for _, fp := range members {
+			if !isFSPath(fp.Metadata.Path) {
+				candidates = append(candidates, fp.ID)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = ids
+		}
+
+		mainImage := e.selectBestImage(candidates, members, policy)
+		groups[i].MainImage = mainImage
+		groups[i].DuplicateIDs = e.removeElement(ids, mainImage)
+	}
+}
+
 // calculateImageScore computes a score for an image based on selection policy
 func (e *Engine) calculateImageScore(fp api.ImageFingerprint, policy api.SelectionPolicy) float64 {
 	switch policy {
 	case api.PolicyHighestQuality:
 		return fp.Quality.FinalScore
 	case api.PolicyHighestResolution:
-		return float64(fp.Metadata.Width * fp.Metadata.Height)
+		width, height := fp.Metadata.DisplaySize()
+		return float64(width * height)
 	case api.PolicyBestExposure:
 		// Score based on how close exposure is to ideal (0.5)
-		return 1.0 - math.Abs(fp.Quality.Exposure-0.5)*2
+		return quality.ExposureScore(fp.Quality.Exposure)
 	case api.PolicyOldest:
 		return -float64(fp.Metadata.ModifiedAt.Unix()) // Negative for oldest first
 	case api.PolicyNewest:
 		return float64(fp.Metadata.ModifiedAt.Unix())
+	case api.PolicyCompositeQuality:
+		return e.quality.CompositeScore(fp.Quality)
+	case api.PolicyLargestFileSize:
+		return float64(fp.Metadata.SizeBytes)
+	case api.PolicySmallestFileSize:
+		return -float64(fp.Metadata.SizeBytes)
 	default:
 		return fp.Quality.FinalScore
 	}
@@ -867,6 +2366,20 @@ func (e *Engine) calculateFreedSpace(movedFiles int) int64 {
 	return int64(movedFiles) * 5 * 1024 * 1024 // Estimate 5MB per file
 }
 
+// contentGroupID names a group after its members: prefix followed by the
+// start of the SHA256 of the sorted member IDs, so the same set of images
+// gets the same ID on every run
+func contentGroupID(prefix string, members []api.ImageID) string {
+	ids := make([]string, len(members))
+	for i, id := range members {
+		ids[i] = string(id)
+	}
+	sort.Strings(ids)
+
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\n")))
+	return prefix + "_" + hex.EncodeToString(sum[:])[:16]
+}
+
 // removeElement removes a specific element from a slice
 func (e *Engine) removeElement(slice []api.ImageID, element api.ImageID) []api.ImageID {
 	result := make([]api.ImageID, 0, len(slice)-1)
@@ -878,15 +2391,62 @@ func (e *Engine) removeElement(slice []api.ImageID, element api.ImageID) []api.I
 	return result
 }
 
+// SetReverseGeocoder sets how cluster names describe locations; by default
+// they show rounded coordinates
+func (e *Engine) SetReverseGeocoder(g imgmeta.ReverseGeocoder) {
+	e.namer = imgmeta.NewClusterNamer(g)
+}
+
+// SetFileMover replaces the low-level mover used when relocating files
+func (e *Engine) SetFileMover(m filesystem.Mover) {
+	e.organizer.SetMover(m)
+}
+
+// GetFingerprint returns the stored fingerprint for an image ID
+func (e *Engine) GetFingerprint(id api.ImageID) (*api.ImageFingerprint, error) {
+	return e.index.GetFingerprint(id)
+}
+
+// GetAllFingerprints returns every fingerprint in the index
+func (e *Engine) GetAllFingerprints() ([]api.ImageFingerprint, error) {
+	return e.index.GetAllFingerprints()
+}
+
 // GetStats returns statistics about the image index
 func (e *Engine) GetStats() (*index.Stats, error) {
 	return e.index.GetStats()
 }
 
-// Close safely closes the engine and releases all resources
+// begin registers a running operation, which must call e.active.Done when it
+// ends, so Close waits for it. It fails with api.ErrEngineClosed once Close
+// has begun.
+func (e *Engine) begin() error {
+	e.closeMu.Lock()
+	defer e.closeMu.Unlock()
+
+	if e.closed {
+		return api.ErrEngineClosed
+	}
+	e.active.Add(1)
+	return nil
+}
+
+// Close safely closes the engine and releases all resources.
+// It waits for running scans, watches, duplicate searches and cleans first;
+// operations started afterwards fail with api.ErrEngineClosed.
 func (e *Engine) Close() error {
+	e.closeMu.Lock()
+	if e.closed {
+		e.closeMu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.closeMu.Unlock()
+
 	e.logger.Info("Closing image processing engine")
 
+	e.active.Wait()
+
 	if e.index != nil {
 		return e.index.Close()
 	}
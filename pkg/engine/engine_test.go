@@ -1,13 +1,38 @@
 package engine_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"math"
+	"math/bits"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/HaiderBassem/imaged/internal/index"
+	"github.com/HaiderBassem/imaged/internal/similarity"
+	"github.com/HaiderBassem/imaged/pkg/api"
 	"github.com/HaiderBassem/imaged/pkg/engine"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+	"github.com/disintegration/imaging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,24 +50,597 @@ func TestEngine_ScanFolder(t *testing.T) {
 	defer eng.Close()
 
 	ctx := context.Background()
-	err = eng.ScanFolder(ctx, tempDir, nil)
+	report, err := eng.ScanFolder(ctx, tempDir, nil)
 	assert.NoError(t, err)
+	require.NotNil(t, report)
+	assert.Equal(t, 3, report.TotalFiles)
+	assert.Equal(t, 3, report.ProcessedImages)
+	assert.Zero(t, report.SkippedFiles)
+	assert.False(t, report.CompletedAt.Before(report.StartedAt))
+	assert.Equal(t, report.CompletedAt.Sub(report.StartedAt), report.ScanDuration)
 
 	stats, err := eng.GetStats()
 	assert.NoError(t, err)
 	assert.Greater(t, stats.TotalImages, int64(0))
 }
 
+func TestEngine_ScanFolderReportsSkippedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	// Files with image extensions that cannot be decoded
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "truncated.jpg"), []byte{0xFF, 0xD8, 0xFF}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "notes.png"), []byte("not an image"), 0644))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	report, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.TotalFiles)
+	assert.Equal(t, 3, report.ProcessedImages)
+	assert.Equal(t, 2, report.SkippedFiles)
+	assert.Positive(t, report.ScanDuration)
+
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalImages)
+}
+
+func TestEngine_ScanFolderReportsInaccessibleFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+	broken := filepath.Join(tempDir, "broken.jpg")
+	require.NoError(t, os.Symlink(filepath.Join(tempDir, "missing.jpg"), broken))
+	corrupt := filepath.Join(tempDir, "corrupt.png")
+	require.NoError(t, os.WriteFile(corrupt, []byte("not a png"), 0644))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	report, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.TotalFiles)
+	assert.Equal(t, 3, report.ProcessedImages)
+	assert.Equal(t, 2, report.SkippedFiles)
+
+	reasons := make(map[string]string)
+	for _, scanErr := range report.Errors {
+		reasons[scanErr.Path] = scanErr.Reason
+	}
+	assert.Len(t, reasons, 2)
+	assert.Contains(t, reasons[broken], "no such file")
+	assert.Contains(t, reasons[corrupt], "decode")
+}
+
+func TestEngine_ScanFolderSkipsUnchangedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	first, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, first.ProcessedImages)
+	assert.Equal(t, 0, first.UnchangedFiles)
+
+	second, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, second.TotalFiles)
+	assert.Equal(t, 0, second.ProcessedImages)
+	assert.Equal(t, 3, second.UnchangedFiles)
+
+	// A touched file is reprocessed and replaces its old entry
+	touched := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(tempDir, "test0.jpg"), touched, touched))
+	third, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, third.ProcessedImages)
+	assert.Equal(t, 2, third.UnchangedFiles)
+
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalImages)
+}
+
+func TestEngine_ScanFolderForceRescan(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.ForceRescan = true
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	report, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.ProcessedImages)
+	assert.Equal(t, 0, report.UnchangedFiles)
+}
+
+func TestEngine_ScanFolderFollowsRenamedFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+
+	oldPath := filepath.Join(tempDir, "test0.jpg")
+	newPath := filepath.Join(tempDir, "renamed.jpg")
+	var originalID api.ImageID
+	fps, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	for _, fp := range fps {
+		if fp.Metadata.Path == oldPath {
+			originalID = fp.ID
+		}
+	}
+	require.NotEmpty(t, originalID)
+	require.NoError(t, os.Rename(oldPath, newPath))
+
+	report, err := eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.RenamedFiles)
+	assert.Equal(t, 2, report.UnchangedFiles)
+
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalImages)
+
+	fp, err := eng.GetFingerprint(originalID)
+	require.NoError(t, err)
+	assert.Equal(t, newPath, fp.Metadata.Path)
+}
+
+func TestEngine_PruneMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "test1.png")))
+
+	pruned, err := eng.PruneMissing()
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+
+	fps, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	require.Len(t, fps, 2)
+	for _, fp := range fps {
+		assert.FileExists(t, fp.Metadata.Path)
+	}
+
+	pruned, err = eng.PruneMissing()
+	require.NoError(t, err)
+	assert.Zero(t, pruned)
+}
+
+func TestEngine_VerifyIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	paths := make(map[string]string)
+	for _, name := range []string{"ok.png", "missing.png", "edited.png", "touched.png"} {
+		paths[name] = createTestImage(t, tempDir, name)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+
+	fps, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	var editedID api.ImageID
+	for _, fp := range fps {
+		if fp.Metadata.Path == paths["edited.png"] {
+			editedID = fp.ID
+		}
+	}
+
+	// One file is deleted, one rewritten with new pixels, one only touched
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Remove(paths["missing.png"]))
+	writeTestImage(t, paths["edited.png"], 99)
+	require.NoError(t, os.Chtimes(paths["edited.png"], later, later))
+	require.NoError(t, os.Chtimes(paths["touched.png"], later, later))
+
+	report, err := eng.VerifyIndex(api.VerifyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	assert.Equal(t, 1, report.OK)
+	assert.Equal(t, []string{paths["missing.png"]}, report.MissingPaths)
+	assert.ElementsMatch(t, []string{paths["edited.png"], paths["touched.png"]}, report.ChangedPaths)
+
+	// Re-hashing clears the file whose content did not change
+	report, err = eng.VerifyIndex(api.VerifyOptions{Rehash: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.OK)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, []string{paths["edited.png"]}, report.ChangedPaths)
+	assert.Zero(t, report.Pruned)
+
+	report, err = eng.VerifyIndex(api.VerifyOptions{Rehash: true, Fix: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Pruned)
+	assert.Equal(t, 1, report.Updated)
+	assert.Empty(t, report.Errors)
+
+	fp, err := eng.GetFingerprint(editedID)
+	require.NoError(t, err)
+	data, err := os.ReadFile(paths["edited.png"])
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(data)), fp.Metadata.SHA256)
+
+	report, err = eng.VerifyIndex(api.VerifyOptions{Rehash: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.Equal(t, 3, report.OK)
+}
+
+func TestEngine_CatalogExportImport(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	srcCfg := engine.DefaultConfig()
+	srcCfg.IndexType = index.StoreTypeSQLite
+	srcCfg.IndexPath = filepath.Join(t.TempDir(), "source.sqlite")
+	src, err := engine.NewEngine(srcCfg)
+	require.NoError(t, err)
+	defer src.Close()
+
+	_, err = src.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+
+	var catalog bytes.Buffer
+	require.NoError(t, src.ExportCatalog(&catalog))
+	assert.Equal(t, 3, strings.Count(catalog.String(), "\n"))
+
+	dstCfg := engine.DefaultConfig()
+	dstCfg.IndexPath = filepath.Join(t.TempDir(), "target.db")
+	dst, err := engine.NewEngine(dstCfg)
+	require.NoError(t, err)
+	defer dst.Close()
+
+	require.NoError(t, dst.ImportCatalog(&catalog))
+
+	want, err := src.GetAllFingerprints()
+	require.NoError(t, err)
+	got, err := dst.GetAllFingerprints()
+	require.NoError(t, err)
+	require.Len(t, got, len(want))
+
+	sort.Slice(want, func(i, j int) bool { return want[i].ID < want[j].ID })
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	for i := range want {
+		wantJSON, err := json.Marshal(want[i])
+		require.NoError(t, err)
+		gotJSON, err := json.Marshal(got[i])
+		require.NoError(t, err)
+		assert.JSONEq(t, string(wantJSON), string(gotJSON))
+	}
+
+	// The path index is rebuilt too, so rescanning finds nothing new
+	report, err := dst.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.UnchangedFiles)
+
+	assert.Error(t, dst.ImportCatalog(strings.NewReader("{not json}\n")))
+}
+
+func TestEngine_ColorHistRoundTripsThroughIndex(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	for name, storeType := range map[string]index.StoreType{"bolt": index.StoreTypeBoltDB, "sqlite": index.StoreTypeSQLite} {
+		t.Run(name, func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexType = storeType
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.HashConfig.ComputeColorHist = true
+			cfg.HashConfig.ColorHistBins = 8
+
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+			require.NoError(t, err)
+			scanned, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			require.NoError(t, eng.Close())
+
+			// Reopen so the histograms are read back from disk
+			eng, err = engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+
+			reloaded := make(map[api.ImageID][]float64)
+			fps, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			for _, fp := range fps {
+				reloaded[fp.ID] = fp.ColorHist
+			}
+
+			require.Len(t, scanned, 3)
+			for _, fp := range scanned {
+				require.Len(t, fp.ColorHist, 24)
+				var red float64
+				for _, v := range fp.ColorHist[:8] {
+					red += v
+				}
+				assert.InDelta(t, 1.0, red, 1e-9)
+				assert.Equal(t, fp.ColorHist, reloaded[fp.ID])
+			}
+		})
+	}
+}
+
+func TestEngine_HashSizeWidensHashes(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	// Bytes per wide hash; sizes are rounded up to a multiple of 8
+	for size, wantBytes := range map[int]int{8: 0, 12: 32, 16: 32, 32: 128} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.HashConfig.HashSize = size
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+			_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+			require.NoError(t, err)
+
+			fps, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			require.Len(t, fps, 3)
+			for _, fp := range fps {
+				assert.NotZero(t, fp.PHashes.AHash)
+				assert.Len(t, fp.PHashes.AHashWide, wantBytes)
+				assert.Len(t, fp.PHashes.PHashWide, wantBytes)
+				assert.Len(t, fp.PHashes.DHashWide, wantBytes)
+			}
+		})
+	}
+}
+
+func TestEngine_PaletteSizeStoresPalette(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+
+	for _, size := range []int{0, 3} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.HashConfig.PaletteSize = size
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+			_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+			require.NoError(t, err)
+
+			fps, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			require.Len(t, fps, 3)
+			for _, fp := range fps {
+				if size == 0 {
+					assert.Empty(t, fp.Palette)
+					continue
+				}
+				require.NotEmpty(t, fp.Palette)
+				assert.LessOrEqual(t, len(fp.Palette), size)
+				var coverage float64
+				for i, c := range fp.Palette {
+					coverage += c.Coverage
+					if i > 0 {
+						assert.LessOrEqual(t, c.Coverage, fp.Palette[i-1].Coverage)
+					}
+				}
+				assert.InDelta(t, 1.0, coverage, 1e-9)
+			}
+		})
+	}
+}
+
+func TestEngine_FindByPaletteRanksWarmImagesFirst(t *testing.T) {
+	dir := t.TempDir()
+	palettes := map[string][2]color.RGBA{
+		"warm_sunset.png": {{R: 230, G: 90, B: 30, A: 255}, {R: 250, G: 200, B: 60, A: 255}},
+		"warm_brick.png":  {{R: 180, G: 40, B: 30, A: 255}, {R: 240, G: 120, B: 50, A: 255}},
+		"cool_sea.png":    {{R: 20, G: 60, B: 180, A: 255}, {R: 40, G: 170, B: 200, A: 255}},
+		"cool_forest.png": {{R: 20, G: 120, B: 50, A: 255}, {R: 70, G: 90, B: 160, A: 255}},
+	}
+	for name, colors := range palettes {
+		// Two bands of color, shuffled slightly so no two files are identical
+		rng := rand.New(rand.NewSource(int64(len(name))))
+		img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				c := colors[y*2/64]
+				c.R += uint8(rng.Intn(5))
+				img.Set(x, y, c)
+			}
+		}
+		file, err := os.Create(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NoError(t, png.Encode(file, img))
+		require.NoError(t, file.Close())
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.HashConfig.PaletteSize = 4
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), dir, nil)
+	require.NoError(t, err)
+
+	warm := []color.Color{color.RGBA{R: 220, G: 80, B: 30, A: 255}, color.RGBA{R: 245, G: 170, B: 60, A: 255}}
+	results, err := eng.FindByPalette(warm, math.MaxFloat64)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for i, fp := range results {
+		name := filepath.Base(fp.Metadata.Path)
+		if i < 2 {
+			assert.True(t, strings.HasPrefix(name, "warm_"), "rank %d is %s", i, name)
+		} else {
+			assert.True(t, strings.HasPrefix(name, "cool_"), "rank %d is %s", i, name)
+		}
+	}
+
+	// A tight tolerance leaves the cool images out
+	results, err = eng.FindByPalette(warm, 30)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	_, err = eng.FindByPalette(nil, 30)
+	assert.Error(t, err)
+}
+
+func TestEngine_DecodeCacheReusesUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		paths = append(paths, createTestImage(t, dir, fmt.Sprintf("photo_%02d.png", i)))
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.MaxMemoryMB = 1 // a 256KB cache, room for 16 of the 64x64 images
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, first, err := eng.LoadImage(paths[0])
+	require.NoError(t, err)
+	_, second, err := eng.LoadImage(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), eng.DecodeCount())
+	assert.Equal(t, first, second)
+
+	// A modified file is decoded again
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(paths[0], later, later))
+	_, _, err = eng.LoadImage(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), eng.DecodeCount())
+
+	// Loading every other image evicts the least recently used one
+	for _, path := range paths[1:] {
+		_, _, err = eng.LoadImage(path)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(21), eng.DecodeCount())
+	_, _, err = eng.LoadImage(paths[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(22), eng.DecodeCount())
+	_, _, err = eng.LoadImage(paths[19])
+	require.NoError(t, err)
+	assert.Equal(t, int64(22), eng.DecodeCount())
+}
+
+func TestEngine_MaxMemoryMBBoundsConcurrentDecodes(t *testing.T) {
+	// Eight 512x512 images of about 1MB each once decoded
+	photosDir := t.TempDir()
+	for i := 0; i < 8; i++ {
+		img := image.NewRGBA(image.Rect(0, 0, 512, 512))
+		for y := 0; y < 512; y++ {
+			for x := 0; x < 512; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(x + i*30), G: uint8(y), B: uint8(x ^ y), A: 255})
+			}
+		}
+		writeImageFile(t, filepath.Join(photosDir, fmt.Sprintf("photo_%d.png", i)), img, png.Encode)
+	}
+
+	for _, tc := range []struct {
+		maxMemoryMB int
+		concurrent  bool
+	}{{1, false}, {1024, true}} {
+		t.Run(fmt.Sprintf("max=%dMB", tc.maxMemoryMB), func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.NumWorkers = 4
+			cfg.MaxMemoryMB = tc.maxMemoryMB
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+
+			report, err := eng.ScanFolder(context.Background(), photosDir, nil)
+			require.NoError(t, err)
+			assert.Equal(t, 8, report.ProcessedImages)
+			if tc.concurrent {
+				assert.Greater(t, eng.PeakConcurrentDecodes(), 1)
+			} else {
+				assert.Equal(t, 1, eng.PeakConcurrentDecodes())
+			}
+		})
+	}
+}
+
+func TestEngine_DecodeReservationOutlivesCaller(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.png")
+	writeImageFile(t, path, image.NewRGBA(image.Rect(0, 0, 256, 256)), png.Encode)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	release, hold := eng.ReserveDecode(path)
+	assert.Equal(t, int64(256*256*4), eng.ReservedDecodeBytes())
+
+	// A decode abandoned after DecodeTimeout still holds the memory when the caller gives up
+	decodeDone := hold()
+	release()
+	assert.Equal(t, int64(256*256*4), eng.ReservedDecodeBytes())
+
+	decodeDone()
+	assert.Zero(t, eng.ReservedDecodeBytes())
+}
+
 func TestEngine_FindExactDuplicates(t *testing.T) {
 	tempDir := t.TempDir()
 
 	// Create identical files
-	data := []byte("fake image data")
-	file1 := filepath.Join(tempDir, "image1.jpg")
-	file2 := filepath.Join(tempDir, "image2.jpg")
-
-	require.NoError(t, os.WriteFile(file1, data, 0644))
-	require.NoError(t, os.WriteFile(file2, data, 0644))
+	file1 := createTestImage(t, tempDir, "image1.jpg")
+	data, err := os.ReadFile(file1)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "image2.jpg"), data, 0644))
 
 	cfg := engine.DefaultConfig()
 	cfg.IndexPath = filepath.Join(tempDir, "test.db")
@@ -52,7 +650,8 @@ func TestEngine_FindExactDuplicates(t *testing.T) {
 	defer eng.Close()
 
 	ctx := context.Background()
-	require.NoError(t, eng.ScanFolder(ctx, tempDir, nil))
+	_, err = eng.ScanFolder(ctx, tempDir, nil)
+	require.NoError(t, err)
 
 	duplicates, err := eng.FindExactDuplicates()
 	assert.NoError(t, err)
@@ -60,11 +659,100 @@ func TestEngine_FindExactDuplicates(t *testing.T) {
 	assert.Len(t, duplicates[0].DuplicateIDs, 1)
 }
 
+func TestEngine_GroupIDsStableAcrossRuns(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.png"} {
+		data, err := os.ReadFile(createTestImage(t, tempDir, name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "copy_"+name), data, 0644))
+	}
+	writeBlockImage(t, filepath.Join(tempDir, "shot.png"), 7, 0)
+	writeBlockImage(t, filepath.Join(tempDir, "shot_edit.png"), 7, 2)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+
+	groupIDs := func() []string {
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+		defer eng.Close()
+		_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+		require.NoError(t, err)
+
+		exact, err := eng.FindExactDuplicates()
+		require.NoError(t, err)
+		near, err := eng.FindNearDuplicates(0.9)
+		require.NoError(t, err)
+
+		var ids []string
+		for _, group := range append(exact, near...) {
+			ids = append(ids, group.GroupID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// The second run reopens the index and finds every file unchanged
+	first := groupIDs()
+	second := groupIDs()
+	assert.Equal(t, first, second)
+
+	var exactIDs int
+	for _, id := range first {
+		if strings.HasPrefix(id, "exact_") {
+			exactIDs++
+		}
+	}
+	assert.Equal(t, 2, exactIDs)
+	assert.Contains(t, strings.Join(first, " "), "near_")
+	for i := 1; i < len(first); i++ {
+		assert.NotEqual(t, first[i-1], first[i])
+	}
+}
+
+func TestEngine_SimilarityMatrixIsSymmetric(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestImages(t, tempDir)
+	writeBlockImage(t, filepath.Join(tempDir, "shot.png"), 7, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+
+	matrix, err := eng.SimilarityMatrix(nil)
+	require.NoError(t, err)
+	require.Len(t, matrix, 4)
+	for i, row := range matrix {
+		require.Len(t, row, 4)
+		assert.Equal(t, 1.0, row[i])
+		for j, score := range row {
+			assert.Equal(t, score, matrix[j][i])
+			assert.GreaterOrEqual(t, score, 0.0)
+			assert.LessOrEqual(t, score, 1.0)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, eng.WriteSimilarityMatrixCSV(&buf, nil))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], "image_id,"))
+	assert.Len(t, strings.Split(lines[1], ","), 5)
+
+	_, err = eng.SimilarityMatrix([]api.ImageID{"img_missing"})
+	assert.Error(t, err)
+}
+
 func TestEngine_ImageQuality(t *testing.T) {
 	tempDir := t.TempDir()
 	testImage := createTestImage(t, tempDir, "test.jpg")
 
 	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(tempDir, "test.db")
 	eng, err := engine.NewEngine(cfg)
 	require.NoError(t, err)
 	defer eng.Close()
@@ -75,20 +763,2184 @@ func TestEngine_ImageQuality(t *testing.T) {
 	assert.LessOrEqual(t, quality.FinalScore, 100.0)
 }
 
-// Helper functions
-func createTestImages(t *testing.T, dir string) {
+func TestEngine_UndoClean(t *testing.T) {
+	photosDir := filepath.Join(t.TempDir(), "photos")
+	require.NoError(t, os.MkdirAll(filepath.Join(photosDir, "sub"), 0755))
+
+	original := createTestImage(t, photosDir, "a.jpg")
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	copies := []string{filepath.Join(photosDir, "b.jpg"), filepath.Join(photosDir, "sub", "a.jpg")}
+	for _, p := range copies {
+		require.NoError(t, os.WriteFile(p, data, 0644))
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "duplicates")
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		MoveDuplicates:         true,
+		OutputDir:              outputDir,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, report.MovedFiles)
+	require.FileExists(t, report.ManifestPath)
+
+	remaining := 0
+	for _, p := range append([]string{original}, copies...) {
+		if _, err := os.Stat(p); err == nil {
+			remaining++
+		}
+	}
+	require.Equal(t, 1, remaining)
+
+	// Re-occupy one of the vacated paths before undoing
+	var occupied string
+	for _, p := range append([]string{original}, copies...) {
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			occupied = p
+			break
+		}
+	}
+	require.NoError(t, os.WriteFile(occupied, []byte("new file"), 0644))
+
+	undo, err := eng.UndoClean(report.ManifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, undo.MovedFiles)
+	assert.Equal(t, 0, undo.Errors)
+
+	for _, p := range append([]string{original}, copies...) {
+		assert.FileExists(t, p)
+	}
+	content, err := os.ReadFile(occupied)
+	require.NoError(t, err)
+	assert.Equal(t, "new file", string(content), "occupied path must not be overwritten")
+
+	ext := filepath.Ext(occupied)
+	assert.FileExists(t, strings.TrimSuffix(occupied, ext)+"_1"+ext)
+
+	// The index follows the files back
+	groups, err := eng.FindExactDuplicates()
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+		fp, err := eng.GetFingerprint(id)
+		require.NoError(t, err)
+		assert.FileExists(t, fp.Metadata.Path)
+		assert.NotContains(t, fp.Metadata.Path, outputDir)
+	}
+}
+
+func TestEngine_CleanDuplicatesDryRunPlansActions(t *testing.T) {
+	for _, action := range []string{api.ActionMove, api.ActionDelete} {
+		t.Run(action, func(t *testing.T) {
+			photosDir := t.TempDir()
+			original := createTestImage(t, photosDir, "a.jpg")
+			data, err := os.ReadFile(original)
+			require.NoError(t, err)
+			for _, name := range []string{"b.jpg", "c.jpg"} {
+				require.NoError(t, os.WriteFile(filepath.Join(photosDir, name), data, 0644))
+			}
+
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+			_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+			require.NoError(t, err)
+
+			options := api.CleanOptions{
+				DryRun:                 true,
+				MaxSimilarityThreshold: 0.9,
+				MoveDuplicates:         action == api.ActionMove,
+				OutputDir:              filepath.Join(t.TempDir(), "duplicates"),
+			}
+			planned, err := eng.CleanDuplicates(options)
+			require.NoError(t, err)
+			require.Len(t, planned.Actions, 2)
+			assert.Zero(t, planned.MovedFiles)
+			assert.Empty(t, planned.ManifestPath)
+
+			for _, planned := range planned.Actions {
+				assert.Equal(t, action, planned.Action)
+				assert.Equal(t, int64(len(data)), planned.SizeBytes)
+				assert.FileExists(t, planned.Source, "dry run must not touch files")
+				if action == api.ActionMove {
+					assert.Equal(t, filepath.Join(options.OutputDir, planned.GroupID, filepath.Base(planned.Source)), planned.Dest)
+					assert.NoFileExists(t, planned.Dest)
+				} else {
+					assert.Empty(t, planned.Dest)
+				}
+			}
+
+			options.DryRun = false
+			performed, err := eng.CleanDuplicates(options)
+			require.NoError(t, err)
+			assert.Equal(t, planned.Actions, performed.Actions)
+			assert.Equal(t, 2, performed.MovedFiles)
+			assert.Equal(t, 2*int64(len(data)), performed.FreedSpace)
+
+			for _, performed := range performed.Actions {
+				assert.NoFileExists(t, performed.Source)
+				if action == api.ActionMove {
+					assert.FileExists(t, performed.Dest)
+				}
+			}
+			if action == api.ActionDelete {
+				assert.NoDirExists(t, options.OutputDir)
+			}
+			fingerprints, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			assert.Len(t, fingerprints, map[string]int{api.ActionMove: 3, api.ActionDelete: 1}[action])
+		})
+	}
+}
+
+func TestEngine_CleanDuplicatesFlagsAmbiguousQuality(t *testing.T) {
+	photosDir := t.TempDir()
+	shotA := filepath.Join(photosDir, "shot_a.png")
+	shotB := filepath.Join(photosDir, "shot_b.png")
+	writeBlockImage(t, shotA, 7, 0)
+	writeBlockImage(t, shotB, 7, 2)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindNearDuplicates(0.9)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		OutputDir:              filepath.Join(t.TempDir(), "duplicates"),
+		QualityMargin:          5,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, report.MovedFiles)
+	assert.Empty(t, report.Actions)
+	assert.ElementsMatch(t, []string{shotA, shotB}, report.NeedsReview)
+	assert.FileExists(t, shotA)
+	assert.FileExists(t, shotB)
+
+	// Without a margin one of the shots is cleaned as usual
+	report, err = eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		OutputDir:              filepath.Join(t.TempDir(), "duplicates"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.MovedFiles)
+	assert.Empty(t, report.NeedsReview)
+}
+
+// crossDeviceMover fails every rename as if source and destination were on different filesystems
+type crossDeviceMover struct {
+	calls int
+}
+
+func (m *crossDeviceMover) Rename(oldPath, newPath string) error {
+	m.calls++
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EXDEV}
+}
+
+// failingMover renames files normally except for the failOn-th call, which fails
+type failingMover struct {
+	calls  int
+	failOn int
+}
+
+func (m *failingMover) Rename(oldPath, newPath string) error {
+	m.calls++
+	if m.calls == m.failOn {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: syscall.EACCES}
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+func TestEngine_CleanDuplicatesRollsBackFailedGroup(t *testing.T) {
+	photosDir := t.TempDir()
+	original := createTestImage(t, photosDir, "a.jpg")
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	copies := []string{original}
+	for _, name := range []string{"b.jpg", "c.jpg"} {
+		path := filepath.Join(photosDir, name)
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		copies = append(copies, path)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	eng.SetFileMover(&failingMover{failOn: 2})
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "duplicates")
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		MoveDuplicates:         true,
+		OutputDir:              outputDir,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.MovedFiles)
+	assert.Equal(t, 1, report.Errors)
+	assert.Empty(t, report.ManifestPath)
+	require.Len(t, report.Groups, 1)
+	assert.False(t, report.Groups[0].Succeeded)
+	assert.True(t, report.Groups[0].RolledBack)
+	assert.NotEmpty(t, report.Groups[0].Error)
+
+	// The first move was undone, so every copy is back where it started
+	for _, path := range copies {
+		assert.FileExists(t, path)
+	}
+	moved, err := filepath.Glob(filepath.Join(outputDir, "*", "*.jpg"))
+	require.NoError(t, err)
+	assert.Empty(t, moved)
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	var indexed []string
+	for _, fp := range fingerprints {
+		indexed = append(indexed, fp.Metadata.Path)
+	}
+	assert.ElementsMatch(t, copies, indexed)
+}
+
+func TestEngine_CleanDuplicatesRestoresTrashedFilesOfFailedGroup(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG trash layout is only used on Linux")
+	}
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	photosDir := t.TempDir()
+	original := createTestImage(t, photosDir, "a.jpg")
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	copies := []string{original}
+	for _, name := range []string{"b.jpg", "c.jpg"} {
+		path := filepath.Join(photosDir, name)
+		require.NoError(t, os.WriteFile(path, data, 0644))
+		copies = append(copies, path)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	// The first duplicate goes to the trash, the second fails
+	eng.SetFileMover(&failingMover{failOn: 2})
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		TrashDuplicates:        true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.MovedFiles)
+	assert.Zero(t, report.FreedSpace)
+	assert.Equal(t, 1, report.Errors)
+	require.Len(t, report.Groups, 1)
+	assert.False(t, report.Groups[0].Succeeded)
+	assert.True(t, report.Groups[0].RolledBack)
+	assert.Empty(t, report.Groups[0].NotRestored)
+
+	// The trashed copy was restored and nothing is left in the trash
+	for _, path := range copies {
+		assert.FileExists(t, path)
+	}
+	for _, dir := range []string{"files", "info"} {
+		entries, err := os.ReadDir(filepath.Join(dataHome, "Trash", dir))
+		require.NoError(t, err)
+		assert.Empty(t, entries, dir)
+	}
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	var indexed []string
+	for _, fp := range fingerprints {
+		indexed = append(indexed, fp.Metadata.Path)
+	}
+	assert.ElementsMatch(t, copies, indexed)
+}
+
+func TestEngine_CleanDuplicatesAcrossDevices(t *testing.T) {
+	photosDir := t.TempDir()
+	original := createTestImage(t, photosDir, "a.jpg")
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	duplicate := filepath.Join(photosDir, "b.jpg")
+	require.NoError(t, os.WriteFile(duplicate, data, 0644))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	mover := &crossDeviceMover{}
+	eng.SetFileMover(mover)
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "duplicates")
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		MoveDuplicates:         true,
+		OutputDir:              outputDir,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.MovedFiles)
+	assert.Equal(t, 0, report.Errors)
+	assert.Equal(t, int64(len(data)), report.FreedSpace)
+	assert.Equal(t, 1, mover.calls)
+
+	// Exactly one of the pair was copied out and removed from the source folder
+	remaining := 0
+	for _, p := range []string{original, duplicate} {
+		if _, err := os.Stat(p); err == nil {
+			remaining++
+		}
+	}
+	assert.Equal(t, 1, remaining)
+
+	moved, err := filepath.Glob(filepath.Join(outputDir, "*", "*.jpg"))
+	require.NoError(t, err)
+	require.Len(t, moved, 1)
+	content, err := os.ReadFile(moved[0])
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
+
+func TestEngine_FindNearDuplicatesWithLSH(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 40; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+	// A slightly brightened copy of photo_07
+	writeBlockImage(t, filepath.Join(photosDir, "photo_07_edit.png"), 7, 6)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.UseFeatureVec = true
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindNearDuplicates(0.9)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	var paths []string
+	for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+		fp, err := eng.GetFingerprint(id)
+		require.NoError(t, err)
+		assert.NotEmpty(t, fp.FeatureVec)
+		paths = append(paths, filepath.Base(fp.Metadata.Path))
+	}
+	assert.ElementsMatch(t, []string{"photo_07.png", "photo_07_edit.png"}, paths)
+}
+
+func TestEngine_FindNearDuplicatesWideHashesMatchPairwise(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.HashConfig.HashSize = 16
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	// Pairs of images whose 16x16 pHashes nearly match while their 64-bit
+	// pHashes are far apart, among unrelated images
+	rng := rand.New(rand.NewSource(3))
+	wideHash := func() []byte {
+		hash := make([]byte, 32)
+		rng.Read(hash)
+		return hash
+	}
+	var catalog bytes.Buffer
+	encoder := json.NewEncoder(&catalog)
+	for i := 0; i < 12; i++ {
+		fp := api.ImageFingerprint{
+			ID:       api.ImageID(fmt.Sprintf("img_%02d", i)),
+			Metadata: api.ImageMetadata{Path: fmt.Sprintf("/photos/%02d.jpg", i), SHA256: fmt.Sprintf("sha_%02d", i)},
+			PHashes: api.PerceptualHashes{
+				AHash: rng.Uint64(), PHash: rng.Uint64(), DHash: rng.Uint64(), WHash: rng.Uint64(),
+				PHashWide: wideHash(),
+			},
+		}
+		require.NoError(t, encoder.Encode(fp))
+
+		if i%4 == 0 {
+			copied := fp
+			copied.ID += "_copy"
+			copied.Metadata.Path = fmt.Sprintf("/photos/%02d_copy.jpg", i)
+			copied.Metadata.SHA256 += "_copy"
+			copied.PHashes.PHash ^= 0xFFFFFFFFFF // 40 bits apart
+			copied.PHashes.PHashWide = append([]byte(nil), fp.PHashes.PHashWide...)
+			copied.PHashes.PHashWide[0] ^= byte(i/4 + 1)
+			require.NoError(t, encoder.Encode(copied))
+		}
+	}
+	require.NoError(t, eng.ImportCatalog(&catalog))
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	sort.Slice(fingerprints, func(i, j int) bool { return fingerprints[i].ID < fingerprints[j].ID })
+	ids := make([]api.ImageID, len(fingerprints))
+	for i, fp := range fingerprints {
+		ids[i] = fp.ID
+	}
+	matrix, err := eng.SimilarityMatrix(ids)
+	require.NoError(t, err)
+
+	for _, threshold := range []float64{0.8, 0.85, 0.9} {
+		// Group every pair above the threshold, comparing each pair directly
+		components := similarity.NewDisjointSet(len(ids))
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				if matrix[i][j] >= threshold {
+					components.Union(i, j)
+				}
+			}
+		}
+		var expected []string
+		for _, component := range components.Components() {
+			if len(component) < 2 {
+				continue
+			}
+			var names []string
+			for _, idx := range component {
+				names = append(names, string(ids[idx]))
+			}
+			sort.Strings(names)
+			expected = append(expected, strings.Join(names, ","))
+		}
+
+		groups, err := eng.FindNearDuplicates(threshold)
+		require.NoError(t, err)
+		var got []string
+		for _, group := range groups {
+			var names []string
+			for _, id := range append([]api.ImageID{group.MainImage}, group.DuplicateIDs...) {
+				names = append(names, string(id))
+			}
+			sort.Strings(names)
+			got = append(got, strings.Join(names, ","))
+		}
+
+		sort.Strings(expected)
+		sort.Strings(got)
+		assert.NotEmpty(t, expected, "threshold=%.2f", threshold)
+		assert.Equal(t, expected, got, "threshold=%.2f", threshold)
+	}
+}
+
+func TestEngine_FindNearDuplicatesCtxCancel(t *testing.T) {
+	photosDir := t.TempDir()
+	const total = 20
+	for seed := 1; seed <= total; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	progress := make(chan api.ScanProgress)
+	findErr := make(chan error, 1)
+	go func() {
+		_, err := eng.FindNearDuplicatesCtx(ctx, 0.9, progress)
+		findErr <- err
+	}()
+
+	first := <-progress
+	assert.Equal(t, 1, first.Current)
+	assert.Equal(t, total, first.Total)
+	cancel()
+
+	select {
+	case err := <-findErr:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(2 * time.Second):
+		t.Fatal("FindNearDuplicatesCtx did not return after cancellation")
+	}
+}
+
+func TestEngine_RotationInvariantGroupsRotatedCopies(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 10; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+
+	// A copy of photo_03 turned by 90 degrees
+	src, err := os.Open(filepath.Join(photosDir, "photo_03.png"))
+	require.NoError(t, err)
+	img, err := png.Decode(src)
+	src.Close()
+	require.NoError(t, err)
+	writeImageFile(t, filepath.Join(photosDir, "photo_03_rotated.png"), imaging.Rotate90(img), png.Encode)
+
+	for _, invariant := range []bool{false, true} {
+		t.Run(fmt.Sprintf("invariant=%v", invariant), func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.RotationInvariant = invariant
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+			_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+			require.NoError(t, err)
+
+			groups, err := eng.FindNearDuplicates(0.9)
+			require.NoError(t, err)
+
+			grouped := false
+			for _, group := range groups {
+				var paths []string
+				for _, id := range append([]api.ImageID{group.MainImage}, group.DuplicateIDs...) {
+					fp, err := eng.GetFingerprint(id)
+					require.NoError(t, err)
+					paths = append(paths, filepath.Base(fp.Metadata.Path))
+				}
+				sort.Strings(paths)
+				if strings.Join(paths, ",") == "photo_03.png,photo_03_rotated.png" {
+					grouped = true
+				}
+			}
+			assert.Equal(t, invariant, grouped)
+		})
+	}
+}
+
+func TestEngine_RotationInvariantMatchesRecompressedCopies(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 10; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+
+	// Recompressed JPEG copies of every photo, matched as stored
+	for seed := 1; seed <= 10; seed++ {
+		src, err := os.Open(filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)))
+		require.NoError(t, err)
+		img, err := png.Decode(src)
+		src.Close()
+		require.NoError(t, err)
+		writeImageFile(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.jpg", seed)), img, func(w io.Writer, m image.Image) error {
+			return jpeg.Encode(w, m, &jpeg.Options{Quality: 60})
+		})
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.RotationInvariant = true
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindNearDuplicates(0.9)
+	require.NoError(t, err)
+
+	pairs := make(map[string]bool)
+	for _, group := range groups {
+		var names []string
+		for _, id := range append([]api.ImageID{group.MainImage}, group.DuplicateIDs...) {
+			fp, err := eng.GetFingerprint(id)
+			require.NoError(t, err)
+			names = append(names, strings.TrimSuffix(filepath.Base(fp.Metadata.Path), filepath.Ext(fp.Metadata.Path)))
+		}
+		if len(names) == 2 && names[0] == names[1] {
+			pairs[names[0]] = true
+		}
+	}
+	assert.Len(t, pairs, 10)
+}
+
+func TestEngine_FindDuplicatesRecomputesAfterSettingsChange(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 6; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+	data, err := os.ReadFile(filepath.Join(photosDir, "photo_01.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo_01_copy.png"), data, 0644))
+
+	src, err := os.Open(filepath.Join(photosDir, "photo_05.png"))
+	require.NoError(t, err)
+	img, err := png.Decode(src)
+	src.Close()
+	require.NoError(t, err)
+	writeImageFile(t, filepath.Join(photosDir, "photo_05_mirrored.png"), imaging.FlipH(img), png.Encode)
+
+	indexPath := filepath.Join(t.TempDir(), "test.db")
+	nearGroups := func(flipInvariant bool) int {
+		cfg := engine.DefaultConfig()
+		cfg.IndexPath = indexPath
+		cfg.FlipInvariant = flipInvariant
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+		defer eng.Close()
+
+		_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+		require.NoError(t, err)
+		exact, near, err := eng.FindDuplicates(0.9, false)
+		require.NoError(t, err)
+		require.Len(t, exact, 1)
+		return len(near)
+	}
+
+	// The saved groups were found without flip invariance, so the mirrored
+	// copy is only grouped once the search runs again
+	assert.Equal(t, 1, nearGroups(false))
+	assert.Equal(t, 2, nearGroups(true))
+}
+
+func TestEngine_FlipInvariantGroupsMirroredCopies(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 10; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+
+	// A horizontally mirrored copy of photo_05
+	src, err := os.Open(filepath.Join(photosDir, "photo_05.png"))
+	require.NoError(t, err)
+	img, err := png.Decode(src)
+	src.Close()
+	require.NoError(t, err)
+	writeImageFile(t, filepath.Join(photosDir, "photo_05_mirrored.png"), imaging.FlipH(img), png.Encode)
+
+	for _, invariant := range []bool{false, true} {
+		t.Run(fmt.Sprintf("invariant=%v", invariant), func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.FlipInvariant = invariant
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+			_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+			require.NoError(t, err)
+
+			groups, err := eng.FindNearDuplicates(0.9)
+			require.NoError(t, err)
+
+			grouped := false
+			for _, group := range groups {
+				var paths []string
+				for _, id := range append([]api.ImageID{group.MainImage}, group.DuplicateIDs...) {
+					fp, err := eng.GetFingerprint(id)
+					require.NoError(t, err)
+					paths = append(paths, filepath.Base(fp.Metadata.Path))
+				}
+				sort.Strings(paths)
+				if strings.Join(paths, ",") == "photo_05.png,photo_05_mirrored.png" {
+					grouped = true
+				}
+			}
+			assert.Equal(t, invariant, grouped)
+		})
+	}
+}
+
+func TestEngine_FindNearDuplicatesMergesChains(t *testing.T) {
+	// A~B and B~C clear the threshold but A~C does not. IDs are random per
+	// scan, so repeating the scan varies the order the store returns them in.
+	for run := 0; run < 5; run++ {
+		photosDir := t.TempDir()
+		for step, name := range []string{"a.png", "b.png", "c.png"} {
+			writeChainImage(t, filepath.Join(photosDir, name), step)
+		}
+		writeBlockImage(t, filepath.Join(photosDir, "other.png"), 3, 0)
+
+		cfg := engine.DefaultConfig()
+		cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+		_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+		require.NoError(t, err)
+
+		groups, err := eng.FindNearDuplicates(0.94)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Len(t, groups[0].DuplicateIDs, 2)
+
+		var paths []string
+		for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+			fp, err := eng.GetFingerprint(id)
+			require.NoError(t, err)
+			paths = append(paths, filepath.Base(fp.Metadata.Path))
+		}
+		assert.ElementsMatch(t, []string{"a.png", "b.png", "c.png"}, paths)
+		require.NoError(t, eng.Close())
+	}
+}
+
+func TestEngine_FindNearDuplicatesHashMinimums(t *testing.T) {
+	photosDir := t.TempDir()
+	writeChainImage(t, filepath.Join(photosDir, "a.png"), 0)
+	writeChainImage(t, filepath.Join(photosDir, "b.png"), 1)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindNearDuplicatesWithOptions(context.Background(), api.NearDuplicateOptions{MinCombined: 0.94}, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	// The pair clears the blend but not an exact dHash floor
+	groups, err = eng.FindNearDuplicatesWithOptions(context.Background(), api.NearDuplicateOptions{
+		MinCombined: 0.94,
+		MinDHash:    1.0,
+	}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestEngine_FindSimilarTo(t *testing.T) {
+	photosDir := t.TempDir()
+	for seed := 1; seed <= 10; seed++ {
+		writeBlockImage(t, filepath.Join(photosDir, fmt.Sprintf("photo_%02d.png", seed)), int64(seed), 0)
+	}
+	data, err := os.ReadFile(filepath.Join(photosDir, "photo_04.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo_04_copy.png"), data, 0644))
+
+	// A brightened edit of photo_04 that is not indexed
+	edited := filepath.Join(t.TempDir(), "edited.png")
+	writeBlockImage(t, edited, 4, 10)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	matchNames := func(groups []api.DuplicateGroup) []string {
+		var names []string
+		for _, group := range groups {
+			fp, err := eng.GetFingerprint(group.MainImage)
+			require.NoError(t, err)
+			names = append(names, filepath.Base(fp.Metadata.Path))
+		}
+		return names
+	}
+
+	// The indexed query leaves itself out and finds its byte-identical copy
+	groups, err := eng.FindSimilarTo(filepath.Join(photosDir, "photo_04.png"), 0.9)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, []string{"photo_04_copy.png"}, matchNames(groups))
+	assert.Equal(t, api.ReasonExact, groups[0].Reason)
+	assert.Equal(t, 1.0, groups[0].Confidence)
+
+	groups, err = eng.FindSimilarTo(edited, 0.9)
+	require.NoError(t, err)
+	names := matchNames(groups)
+	sort.Strings(names)
+	assert.Equal(t, []string{"photo_04.png", "photo_04_copy.png"}, names)
+	for i, group := range groups {
+		assert.Equal(t, api.ReasonNear, group.Reason)
+		assert.GreaterOrEqual(t, group.Confidence, 0.9)
+		if i > 0 {
+			assert.LessOrEqual(t, group.Confidence, groups[i-1].Confidence)
+		}
+	}
+}
+
+func TestEngine_SelectionPolicies(t *testing.T) {
+	// A crafted near-duplicate group whose stored FinalScore is stale, so the
+	// composite policy has to rescore the individual metrics
+	hashes := api.PerceptualHashes{AHash: 0xF0F0F0F0F0F0F0F0, PHash: 0x0FF00FF00FF00FF0, DHash: 0x3C3C3C3C3C3C3C3C, WHash: 0xAAAA5555AAAA5555}
+	group := []api.ImageFingerprint{
+		{
+			ID:       "img_large",
+			Metadata: api.ImageMetadata{Path: "/photos/large.tiff", SizeBytes: 9 << 20},
+			PHashes:  hashes,
+			Quality:  api.ImageQuality{Sharpness: 0.3, Noise: 0.5, Exposure: 0.8, Contrast: 0.3, FinalScore: 95},
+		},
+		{
+			ID:       "img_sharp",
+			Metadata: api.ImageMetadata{Path: "/photos/sharp.jpg", SizeBytes: 4 << 20},
+			PHashes:  hashes,
+			Quality:  api.ImageQuality{Sharpness: 0.95, Noise: 0.05, Exposure: 0.5, Contrast: 0.8, FinalScore: 40},
+		},
+		{
+			ID:       "img_small",
+			Metadata: api.ImageMetadata{Path: "/photos/small.jpg", SizeBytes: 1 << 20},
+			PHashes:  hashes,
+			Quality:  api.ImageQuality{Sharpness: 0.6, Noise: 0.2, Exposure: 0.4, Contrast: 0.5, FinalScore: 60},
+		},
+	}
+	for i := range group {
+		group[i].Metadata.SHA256 = fmt.Sprintf("%064d", i)
+	}
+
+	indexPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := index.NewBoltStore(indexPath)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveFingerprints(group))
+	require.NoError(t, store.Close())
+
+	tests := []struct {
+		policy api.SelectionPolicy
+		want   api.ImageID
+	}{
+		{api.PolicyHighestQuality, "img_large"},
+		{api.PolicyCompositeQuality, "img_sharp"},
+		{api.PolicyLargestFileSize, "img_large"},
+		{api.PolicySmallestFileSize, "img_small"},
+	}
+
+	for _, tt := range tests {
+		cfg := engine.DefaultConfig()
+		cfg.IndexPath = indexPath
+		cfg.SelectionPolicy = tt.policy
+
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+
+		groups, err := eng.FindNearDuplicates(0.9)
+		require.NoError(t, eng.Close())
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, tt.want, groups[0].MainImage, "policy %d", tt.policy)
+		assert.Len(t, groups[0].DuplicateIDs, 2)
+	}
+}
+
+func TestEngine_CleanPolicyNamesSelectKeeper(t *testing.T) {
+	// A crafted near-duplicate group where each image wins under one policy
+	hashes := api.PerceptualHashes{AHash: 0xF0F0F0F0F0F0F0F0, PHash: 0x0FF00FF00FF00FF0, DHash: 0x3C3C3C3C3C3C3C3C}
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	craft := func(name string, score float64, width int, exposure float64, age int) api.ImageFingerprint {
+		return api.ImageFingerprint{
+			ID: api.ImageID("img_" + name),
+			Metadata: api.ImageMetadata{
+				Path: "/photos/" + name + ".jpg", Width: width, Height: width, SizeBytes: 1 << 20,
+				SHA256: fmt.Sprintf("%064s", name), ModifiedAt: base.AddDate(0, 0, age),
+			},
+			PHashes: hashes,
+			Quality: api.ImageQuality{FinalScore: score, Exposure: exposure},
+		}
+	}
+	group := []api.ImageFingerprint{
+		craft("quality", 90, 800, 0.8, 2),
+		craft("resolution", 60, 4000, 0.8, 3),
+		craft("exposure", 60, 800, 0.5, 4),
+		craft("oldest", 60, 800, 0.8, 0),
+		craft("newest", 60, 800, 0.8, 10),
+	}
+
+	indexPath := filepath.Join(t.TempDir(), "test.db")
+	store, err := index.NewBoltStore(indexPath)
+	require.NoError(t, err)
+	require.NoError(t, store.SaveFingerprints(group))
+	require.NoError(t, store.Close())
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = indexPath
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	for _, name := range []string{"quality", "resolution", "exposure", "oldest", "newest"} {
+		policy, err := api.ParseSelectionPolicy(name)
+		require.NoError(t, err)
+
+		report, err := eng.CleanDuplicates(api.CleanOptions{
+			DryRun:                 true,
+			SelectionPolicy:        policy,
+			MaxSimilarityThreshold: 0.9,
+			MoveDuplicates:         true,
+			OutputDir:              t.TempDir(),
+		})
+		require.NoError(t, err)
+
+		var removed []string
+		for _, action := range report.Actions {
+			removed = append(removed, action.Source)
+		}
+		assert.Len(t, removed, 4, name)
+		assert.NotContains(t, removed, "/photos/"+name+".jpg", name)
+	}
+
+	_, err = api.ParseSelectionPolicy("largest")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quality, resolution, exposure, oldest, newest")
+}
+
+func TestEngine_FindNearDuplicatesTagsRecompressed(t *testing.T) {
+	photosDir := t.TempDir()
+	source := filepath.Join(photosDir, "photo.png")
+	writeBlockImage(t, source, 7, 0)
+
+	// Re-encode the same picture as a JPEG
+	file, err := os.Open(source)
+	require.NoError(t, err)
+	img, err := png.Decode(file)
+	file.Close()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo.jpg"), buf.Bytes(), 0644))
+
+	for _, detect := range []bool{false, true} {
+		cfg := engine.DefaultConfig()
+		cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.DetectRecompressed = detect
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+		_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+		require.NoError(t, err)
+
+		groups, err := eng.FindNearDuplicates(0.9)
+		require.NoError(t, eng.Close())
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+
+		if detect {
+			assert.Equal(t, api.ReasonRecompressed, groups[0].Reason)
+			assert.Equal(t, []string{"jpeg", "png"}, groups[0].Formats)
+		} else {
+			assert.Equal(t, api.ReasonNear, groups[0].Reason)
+			assert.Empty(t, groups[0].Formats)
+		}
+	}
+}
+
+func TestEngine_FindNearDuplicatesVerifySSIM(t *testing.T) {
+	photosDir := t.TempDir()
+	horizontal := stripedImage(false)
+	writeImageFile(t, filepath.Join(photosDir, "horizontal.png"), horizontal, png.Encode)
+	writeImageFile(t, filepath.Join(photosDir, "vertical.png"), stripedImage(true), png.Encode)
+	writeImageFile(t, filepath.Join(photosDir, "horizontal.jpg"), horizontal, func(w io.Writer, img image.Image) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	})
+
+	for _, verify := range []bool{false, true} {
+		cfg := engine.DefaultConfig()
+		cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+		cfg.VerifySSIM = verify
+		eng, err := engine.NewEngine(cfg)
+		require.NoError(t, err)
+		_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+		require.NoError(t, err)
+
+		groups, err := eng.FindNearDuplicates(0.9)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+
+		var paths []string
+		for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+			fp, err := eng.GetFingerprint(id)
+			require.NoError(t, err)
+			paths = append(paths, filepath.Base(fp.Metadata.Path))
+		}
+		require.NoError(t, eng.Close())
+
+		if verify {
+			// The stripes' orientation is invisible to the hashes but not to SSIM
+			assert.ElementsMatch(t, []string{"horizontal.png", "horizontal.jpg"}, paths)
+		} else {
+			assert.ElementsMatch(t, []string{"horizontal.png", "horizontal.jpg", "vertical.png"}, paths)
+		}
+	}
+}
+
+func TestEngine_CompareFiles(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.png")
+	writeBlockImage(t, original, 7, 0)
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	identical := filepath.Join(dir, "identical.png")
+	require.NoError(t, os.WriteFile(identical, data, 0644))
+	brighter := filepath.Join(dir, "brighter.png")
+	writeBlockImage(t, brighter, 7, 2)
+	unrelated := filepath.Join(dir, "unrelated.png")
+	writeBlockImage(t, unrelated, 99, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	result, err := eng.CompareFiles(original, identical)
+	require.NoError(t, err)
+	assert.True(t, result.ExactMatch)
+	assert.True(t, result.IsNearDuplicate)
+	assert.Equal(t, 1.0, result.Similarity)
+	assert.Equal(t, map[string]int{"ahash": 0, "phash": 0, "dhash": 0}, result.HammingDistances)
+
+	result, err = eng.CompareFiles(original, brighter)
+	require.NoError(t, err)
+	assert.False(t, result.ExactMatch)
+	assert.True(t, result.IsNearDuplicate)
+	assert.GreaterOrEqual(t, result.Similarity, result.Threshold)
+
+	result, err = eng.CompareFiles(original, unrelated)
+	require.NoError(t, err)
+	assert.False(t, result.ExactMatch)
+	assert.False(t, result.IsNearDuplicate)
+	assert.Less(t, result.Similarity, result.Threshold)
+	assert.Greater(t, result.HammingDistances["ahash"], 16)
+
+	_, err = eng.CompareFiles(original, filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+
+	// Comparing files never indexes them
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalImages)
+}
+
+func TestEngine_AnimatedGIFUsesMiddleFrame(t *testing.T) {
+	photosDir := t.TempDir()
+	// Both animations open on the same frame but end differently
+	writeBlockGIF(t, filepath.Join(photosDir, "a.gif"), 1, 2)
+	writeBlockGIF(t, filepath.Join(photosDir, "b.gif"), 1, 3)
+	// A still of the second frame of a.gif
+	writeBlockImage(t, filepath.Join(photosDir, "a_still.png"), 2, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindNearDuplicates(0.9)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	var paths []string
+	for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+		fp, err := eng.GetFingerprint(id)
+		require.NoError(t, err)
+		paths = append(paths, filepath.Base(fp.Metadata.Path))
+		if fp.Metadata.Format == "gif" {
+			assert.Equal(t, 2, fp.Metadata.FrameCount)
+			assert.Equal(t, 64, fp.Metadata.Width)
+		}
+	}
+	assert.ElementsMatch(t, []string{"a.gif", "a_still.png"}, paths)
+}
+
+func TestEngine_ClusterImages(t *testing.T) {
+	photosDir := t.TempDir()
+	for _, name := range []string{"a.png", "a_copy1.png", "a_copy2.png"} {
+		writeBlockImage(t, filepath.Join(photosDir, name), 1, 0)
+	}
+	writeBlockImage(t, filepath.Join(photosDir, "b.png"), 2, 0)
+	writeBlockImage(t, filepath.Join(photosDir, "c.png"), 3, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	clusters, err := eng.ClusterImages(0.9)
+	require.NoError(t, err)
+	require.Len(t, clusters, 3)
+
+	var sizes []int
+	for _, cluster := range clusters {
+		sizes = append(sizes, len(cluster.Images))
+		if len(cluster.Images) == 3 {
+			for _, id := range cluster.Images {
+				fp, err := eng.GetFingerprint(id)
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(filepath.Base(fp.Metadata.Path), "a"))
+			}
+		}
+	}
+	assert.ElementsMatch(t, []int{3, 1, 1}, sizes)
+}
+
+func TestEngine_CleanDuplicatesToTrash(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG trash layout is only used on Linux")
+	}
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	photosDir := t.TempDir()
+	original := createTestImage(t, photosDir, "a.jpg")
+	data, err := os.ReadFile(original)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "b.jpg"), data, 0644))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		MaxSimilarityThreshold: 0.9,
+		TrashDuplicates:        true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.MovedFiles)
+	assert.Equal(t, int64(len(data)), report.FreedSpace)
+
+	trashed, err := os.ReadDir(filepath.Join(dataHome, "Trash", "files"))
+	require.NoError(t, err)
+	assert.Len(t, trashed, 1)
+
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.TotalImages)
+}
+
+func TestEngine_ScanFolderFlushesOnCancel(t *testing.T) {
+	tempDir := t.TempDir()
+	const total = 40
+	for i := 0; i < total; i++ {
+		writeTestImage(t, filepath.Join(tempDir, fmt.Sprintf("img%02d.png", i)), i)
+	}
+
+	const workers = 2
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.NumWorkers = workers
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	progress := make(chan api.ScanProgress)
+	scanErr := make(chan error, 1)
+	go func() {
+		_, err := eng.ScanFolder(ctx, tempDir, progress)
+		scanErr <- err
+		close(progress)
+	}()
+
+	// Hold the first update so workers fill the pipeline with computed results,
+	// then cancel and let the scan drain
+	<-progress
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+
+	reported := 1
+	for range progress {
+		reported++
+	}
+	assert.ErrorIs(t, <-scanErr, context.Canceled)
+
+	stats, err := eng.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(reported), stats.TotalImages)
+	// The saved image, the buffered results and one blocked result per worker
+	assert.GreaterOrEqual(t, stats.TotalImages, int64(2*workers+1))
+	assert.Less(t, stats.TotalImages, int64(total))
+}
+
+func TestEngine_ExtractsEXIF(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTestJPEG(t, filepath.Join(tempDir, "camera.jpg"), 32, 32, testEXIF{Make: "Canon", Model: "EOS R5", ISO: 400})
+	data, err := os.ReadFile(filepath.Join(tempDir, "camera.jpg"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "camera_copy.jpg"), data, 0644))
+
+	// Plain PNGs carry no EXIF and must not get invented camera data
+	createTestImage(t, tempDir, "plain.png")
+	createTestImage(t, tempDir, "plain_copy.png")
+	data, err = os.ReadFile(filepath.Join(tempDir, "plain.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "plain_copy.png"), data, 0644))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+
+	groups, err := eng.FindExactDuplicates()
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	for _, group := range groups {
+		fp, err := eng.GetFingerprint(group.MainImage)
+		require.NoError(t, err)
+
+		if fp.Metadata.Format == "jpeg" {
+			require.NotNil(t, fp.Metadata.EXIF)
+			assert.Equal(t, "Canon EOS R5", fp.Metadata.EXIF.CameraModel)
+			assert.Equal(t, 400, fp.Metadata.EXIF.ISO)
+		} else {
+			assert.Nil(t, fp.Metadata.EXIF)
+		}
+	}
+}
+
+func TestEngine_DisplayDimensionsFromEXIFOrientation(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Landscape pixels tagged "rotate 90 CW" (orientation 6), an identical copy,
+	// and the same pixels without the tag
+	rotated := filepath.Join(tempDir, "rotated.jpg")
+	writeTestJPEG(t, rotated, 64, 32, testEXIF{Orientation: 6})
+	data, err := os.ReadFile(rotated)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "rotated_copy.jpg"), data, 0644))
+	writeTestJPEG(t, filepath.Join(tempDir, "untagged.jpg"), 64, 32, testEXIF{})
+
+	for _, tc := range []struct {
+		name      string
+		maxDiff   float64
+		groupSize int
+	}{
+		{"guard uses display dimensions", 0.1, 2},
+		// Once rotated upright, the tagged pixels no longer match the untagged ones
+		{"guard disabled", 0, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.MaxAspectRatioDiff = tc.maxDiff
+
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+
+			_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+			require.NoError(t, err)
+
+			exact, err := eng.FindExactDuplicates()
+			require.NoError(t, err)
+			require.Len(t, exact, 1)
+
+			fp, err := eng.GetFingerprint(exact[0].MainImage)
+			require.NoError(t, err)
+			assert.Equal(t, 6, fp.Metadata.Orientation)
+			assert.Equal(t, 64, fp.Metadata.Width)
+			assert.Equal(t, 32, fp.Metadata.Height)
+			assert.Equal(t, 32, fp.Metadata.DisplayWidth)
+			assert.Equal(t, 64, fp.Metadata.DisplayHeight)
+
+			near, err := eng.FindNearDuplicates(0.95)
+			require.NoError(t, err)
+			require.Len(t, near, 1)
+			assert.Len(t, near[0].DuplicateIDs, tc.groupSize-1)
+		})
+	}
+}
+
+func TestEngine_EXIFOrientationAppliedBeforeHashing(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A portrait photo stored as landscape sensor pixels tagged orientation 6,
+	// and the upright re-export a viewer would save
+	tagged := filepath.Join(tempDir, "tagged.jpg")
+	writeTestJPEG(t, tagged, 64, 32, testEXIF{Orientation: 6})
+	raw, err := imaging.Open(tagged)
+	require.NoError(t, err)
+	upright := filepath.Join(tempDir, "upright.jpg")
+	require.NoError(t, imaging.Save(imaging.Rotate270(raw), upright, imaging.JPEGQuality(95)))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	img, metadata, err := eng.LoadImage(tagged)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 64), img.Bounds())
+	assert.Equal(t, 6, metadata.AppliedOrientation)
+	assert.Equal(t, 64, metadata.Width)
+	assert.Equal(t, 32, metadata.Height)
+
+	_, err = eng.ScanFolder(context.Background(), tempDir, nil)
+	require.NoError(t, err)
+	fps, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	require.Len(t, fps, 2)
+
+	hashes := make(map[string]api.PerceptualHashes)
+	for _, fp := range fps {
+		hashes[filepath.Base(fp.Metadata.Path)] = fp.PHashes
+	}
+	assert.Equal(t, 6, fps[0].Metadata.AppliedOrientation+fps[1].Metadata.AppliedOrientation)
+	assert.LessOrEqual(t, bits.OnesCount64(hashes["tagged.jpg"].AHash^hashes["upright.jpg"].AHash), 4)
+	assert.LessOrEqual(t, bits.OnesCount64(hashes["tagged.jpg"].PHash^hashes["upright.jpg"].PHash), 4)
+	assert.LessOrEqual(t, bits.OnesCount64(hashes["tagged.jpg"].DHash^hashes["upright.jpg"].DHash), 4)
+}
+
+func BenchmarkEngine_ScanFolder(b *testing.B) {
+	imageDir := b.TempDir()
+	for i := 0; i < 64; i++ {
+		writeTestImage(b, filepath.Join(imageDir, fmt.Sprintf("bench%03d.jpg", i)), i)
+	}
+
+	for _, workers := range []int{1, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cfg := engine.DefaultConfig()
+				cfg.NumWorkers = workers
+				cfg.LogLevel = "error"
+				cfg.IndexPath = filepath.Join(b.TempDir(), "bench.db")
+				eng, err := engine.NewEngine(cfg)
+				require.NoError(b, err)
+				b.StartTimer()
+
+				_, err = eng.ScanFolder(context.Background(), imageDir, nil)
+				require.NoError(b, err)
+
+				b.StopTimer()
+				eng.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// Helper functions
+func createTestImages(t *testing.T, dir string) {
 	// Create some dummy image files for testing
-	extensions := []string{".jpg", ".png", ".webp"}
+	extensions := []string{".jpg", ".png", ".jpeg"}
 
 	for i, ext := range extensions {
 		createTestImage(t, dir, fmt.Sprintf("test%d%s", i, ext))
 	}
 }
 
-func createTestImage(t *testing.T, dir, filename string) string {
-	// In real tests, you would create actual image files
-	// For now, create empty files
+func createTestImage(t testing.TB, dir, filename string) string {
 	path := filepath.Join(dir, filename)
-	require.NoError(t, os.WriteFile(path, []byte("fake image data"), 0644))
+	writeTestImage(t, path, len(filename))
 	return path
 }
+
+// writeTestImage encodes a gradient image whose pattern varies with seed,
+// picking the encoder from the file extension
+func writeTestImage(t testing.TB, path string, seed int) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{
+				R: uint8(x * 4),
+				G: uint8(y * 4),
+				B: uint8((x*y + seed*37) % 256),
+				A: 255,
+			})
+		}
+	}
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg":
+		require.NoError(t, jpeg.Encode(file, img, &jpeg.Options{Quality: 90}))
+	default:
+		require.NoError(t, png.Encode(file, img))
+	}
+}
+
+// writeBlockImage writes a PNG of random 8x8 color blocks drawn from seed,
+// with every channel raised by brighten
+func writeBlockImage(t testing.TB, path string, seed int64, brighten int) {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for by := 0; by < 8; by++ {
+		for bx := 0; bx < 8; bx++ {
+			c := color.RGBA{
+				R: uint8(rng.Intn(200) + brighten),
+				G: uint8(rng.Intn(200) + brighten),
+				B: uint8(rng.Intn(200) + brighten),
+				A: 255,
+			}
+			for y := by * 8; y < by*8+8; y++ {
+				for x := bx * 8; x < bx*8+8; x++ {
+					img.Set(x, y, c)
+				}
+			}
+		}
+	}
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, png.Encode(file, img))
+}
+
+// writeBlockGIF writes a GIF animation with one full-canvas frame per seed,
+// each laid out like writeBlockImage
+func writeBlockGIF(t testing.TB, path string, seeds ...int64) {
+	animation := &gif.GIF{}
+	for _, seed := range seeds {
+		rng := rand.New(rand.NewSource(seed))
+		palette := make(color.Palette, 64)
+		frame := image.NewPaletted(image.Rect(0, 0, 64, 64), palette)
+		for block := range palette {
+			palette[block] = color.RGBA{R: uint8(rng.Intn(200)), G: uint8(rng.Intn(200)), B: uint8(rng.Intn(200)), A: 255}
+			bx, by := block%8, block/8
+			for y := by * 8; y < by*8+8; y++ {
+				for x := bx * 8; x < bx*8+8; x++ {
+					frame.SetColorIndex(x, y, uint8(block))
+				}
+			}
+		}
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, gif.EncodeAll(file, animation))
+}
+
+// writeChainImage writes the block image for seed 1 with its first step*10
+// blocks taken from seed 2, so consecutive steps are closer than distant ones
+func writeChainImage(t testing.TB, path string, step int) {
+	base := rand.New(rand.NewSource(1))
+	alt := rand.New(rand.NewSource(2))
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for b := 0; b < 64; b++ {
+		c := color.RGBA{R: uint8(base.Intn(200)), G: uint8(base.Intn(200)), B: uint8(base.Intn(200)), A: 255}
+		replacement := color.RGBA{R: uint8(alt.Intn(200)), G: uint8(alt.Intn(200)), B: uint8(alt.Intn(200)), A: 255}
+		if b < step*10 {
+			c = replacement
+		}
+		bx, by := b%8, b/8
+		for y := by * 8; y < by*8+8; y++ {
+			for x := bx * 8; x < bx*8+8; x++ {
+				img.Set(x, y, c)
+			}
+		}
+	}
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, png.Encode(file, img))
+}
+
+// testEXIF lists the EXIF fields writeTestJPEG can embed; zero values are omitted
+type testEXIF struct {
+	Orientation uint16
+	Make        string
+	Model       string
+	ISO         uint16
+	DateTime    string // "2006:01:02 15:04:05"
+
+	DateTimeOriginal   string
+	OffsetTimeOriginal string // "+02:00"
+	SubSecTimeOriginal string // "123" for .123s
+}
+
+// writeTestJPEG encodes a width x height JPEG and embeds a minimal EXIF segment
+// when any field of tags is set
+func writeTestJPEG(t testing.TB, path string, width, height int, tags testEXIF) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8(x * y), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	encoded := buf.Bytes()
+
+	if tags != (testEXIF{}) {
+		payload := append([]byte("Exif\x00\x00"), buildTestTIFF(tags)...)
+		segment := []byte{0xff, 0xe1}
+		segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+		segment = append(segment, payload...)
+
+		encoded = append(append(append([]byte{}, encoded[:2]...), segment...), encoded[2:]...)
+	}
+
+	require.NoError(t, os.WriteFile(path, encoded, 0644))
+}
+
+// buildTestTIFF lays out a little-endian TIFF block: header, IFD0, an optional
+// Exif sub-IFD, then the out-of-line string data
+func buildTestTIFF(tags testEXIF) []byte {
+	type entry struct {
+		tag, typ uint16
+		count    uint32
+		value    []byte // inline value (<= 4 bytes) or out-of-line data
+	}
+
+	short := func(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+	ascii := func(v string) []byte { return append([]byte(v), 0) }
+
+	var ifd0, exifIFD []entry
+	if tags.Make != "" {
+		ifd0 = append(ifd0, entry{0x010f, 2, uint32(len(tags.Make) + 1), ascii(tags.Make)})
+	}
+	if tags.Model != "" {
+		ifd0 = append(ifd0, entry{0x0110, 2, uint32(len(tags.Model) + 1), ascii(tags.Model)})
+	}
+	if tags.Orientation != 0 {
+		ifd0 = append(ifd0, entry{0x0112, 3, 1, short(tags.Orientation)})
+	}
+	if tags.DateTime != "" {
+		ifd0 = append(ifd0, entry{0x0132, 2, uint32(len(tags.DateTime) + 1), ascii(tags.DateTime)})
+	}
+	if tags.ISO != 0 {
+		exifIFD = append(exifIFD, entry{0x8827, 3, 1, short(tags.ISO)})
+	}
+	if tags.DateTimeOriginal != "" {
+		exifIFD = append(exifIFD, entry{0x9003, 2, uint32(len(tags.DateTimeOriginal) + 1), ascii(tags.DateTimeOriginal)})
+	}
+	if tags.OffsetTimeOriginal != "" {
+		exifIFD = append(exifIFD, entry{0x9011, 2, uint32(len(tags.OffsetTimeOriginal) + 1), ascii(tags.OffsetTimeOriginal)})
+	}
+	if tags.SubSecTimeOriginal != "" {
+		exifIFD = append(exifIFD, entry{0x9291, 2, uint32(len(tags.SubSecTimeOriginal) + 1), ascii(tags.SubSecTimeOriginal)})
+	}
+
+	ifdSize := func(n int) uint32 { return uint32(2 + n*12 + 4) }
+	ifd0Len := len(ifd0)
+	if len(exifIFD) > 0 {
+		ifd0Len++ // Exif IFD pointer
+	}
+	exifOffset := 8 + ifdSize(ifd0Len)
+	dataOffset := exifOffset
+	if len(exifIFD) > 0 {
+		dataOffset += ifdSize(len(exifIFD))
+	}
+
+	var data []byte
+	writeIFD := func(out []byte, entries []entry, extra []byte) []byte {
+		count := len(entries)
+		if extra != nil {
+			count++
+		}
+		out = binary.LittleEndian.AppendUint16(out, uint16(count))
+		for _, e := range entries {
+			out = binary.LittleEndian.AppendUint16(out, e.tag)
+			out = binary.LittleEndian.AppendUint16(out, e.typ)
+			out = binary.LittleEndian.AppendUint32(out, e.count)
+			if len(e.value) > 4 {
+				out = binary.LittleEndian.AppendUint32(out, dataOffset+uint32(len(data)))
+				data = append(data, e.value...)
+			} else {
+				out = append(out, append(e.value, make([]byte, 4-len(e.value))...)...)
+			}
+		}
+		out = append(out, extra...)
+		return binary.LittleEndian.AppendUint32(out, 0)
+	}
+
+	out := []byte{'I', 'I', 0x2a, 0x00, 0x08, 0x00, 0x00, 0x00}
+	var pointer []byte
+	if len(exifIFD) > 0 {
+		pointer = binary.LittleEndian.AppendUint16(nil, 0x8769)
+		pointer = binary.LittleEndian.AppendUint16(pointer, 4)
+		pointer = binary.LittleEndian.AppendUint32(pointer, 1)
+		pointer = binary.LittleEndian.AppendUint32(pointer, exifOffset)
+	}
+	out = writeIFD(out, ifd0, pointer)
+	if len(exifIFD) > 0 {
+		out = writeIFD(out, exifIFD, nil)
+	}
+
+	return append(out, data...)
+}
+
+// stripedImage draws 8px stripes over a gradient. The stripes average out in
+// the downscales the perceptual hashes use, so horizontal and vertical
+// versions hash alike even though they are structurally different.
+func stripedImage(vertical bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			v := 60 + (x+y)/4
+			stripe := y / 4
+			if vertical {
+				stripe = x / 4
+			}
+			if stripe%2 == 0 {
+				v += 40
+			} else {
+				v -= 40
+			}
+			img.Set(x, y, color.RGBA{R: uint8(v / 2), G: uint8(v), B: 200, A: 255})
+		}
+	}
+	return img
+}
+
+// writeImageFile encodes img to path with encode
+func writeImageFile(t testing.TB, path string, img image.Image, encode func(io.Writer, image.Image) error) {
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, encode(file, img))
+}
+
+func TestEngine_OrganizeByClusters(t *testing.T) {
+	photosDir := t.TempDir()
+	for _, name := range []string{"a.png", "a_copy.png"} {
+		writeBlockImage(t, filepath.Join(photosDir, name), 1, 0)
+	}
+	writeBlockImage(t, filepath.Join(photosDir, "b.png"), 2, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	clusters, err := eng.ClusterImages(0.9)
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+	for i := range clusters {
+		if len(clusters[i].Images) == 2 {
+			clusters[i].Name = "2023-07 Paris"
+		}
+	}
+
+	target := t.TempDir()
+	report, err := eng.OrganizeByClusters(clusters, target, api.OrganizeOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Len(t, report.Files, 3)
+	assert.Zero(t, report.Placed)
+	assert.FileExists(t, filepath.Join(photosDir, "b.png"))
+	assert.NoDirExists(t, filepath.Join(target, "2023-07 Paris"))
+
+	report, err = eng.OrganizeByClusters(clusters, target, api.OrganizeOptions{Mode: api.OrganizeMove})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Placed)
+	assert.Zero(t, report.Errors)
+
+	assert.FileExists(t, filepath.Join(target, "2023-07 Paris", "a.png"))
+	assert.FileExists(t, filepath.Join(target, "2023-07 Paris", "a_copy.png"))
+	assert.NoFileExists(t, filepath.Join(photosDir, "b.png"))
+
+	for _, cluster := range clusters {
+		if len(cluster.Images) != 1 {
+			continue
+		}
+		moved := filepath.Join(target, cluster.ClusterID, "b.png")
+		assert.FileExists(t, moved)
+		fp, err := eng.GetFingerprint(cluster.Images[0])
+		require.NoError(t, err)
+		assert.Equal(t, moved, fp.Metadata.Path)
+	}
+
+	// A second run finds every file in place and leaves it there
+	before := listFiles(t, target)
+	report, err = eng.OrganizeByClusters(clusters, target, api.OrganizeOptions{Mode: api.OrganizeMove})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Placed)
+	assert.Equal(t, before, listFiles(t, target))
+}
+
+func TestEngine_OrganizeByDate(t *testing.T) {
+	photosDir := t.TempDir()
+	writeTestJPEG(t, filepath.Join(photosDir, "summer.jpg"), 32, 32, testEXIF{DateTime: "2023:07:14 10:30:00"})
+	require.NoError(t, os.Mkdir(filepath.Join(photosDir, "trip"), 0755))
+	writeTestJPEG(t, filepath.Join(photosDir, "trip", "summer.jpg"), 48, 32, testEXIF{DateTime: "2023:07:20 18:00:00"})
+	undated := createTestImage(t, photosDir, "undated.png")
+	mtime := time.Date(2021, time.March, 5, 12, 0, 0, 0, time.Local)
+	require.NoError(t, os.Chtimes(undated, mtime, mtime))
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	target := t.TempDir()
+	require.NoError(t, eng.OrganizeByDate(target, "2006/01", api.OrganizeOptions{DryRun: true}))
+	assert.NoDirExists(t, filepath.Join(target, "2023"))
+	assert.FileExists(t, undated)
+
+	require.NoError(t, eng.OrganizeByDate(target, "2006/01", api.OrganizeOptions{Mode: api.OrganizeCopy}))
+	assert.FileExists(t, filepath.Join(target, "2023", "07", "summer.jpg"))
+	assert.FileExists(t, filepath.Join(target, "2023", "07", "summer_1.jpg"))
+	assert.FileExists(t, filepath.Join(target, "2021", "03", "undated.png"))
+	assert.FileExists(t, undated)
+}
+
+func TestEngine_OrganizeByDateMoveTwiceChangesNothing(t *testing.T) {
+	photosDir := t.TempDir()
+	writeTestJPEG(t, filepath.Join(photosDir, "summer.jpg"), 32, 32, testEXIF{DateTime: "2023:07:14 10:30:00"})
+	writeTestJPEG(t, filepath.Join(photosDir, "later.jpg"), 48, 32, testEXIF{DateTime: "2023:07:20 18:00:00"})
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+	_, err = eng.ScanFolder(context.Background(), photosDir, nil)
+	require.NoError(t, err)
+
+	target := t.TempDir()
+	require.NoError(t, eng.OrganizeByDate(target, "2006/01", api.OrganizeOptions{Mode: api.OrganizeMove}))
+	want := []string{
+		filepath.Join("2023", "07", "later.jpg"),
+		filepath.Join("2023", "07", "summer.jpg"),
+	}
+	assert.Equal(t, want, listFiles(t, target))
+
+	require.NoError(t, eng.OrganizeByDate(target, "2006/01", api.OrganizeOptions{Mode: api.OrganizeMove}))
+	assert.Equal(t, want, listFiles(t, target))
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	for _, fp := range fingerprints {
+		assert.Equal(t, filepath.Join(target, "2023", "07"), filepath.Dir(fp.Metadata.Path))
+	}
+}
+
+// listFiles returns the sorted paths of the regular files under root, relative to it
+func listFiles(t testing.TB, root string) []string {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		files = append(files, rel)
+		return err
+	})
+	require.NoError(t, err)
+	sort.Strings(files)
+	return files
+}
+
+func TestEngine_FindBursts(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	start := time.Date(2023, time.July, 14, 10, 30, 0, 0, time.UTC)
+	frame := func(id string, offset time.Duration, phash uint64) api.ImageFingerprint {
+		return api.ImageFingerprint{
+			ID: api.ImageID(id),
+			Metadata: api.ImageMetadata{
+				Path:   "/photos/" + id + ".jpg",
+				SHA256: "sha_" + id,
+				EXIF:   &api.EXIFInfo{TakenAt: start.Add(offset)},
+			},
+			PHashes: api.PerceptualHashes{PHash: phash},
+		}
+	}
+
+	var catalog bytes.Buffer
+	encoder := json.NewEncoder(&catalog)
+	for _, fp := range []api.ImageFingerprint{
+		frame("burst_1", 0, 0xF0F0F0F0F0F0F0F0),
+		frame("burst_2", 100*time.Millisecond, 0xF0F0F0F0F0F0F0F1),
+		frame("burst_3", 200*time.Millisecond, 0xF0F0F0F0F0F0F0F3),
+		frame("other", 300*time.Millisecond, 0x0F0F0F0F0F0F0F0F),
+		frame("later", time.Hour, 0xF0F0F0F0F0F0F0F0),
+	} {
+		require.NoError(t, encoder.Encode(fp))
+	}
+	require.NoError(t, eng.ImportCatalog(&catalog))
+
+	groups, err := eng.FindBursts(150, 3)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, api.ReasonBurst, groups[0].Reason)
+
+	members := append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...)
+	assert.ElementsMatch(t, []api.ImageID{"burst_1", "burst_2", "burst_3"}, members)
+}
+
+func TestEngine_FindBurstsUsesSubSecondEXIF(t *testing.T) {
+	// All four frames share a whole second; only SubSecTimeOriginal separates
+	// the burst from the frame taken 600ms later
+	dir := t.TempDir()
+	for name, subSec := range map[string]string{"burst_1": "1", "burst_2": "2", "burst_3": "30", "late": "9"} {
+		writeTestJPEG(t, filepath.Join(dir, name+".jpg"), 32, 32, testEXIF{
+			DateTimeOriginal:   "2023:07:14 10:30:00",
+			OffsetTimeOriginal: "+02:00",
+			SubSecTimeOriginal: subSec,
+		})
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, err = eng.ScanFolder(context.Background(), dir, nil)
+	require.NoError(t, err)
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	names := make(map[api.ImageID]string)
+	for _, fp := range fingerprints {
+		names[fp.ID] = filepath.Base(fp.Metadata.Path)
+		if names[fp.ID] == "burst_3.jpg" {
+			require.NotNil(t, fp.Metadata.EXIF)
+			want := time.Date(2023, time.July, 14, 8, 30, 0, 300*int(time.Millisecond), time.UTC)
+			assert.True(t, fp.Metadata.EXIF.TakenAt.Equal(want), "got %v", fp.Metadata.EXIF.TakenAt)
+		}
+	}
+
+	groups, err := eng.FindBursts(150, 3)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	var members []string
+	for _, id := range append([]api.ImageID{groups[0].MainImage}, groups[0].DuplicateIDs...) {
+		members = append(members, names[id])
+	}
+	assert.ElementsMatch(t, []string{"burst_1.jpg", "burst_2.jpg", "burst_3.jpg"}, members)
+}
+
+// widthHasher hashes an image to its width in pixels
+type widthHasher struct{}
+
+func (widthHasher) Name() string {
+	return "width"
+}
+
+func (widthHasher) Compute(img image.Image) (uint64, error) {
+	return uint64(img.Bounds().Dx()), nil
+}
+
+func TestEngine_RegisterHasher(t *testing.T) {
+	dir := t.TempDir()
+	writeBlockImage(t, filepath.Join(dir, "a.png"), 1, 0)
+	writeBlockImage(t, filepath.Join(dir, "b.png"), 2, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	require.NoError(t, eng.RegisterHasher(widthHasher{}))
+	assert.Error(t, eng.RegisterHasher(widthHasher{}))
+
+	_, err = eng.ScanFolder(context.Background(), dir, nil)
+	require.NoError(t, err)
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 2)
+	for _, fp := range fingerprints {
+		assert.Equal(t, map[string]uint64{"width": 64}, fp.PHashes.Custom)
+	}
+
+	result, err := eng.CompareFiles(filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.HammingDistances["width"])
+}
+
+func TestEngine_WatchIndexesCreatedAndDeletedFiles(t *testing.T) {
+	for name, polling := range map[string]bool{"notify": false, "polling": true} {
+		t.Run(name, func(t *testing.T) {
+			photosDir := t.TempDir()
+
+			cfg := engine.DefaultConfig()
+			cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+			cfg.WatchPolling = polling
+			cfg.WatchInterval = 20 * time.Millisecond
+			cfg.WatchDebounce = 20 * time.Millisecond
+			eng, err := engine.NewEngine(cfg)
+			require.NoError(t, err)
+			defer eng.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			events := make(chan api.IndexEvent, 10)
+			watchErr := make(chan error, 1)
+			go func() { watchErr <- eng.Watch(ctx, photosDir, events) }()
+
+			nextEvent := func() api.IndexEvent {
+				select {
+				case event := <-events:
+					return event
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for an index event")
+					return api.IndexEvent{}
+				}
+			}
+
+			photo := filepath.Join(photosDir, "new.png")
+			writeBlockImage(t, photo, 1, 0)
+			require.NoError(t, os.WriteFile(filepath.Join(photosDir, ".new.png.swp.png"), []byte("partial"), 0644))
+
+			event := nextEvent()
+			assert.Equal(t, api.IndexEventAdded, event.Type)
+			assert.Equal(t, photo, event.Path)
+			fingerprints, err := eng.GetAllFingerprints()
+			require.NoError(t, err)
+			require.Len(t, fingerprints, 1)
+			assert.Equal(t, event.ImageID, fingerprints[0].ID)
+
+			// Folders created while watching are watched too
+			albumDir := filepath.Join(photosDir, "album")
+			require.NoError(t, os.Mkdir(albumDir, 0755))
+			time.Sleep(50 * time.Millisecond)
+			albumPhoto := filepath.Join(albumDir, "trip.png")
+			writeBlockImage(t, albumPhoto, 2, 0)
+
+			event = nextEvent()
+			assert.Equal(t, api.IndexEventAdded, event.Type)
+			assert.Equal(t, albumPhoto, event.Path)
+
+			require.NoError(t, os.Remove(photo))
+			event = nextEvent()
+			assert.Equal(t, api.IndexEventRemoved, event.Type)
+			assert.Equal(t, photo, event.Path)
+			fingerprints, err = eng.GetAllFingerprints()
+			require.NoError(t, err)
+			require.Len(t, fingerprints, 1)
+			assert.Equal(t, albumPhoto, fingerprints[0].Metadata.Path)
+
+			cancel()
+			assert.NoError(t, <-watchErr)
+		})
+	}
+}
+
+func TestEngine_OperationsAfterCloseFail(t *testing.T) {
+	photosDir := t.TempDir()
+	createTestImage(t, photosDir, "a.png")
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	require.NoError(t, eng.Close())
+	require.NoError(t, eng.Close(), "closing twice is harmless")
+
+	ctx := context.Background()
+	_, err = eng.ScanFolder(ctx, photosDir, nil)
+	assert.ErrorIs(t, err, api.ErrEngineClosed)
+	assert.ErrorIs(t, eng.Watch(ctx, photosDir, nil), api.ErrEngineClosed)
+	assert.ErrorIs(t, eng.ScanFS(ctx, fstest.MapFS{}, ".", nil), api.ErrEngineClosed)
+	_, _, err = eng.FindDuplicatesCtx(ctx, 0.9, true)
+	assert.ErrorIs(t, err, api.ErrEngineClosed)
+	_, err = eng.CleanDuplicatesCtx(ctx, api.CleanOptions{DryRun: true})
+	assert.ErrorIs(t, err, api.ErrEngineClosed)
+}
+
+func TestEngine_CloseWaitsForWatch(t *testing.T) {
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.WatchDebounce = 10 * time.Millisecond
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchErr := make(chan error, 1)
+	go func() { watchErr <- eng.Watch(ctx, t.TempDir(), nil) }()
+	time.Sleep(50 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- eng.Close() }()
+
+	// The index stays open while the watch can still write to it
+	select {
+	case <-closed:
+		t.Fatal("Close returned while Watch was running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	assert.NoError(t, <-watchErr)
+	select {
+	case err := <-closed:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after Watch stopped")
+	}
+}
+
+func TestEngine_LoadImageRejectsImagesOverPixelBudget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "large.png")
+	writeBlockImage(t, path, 1, 0)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.MaxDecodePixels = 32 * 32
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	_, _, err = eng.LoadImage(path)
+	assert.ErrorIs(t, err, pkgimaging.ErrImageTooLarge)
+}
+
+func TestEngine_ScanFSIndexesImagesInFS(t *testing.T) {
+	tempDir := t.TempDir()
+	readImage := func(name string) []byte {
+		data, err := os.ReadFile(createTestImage(t, tempDir, name))
+		require.NoError(t, err)
+		return data
+	}
+	original := readImage("a.png")
+	fsys := fstest.MapFS{
+		"photos/a.png":      {Data: original},
+		"photos/copy/a.png": {Data: original},
+		"photos/other.png":  {Data: readImage("other.png")},
+		"photos/notes.txt":  {Data: []byte("not an image")},
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	ctx := context.Background()
+	require.NoError(t, eng.ScanFS(ctx, fsys, "photos", nil))
+
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 3)
+	for _, fp := range fingerprints {
+		assert.True(t, strings.HasPrefix(fp.Metadata.Path, api.FSPathPrefix+"photos:photos/"), fp.Metadata.Path)
+		assert.Equal(t, "png", fp.Metadata.Format)
+	}
+
+	duplicates, err := eng.FindExactDuplicates()
+	require.NoError(t, err)
+	require.Len(t, duplicates, 1)
+	assert.Len(t, duplicates[0].DuplicateIDs, 1)
+
+	// Rescanning replaces entries, and pruning leaves them alone
+	require.NoError(t, eng.ScanFS(ctx, fsys, "photos", nil))
+	pruned, err := eng.PruneMissing()
+	require.NoError(t, err)
+	assert.Zero(t, pruned)
+	fingerprints, err = eng.GetAllFingerprints()
+	require.NoError(t, err)
+	assert.Len(t, fingerprints, 3)
+}
+
+func TestEngine_ScanFSMixedWithDisk(t *testing.T) {
+	photosDir := t.TempDir()
+	shot := filepath.Join(photosDir, "shot.png")
+	writeBlockImage(t, shot, 7, 0)
+	shotData, err := os.ReadFile(shot)
+	require.NoError(t, err)
+	shotCopy := filepath.Join(photosDir, "shot_copy.png")
+	require.NoError(t, os.WriteFile(shotCopy, shotData, 0644))
+
+	readBlockImage := func(seed int64, brighten int) []byte {
+		path := filepath.Join(t.TempDir(), "image.png")
+		writeBlockImage(t, path, seed, brighten)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return data
+	}
+	backup := fstest.MapFS{
+		"archive/shot.png":      {Data: shotData},
+		"archive/shot_edit.png": {Data: readBlockImage(7, 2)},
+	}
+	phone := fstest.MapFS{
+		"archive/shot.png": {Data: readBlockImage(99, 0)},
+	}
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	cfg.VerifySSIM = true
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	defer eng.Close()
+
+	ctx := context.Background()
+	_, err = eng.ScanFolder(ctx, photosDir, nil)
+	require.NoError(t, err)
+	require.NoError(t, eng.ScanFSWithLabel(ctx, backup, "backup", "archive", nil))
+	require.NoError(t, eng.ScanFSWithLabel(ctx, phone, "phone", "archive", nil))
+	assert.Error(t, eng.ScanFSWithLabel(ctx, phone, "", "archive", nil))
+	assert.Error(t, eng.ScanFSWithLabel(ctx, phone, "a:b", "archive", nil))
+
+	// The same name in two file systems makes two entries
+	fingerprints, err := eng.GetAllFingerprints()
+	require.NoError(t, err)
+	pathIDs := make(map[string]api.ImageID)
+	for _, fp := range fingerprints {
+		pathIDs[fp.Metadata.Path] = fp.ID
+	}
+	assert.Len(t, pathIDs, 5)
+	assert.Contains(t, pathIDs, "fs:backup:archive/shot.png")
+	assert.Contains(t, pathIDs, "fs:phone:archive/shot.png")
+
+	// An FS image that cannot be re-read for SSIM still joins its near group
+	near, err := eng.FindNearDuplicates(0.9)
+	require.NoError(t, err)
+	var grouped []api.ImageID
+	for _, group := range near {
+		grouped = append(grouped, group.MainImage)
+		grouped = append(grouped, group.DuplicateIDs...)
+	}
+	assert.Contains(t, grouped, pathIDs["fs:backup:archive/shot_edit.png"])
+
+	// The disk copies are verified byte by byte and the FS copy by its SHA256;
+	// one disk copy is kept and the FS copies are left alone
+	report, err := eng.CleanDuplicates(api.CleanOptions{
+		DryRun:                 true,
+		MoveDuplicates:         true,
+		OutputDir:              t.TempDir(),
+		MaxSimilarityThreshold: 0.9,
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Actions, 1)
+	assert.Contains(t, []string{shot, shotCopy}, report.Actions[0].Source)
+	assert.True(t, strings.HasPrefix(report.Actions[0].GroupID, "exact_"), report.Actions[0].GroupID)
+}
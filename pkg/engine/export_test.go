@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"image"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// LoadImage exposes loadImage to the engine_test package
+func (e *Engine) LoadImage(path string) (image.Image, api.ImageMetadata, error) {
+	return e.loadImage(path, nil)
+}
+
+// DecodeCount reports how many image files the engine has decoded
+func (e *Engine) DecodeCount() int64 {
+	return e.decodes.Load()
+}
+
+// PeakConcurrentDecodes reports the most images held under the memory limit at once
+func (e *Engine) PeakConcurrentDecodes() int {
+	e.memory.mu.Lock()
+	defer e.memory.mu.Unlock()
+	return e.memory.peak
+}
+
+// ReserveDecode exposes reserveDecode, returning the caller's release and the
+// function that adds another holder, as a decode goroutine does
+func (e *Engine) ReserveDecode(path string) (release func(), hold func() func()) {
+	reservation := e.reserveDecode(path)
+	return reservation.release, reservation.hold
+}
+
+// ReservedDecodeBytes reports the memory currently reserved for decodes
+func (e *Engine) ReservedDecodeBytes() int64 {
+	e.memory.mu.Lock()
+	defer e.memory.mu.Unlock()
+	return e.memory.used
+}
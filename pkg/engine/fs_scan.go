@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+)
+
+// ScanFS indexes the images under root in fsys, such as a zip archive or
+// embedded files, so they take part in duplicate detection and clustering.
+// It is ScanFSWithLabel labelled with root, so file systems scanned from the
+// same root share index entries; use ScanFSWithLabel to keep them apart.
+func (e *Engine) ScanFS(ctx context.Context, fsys fs.FS, root string, progress chan<- api.ScanProgress) error {
+	return e.ScanFSWithLabel(ctx, fsys, rootLabel(root), root, progress)
+}
+
+// ScanFSWithLabel indexes the images under root in fsys like ScanFS. label
+// names fsys in the index: each image is indexed with the path
+// "fs:<label>:<name>", so file systems scanned under different labels never
+// share entries, and rescanning under the same label replaces the entries of
+// each name under their existing ID. The files cannot be moved, so clean and
+// organize operations leave them alone. Files are processed one at a time.
+// If ctx is cancelled, the images already processed are saved and ctx.Err()
+// is returned.
+func (e *Engine) ScanFSWithLabel(ctx context.Context, fsys fs.FS, label, root string, progress chan<- api.ScanProgress) error {
+	if label == "" || strings.Contains(label, ":") {
+		return fmt.Errorf("invalid file system label %q: it must be non-empty and contain no colon", label)
+	}
+
+	if err := e.begin(); err != nil {
+		return err
+	}
+	defer e.active.Done()
+
+	names, err := e.scanner.ScanFS(ctx, fsys, root)
+	limitReached := errors.Is(err, api.ErrScanLimitReached)
+	if err != nil && !limitReached {
+		return err
+	}
+
+	startTime := time.Now()
+	batch := make([]api.ImageFingerprint, 0, saveBatchSize)
+	processed, skipped := 0, 0
+	for i, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+
+		fp, err := e.processFSImage(fsys, label, name)
+		if err != nil {
+			e.logger.Warnf("Failed to process image %s: %v", name, err)
+			skipped++
+			continue
+		}
+
+		// A rescan replaces the entry of the same name, keeping its ID
+		if indexed, id, err := e.index.Exists(fp.Metadata.Path); err == nil && indexed {
+			if old, err := e.index.GetFingerprint(id); err == nil {
+				fp.ID = id
+				fp.CreatedAt = old.CreatedAt
+			}
+		}
+
+		batch = append(batch, fp)
+		if len(batch) == saveBatchSize {
+			saved := e.saveBatch(batch)
+			processed += saved
+			skipped += len(batch) - saved
+			batch = batch[:0]
+		}
+
+		if progress != nil {
+			progress <- api.ScanProgress{
+				Current:     i + 1,
+				Total:       len(names),
+				CurrentFile: name,
+				Percentage:  float64(i+1) / float64(len(names)) * 100,
+			}
+		}
+	}
+	saved := e.saveBatch(batch)
+	processed += saved
+	skipped += len(batch) - saved
+
+	if ctx.Err() != nil {
+		e.logger.Infof("File system scan cancelled after saving %d images", processed)
+		return ctx.Err()
+	}
+
+	e.logger.Infof("File system scan completed. Processed %d images (%d skipped) in %v", processed, skipped, time.Since(startTime))
+	if limitReached {
+		return api.ErrScanLimitReached
+	}
+	return nil
+}
+
+// processFSImage analyzes the image called name in the file system labelled label
+func (e *Engine) processFSImage(fsys fs.FS, label, name string) (api.ImageFingerprint, error) {
+	fingerprint := api.ImageFingerprint{CreatedAt: time.Now()}
+	path := fsPath(label, name)
+	fingerprint.ID = api.ImageID(generateImageID(path))
+
+	img, metadata, err := e.loadFSImage(fsys, name, path)
+	if err != nil {
+		return fingerprint, fmt.Errorf("failed to load image %s: %w", path, err)
+	}
+
+	fingerprint.Metadata = metadata
+	e.analyzeImage(&fingerprint, img)
+	return fingerprint, nil
+}
+
+// loadFSImage reads and decodes the image called name in fsys, indexed as
+// path, like loadImage does for files on disk
+func (e *Engine) loadFSImage(fsys fs.FS, name, path string) (image.Image, api.ImageMetadata, error) {
+	metadata := api.ImageMetadata{Path: path}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("failed to get file info: %w", err)
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, metadata, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	metadata.SizeBytes = int64(len(data))
+	metadata.ModifiedAt = info.ModTime()
+	sum := sha256.Sum256(data)
+	metadata.SHA256 = hex.EncodeToString(sum[:])
+
+	// Animations yield their middle frame
+	var img image.Image
+	var format string
+	var frames int
+	err = pkgimaging.DecodeWithLimits(context.Background(), bytes.NewReader(data), e.decodeLimits(), func(r io.Reader) error {
+		var err error
+		img, format, frames, err = pkgimaging.DecodeRepresentativeFrame(r)
+		return err
+	})
+	if err != nil {
+		return nil, metadata, fmt.Errorf("failed to decode image: %w", err)
+	}
+	e.decodes.Add(1)
+	metadata.Format = format
+	metadata.FrameCount = frames
+
+	orientation := e.exif.ReadOrientationFrom(bytes.NewReader(data))
+	img = e.orientImage(img, orientation, &metadata)
+
+	// Extract EXIF metadata; files without EXIF keep a nil EXIF field
+	exifInfo, err := e.exif.ExtractEXIFFrom(bytes.NewReader(data), metadata.Path)
+	if err != nil {
+		e.logger.Debugf("No EXIF metadata extracted from %s: %v", metadata.Path, err)
+	} else {
+		metadata.EXIF = exifInfo
+	}
+
+	return img, metadata, nil
+}
+
+// rootLabel derives the label ScanFS indexes a file system under from its root
+func rootLabel(root string) string {
+	return strings.ReplaceAll(path.Clean(root), ":", "_")
+}
+
+// fsPath returns the index path of the image called name in the file system labelled label
+func fsPath(label, name string) string {
+	return api.FSPathPrefix + label + ":" + name
+}
+
+// isFSPath reports whether path belongs to an image indexed by ScanFS
+func isFSPath(path string) bool {
+	return strings.HasPrefix(path, api.FSPathPrefix)
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"image"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+	"github.com/disintegration/imaging"
+)
+
+// normalizeHashSize rounds size up to a multiple of 8 between DefaultHashSize
+// and MaximumHashSize, so every row of a wide hash fills whole bytes
+func normalizeHashSize(size int) int {
+	size = (size + 7) / 8 * 8
+	return max(api.DefaultHashSize, min(size, api.MaximumHashSize))
+}
+
+// computeWideHashes adds the HashSize x HashSize versions of the enabled
+// aHash, pHash and dHash to hashes. They are sampled the same way as their
+// 64-bit counterparts, on a grid HashSize wide instead of 8.
+func (e *Engine) computeWideHashes(img image.Image, hashes *api.PerceptualHashes) {
+	n := e.config.HashConfig.HashSize
+
+	if e.config.HashConfig.ComputeAHash {
+		values := luminanceGrid(img, n, n)
+		hashes.AHashWide = aboveMeanBits(values, n*n)
+	}
+
+	// Like the 64-bit pHash, a grid four times wider is folded onto the hash
+	if e.config.HashConfig.ComputePHash {
+		values := luminanceGrid(img, 4*n, 4*n)
+		hashes.PHashWide = aboveMeanBits(values, n*n)
+	}
+
+	if e.config.HashConfig.ComputeDHash {
+		values := luminanceGrid(img, n+1, n)
+		wide := make([]byte, n*n/8)
+		for y := 0; y < n; y++ {
+			for x := 0; x < n; x++ {
+				if values[y*(n+1)+x+1] > values[y*(n+1)+x] {
+					bit := y*n + x
+					wide[bit/8] |= 1 << uint(bit%8)
+				}
+			}
+		}
+		hashes.DHashWide = wide
+	}
+}
+
+// luminanceGrid resizes img to width x height and returns its luminance
+// values in row-major order
+func luminanceGrid(img image.Image, width, height int) []float64 {
+	resized := imaging.Resize(img, width, height, imaging.Lanczos)
+	values := make([]float64, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			values = append(values, pkgimaging.Luminance(resized.At(x, y)))
+		}
+	}
+	return values
+}
+
+// aboveMeanBits sets bit i%bits for every value above the mean
+func aboveMeanBits(values []float64, bits int) []byte {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	average := sum / float64(len(values))
+
+	hash := make([]byte, bits/8)
+	for i, value := range values {
+		if value > average {
+			bit := i % bits
+			hash[bit/8] |= 1 << uint(bit%8)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// Hasher computes a 64-bit perceptual hash of an image. Images are compared
+// on a hash by the number of bits their values differ in, so similar images
+// should get hashes that differ in few bits.
+type Hasher interface {
+	Name() string
+	Compute(img image.Image) (uint64, error)
+}
+
+// hashFunc adapts a hash function to the Hasher interface
+type hashFunc struct {
+	name    string
+	compute func(image.Image) (uint64, error)
+}
+
+// Name returns the hash's name
+func (h hashFunc) Name() string {
+	return h.name
+}
+
+// Compute hashes img
+func (h hashFunc) Compute(img image.Image) (uint64, error) {
+	return h.compute(img)
+}
+
+// defaultHasher pairs a built-in hasher with its HashConfig switch and the
+// PerceptualHashes field it fills
+type defaultHasher struct {
+	Hasher
+	enabled bool
+	field   func(*api.PerceptualHashes) *uint64
+}
+
+// defaultHashers returns the built-in aHash, pHash, dHash and wHash
+func (e *Engine) defaultHashers() []defaultHasher {
+	cfg := e.config.HashConfig
+	return []defaultHasher{
+		{hashFunc{"ahash", e.computeAHash}, cfg.ComputeAHash, func(h *api.PerceptualHashes) *uint64 { return &h.AHash }},
+		{hashFunc{"phash", e.computePHash}, cfg.ComputePHash, func(h *api.PerceptualHashes) *uint64 { return &h.PHash }},
+		{hashFunc{"dhash", e.computeDHash}, cfg.ComputeDHash, func(h *api.PerceptualHashes) *uint64 { return &h.DHash }},
+		{hashFunc{"whash", e.computeWHash}, cfg.ComputeWHash, func(h *api.PerceptualHashes) *uint64 { return &h.WHash }},
+	}
+}
+
+// RegisterHasher adds a custom hash computed for every image processed from
+// now on and stored in PerceptualHashes.Custom under its name. Names must be
+// unique and may not be one of the built-in ahash, phash, dhash or whash.
+// Register hashers before scanning; they are not safe to add during a scan.
+func (e *Engine) RegisterHasher(h Hasher) error {
+	name := h.Name()
+	if name == "" {
+		return fmt.Errorf("failed to register hasher: empty name")
+	}
+	for _, builtin := range e.defaultHashers() {
+		if builtin.Name() == name {
+			return fmt.Errorf("failed to register hasher: %s is a built-in hash", name)
+		}
+	}
+	for _, registered := range e.hashers {
+		if registered.Name() == name {
+			return fmt.Errorf("failed to register hasher: %s is already registered", name)
+		}
+	}
+
+	e.hashers = append(e.hashers, h)
+	return nil
+}
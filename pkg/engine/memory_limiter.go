@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"image"
+	"os"
+	"sync"
+
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+)
+
+// memoryLimiter is a weighted semaphore over an estimate of decoded image
+// memory. It admits images while their combined estimate fits its capacity;
+// an image larger than the whole capacity waits until it can run alone.
+type memoryLimiter struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	capacity int64
+	used     int64
+	inFlight int
+	peak     int
+}
+
+// newMemoryLimiter creates a limiter of capacity bytes; zero means unlimited
+func newMemoryLimiter(capacity int64) *memoryLimiter {
+	l := &memoryLimiter{capacity: capacity}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until bytes fit alongside the reservations in flight and
+// returns the function that releases the reservation
+func (l *memoryLimiter) acquire(bytes int64) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.capacity > 0 {
+		bytes = min(bytes, l.capacity)
+		for l.used+bytes > l.capacity {
+			l.cond.Wait()
+		}
+	}
+	l.used += bytes
+	l.inFlight++
+	l.peak = max(l.peak, l.inFlight)
+
+	return func() {
+		l.mu.Lock()
+		l.used -= bytes
+		l.inFlight--
+		l.mu.Unlock()
+		l.cond.Broadcast()
+	}
+}
+
+// decodeReservation is the memory reserved for decoding one image. It is
+// freed once its last holder is done, so a decode abandoned after
+// DecodeTimeout keeps its share of MaxMemoryMB until its goroutine returns.
+type decodeReservation struct {
+	mu      sync.Mutex
+	holders int
+	free    func()
+}
+
+// hold adds a holder, such as a decode goroutine that may outlive the
+// caller, and returns the function that drops it. A nil reservation holds nothing.
+func (r *decodeReservation) hold() func() {
+	if r == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	r.holders++
+	r.mu.Unlock()
+	return r.release
+}
+
+// release drops a holder, freeing the memory when it was the last one
+func (r *decodeReservation) release() {
+	r.mu.Lock()
+	r.holders--
+	last := r.holders == 0
+	r.mu.Unlock()
+	if last {
+		r.free()
+	}
+}
+
+// reserveDecode blocks until the image at path can be decoded without the
+// decodes in flight exceeding MaxMemoryMB, and returns the reservation, held
+// by the caller until it calls release. The footprint is estimated at four
+// bytes per pixel from the image header; files whose header cannot be read
+// reserve nothing.
+func (e *Engine) reserveDecode(path string) *decodeReservation {
+	cfg, ok := e.scanner.ImageConfig(path)
+	if !ok {
+		cfg, ok = readImageConfig(path)
+	}
+	var estimate int64
+	if ok {
+		estimate = int64(cfg.Width) * int64(cfg.Height) * 4
+	}
+	return &decodeReservation{holders: 1, free: e.memory.acquire(estimate)}
+}
+
+// readImageConfig reads the dimensions from an image file's header
+func readImageConfig(path string) (image.Config, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, false
+	}
+	defer file.Close()
+
+	cfg, _, err := pkgimaging.DecodeConfig(file)
+	return cfg, err == nil
+}
@@ -1,6 +1,10 @@
 package engine
 
-import "github.com/HaiderBassem/imaged/internal/quality"
+import (
+	"github.com/HaiderBassem/imaged/internal/quality"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+)
 
 // DefaultConfig returns sensible default configuration for the engine
 func DefaultConfig() EngineConfig {
@@ -17,7 +21,14 @@ func DefaultConfig() EngineConfig {
 			ComputeWHash: false,
 			HashSize:     8,
 		},
-		QualityConfig: quality.DefaultConfig(),
+		QualityConfig:             quality.DefaultConfig(),
+		LSHTables:                 8,
+		LSHHashesPerTable:         16,
+		SelectionPolicy:           api.PolicyHighestQuality,
+		RecompressedMinConfidence: 0.95,
+		SSIMThreshold:             0.8,
+		MaxDecodePixels:           pkgimaging.DefaultDecodeLimits().MaxPixels,
+		DecodeTimeout:             pkgimaging.DefaultDecodeLimits().Timeout,
 	}
 }
 
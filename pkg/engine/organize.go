@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/filesystem"
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// OrganizeByClusters places the members of each cluster in a folder of their
+// own under targetRoot, named after the cluster or, for unnamed clusters, its
+// ID. Files are moved, copied or linked as opts.Mode says, taking a free name
+// when one is already used in the folder; moves update the index. Files
+// already in their folder are left alone, so reorganizing is a no-op. A failure
+// on one file is counted in the report and does not stop the others.
+func (e *Engine) OrganizeByClusters(clusters []api.Cluster, targetRoot string, opts api.OrganizeOptions) (*api.OrganizeReport, error) {
+	if targetRoot == "" {
+		return nil, fmt.Errorf("failed to organize clusters: no target directory")
+	}
+
+	report := &api.OrganizeReport{}
+	paths := filesystem.NewPathUtils()
+	used := make(map[string]bool)
+
+	for _, cluster := range clusters {
+		folder := clusterFolderName(cluster)
+		if used[folder] {
+			// Clusters can share a name, such as two trips in the same month
+			folder = folder + "_" + sanitizeFolderName(cluster.ClusterID)
+		}
+		used[folder] = true
+
+		dir, err := paths.SafeJoin(targetRoot, folder)
+		if err != nil {
+			e.logger.Warnf("Skipping cluster %s: %v", cluster.ClusterID, err)
+			report.Errors += len(cluster.Images)
+			continue
+		}
+
+		for _, id := range cluster.Images {
+			fp, err := e.index.GetFingerprint(id)
+			if err != nil {
+				e.logger.Warnf("Failed to load fingerprint %s: %v", id, err)
+				report.Errors++
+				continue
+			}
+
+			file := api.OrganizedFile{
+				ImageID: id,
+				Source:  fp.Metadata.Path,
+				Dest:    filepath.Join(dir, filepath.Base(fp.Metadata.Path)),
+				Folder:  folder,
+			}
+
+			if opts.DryRun {
+				e.logger.Infof("DRY RUN: would place %s in %s", file.Source, dir)
+				report.Files = append(report.Files, file)
+				continue
+			}
+
+			dest, err := e.placeFile(fp, dir, opts.Mode)
+			if err != nil {
+				e.logger.Warnf("Failed to organize %s: %v", file.Source, err)
+				report.Errors++
+				continue
+			}
+
+			file.Dest = dest
+			report.Files = append(report.Files, file)
+			report.Placed++
+		}
+	}
+
+	e.logger.Infof("Organized %d files into %d cluster folders", report.Placed, len(used))
+	return report, nil
+}
+
+// OrganizeByDate places every indexed image in a folder under targetRoot
+// named by formatting its EXIF capture date, or its file modification time
+// when it has none, with layout, a time layout such as "2006/01". Files are
+// moved, copied or linked as opts.Mode says, taking a free name when one is
+// already used in the folder; files already in their folder are left alone.
+// Every image is attempted; the error reports
+// how many could not be placed.
+func (e *Engine) OrganizeByDate(targetRoot string, layout string, opts api.OrganizeOptions) error {
+	if targetRoot == "" || layout == "" {
+		return fmt.Errorf("failed to organize by date: target directory and layout are required")
+	}
+
+	fingerprints, err := e.index.GetAllFingerprints()
+	if err != nil {
+		return fmt.Errorf("failed to get fingerprints: %w", err)
+	}
+
+	// Place files in path order so name conflicts resolve the same way every run
+	sort.Slice(fingerprints, func(i, j int) bool {
+		return fingerprints[i].Metadata.Path < fingerprints[j].Metadata.Path
+	})
+
+	paths := filesystem.NewPathUtils()
+	var placed, failed int
+	var firstErr error
+	for i := range fingerprints {
+		fp := &fingerprints[i]
+		dir, err := paths.SafeJoin(targetRoot, filepath.FromSlash(captureTime(fp.Metadata).Format(layout)))
+		if err == nil && !opts.DryRun {
+			_, err = e.placeFile(fp, dir, opts.Mode)
+		}
+		if err != nil {
+			e.logger.Warnf("Failed to organize %s: %v", fp.Metadata.Path, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+
+		if opts.DryRun {
+			e.logger.Infof("DRY RUN: would place %s in %s", fp.Metadata.Path, dir)
+			continue
+		}
+		placed++
+	}
+
+	e.logger.Infof("Organized %d files by date", placed)
+	if failed > 0 {
+		return fmt.Errorf("failed to organize %d of %d images: %w", failed, len(fingerprints), firstErr)
+	}
+	return nil
+}
+
+// captureTime returns when an image was taken according to its EXIF data,
+// falling back to the file's modification time
+func captureTime(meta api.ImageMetadata) time.Time {
+	if meta.EXIF != nil && !meta.EXIF.TakenAt.IsZero() {
+		return meta.EXIF.TakenAt
+	}
+	return meta.ModifiedAt
+}
+
+// placeFile moves, copies or links fp's file into dir and returns where it
+// landed. A file that is already in dir is left where it is.
+func (e *Engine) placeFile(fp *api.ImageFingerprint, dir string, mode api.OrganizeMode) (string, error) {
+	src := fp.Metadata.Path
+	if isFSPath(src) {
+		return "", fmt.Errorf("%s is in a read-only file system", src)
+	}
+
+	// Placing a file in its own folder would collide with itself and rename
+	// it to a free name on every run
+	if inDir(src, dir) {
+		return src, nil
+	}
+
+	switch mode {
+	case api.OrganizeCopy:
+		return e.organizer.CopyFile(src, dir)
+	case api.OrganizeSymlink:
+		return e.organizer.LinkFile(src, dir)
+	case api.OrganizeMove:
+		dest, err := e.organizer.MoveFile(src, dir)
+		if err != nil {
+			return "", err
+		}
+		fp.Metadata.Path = dest
+		if err := e.index.UpdateMetadata(fp.ID, fp.Metadata); err != nil {
+			e.logger.Warnf("Failed to update fingerprint after move: %v", err)
+		}
+		return dest, nil
+	default:
+		return "", fmt.Errorf("unknown organize mode: %d", mode)
+	}
+}
+
+// inDir reports whether path is directly inside dir
+func inDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	return filepath.Dir(absPath) == absDir
+}
+
+// clusterFolderName returns the folder a cluster's members are placed in
+func clusterFolderName(cluster api.Cluster) string {
+	if name := sanitizeFolderName(cluster.Name); name != "" {
+		return name
+	}
+	return sanitizeFolderName(cluster.ClusterID)
+}
+
+// sanitizeFolderName replaces characters that are not allowed in folder names
+// on common filesystems, and trims the spaces and dots Windows rejects at the ends
+func sanitizeFolderName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(name, " .")
+}
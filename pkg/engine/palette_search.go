@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// FindByPalette returns the indexed images whose stored palette is within
+// tolerance of colors, closest palette first. The distance is the CIE76
+// Delta E between the palettes, so images match on their colors alone,
+// whatever their content. Only images indexed with a PaletteSize have a
+// palette to compare.
+func (e *Engine) FindByPalette(colors []color.Color, tolerance float64) ([]api.ImageFingerprint, error) {
+	if len(colors) == 0 {
+		return nil, fmt.Errorf("failed to search by palette: no query colors")
+	}
+
+	fingerprints, err := e.index.GetAllFingerprints()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fingerprints: %w", err)
+	}
+
+	type match struct {
+		fp       api.ImageFingerprint
+		distance float64
+	}
+	var matches []match
+	for _, fp := range fingerprints {
+		if len(fp.Palette) == 0 {
+			continue
+		}
+		if distance := e.paletteDistance(colors, fp.Palette); distance <= tolerance {
+			matches = append(matches, match{fp: fp, distance: distance})
+		}
+	}
+
+	// Ties are ordered by ID for stable output
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].fp.ID < matches[j].fp.ID
+	})
+
+	results := make([]api.ImageFingerprint, len(matches))
+	for i, m := range matches {
+		results[i] = m.fp
+	}
+
+	e.logger.Infof("Found %d images matching a palette of %d colors", len(results), len(colors))
+	return results, nil
+}
+
+// paletteDistance measures how far apart a query palette and a stored one
+// are. It averages two directions: how close each query color comes to the
+// stored palette, and how close the stored colors, weighted by coverage, come
+// to the query, so an image whose main color is missing from the query does
+// not match on a small patch alone.
+func (e *Engine) paletteDistance(query []color.Color, palette []api.PaletteColor) float64 {
+	stored := make([]color.Color, len(palette))
+	for i, c := range palette {
+		stored[i] = color.RGBA{R: c.R, G: c.G, B: c.B, A: 255}
+	}
+
+	var forward float64
+	for _, q := range query {
+		forward += e.nearestColorDistance(q, stored)
+	}
+	forward /= float64(len(query))
+
+	var backward, coverage float64
+	for i, s := range stored {
+		backward += palette[i].Coverage * e.nearestColorDistance(s, query)
+		coverage += palette[i].Coverage
+	}
+	if coverage > 0 {
+		backward /= coverage
+	}
+
+	return (forward + backward) / 2
+}
+
+// nearestColorDistance returns the LAB distance from c to the closest of colors
+func (e *Engine) nearestColorDistance(c color.Color, colors []color.Color) float64 {
+	nearest := math.Inf(1)
+	for _, other := range colors {
+		nearest = math.Min(nearest, e.colorSpace.ColorDistance(c, other))
+	}
+	return nearest
+}
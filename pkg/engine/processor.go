@@ -102,6 +102,61 @@ func (p *Processor) worker(ctx context.Context, id int, jobs <-chan string, resu
 	}
 }
 
+// ProcessResult is the outcome of processing a single image
+type ProcessResult struct {
+	Path        string
+	Fingerprint api.ImageFingerprint
+	Err         error
+}
+
+// ProcessStream processes images concurrently and delivers each result as soon as it is ready.
+// On cancellation workers stop taking new paths but still deliver every fingerprint they have
+// already computed, so callers must drain the channel until it is closed.
+func (p *Processor) ProcessStream(ctx context.Context, imagePaths []string) <-chan ProcessResult {
+	workers := p.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	results := make(chan ProcessResult, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for path := range jobs {
+				if ctx.Err() != nil {
+					p.logger.Debugf("Worker %d stopping due to context cancellation", id)
+					return
+				}
+				fingerprint, err := p.engine.processImage(path)
+				results <- ProcessResult{Path: path, Fingerprint: fingerprint, Err: err}
+			}
+		}(i)
+	}
+
+	// Feed jobs until the paths run out or the context is cancelled
+	go func() {
+		defer close(jobs)
+		for _, path := range imagePaths {
+			select {
+			case jobs <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
 // ProcessWithProgress processes images with progress reporting
 func (p *Processor) ProcessWithProgress(ctx context.Context, imagePaths []string, progress chan<- api.ScanProgress) ([]api.ImageFingerprint, error) {
 	total := len(imagePaths)
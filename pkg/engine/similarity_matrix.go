@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+)
+
+// SimilarityMatrix returns the pairwise CompareFingerprints scores of the
+// images with the given IDs, row and column i belonging to ids[i]. A nil ids
+// covers every indexed image in ID order. The matrix is symmetric, so each
+// pair is compared once, and its diagonal is 1.
+func (e *Engine) SimilarityMatrix(ids []api.ImageID) ([][]float64, error) {
+	fingerprints, err := e.matrixFingerprints(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make([][]float64, len(fingerprints))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(fingerprints))
+		matrix[i][i] = 1
+	}
+	for i := range fingerprints {
+		for j := i + 1; j < len(fingerprints); j++ {
+			score, err := e.similarity.CompareFingerprints(fingerprints[i], fingerprints[j])
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s and %s: %w", fingerprints[i].ID, fingerprints[j].ID, err)
+			}
+			matrix[i][j], matrix[j][i] = score, score
+		}
+	}
+
+	return matrix, nil
+}
+
+// WriteSimilarityMatrixCSV writes the similarity matrix of ids to w as CSV,
+// with a header row and a first column of image IDs
+func (e *Engine) WriteSimilarityMatrixCSV(w io.Writer, ids []api.ImageID) error {
+	if ids == nil {
+		fingerprints, err := e.matrixFingerprints(nil)
+		if err != nil {
+			return err
+		}
+		ids = make([]api.ImageID, len(fingerprints))
+		for i, fp := range fingerprints {
+			ids[i] = fp.ID
+		}
+	}
+
+	matrix, err := e.SimilarityMatrix(ids)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"image_id"}
+	for _, id := range ids {
+		header = append(header, string(id))
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for i, id := range ids {
+		row := []string{string(id)}
+		for _, score := range matrix[i] {
+			row = append(row, strconv.FormatFloat(score, 'f', 4, 64))
+		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write similarity matrix: %w", err)
+	}
+	return nil
+}
+
+// matrixFingerprints loads the fingerprints of ids in order, or every
+// fingerprint sorted by ID when ids is nil
+func (e *Engine) matrixFingerprints(ids []api.ImageID) ([]api.ImageFingerprint, error) {
+	if ids == nil {
+		fingerprints, err := e.index.GetAllFingerprints()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve fingerprints: %w", err)
+		}
+		sort.Slice(fingerprints, func(i, j int) bool {
+			return fingerprints[i].ID < fingerprints[j].ID
+		})
+		return fingerprints, nil
+	}
+
+	fingerprints := make([]api.ImageFingerprint, len(ids))
+	for i, id := range ids {
+		fp, err := e.index.GetFingerprint(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fingerprint %s: %w", id, err)
+		}
+		fingerprints[i] = *fp
+	}
+	return fingerprints, nil
+}
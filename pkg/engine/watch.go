@@ -0,0 +1,373 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultWatchInterval is how often Watch polls when WatchInterval is unset
+const defaultWatchInterval = 2 * time.Second
+
+// defaultWatchDebounce is how long Watch waits for notifications to stop when WatchDebounce is unset
+const defaultWatchDebounce = 500 * time.Millisecond
+
+// fileStamp is the size and modification time Watch compares to spot changes
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// equal reports whether two stamps describe the same file contents
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime)
+}
+
+// watchedFile is an indexed file under the watched folder
+type watchedFile struct {
+	id    api.ImageID
+	stamp fileStamp
+}
+
+// watchState is what Watch knows about the folder between polls
+type watchState struct {
+	indexed map[string]watchedFile // files in the index
+	seen    map[string]fileStamp   // files found by the previous poll
+	failed  map[string]fileStamp   // files that could not be processed, until they change
+}
+
+// Watch keeps the index in step with the image files under root until ctx is
+// done. It indexes new files, reprocesses changed ones under their existing ID
+// and removes the entries of deleted ones, reporting each change on events
+// when it is non-nil. The folder tree is watched with OS file notifications
+// and rescanned once they have been quiet for WatchDebounce; with
+// WatchPolling set, or when notifications are unavailable, it is polled every
+// WatchInterval instead. A new or changed file is only processed once its size
+// and modification time have held across two rescans, so files still being
+// written or copied are left until they are complete; hidden and editor
+// temporary files are ignored. Close waits for Watch to return, so cancel ctx
+// before closing the engine.
+func (e *Engine) Watch(ctx context.Context, root string, events chan<- api.IndexEvent) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	defer e.active.Done()
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	state := &watchState{
+		indexed: make(map[string]watchedFile),
+		failed:  make(map[string]fileStamp),
+	}
+	prefix := absRoot + string(filepath.Separator)
+	err = e.index.IterateFingerprints(ctx, func(fp api.ImageFingerprint) error {
+		if strings.HasPrefix(fp.Metadata.Path, prefix) {
+			state.indexed[fp.Metadata.Path] = watchedFile{
+				id:    fp.ID,
+				stamp: fileStamp{size: fp.Metadata.SizeBytes, modTime: fp.Metadata.ModifiedAt},
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate fingerprints: %w", err)
+	}
+
+	if !e.config.WatchPolling {
+		watcher, err := newFolderWatcher(absRoot)
+		if err == nil {
+			defer watcher.Close()
+			return e.watchNotify(ctx, absRoot, state, watcher, events)
+		}
+		e.logger.Warnf("File notifications are unavailable for %s, polling instead: %v", absRoot, err)
+	}
+	return e.watchPoll(ctx, absRoot, state, events)
+}
+
+// watchNotify rescans root whenever its file notifications have been quiet
+// for WatchDebounce, and again after that while files are still settling
+func (e *Engine) watchNotify(ctx context.Context, root string, state *watchState, watcher *fsnotify.Watcher, events chan<- api.IndexEvent) error {
+	debounce := e.config.WatchDebounce
+	if debounce <= 0 {
+		debounce = defaultWatchDebounce
+	}
+
+	// Fire at once for the initial scan
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	e.logger.Infof("Watching %s for changes", root)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("file watcher closed unexpectedly")
+			}
+			if isTemporaryFile(event.Name) {
+				continue
+			}
+			// Watches are per directory, so new subdirectories need their own
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchDirs(watcher, event.Name); err != nil {
+						e.logger.Warnf("Failed to watch new folder %s: %v", event.Name, err)
+					}
+				}
+			}
+			resetTimer(timer, debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("file watcher closed unexpectedly")
+			}
+			// Notifications may have been dropped; a rescan catches up
+			e.logger.Warnf("File watcher error for %s: %v", root, err)
+			resetTimer(timer, debounce)
+
+		case <-timer.C:
+			settling, err := e.rescanFolder(ctx, root, state, events)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if settling {
+				timer.Reset(debounce)
+			}
+		}
+	}
+}
+
+// watchPoll rescans root every WatchInterval
+func (e *Engine) watchPoll(ctx context.Context, root string, state *watchState, events chan<- api.IndexEvent) error {
+	interval := e.config.WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	e.logger.Infof("Watching %s for changes every %v", root, interval)
+	for {
+		_, err := e.rescanFolder(ctx, root, state, events)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// rescanFolder snapshots root and applies the changes since the previous
+// rescan, reporting whether any file is still waiting to settle
+func (e *Engine) rescanFolder(ctx context.Context, root string, state *watchState, events chan<- api.IndexEvent) (bool, error) {
+	current, complete, err := e.snapshotFolder(ctx, root)
+	if err != nil {
+		return false, err
+	}
+
+	settling := e.applyFolderChanges(ctx, state, current, complete, events)
+	state.seen = current
+	return settling, nil
+}
+
+// newFolderWatcher creates a file watcher on root and every folder below it
+func newFolderWatcher(root string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := addWatchDirs(watcher, root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// addWatchDirs adds dir and every folder below it to watcher
+func addWatchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable subfolders are still picked up by rescans
+			if path == dir {
+				return err
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// resetTimer restarts timer to fire after d, discarding a pending fire
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// snapshotFolder stats every image file the scanner finds under root.
+// complete is false when discovery stopped at MaxImages, in which case
+// files missing from the snapshot may still exist.
+func (e *Engine) snapshotFolder(ctx context.Context, root string) (map[string]fileStamp, bool, error) {
+	result, err := e.scanner.ScanFolderWithResult(ctx, root, nil)
+	limitReached := errors.Is(err, api.ErrScanLimitReached)
+	if err != nil && !limitReached {
+		return nil, false, fmt.Errorf("failed to scan folder: %w", err)
+	}
+
+	files := make(map[string]fileStamp, len(result.ImagePaths))
+	for _, path := range result.ImagePaths {
+		if isTemporaryFile(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // removed since it was found
+		}
+		files[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+
+	// Files the scanner could not read may still be there
+	return files, !limitReached && len(result.Errors) == 0, nil
+}
+
+// applyFolderChanges indexes the new and changed files in current that have
+// settled since the previous rescan and, when current is complete, removes the
+// entries of files that are gone. It reports whether any file has not settled yet.
+func (e *Engine) applyFolderChanges(ctx context.Context, state *watchState, current map[string]fileStamp, complete bool, events chan<- api.IndexEvent) bool {
+	paths := make([]string, 0, len(current))
+	for path := range current {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	settling := false
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return settling
+		}
+
+		stamp := current[path]
+		known, isIndexed := state.indexed[path]
+		if isIndexed && known.stamp.equal(stamp) {
+			continue
+		}
+		if failed, ok := state.failed[path]; ok && failed.equal(stamp) {
+			continue
+		}
+		// Still being written: wait until it looks the same on two rescans
+		if previous, ok := state.seen[path]; !ok || !previous.equal(stamp) {
+			settling = true
+			continue
+		}
+
+		event := e.indexWatchedFile(path, known.id, isIndexed)
+		if event.Type == api.IndexEventFailed {
+			state.failed[path] = stamp
+		} else {
+			delete(state.failed, path)
+			state.indexed[path] = watchedFile{id: event.ImageID, stamp: stamp}
+		}
+		sendIndexEvent(ctx, events, event)
+	}
+
+	if !complete {
+		return settling
+	}
+	for path := range state.failed {
+		if _, ok := current[path]; !ok {
+			delete(state.failed, path)
+		}
+	}
+	for path, file := range state.indexed {
+		if _, ok := current[path]; ok {
+			continue
+		}
+		if err := e.index.DeleteFingerprint(file.id); err != nil && !errors.Is(err, api.ErrImageNotFound) {
+			e.logger.Warnf("Failed to remove fingerprint of deleted file %s: %v", path, err)
+			continue
+		}
+		delete(state.indexed, path)
+		e.logger.Infof("Removed deleted file from index: %s", path)
+		sendIndexEvent(ctx, events, api.IndexEvent{Type: api.IndexEventRemoved, Path: path, ImageID: file.id})
+	}
+	return settling
+}
+
+// indexWatchedFile processes the file at path and saves its fingerprint,
+// keeping the ID and creation time of id when the file was already indexed
+func (e *Engine) indexWatchedFile(path string, id api.ImageID, isIndexed bool) api.IndexEvent {
+	fp, err := e.processImage(path)
+	if err != nil {
+		e.logger.Warnf("Failed to process image %s: %v", path, err)
+		return api.IndexEvent{Type: api.IndexEventFailed, Path: path, Error: err.Error()}
+	}
+
+	eventType := api.IndexEventAdded
+	if isIndexed {
+		eventType = api.IndexEventUpdated
+		fp.ID = id
+		if old, err := e.index.GetFingerprint(id); err == nil {
+			fp.CreatedAt = old.CreatedAt
+		}
+	}
+
+	if err := e.index.SaveFingerprint(fp); err != nil {
+		e.logger.Warnf("Failed to save fingerprint for %s: %v", path, err)
+		return api.IndexEvent{Type: api.IndexEventFailed, Path: path, Error: err.Error()}
+	}
+
+	e.logger.Infof("Indexed %s file: %s", eventType, path)
+	return api.IndexEvent{Type: eventType, Path: path, ImageID: fp.ID}
+}
+
+// isTemporaryFile reports whether path names a hidden file or an editor's
+// temporary copy, such as ".photo.jpg", "~photo.jpg" or "photo.jpg~"
+func isTemporaryFile(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") || strings.HasSuffix(name, "~")
+}
+
+// sendIndexEvent reports event on events unless events is nil or ctx is done
+func sendIndexEvent(ctx context.Context, events chan<- api.IndexEvent, event api.IndexEvent) {
+	if events == nil {
+		return
+	}
+	select {
+	case events <- event:
+	case <-ctx.Done():
+	}
+}
@@ -4,8 +4,10 @@ package imaged
 
 import (
 	"context"
-	"time"
+	"os"
+	"path/filepath"
 
+	"github.com/HaiderBassem/imaged/internal/index"
 	"github.com/HaiderBassem/imaged/internal/quality"
 	"github.com/HaiderBassem/imaged/internal/scanner"
 	"github.com/HaiderBassem/imaged/internal/similarity"
@@ -25,13 +27,16 @@ var (
 
 // Common types
 type (
-	EngineConfig     = engine.EngineConfig
-	ImageFingerprint = api.ImageFingerprint
-	ImageQuality     = api.ImageQuality
-	DuplicateGroup   = api.DuplicateGroup
-	ScanReport       = api.ScanReport
-	CleanOptions     = api.CleanOptions
-	SelectionPolicy  = api.SelectionPolicy
+	EngineConfig         = engine.EngineConfig
+	ImageFingerprint     = api.ImageFingerprint
+	ImageQuality         = api.ImageQuality
+	DuplicateGroup       = api.DuplicateGroup
+	ScanReport           = api.ScanReport
+	CleanOptions         = api.CleanOptions
+	ComparisonResult     = api.ComparisonResult
+	NearDuplicateOptions = api.NearDuplicateOptions
+	SelectionPolicy      = api.SelectionPolicy
+	StoreType            = index.StoreType
 )
 
 // Constants
@@ -41,6 +46,14 @@ const (
 	PolicyBestExposure      = api.PolicyBestExposure
 	PolicyOldest            = api.PolicyOldest
 	PolicyNewest            = api.PolicyNewest
+	PolicyCompositeQuality  = api.PolicyCompositeQuality
+	PolicyLargestFileSize   = api.PolicyLargestFileSize
+	PolicySmallestFileSize  = api.PolicySmallestFileSize
+
+	StoreTypeBoltDB   = index.StoreTypeBoltDB
+	StoreTypeSQLite   = index.StoreTypeSQLite
+	StoreTypeMemory   = index.StoreTypeMemory
+	StoreTypePostgres = index.StoreTypePostgres
 )
 
 // Scanner functionality
@@ -62,7 +75,8 @@ var (
 
 // Utility functions for common operations
 
-// QuickScan performs a quick scan and returns basic statistics
+// QuickScan scans a directory into the default index and returns the timings
+// and file counts of that scan
 func QuickScan(directoryPath string) (*api.ScanReport, error) {
 	cfg := engine.DefaultConfig()
 	eng, err := engine.NewEngine(cfg)
@@ -71,22 +85,7 @@ func QuickScan(directoryPath string) (*api.ScanReport, error) {
 	}
 	defer eng.Close()
 
-	// Perform scan
-	if err := eng.ScanFolder(context.Background(), directoryPath, nil); err != nil {
-		return nil, err
-	}
-
-	// Get basic report
-	stats, err := eng.GetStats()
-	if err != nil {
-		return nil, err
-	}
-
-	return &api.ScanReport{
-		TotalFiles:      int(stats.TotalImages),
-		ProcessedImages: int(stats.TotalImages),
-		ScanDuration:    time.Since(time.Now()),
-	}, nil
+	return eng.ScanFolder(context.Background(), directoryPath, nil)
 }
 
 // FindDuplicatesQuick quickly finds duplicates in a directory
@@ -99,7 +98,7 @@ func FindDuplicatesQuick(directoryPath string, similarityThreshold float64) ([]a
 	defer eng.Close()
 
 	// Scan directory
-	if err := eng.ScanFolder(context.Background(), directoryPath, nil); err != nil {
+	if _, err := eng.ScanFolder(context.Background(), directoryPath, nil); err != nil {
 		return nil, err
 	}
 
@@ -117,6 +116,30 @@ func FindDuplicatesQuick(directoryPath string, similarityThreshold float64) ([]a
 	return append(exactDuplicates, nearDuplicates...), nil
 }
 
+// IsDuplicateOf reports whether two image files are exact or near duplicates,
+// using a throwaway index so no index is left behind
+func IsDuplicateOf(pathA, pathB string) (bool, error) {
+	indexDir, err := os.MkdirTemp("", "imaged-compare-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.RemoveAll(indexDir)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(indexDir, "imaged.db")
+	eng, err := engine.NewEngine(cfg)
+	if err != nil {
+		return false, err
+	}
+	defer eng.Close()
+
+	result, err := eng.CompareFiles(pathA, pathB)
+	if err != nil {
+		return false, err
+	}
+	return result.IsNearDuplicate, nil
+}
+
 // AnalyzeImageQuality provides quick quality analysis for a single image
 func AnalyzeImageQuality(imagePath string) (*api.ImageQuality, error) {
 	eng, err := engine.NewEngine(engine.DefaultConfig())
@@ -0,0 +1,214 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+
+	"golang.org/x/image/webp"
+)
+
+// DecodeRepresentativeFrame decodes an image, returning its format and frame
+// count. For animated GIF and WebP files it composites the animation up to the
+// middle frame, so two animations that only share a first frame do not hash alike.
+func DecodeRepresentativeFrame(r io.Reader) (image.Image, string, int, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(21)
+
+	switch {
+	case bytes.HasPrefix(header, []byte("GIF8")):
+		animation, err := gif.DecodeAll(br)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		img, err := gifFrame(animation, len(animation.Image)/2)
+		return img, "gif", len(animation.Image), err
+
+	case isAnimatedWebP(header):
+		data, err := io.ReadAll(br)
+		if err != nil {
+			return nil, "", 0, err
+		}
+		img, frames, err := decodeAnimatedWebP(data)
+		return img, "webp", frames, err
+	}
+
+	img, format, err := image.Decode(br)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return img, format, 1, nil
+}
+
+// gifFrame composites frames 0..target onto the logical screen, applying each
+// frame's disposal method before the next one is drawn
+func gifFrame(animation *gif.GIF, target int) (image.Image, error) {
+	if len(animation.Image) == 0 {
+		return nil, fmt.Errorf("GIF has no frames")
+	}
+
+	bounds := image.Rect(0, 0, animation.Config.Width, animation.Config.Height)
+	if bounds.Empty() {
+		bounds = animation.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+
+	for i := 0; i <= target; i++ {
+		frame := animation.Image[i]
+		var disposal byte
+		if i < len(animation.Disposal) {
+			disposal = animation.Disposal[i]
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, bounds.Min, draw.Src)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if i == target {
+			break
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return canvas, nil
+}
+
+// isAnimatedWebP reports whether header starts an extended WebP file with the animation flag set
+func isAnimatedWebP(header []byte) bool {
+	const animationBit = 1 << 1
+	return len(header) >= 21 &&
+		string(header[0:4]) == "RIFF" &&
+		string(header[8:12]) == "WEBP" &&
+		string(header[12:16]) == "VP8X" &&
+		header[20]&animationBit != 0
+}
+
+// webpFrame is one ANMF chunk of an animated WebP
+type webpFrame struct {
+	bounds      image.Rectangle
+	noBlend     bool
+	dispose     bool
+	bitstream   []byte // the frame's ALPH/VP8/VP8L chunks
+	alphaChunks bool   // bitstream starts with an ALPH chunk
+}
+
+// decodeAnimatedWebP composites an animated WebP up to its middle frame and returns it with the frame count
+func decodeAnimatedWebP(data []byte) (image.Image, int, error) {
+	if len(data) < 12 {
+		return nil, 0, fmt.Errorf("truncated WebP header")
+	}
+
+	var canvas *image.RGBA
+	var frames []webpFrame
+	chunks := data[12:]
+	for len(chunks) >= 8 {
+		fourCC := string(chunks[:4])
+		size := int(binary.LittleEndian.Uint32(chunks[4:8]))
+		if size < 0 || 8+size > len(chunks) {
+			return nil, 0, fmt.Errorf("invalid size for WebP %s chunk", fourCC)
+		}
+		payload := chunks[8 : 8+size]
+
+		switch fourCC {
+		case "VP8X":
+			if len(payload) < 10 {
+				return nil, 0, fmt.Errorf("truncated WebP VP8X chunk")
+			}
+			canvas = image.NewRGBA(image.Rect(0, 0, int(uint24(payload[4:]))+1, int(uint24(payload[7:]))+1))
+		case "ANMF":
+			if len(payload) < 16 {
+				return nil, 0, fmt.Errorf("truncated WebP ANMF chunk")
+			}
+			x, y := 2*int(uint24(payload[0:])), 2*int(uint24(payload[3:]))
+			w, h := int(uint24(payload[6:]))+1, int(uint24(payload[9:]))+1
+			frames = append(frames, webpFrame{
+				bounds:      image.Rect(x, y, x+w, y+h),
+				noBlend:     payload[15]&0x02 != 0,
+				dispose:     payload[15]&0x01 != 0,
+				bitstream:   payload[16:],
+				alphaChunks: bytes.HasPrefix(payload[16:], []byte("ALPH")),
+			})
+		}
+
+		// Chunks are padded to an even length, though the final pad byte may be missing
+		next := 8 + size + size%2
+		if next > len(chunks) {
+			next = len(chunks)
+		}
+		chunks = chunks[next:]
+	}
+
+	if canvas == nil || len(frames) == 0 {
+		return nil, 0, fmt.Errorf("animated WebP has no frames")
+	}
+
+	target := len(frames) / 2
+	for i := 0; i <= target; i++ {
+		frame := frames[i]
+		img, err := webp.Decode(bytes.NewReader(frame.standalone()))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode WebP frame %d: %w", i, err)
+		}
+
+		op := draw.Over
+		if frame.noBlend {
+			op = draw.Src
+		}
+		draw.Draw(canvas, frame.bounds, img, img.Bounds().Min, op)
+		if i == target {
+			break
+		}
+
+		if frame.dispose {
+			draw.Draw(canvas, frame.bounds, image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+
+	return canvas, len(frames), nil
+}
+
+// standalone wraps the frame's bitstream in a still WebP file so it can be decoded on its own
+func (f webpFrame) standalone() []byte {
+	body := []byte("WEBP")
+	if f.alphaChunks {
+		// ALPH chunks are only valid inside an extended file
+		const alphaBit = 1 << 4
+		vp8x := make([]byte, 18)
+		copy(vp8x, "VP8X")
+		binary.LittleEndian.PutUint32(vp8x[4:], 10)
+		vp8x[8] = alphaBit
+		putUint24(vp8x[12:], uint32(f.bounds.Dx()-1))
+		putUint24(vp8x[15:], uint32(f.bounds.Dy()-1))
+		body = append(body, vp8x...)
+	}
+	body = append(body, f.bitstream...)
+
+	file := make([]byte, 8, 8+len(body))
+	copy(file, "RIFF")
+	binary.LittleEndian.PutUint32(file[4:], uint32(len(body)))
+	return append(file, body...)
+}
+
+// uint24 reads a little-endian 24-bit integer
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// putUint24 writes a little-endian 24-bit integer
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"sort"
 )
 
 // ColorSpace provides color space conversion utilities
@@ -180,50 +181,186 @@ func (cs *ColorSpace) IsSimilarColor(c1, c2 color.Color, threshold float64) bool
 	return distance <= threshold
 }
 
-// GetDominantColors extracts dominant colors from an image (simplified)
+// GetDominantColors returns up to maxColors dominant colors of img, most
+// widespread first
 func (cs *ColorSpace) GetDominantColors(img image.Image, maxColors int) []color.Color {
-	// This is a simplified implementation
-	// In production, use proper color quantization algorithms
+	dominant := cs.DominantColors(img, maxColors)
+	colors := make([]color.Color, len(dominant))
+	for i, d := range dominant {
+		colors[i] = d.Color
+	}
+	return colors
+}
+
+// DominantColor is a representative color of an image together with the
+// fraction of the image's pixels closest to it
+type DominantColor struct {
+	Color    color.RGBA
+	Coverage float64
+}
+
+// dominantColorSamples bounds the pixels clustered by DominantColors
+const dominantColorSamples = 4096
 
+// dominantColorIterations bounds the k-means refinement rounds
+const dominantColorIterations = 20
+
+// DominantColors quantizes img into at most n colors with k-means clustering
+// in LAB space, where distances follow perceived color differences, and
+// returns them by coverage, largest first. Pixels are sampled on a grid of
+// about dominantColorSamples points. Each color is the mean RGB of its
+// cluster; clusters that end up empty are dropped.
+func (cs *ColorSpace) DominantColors(img image.Image, n int) []DominantColor {
 	bounds := img.Bounds()
-	colorCount := make(map[color.RGBA]int)
-
-	// Sample pixels for color analysis
-	sampleRate := 10
-	for y := bounds.Min.Y; y < bounds.Max.Y; y += sampleRate {
-		for x := bounds.Min.X; x < bounds.Max.X; x += sampleRate {
-			c := img.At(x, y)
-			rgba := color.RGBAModel.Convert(c).(color.RGBA)
-
-			// Quantize color to reduce variations
-			quantized := cs.quantizeColor(rgba, 16)
-			colorCount[quantized]++
+	if n <= 0 || bounds.Empty() {
+		return nil
+	}
+
+	type sample struct {
+		lab     [3]float64
+		r, g, b float64
+	}
+	step := max(1, int(math.Sqrt(float64(bounds.Dx()*bounds.Dy())/dominantColorSamples)))
+	var samples []sample
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			r, g, b, _ := img.At(x, y).RGBA()
+			l, a, b2 := cs.RGBToLAB(r, g, b)
+			samples = append(samples, sample{lab: [3]float64{l, a, b2}, r: float64(r >> 8), g: float64(g >> 8), b: float64(b >> 8)})
 		}
 	}
 
-	// Find most frequent colors
-	var colors []color.Color
-	for c, count := range colorCount {
-		if count > len(colorCount)/20 { // At least 5% frequency
-			colors = append(colors, c)
+	distance := func(p, q [3]float64) float64 {
+		dl, da, db := p[0]-q[0], p[1]-q[1], p[2]-q[2]
+		return dl*dl + da*da + db*db
+	}
+
+	// Seed deterministically: the sample closest to the mean color, then
+	// repeatedly the sample farthest from every center chosen so far
+	var mean [3]float64
+	for _, s := range samples {
+		for c := range mean {
+			mean[c] += s.lab[c] / float64(len(samples))
+		}
+	}
+	nearest := make([]float64, len(samples))
+	first := 0
+	for i, s := range samples {
+		nearest[i] = distance(s.lab, mean)
+		if nearest[i] < nearest[first] {
+			first = i
+		}
+	}
+	centers := [][3]float64{samples[first].lab}
+	for i, s := range samples {
+		nearest[i] = distance(s.lab, centers[0])
+	}
+	for len(centers) < n {
+		farthest := 0
+		for i := range samples {
+			if nearest[i] > nearest[farthest] {
+				farthest = i
+			}
+		}
+		if nearest[farthest] == 0 {
+			break // fewer distinct colors than n
+		}
+		centers = append(centers, samples[farthest].lab)
+		for i, s := range samples {
+			nearest[i] = math.Min(nearest[i], distance(s.lab, samples[farthest].lab))
 		}
 	}
 
-	// Limit to maxColors
-	if len(colors) > maxColors {
-		colors = colors[:maxColors]
+	assignment := make([]int, len(samples))
+	for iteration := 0; iteration < dominantColorIterations; iteration++ {
+		changed := iteration == 0
+		for i, s := range samples {
+			best := 0
+			for c := range centers {
+				if distance(s.lab, centers[c]) < distance(s.lab, centers[best]) {
+					best = c
+				}
+			}
+			if assignment[i] != best {
+				assignment[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][3]float64, len(centers))
+		counts := make([]int, len(centers))
+		for i, s := range samples {
+			for c := range sums[assignment[i]] {
+				sums[assignment[i]][c] += s.lab[c]
+			}
+			counts[assignment[i]]++
+		}
+		for c := range centers {
+			if counts[c] > 0 {
+				for k := range centers[c] {
+					centers[c][k] = sums[c][k] / float64(counts[c])
+				}
+			}
+		}
+	}
+
+	rgb := make([][3]float64, len(centers))
+	counts := make([]int, len(centers))
+	for i, s := range samples {
+		c := assignment[i]
+		rgb[c][0] += s.r
+		rgb[c][1] += s.g
+		rgb[c][2] += s.b
+		counts[c]++
 	}
 
+	var colors []DominantColor
+	for c := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		count := float64(counts[c])
+		colors = append(colors, DominantColor{
+			Color: color.RGBA{
+				R: uint8(math.Round(rgb[c][0] / count)),
+				G: uint8(math.Round(rgb[c][1] / count)),
+				B: uint8(math.Round(rgb[c][2] / count)),
+				A: 255,
+			},
+			Coverage: count / float64(len(samples)),
+		})
+	}
+	sort.SliceStable(colors, func(i, j int) bool { return colors[i].Coverage > colors[j].Coverage })
 	return colors
 }
 
-// quantizeColor reduces color precision
-func (cs *ColorSpace) quantizeColor(c color.RGBA, levels int) color.RGBA {
-	step := 256 / levels
-	return color.RGBA{
-		R: uint8((int(c.R) / step) * step),
-		G: uint8((int(c.G) / step) * step),
-		B: uint8((int(c.B) / step) * step),
-		A: c.A,
+// Rec. 601 luma weights, as used by JPEG and most grayscale conversions
+const (
+	lumaRed   = 0.299
+	lumaGreen = 0.587
+	lumaBlue  = 0.114
+)
+
+// Luminance returns the Rec. 601 luma of c, between 0 and 1. Green dominates
+// perceived brightness, so a plain average of R, G and B would understate it.
+func Luminance(c color.Color) float64 {
+	r, g, b, _ := c.RGBA()
+	return (lumaRed*float64(r) + lumaGreen*float64(g) + lumaBlue*float64(b)) / 65535.0
+}
+
+// Grayscale converts img to an 8-bit luma image using Luminance
+func Grayscale(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	gray := image.NewGray(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gray.SetGray(x, y, color.Gray{Y: uint8(math.Round(Luminance(img.At(x, y)) * 255))})
+		}
 	}
+
+	return gray
 }
@@ -1,6 +1,7 @@
 package imaging
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"io"
@@ -12,6 +13,7 @@ import (
 // Decoder handles image decoding with format detection and error handling
 type Decoder struct {
 	supportedFormats map[string]bool
+	limits           DecodeLimits
 }
 
 // NewDecoder creates a new image decoder
@@ -20,12 +22,20 @@ func NewDecoder() *Decoder {
 		supportedFormats: map[string]bool{
 			"jpeg": true, "jpg": true, "png": true,
 			"gif": true, "bmp": true, "tiff": true,
-			"webp": true,
+			"webp": true, "heic": true, "heif": true,
 		},
+		limits: DefaultDecodeLimits(),
 	}
 }
 
-// DecodeImage decodes an image from a file with comprehensive error handling
+// SetLimits replaces the pixel budget and timeout DecodeImage enforces
+func (d *Decoder) SetLimits(limits DecodeLimits) {
+	d.limits = limits
+}
+
+// DecodeImage decodes an image from a file with comprehensive error handling.
+// Images over the decoder's pixel budget are rejected from their header, and
+// decodes running past its timeout are abandoned.
 func (d *Decoder) DecodeImage(filePath string) (image.Image, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -34,7 +44,13 @@ func (d *Decoder) DecodeImage(filePath string) (image.Image, error) {
 	defer file.Close()
 
 	// Decode image with format detection
-	img, format, err := image.Decode(file)
+	var img image.Image
+	var format string
+	err = DecodeWithLimits(context.Background(), file, d.limits, func(r io.Reader) error {
+		var err error
+		img, format, err = image.Decode(r)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
@@ -55,7 +71,7 @@ func (d *Decoder) DecodeImageConfig(filePath string) (image.Config, string, erro
 	}
 	defer file.Close()
 
-	config, format, err := image.DecodeConfig(file)
+	config, format, err := DecodeConfig(file)
 	if err != nil {
 		return image.Config{}, "", fmt.Errorf("failed to decode image config: %w", err)
 	}
@@ -0,0 +1,89 @@
+package imaging
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/tiff"
+)
+
+// DefaultJPEGQuality is used when EncodeOptions.Quality is unset
+const DefaultJPEGQuality = 90
+
+// EncodeOptions controls how images are written
+type EncodeOptions struct {
+	// Quality is the JPEG quality from 1 to 100; lossless formats ignore it
+	Quality int
+}
+
+// SaveImage encodes img to path in the format implied by its extension:
+// .jpg/.jpeg, .png, .webp (lossless) or .tif/.tiff. A partially written
+// file is removed if encoding fails.
+func SaveImage(img image.Image, path string, opts EncodeOptions) error {
+	format, err := formatFromExtension(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	err = EncodeImage(w, img, format, opts)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to save image %s: %w", path, err)
+	}
+	return nil
+}
+
+// EncodeImage writes img to w as "jpeg", "png", "webp" or "tiff"
+func EncodeImage(w io.Writer, img image.Image, format string, opts EncodeOptions) error {
+	switch format {
+	case "jpeg":
+		quality := opts.Quality
+		if quality <= 0 || quality > 100 {
+			quality = DefaultJPEGQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case "png":
+		return png.Encode(w, img)
+	case "webp":
+		return encodeWebPLossless(w, img)
+	case "tiff":
+		return tiff.Encode(w, img, &tiff.Options{Compression: tiff.Deflate, Predictor: true})
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// formatFromExtension maps a file extension to an EncodeImage format
+func formatFromExtension(path string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".jpg", ".jpeg":
+		return "jpeg", nil
+	case ".png":
+		return "png", nil
+	case ".webp":
+		return "webp", nil
+	case ".tif", ".tiff":
+		return "tiff", nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %q", ext)
+	}
+}
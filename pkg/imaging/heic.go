@@ -0,0 +1,304 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// HEIC/HEIF files are registered with the image package so image.Decode and
+// image.DecodeConfig recognize them. Dimensions come from the container's
+// ispe property and are read in pure Go; decoding pixels needs an HEVC
+// decoder, which is only linked in when building with the heic tag.
+
+// ErrHEICDecoderUnavailable is returned when decoding HEIC pixels in a build without the heic tag
+var ErrHEICDecoderUnavailable = errors.New("unsupported image format: HEIC decoding requires building with the heic tag")
+
+// heicDecode decodes HEIC pixels; it is set by heic_decode.go when built with the heic tag
+var heicDecode func(r io.Reader) (image.Image, error)
+
+// maxHEICMetaSize bounds the meta box read into memory to find image extents
+const maxHEICMetaSize = 16 << 20
+
+func init() {
+	for _, brand := range []string{"heic", "heix", "hevc", "hevx"} {
+		image.RegisterFormat("heic", "????ftyp"+brand, decodeHEIC, decodeHEICConfig)
+	}
+	for _, brand := range []string{"mif1", "msf1"} {
+		image.RegisterFormat("heif", "????ftyp"+brand, decodeHEIC, decodeHEICConfig)
+	}
+}
+
+// DecodeConfig is image.DecodeConfig, except that HEIC and HEIF containers
+// are always measured by this package's parser. The goheif decoder linked in
+// by the heic tag registers itself for every ISO base media file ahead of it,
+// and cannot size files whose primary image is a grid of tiles.
+func DecodeConfig(r io.Reader) (image.Config, string, error) {
+	br := bufio.NewReader(r)
+	if format := heicFormat(br); format != "" {
+		config, err := decodeHEICConfig(br)
+		return config, format, err
+	}
+	return image.DecodeConfig(br)
+}
+
+// heicFormat returns "heic" or "heif" when r starts with the file type box of
+// one, and "" otherwise
+func heicFormat(r *bufio.Reader) string {
+	header, err := r.Peek(12)
+	if err != nil || string(header[4:8]) != "ftyp" {
+		return ""
+	}
+	switch string(header[8:12]) {
+	case "heic", "heix", "hevc", "hevx":
+		return "heic"
+	case "mif1", "msf1":
+		return "heif"
+	}
+	return ""
+}
+
+// HEICDecodingSupported reports whether this build can decode HEIC pixels
+func HEICDecodingSupported() bool {
+	return heicDecode != nil
+}
+
+// decodeHEIC decodes a HEIC image when a decoder is linked in
+func decodeHEIC(r io.Reader) (image.Image, error) {
+	if heicDecode == nil {
+		return nil, ErrHEICDecoderUnavailable
+	}
+	return heicDecode(r)
+}
+
+// decodeHEICConfig reads the primary image's dimensions from the HEIF container
+func decodeHEICConfig(r io.Reader) (image.Config, error) {
+	meta, err := readHEICMeta(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	children, err := parseBoxes(meta)
+	if err != nil {
+		return image.Config{}, fmt.Errorf("failed to parse HEIC meta box: %w", err)
+	}
+
+	var primary uint32
+	var properties []isoBox
+	associations := make(map[uint32][]int)
+	for _, child := range children {
+		switch child.typ {
+		case "pitm":
+			primary = parsePrimaryItem(child.body)
+		case "iprp":
+			iprp, err := parseBoxes(child.body)
+			if err != nil {
+				return image.Config{}, fmt.Errorf("failed to parse HEIC item properties: %w", err)
+			}
+			for _, box := range iprp {
+				switch box.typ {
+				case "ipco":
+					if properties, err = parseBoxes(box.body); err != nil {
+						return image.Config{}, fmt.Errorf("failed to parse HEIC property container: %w", err)
+					}
+				case "ipma":
+					associations = parseItemAssociations(box.body)
+				}
+			}
+		}
+	}
+
+	// Prefer the primary item's extents, falling back to the largest declared image
+	var width, height int
+	for _, index := range associations[primary] {
+		if index >= 1 && index <= len(properties) && properties[index-1].typ == "ispe" {
+			width, height = parseImageExtents(properties[index-1].body)
+		}
+	}
+	if width == 0 || height == 0 {
+		for _, property := range properties {
+			if property.typ != "ispe" {
+				continue
+			}
+			if w, h := parseImageExtents(property.body); w*h > width*height {
+				width, height = w, h
+			}
+		}
+	}
+	if width == 0 || height == 0 {
+		return image.Config{}, fmt.Errorf("HEIC file declares no image extents")
+	}
+
+	return image.Config{ColorModel: color.YCbCrModel, Width: width, Height: height}, nil
+}
+
+// isoBox is one ISO base media file format box
+type isoBox struct {
+	typ  string
+	body []byte
+}
+
+// readHEICMeta skips top-level boxes until the meta box and returns its
+// children, without the full-box version and flags
+func readHEICMeta(r io.Reader) ([]byte, error) {
+	for {
+		typ, size, err := readBoxHeader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find HEIC meta box: %w", err)
+		}
+
+		if typ == "meta" {
+			if size < 4 || size > maxHEICMetaSize {
+				return nil, fmt.Errorf("invalid HEIC meta box size: %d", size)
+			}
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("failed to read HEIC meta box: %w", err)
+			}
+			return body[4:], nil
+		}
+
+		if size < 0 {
+			return nil, fmt.Errorf("HEIC file has no meta box")
+		}
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return nil, fmt.Errorf("failed to skip HEIC %s box: %w", typ, err)
+		}
+	}
+}
+
+// readBoxHeader returns the type and body length of the next box; a length
+// of -1 means the box runs to the end of the file
+func readBoxHeader(r io.Reader) (string, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", 0, err
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[:4]))
+	typ := string(header[4:])
+	switch size {
+	case 0:
+		return typ, -1, nil
+	case 1:
+		var large [8]byte
+		if _, err := io.ReadFull(r, large[:]); err != nil {
+			return "", 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(large[:])) - 16
+	default:
+		size -= 8
+	}
+
+	if size < 0 {
+		return "", 0, fmt.Errorf("invalid size for %s box", typ)
+	}
+	return typ, size, nil
+}
+
+// parseBoxes splits data into consecutive boxes
+func parseBoxes(data []byte) ([]isoBox, error) {
+	var boxes []isoBox
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated box header")
+		}
+
+		size := uint64(binary.BigEndian.Uint32(data[:4]))
+		typ := string(data[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, fmt.Errorf("truncated %s box header", typ)
+			}
+			size = binary.BigEndian.Uint64(data[8:16])
+			header = 16
+		}
+		if size < header || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid size for %s box", typ)
+		}
+
+		boxes = append(boxes, isoBox{typ: typ, body: data[header:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// parsePrimaryItem returns the item ID stored in a pitm box
+func parsePrimaryItem(body []byte) uint32 {
+	if len(body) >= 8 && body[0] != 0 {
+		return binary.BigEndian.Uint32(body[4:8])
+	}
+	if len(body) >= 6 {
+		return uint32(binary.BigEndian.Uint16(body[4:6]))
+	}
+	return 0
+}
+
+// parseItemAssociations maps item IDs to their 1-based property indices from an ipma box
+func parseItemAssociations(body []byte) map[uint32][]int {
+	associations := make(map[uint32][]int)
+	if len(body) < 8 {
+		return associations
+	}
+
+	version := body[0]
+	wideIndices := body[3]&1 != 0
+	count := binary.BigEndian.Uint32(body[4:8])
+	data := body[8:]
+
+	for i := uint32(0); i < count; i++ {
+		var item uint32
+		if version < 1 {
+			if len(data) < 2 {
+				break
+			}
+			item, data = uint32(binary.BigEndian.Uint16(data)), data[2:]
+		} else {
+			if len(data) < 4 {
+				break
+			}
+			item, data = binary.BigEndian.Uint32(data), data[4:]
+		}
+
+		if len(data) < 1 {
+			break
+		}
+		n := int(data[0])
+		data = data[1:]
+
+		for j := 0; j < n; j++ {
+			// The top bit of each association marks the property as essential
+			if wideIndices {
+				if len(data) < 2 {
+					return associations
+				}
+				associations[item] = append(associations[item], int(binary.BigEndian.Uint16(data)&0x7fff))
+				data = data[2:]
+			} else {
+				if len(data) < 1 {
+					return associations
+				}
+				associations[item] = append(associations[item], int(data[0]&0x7f))
+				data = data[1:]
+			}
+		}
+	}
+
+	return associations
+}
+
+// parseImageExtents returns the width and height from an ispe property
+func parseImageExtents(body []byte) (int, int) {
+	if len(body) < 12 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint32(body[4:8])), int(binary.BigEndian.Uint32(body[8:12]))
+}
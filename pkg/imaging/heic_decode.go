@@ -0,0 +1,14 @@
+//go:build heic
+
+package imaging
+
+// Building with -tags heic links the goheif decoder, which bundles libde265
+// as C++ and so needs cgo and a C++ compiler; it is the one part of the
+// library that is not pure Go, which is why it is opt-in. The requirement is
+// already in go.mod, and `make check-heic` verifies the tagged build.
+
+import "github.com/jdeng/goheif"
+
+func init() {
+	heicDecode = goheif.Decode
+}
@@ -0,0 +1,118 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrImageTooLarge is returned when an image's header declares more pixels than the decode budget allows
+var ErrImageTooLarge = errors.New("image exceeds the pixel budget")
+
+// ErrDecodeTimeout is returned when decoding an image takes longer than the decode timeout
+var ErrDecodeTimeout = errors.New("image decode timed out")
+
+// DecodeLimits guard decoding against decompression bombs: small files whose
+// headers declare enormous dimensions, or that are crafted to decode slowly
+type DecodeLimits struct {
+	MaxPixels int64         // largest width x height decoded; 0 disables the check
+	Timeout   time.Duration // longest a decode may take; 0 disables the timeout
+}
+
+// DefaultDecodeLimits returns a budget of 100 megapixels and a 30 second timeout
+func DefaultDecodeLimits() DecodeLimits {
+	return DecodeLimits{
+		MaxPixels: 100_000_000,
+		Timeout:   30 * time.Second,
+	}
+}
+
+// DecodeWithLimits runs decode on r within limits. The image header is read
+// first and an image with more than MaxPixels pixels is rejected with
+// ErrImageTooLarge before any pixels are decoded; decode then reads the whole
+// stream, header included. After Timeout, or once ctx is done, reads from r
+// fail and ErrDecodeTimeout (or ctx's error) is returned without waiting for
+// decode. A timeout abandons the decode rather than cancelling it: decode
+// keeps running on its own goroutine, holding whatever it has allocated,
+// until its next read fails or it returns.
+func DecodeWithLimits(ctx context.Context, r io.Reader, limits DecodeLimits, decode func(io.Reader) error) error {
+	return DecodeWithLimitsNotify(ctx, r, limits, decode, nil)
+}
+
+// DecodeWithLimitsNotify is DecodeWithLimits that calls finished, when it is
+// non-nil, from the decode goroutine once that goroutine returns. After a
+// timeout this is later than DecodeWithLimitsNotify itself returns, so
+// resources the decode uses can be held until it is really done.
+func DecodeWithLimitsNotify(ctx context.Context, r io.Reader, limits DecodeLimits, decode func(io.Reader) error, finished func()) error {
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if finished != nil {
+			defer finished()
+		}
+		src, err := checkPixelBudget(&contextReader{ctx: ctx, r: r}, limits.MaxPixels)
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- decode(src)
+	}()
+
+	select {
+	case err := <-done:
+		// A decode cut short by a failed read reports the timeout instead
+		if err == nil || ctx.Err() == nil {
+			return err
+		}
+	case <-ctx.Done():
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v", ErrDecodeTimeout, limits.Timeout)
+	}
+	return ctx.Err()
+}
+
+// checkPixelBudget reads the image header from r and rejects images with
+// more than maxPixels pixels. The reader returned replays the header bytes
+// consumed before the rest of r. Headers that cannot be parsed are left for
+// the full decode to report.
+func checkPixelBudget(r io.Reader, maxPixels int64) (io.Reader, error) {
+	if maxPixels <= 0 {
+		return r, nil
+	}
+
+	var header bytes.Buffer
+	config, format, err := DecodeConfig(io.TeeReader(r, &header))
+	replay := io.MultiReader(&header, r)
+	if err != nil {
+		return replay, nil
+	}
+
+	if pixels := int64(config.Width) * int64(config.Height); pixels > maxPixels {
+		return nil, fmt.Errorf("%w: %s is %dx%d pixels, limit is %d", ErrImageTooLarge, format, config.Width, config.Height, maxPixels)
+	}
+	return replay, nil
+}
+
+// contextReader fails reads once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context is done
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
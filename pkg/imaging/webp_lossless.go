@@ -0,0 +1,331 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// VP8L (lossless WebP) bitstream constants
+const (
+	vp8lSignature         = 0x2f
+	vp8lMaxDimension      = 1 << 14
+	vp8lMaxCodeLength     = 15
+	vp8lMaxCodeLengthBits = 7
+	vp8lSubtractGreen     = 2
+)
+
+// vp8lCodeLengthOrder is the order in which code length code lengths are stored
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// vp8lAlphabetSizes are the green (literals plus LZ77 lengths), red, blue,
+// alpha and distance alphabets
+var vp8lAlphabetSizes = [5]int{256 + 24, 256, 256, 256, 40}
+
+// encodeWebPLossless writes img as a lossless WebP. It applies the subtract
+// green transform and Huffman codes each channel; it does not search for
+// LZ77 matches, so it favours simplicity over the smallest output.
+func encodeWebPLossless(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxDimension || height > vp8lMaxDimension {
+		return fmt.Errorf("webp: unsupported dimensions %dx%d", width, height)
+	}
+
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || nrgba.Rect.Min != (image.Point{}) {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
+		draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	}
+
+	// Channel symbols in VP8L coding order: green, red, blue, alpha
+	symbols := make([][4]uint8, 0, width*height)
+	var histograms [5][]int
+	for i, size := range vp8lAlphabetSizes {
+		histograms[i] = make([]int, size)
+	}
+	hasAlpha := false
+	for y := 0; y < height; y++ {
+		row := nrgba.Pix[y*nrgba.Stride : y*nrgba.Stride+4*width]
+		for x := 0; x < 4*width; x += 4 {
+			r, g, b, a := row[x], row[x+1], row[x+2], row[x+3]
+			px := [4]uint8{g, r - g, b - g, a}
+			for c, v := range px {
+				histograms[c][v]++
+			}
+			hasAlpha = hasAlpha || a != 0xff
+			symbols = append(symbols, px)
+		}
+	}
+
+	bw := &vp8lBitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	// One subtract green transform, then the end of the transform list
+	bw.writeBits(1, 1)
+	bw.writeBits(vp8lSubtractGreen, 2)
+	bw.writeBits(0, 1)
+
+	bw.writeBits(0, 1) // no color cache
+	bw.writeBits(0, 1) // a single prefix code group
+
+	var codes [5]*vp8lPrefixCode
+	for i, histogram := range histograms {
+		codes[i] = bw.writePrefixCode(histogram)
+	}
+
+	for _, px := range symbols {
+		for c, v := range px {
+			codes[c].write(bw, int(v))
+		}
+	}
+	data := bw.flush()
+
+	padding := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding != 0 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vp8lBitWriter packs bits least significant bit first, as VP8L expects
+type vp8lBitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+// writeBits appends the low n bits of v
+func (bw *vp8lBitWriter) writeBits(v uint32, n uint) {
+	bw.bits |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits >>= 8
+		bw.nBits -= 8
+	}
+}
+
+// flush returns the written bytes, padding the last one with zeros
+func (bw *vp8lBitWriter) flush() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
+
+// writePrefixCode stores a prefix code for the symbol frequencies in
+// histogram and returns it for encoding those symbols
+func (bw *vp8lBitWriter) writePrefixCode(histogram []int) *vp8lPrefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		// The alphabet is never coded; any valid code will do
+		used = []int{0}
+	}
+
+	// One or two symbols below 256 fit the compact "simple" code
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.writeBits(0, 1)
+			bw.writeBits(uint32(used[0]), 1)
+		} else {
+			bw.writeBits(1, 1)
+			bw.writeBits(uint32(used[0]), 8)
+		}
+		lengths := make([]uint8, len(histogram))
+		if len(used) == 2 {
+			bw.writeBits(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		return newVP8LPrefixCode(lengths)
+	}
+
+	lengths := huffmanCodeLengths(histogram, vp8lMaxCodeLength)
+	bw.writeBits(0, 1)
+
+	// The code lengths are themselves Huffman coded, without run lengths
+	codeLengthHistogram := make([]int, len(vp8lCodeLengthOrder))
+	for _, length := range lengths {
+		codeLengthHistogram[length]++
+	}
+	codeLengthLengths := huffmanCodeLengths(codeLengthHistogram, vp8lMaxCodeLengthBits)
+
+	count := len(vp8lCodeLengthOrder)
+	for count > 4 && codeLengthLengths[vp8lCodeLengthOrder[count-1]] == 0 {
+		count--
+	}
+	bw.writeBits(uint32(count-4), 4)
+	for _, symbol := range vp8lCodeLengthOrder[:count] {
+		bw.writeBits(uint32(codeLengthLengths[symbol]), 3)
+	}
+	bw.writeBits(0, 1) // every symbol's length follows
+
+	codeLengthCode := newVP8LPrefixCode(codeLengthLengths)
+	for _, length := range lengths {
+		codeLengthCode.write(bw, int(length))
+	}
+	return newVP8LPrefixCode(lengths)
+}
+
+// vp8lPrefixCode holds the bit-reversed canonical code of each symbol
+type vp8lPrefixCode struct {
+	codes   []uint32
+	lengths []uint8
+}
+
+// newVP8LPrefixCode builds canonical codes from code lengths. A code with a
+// single symbol takes no bits, matching how decoders read it.
+func newVP8LPrefixCode(lengths []uint8) *vp8lPrefixCode {
+	code := &vp8lPrefixCode{codes: make([]uint32, len(lengths)), lengths: make([]uint8, len(lengths))}
+
+	used := 0
+	var counts [vp8lMaxCodeLength + 1]uint32
+	for _, length := range lengths {
+		counts[length]++
+		if length > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		return code
+	}
+
+	var next [vp8lMaxCodeLength + 1]uint32
+	value := uint32(0)
+	counts[0] = 0
+	for length := 1; length <= vp8lMaxCodeLength; length++ {
+		value = (value + counts[length-1]) << 1
+		next[length] = value
+	}
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		canonical := next[length]
+		next[length]++
+
+		// Decoders read the code's most significant bit first
+		var reversed uint32
+		for i := uint8(0); i < length; i++ {
+			reversed = reversed<<1 | (canonical>>i)&1
+		}
+		code.codes[symbol] = reversed
+		code.lengths[symbol] = length
+	}
+	return code
+}
+
+// write emits the code for symbol
+func (c *vp8lPrefixCode) write(bw *vp8lBitWriter, symbol int) {
+	if length := c.lengths[symbol]; length > 0 {
+		bw.writeBits(c.codes[symbol], uint(length))
+	}
+}
+
+// huffmanCodeLengths returns Huffman code lengths for the symbol frequencies,
+// no longer than maxLength. Frequencies are flattened until the tree is
+// shallow enough. A lone symbol gets length 1.
+func huffmanCodeLengths(histogram []int, maxLength int) []uint8 {
+	freqs := append([]int(nil), histogram...)
+	for {
+		lengths, deepest := huffmanDepths(freqs)
+		if deepest <= maxLength {
+			return lengths
+		}
+		for i, f := range freqs {
+			if f > 0 {
+				freqs[i] = (f + 1) / 2
+			}
+		}
+	}
+}
+
+// huffmanDepths builds a Huffman tree with the two-queue method and returns
+// each symbol's depth together with the deepest one
+func huffmanDepths(freqs []int) ([]uint8, int) {
+	type node struct {
+		freq        int
+		left, right int
+		symbol      int
+	}
+
+	var nodes []node
+	for symbol, f := range freqs {
+		if f > 0 {
+			nodes = append(nodes, node{freq: f, left: -1, right: -1, symbol: symbol})
+		}
+	}
+	lengths := make([]uint8, len(freqs))
+	if len(nodes) == 0 {
+		return lengths, 0
+	}
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbol] = 1
+		return lengths, 1
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].freq < nodes[j].freq })
+
+	leaves := len(nodes)
+	nextLeaf, nextInternal := 0, leaves
+	pick := func() int {
+		if nextLeaf < leaves && (nextInternal >= len(nodes) || nodes[nextLeaf].freq <= nodes[nextInternal].freq) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextInternal++
+		return nextInternal - 1
+	}
+	for i := 0; i < leaves-1; i++ {
+		a, b := pick(), pick()
+		nodes = append(nodes, node{freq: nodes[a].freq + nodes[b].freq, left: a, right: b})
+	}
+
+	deepest := 0
+	type entry struct{ index, depth int }
+	stack := []entry{{len(nodes) - 1, 0}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := nodes[e.index]
+		if n.left < 0 {
+			lengths[n.symbol] = uint8(e.depth)
+			deepest = max(deepest, e.depth)
+			continue
+		}
+		stack = append(stack, entry{n.left, e.depth + 1}, entry{n.right, e.depth + 1})
+	}
+	return lengths, deepest
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	imagedv1 "github.com/HaiderBassem/imaged/proto/imaged/v1"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCService exposes an engine through the imaged.v1.ImageD gRPC service.
+// Every call runs under its request context, so a client that cancels or
+// times out stops the scan, search or clean it started.
+type GRPCService struct {
+	imagedv1.UnimplementedImageDServer
+
+	engine *engine.Engine
+	logger *logrus.Logger
+}
+
+// NewGRPCService creates a gRPC service for eng
+func NewGRPCService(eng *engine.Engine) *GRPCService {
+	return &GRPCService{
+		engine: eng,
+		logger: logrus.New(),
+	}
+}
+
+// Scan indexes the requested folder, streaming progress events and ending
+// with the scan report
+func (s *GRPCService) Scan(req *imagedv1.ScanRequest, stream imagedv1.ImageD_ScanServer) error {
+	if req.GetFolder() == "" {
+		return status.Error(codes.InvalidArgument, "invalid scan request: folder is required")
+	}
+
+	// The scan blocks on progress sends, so updates are drained even after
+	// the client goes away
+	progress := make(chan api.ScanProgress, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range progress {
+			if err := stream.Send(&imagedv1.ScanEvent{Event: &imagedv1.ScanEvent_Progress{Progress: toProtoProgress(p)}}); err != nil {
+				s.logger.Debugf("Failed to send scan progress: %v", err)
+			}
+		}
+	}()
+
+	report, err := s.engine.ScanFolderWithWorkers(stream.Context(), req.GetFolder(), int(req.GetWorkers()), progress)
+	close(progress)
+	<-done
+
+	// A cancelled or truncated scan still reports what it indexed
+	if report != nil {
+		if sendErr := stream.Send(&imagedv1.ScanEvent{Event: &imagedv1.ScanEvent_Report{Report: toProtoScanReport(report)}}); sendErr != nil && err == nil {
+			return sendErr
+		}
+	}
+	if err != nil {
+		return grpcError("failed to scan folder", err)
+	}
+	return nil
+}
+
+// FindDuplicates returns the exact and, unless exact_only is set, near-duplicate groups
+func (s *GRPCService) FindDuplicates(ctx context.Context, req *imagedv1.FindDuplicatesRequest) (*imagedv1.FindDuplicatesResponse, error) {
+	threshold := req.GetThreshold()
+	if threshold == 0 {
+		threshold = api.DefaultSimilarityThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid threshold: %v", threshold)
+	}
+
+	var exact, near []api.DuplicateGroup
+	var err error
+	if req.GetExactOnly() {
+		exact, err = s.engine.FindExactDuplicates()
+	} else {
+		exact, near, err = s.engine.FindDuplicatesCtx(ctx, threshold, req.GetRecompute())
+	}
+	if err != nil {
+		return nil, grpcError("failed to find duplicates", err)
+	}
+
+	return &imagedv1.FindDuplicatesResponse{
+		Exact: toProtoGroups(exact),
+		Near:  toProtoGroups(near),
+	}, nil
+}
+
+// Clean cleans duplicates with the requested options
+func (s *GRPCService) Clean(ctx context.Context, req *imagedv1.CleanRequest) (*imagedv1.CleanReport, error) {
+	options := api.CleanOptions{
+		DryRun:                 req.GetDryRun(),
+		SelectionPolicy:        api.SelectionPolicy(req.GetSelectionPolicy()),
+		MinQualityScore:        req.GetMinQualityScore(),
+		MaxSimilarityThreshold: req.GetMaxSimilarityThreshold(),
+		MoveDuplicates:         req.GetMoveDuplicates(),
+		TrashDuplicates:        req.GetTrashDuplicates(),
+		OutputDir:              req.GetOutputDir(),
+		QualityMargin:          req.GetQualityMargin(),
+	}
+	if options.MaxSimilarityThreshold == 0 {
+		options.MaxSimilarityThreshold = api.DefaultSimilarityThreshold
+	}
+
+	report, err := s.engine.CleanDuplicatesCtx(ctx, options)
+	if err != nil {
+		return nil, grpcError("failed to clean duplicates", err)
+	}
+	return toProtoCleanReport(report), nil
+}
+
+// grpcError converts an engine error into a gRPC status with a matching code
+func grpcError(op string, err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, os.ErrNotExist), errors.Is(err, api.ErrImageNotFound):
+		code = codes.NotFound
+	case errors.Is(err, api.ErrScanLimitReached):
+		code = codes.ResourceExhausted
+	case errors.Is(err, api.ErrEngineClosed):
+		code = codes.Unavailable
+	}
+	return status.Errorf(code, "%s: %v", op, err)
+}
+
+// toProtoProgress converts a scan progress update
+func toProtoProgress(p api.ScanProgress) *imagedv1.ScanProgress {
+	return &imagedv1.ScanProgress{
+		Current:     int32(p.Current),
+		Total:       int32(p.Total),
+		CurrentFile: p.CurrentFile,
+		Percentage:  p.Percentage,
+	}
+}
+
+// toProtoScanReport converts a scan report
+func toProtoScanReport(report *api.ScanReport) *imagedv1.ScanReport {
+	out := &imagedv1.ScanReport{
+		ScanId:          report.ScanID,
+		TotalFiles:      int32(report.TotalFiles),
+		ProcessedImages: int32(report.ProcessedImages),
+		SkippedFiles:    int32(report.SkippedFiles),
+		UnchangedFiles:  int32(report.UnchangedFiles),
+		RenamedFiles:    int32(report.RenamedFiles),
+		ScanDurationMs:  report.ScanDuration.Milliseconds(),
+	}
+	for _, scanErr := range report.Errors {
+		out.Errors = append(out.Errors, &imagedv1.ScanError{Path: scanErr.Path, Reason: scanErr.Reason})
+	}
+	return out
+}
+
+// toProtoGroups converts duplicate groups
+func toProtoGroups(groups []api.DuplicateGroup) []*imagedv1.DuplicateGroup {
+	out := make([]*imagedv1.DuplicateGroup, 0, len(groups))
+	for _, group := range groups {
+		dup := &imagedv1.DuplicateGroup{
+			GroupId:    group.GroupID,
+			MainImage:  string(group.MainImage),
+			Reason:     group.Reason,
+			Confidence: group.Confidence,
+			Formats:    group.Formats,
+			Threshold:  group.Threshold,
+		}
+		for _, id := range group.DuplicateIDs {
+			dup.DuplicateIds = append(dup.DuplicateIds, string(id))
+		}
+		out = append(out, dup)
+	}
+	return out
+}
+
+// toProtoCleanReport converts a clean report
+func toProtoCleanReport(report *api.CleanReport) *imagedv1.CleanReport {
+	out := &imagedv1.CleanReport{
+		TotalProcessed:  int32(report.TotalProcessed),
+		MovedFiles:      int32(report.MovedFiles),
+		FreedSpaceBytes: report.FreedSpace,
+		Errors:          int32(report.Errors),
+		ManifestPath:    report.ManifestPath,
+		NeedsReview:     report.NeedsReview,
+	}
+	for _, action := range report.Actions {
+		out.Actions = append(out.Actions, &imagedv1.PlannedAction{
+			Source:    action.Source,
+			Dest:      action.Dest,
+			Action:    string(action.Action),
+			GroupId:   action.GroupID,
+			SizeBytes: action.SizeBytes,
+		})
+	}
+	for _, group := range report.Groups {
+		out.Groups = append(out.Groups, &imagedv1.CleanGroupResult{
+			GroupId:     group.GroupID,
+			Succeeded:   group.Succeeded,
+			Error:       group.Error,
+			RolledBack:  group.RolledBack,
+			NotRestored: group.NotRestored,
+		})
+	}
+	return out
+}
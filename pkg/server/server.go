@@ -0,0 +1,235 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/sirupsen/logrus"
+)
+
+// ScanRequest is the body of a POST /scan request
+type ScanRequest struct {
+	Path    string `json:"path"`
+	Workers int    `json:"workers,omitempty"` // 0 uses the engine's NumWorkers
+}
+
+// DuplicatesResponse is the body of a GET /duplicates response
+type DuplicatesResponse struct {
+	Exact []api.DuplicateGroup `json:"exact"`
+	Near  []api.DuplicateGroup `json:"near"`
+}
+
+// errorResponse is the body of every failed request
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server exposes an engine as a JSON over HTTP API:
+//
+//	POST /scan                 scan a folder; streams progress as Server-Sent
+//	                           Events when the client accepts text/event-stream
+//	GET  /duplicates           exact and near-duplicate groups (?threshold=&recompute=)
+//	POST /clean                clean duplicates with the api.CleanOptions in the body
+//	GET  /images/{id}/quality  quality analysis of an indexed image
+type Server struct {
+	engine *engine.Engine
+	mux    *http.ServeMux
+	logger *logrus.Logger
+}
+
+// NewServer creates an HTTP API for eng
+func NewServer(eng *engine.Engine) *Server {
+	s := &Server{
+		engine: eng,
+		mux:    http.NewServeMux(),
+		logger: logrus.New(),
+	}
+
+	s.mux.HandleFunc("/scan", s.handleScan)
+	s.mux.HandleFunc("/duplicates", s.handleDuplicates)
+	s.mux.HandleFunc("/clean", s.handleClean)
+	s.mux.HandleFunc("/images/", s.handleImage)
+
+	return s
+}
+
+// ServeHTTP routes a request to its handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleScan scans the folder named in the request body
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+		return
+	}
+	if req.Path == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid scan request: path is required"))
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.streamScan(w, r, req)
+		return
+	}
+
+	report, err := s.engine.ScanFolderWithWorkers(r.Context(), req.Path, req.Workers, nil)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to scan folder: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// streamScan runs a scan, sending each progress update as a "progress" event
+// and finishing with a "report" event, or an "error" event if the scan failed
+func (s *Server) streamScan(w http.ResponseWriter, r *http.Request, req ScanRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// The scan blocks on progress sends, so updates are drained even after
+	// the client goes away
+	progress := make(chan api.ScanProgress, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range progress {
+			s.writeEvent(w, "progress", p)
+			flusher.Flush()
+		}
+	}()
+
+	report, err := s.engine.ScanFolderWithWorkers(r.Context(), req.Path, req.Workers, progress)
+	close(progress)
+	<-done
+
+	if err != nil {
+		s.writeEvent(w, "error", errorResponse{Error: err.Error()})
+	} else {
+		s.writeEvent(w, "report", report)
+	}
+	flusher.Flush()
+}
+
+// handleDuplicates returns the duplicate groups at the requested threshold
+func (s *Server) handleDuplicates(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	threshold := api.DefaultSimilarityThreshold
+	if value := query.Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid threshold: %q", value))
+			return
+		}
+		threshold = parsed
+	}
+	recompute := query.Get("recompute") == "true"
+
+	exact, near, err := s.engine.FindDuplicates(threshold, recompute)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to find duplicates: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, DuplicatesResponse{Exact: exact, Near: near})
+}
+
+// handleClean cleans duplicates with the options in the request body
+func (s *Server) handleClean(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var options api.CleanOptions
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid clean options: %w", err))
+		return
+	}
+
+	report, err := s.engine.CleanDuplicates(options)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to clean duplicates: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleImage serves GET /images/{id}/quality
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/images/"), "/quality")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	fp, err := s.engine.GetFingerprint(api.ImageID(id))
+	if errors.Is(err, api.ErrImageNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("image %s not found", id))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get image: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, fp.Quality)
+}
+
+// writeEvent writes v as a Server-Sent Event of the given type
+func (s *Server) writeEvent(w http.ResponseWriter, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Warnf("Failed to encode %s event: %v", event, err)
+		return
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		s.logger.Debugf("Failed to write %s event: %v", event, err)
+	}
+}
+
+// allowMethod rejects requests whose method is not method
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response with the given status
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,136 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/HaiderBassem/imaged/pkg/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeGradientPNG writes a 64x64 gradient whose direction depends on flip
+func writeGradientPNG(t *testing.T, path string, flip bool) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(x * 4)
+			if flip {
+				v = uint8(y * 4)
+			}
+			img.Set(x, y, color.RGBA{R: v, G: 255 - v, B: uint8(x * y), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, string) {
+	photosDir := t.TempDir()
+	writeGradientPNG(t, filepath.Join(photosDir, "a.png"), false)
+	writeGradientPNG(t, filepath.Join(photosDir, "a_copy.png"), false)
+	writeGradientPNG(t, filepath.Join(photosDir, "b.png"), true)
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "test.db")
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { eng.Close() })
+
+	ts := httptest.NewServer(server.NewServer(eng))
+	t.Cleanup(ts.Close)
+	return ts, photosDir
+}
+
+func TestServer_ScanThenDuplicates(t *testing.T) {
+	ts, photosDir := newTestServer(t)
+
+	body, err := json.Marshal(server.ScanRequest{Path: photosDir, Workers: 2})
+	require.NoError(t, err)
+	resp, err := http.Post(ts.URL+"/scan", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var report api.ScanReport
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, 3, report.ProcessedImages)
+
+	resp, err = http.Get(ts.URL + "/duplicates?threshold=0.9")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var duplicates server.DuplicatesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&duplicates))
+	require.Len(t, duplicates.Exact, 1)
+	assert.Len(t, duplicates.Exact[0].DuplicateIDs, 1)
+
+	resp, err = http.Get(ts.URL + "/images/" + string(duplicates.Exact[0].MainImage) + "/quality")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var quality api.ImageQuality
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&quality))
+	assert.Greater(t, quality.FinalScore, 0.0)
+
+	resp, err = http.Get(ts.URL + "/images/img_missing/quality")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/duplicates?threshold=high")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_ScanStreamsProgressEvents(t *testing.T) {
+	ts, photosDir := newTestServer(t)
+
+	body, err := json.Marshal(server.ScanRequest{Path: photosDir})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/scan", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []string
+	var last string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			last = data
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, events, 4)
+	assert.Equal(t, []string{"progress", "progress", "progress", "report"}, events)
+	var report api.ScanReport
+	require.NoError(t, json.Unmarshal([]byte(last), &report))
+	assert.Equal(t, 3, report.ProcessedImages)
+}
@@ -0,0 +1,226 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: proto/imaged/v1/imaged.proto
+
+package imagedv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	ImageD_Scan_FullMethodName           = "/imaged.v1.ImageD/Scan"
+	ImageD_FindDuplicates_FullMethodName = "/imaged.v1.ImageD/FindDuplicates"
+	ImageD_Clean_FullMethodName          = "/imaged.v1.ImageD/Clean"
+)
+
+// ImageDClient is the client API for ImageD service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ImageD exposes the engine's scan, duplicate search and clean operations.
+// Messages mirror the types in pkg/api; field names follow their JSON tags.
+type ImageDClient interface {
+	// Scan indexes a folder, streaming progress and ending with the report.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (ImageD_ScanClient, error)
+	// FindDuplicates returns the exact and near-duplicate groups in the index.
+	FindDuplicates(ctx context.Context, in *FindDuplicatesRequest, opts ...grpc.CallOption) (*FindDuplicatesResponse, error)
+	// Clean moves or trashes duplicates; dry runs only plan the actions.
+	Clean(ctx context.Context, in *CleanRequest, opts ...grpc.CallOption) (*CleanReport, error)
+}
+
+type imageDClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImageDClient(cc grpc.ClientConnInterface) ImageDClient {
+	return &imageDClient{cc}
+}
+
+func (c *imageDClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (ImageD_ScanClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ImageD_ServiceDesc.Streams[0], ImageD_Scan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imageDScanClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ImageD_ScanClient interface {
+	Recv() (*ScanEvent, error)
+	grpc.ClientStream
+}
+
+type imageDScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *imageDScanClient) Recv() (*ScanEvent, error) {
+	m := new(ScanEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imageDClient) FindDuplicates(ctx context.Context, in *FindDuplicatesRequest, opts ...grpc.CallOption) (*FindDuplicatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindDuplicatesResponse)
+	err := c.cc.Invoke(ctx, ImageD_FindDuplicates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imageDClient) Clean(ctx context.Context, in *CleanRequest, opts ...grpc.CallOption) (*CleanReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CleanReport)
+	err := c.cc.Invoke(ctx, ImageD_Clean_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageDServer is the server API for ImageD service.
+// All implementations must embed UnimplementedImageDServer
+// for forward compatibility
+//
+// ImageD exposes the engine's scan, duplicate search and clean operations.
+// Messages mirror the types in pkg/api; field names follow their JSON tags.
+type ImageDServer interface {
+	// Scan indexes a folder, streaming progress and ending with the report.
+	Scan(*ScanRequest, ImageD_ScanServer) error
+	// FindDuplicates returns the exact and near-duplicate groups in the index.
+	FindDuplicates(context.Context, *FindDuplicatesRequest) (*FindDuplicatesResponse, error)
+	// Clean moves or trashes duplicates; dry runs only plan the actions.
+	Clean(context.Context, *CleanRequest) (*CleanReport, error)
+	mustEmbedUnimplementedImageDServer()
+}
+
+// UnimplementedImageDServer must be embedded to have forward compatible implementations.
+type UnimplementedImageDServer struct {
+}
+
+func (UnimplementedImageDServer) Scan(*ScanRequest, ImageD_ScanServer) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedImageDServer) FindDuplicates(context.Context, *FindDuplicatesRequest) (*FindDuplicatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindDuplicates not implemented")
+}
+func (UnimplementedImageDServer) Clean(context.Context, *CleanRequest) (*CleanReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clean not implemented")
+}
+func (UnimplementedImageDServer) mustEmbedUnimplementedImageDServer() {}
+
+// UnsafeImageDServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImageDServer will
+// result in compilation errors.
+type UnsafeImageDServer interface {
+	mustEmbedUnimplementedImageDServer()
+}
+
+func RegisterImageDServer(s grpc.ServiceRegistrar, srv ImageDServer) {
+	s.RegisterService(&ImageD_ServiceDesc, srv)
+}
+
+func _ImageD_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageDServer).Scan(m, &imageDScanServer{ServerStream: stream})
+}
+
+type ImageD_ScanServer interface {
+	Send(*ScanEvent) error
+	grpc.ServerStream
+}
+
+type imageDScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *imageDScanServer) Send(m *ScanEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ImageD_FindDuplicates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindDuplicatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageDServer).FindDuplicates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageD_FindDuplicates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageDServer).FindDuplicates(ctx, req.(*FindDuplicatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ImageD_Clean_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CleanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImageDServer).Clean(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ImageD_Clean_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImageDServer).Clean(ctx, req.(*CleanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ImageD_ServiceDesc is the grpc.ServiceDesc for ImageD service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ImageD_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "imaged.v1.ImageD",
+	HandlerType: (*ImageDServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FindDuplicates",
+			Handler:    _ImageD_FindDuplicates_Handler,
+		},
+		{
+			MethodName: "Clean",
+			Handler:    _ImageD_Clean_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _ImageD_Scan_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/imaged/v1/imaged.proto",
+}
@@ -0,0 +1,200 @@
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildCLI compiles the imaged command into a temporary directory
+func buildCLI(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds the CLI")
+	}
+	binary := filepath.Join(t.TempDir(), "imaged")
+	build := exec.Command("go", "build", "-o", binary, "../../cmd/imaged-cli")
+	output, err := build.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return binary
+}
+
+func TestCLI_ScanJSONProgress(t *testing.T) {
+	binary := buildCLI(t)
+
+	photosDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 64, int64(i))
+	}
+
+	var stdout, stderr bytes.Buffer
+	scan := exec.Command(binary, "scan", "--path", photosDir,
+		"--index", filepath.Join(t.TempDir(), "index.db"), "--progress-format", "json")
+	scan.Stdout, scan.Stderr = &stdout, &stderr
+	require.NoError(t, scan.Run(), stderr.String())
+
+	// Every stdout line is a progress object; the summary goes to stderr
+	var records []map[string]interface{}
+	lines := bufio.NewScanner(&stdout)
+	for lines.Scan() {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &record), lines.Text())
+		records = append(records, record)
+	}
+	require.Len(t, records, 5)
+
+	for i, record := range records {
+		assert.Equal(t, float64(i+1), record["current"])
+		assert.Equal(t, float64(5), record["total"])
+		assert.Contains(t, record, "percentage")
+		assert.Contains(t, record["current_file"], photosDir)
+		_, err := time.Parse(time.RFC3339Nano, record["timestamp"].(string))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(100), records[4]["percentage"])
+	assert.Contains(t, stderr.String(), "Scan completed successfully")
+}
+
+func TestCLI_DedupDryRun(t *testing.T) {
+	binary := buildCLI(t)
+
+	// photo00 has a byte-identical copy; the other photos are unrelated
+	photosDir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 64, int64(i))
+	}
+	data, err := os.ReadFile(filepath.Join(photosDir, "photo00.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo00_copy.png"), data, 0644))
+
+	outputDir := filepath.Join(t.TempDir(), "duplicates")
+	var stdout, stderr bytes.Buffer
+	dedup := exec.Command(binary, "dedup", "--path", photosDir, "--index", filepath.Join(t.TempDir(), "index.db"),
+		"--output", outputDir, "--dry-run", "--policy", "oldest")
+	dedup.Stdout, dedup.Stderr = &stdout, &stderr
+	require.NoError(t, dedup.Run(), stderr.String())
+
+	output := stdout.String()
+	assert.Contains(t, output, "Images indexed: 4 of 4")
+	assert.Contains(t, output, "Planned actions:")
+	assert.Regexp(t, `move\s+\S*photo00(_copy)?\.png -> `+regexp.QuoteMeta(outputDir), output)
+	assert.Contains(t, output, "This was a dry run")
+
+	// Nothing was moved
+	entries, err := os.ReadDir(photosDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+	assert.NoDirExists(t, outputDir)
+
+	bad := exec.Command(binary, "dedup", "--path", photosDir, "--index", filepath.Join(t.TempDir(), "index.db"),
+		"--dry-run", "--policy", "largest")
+	badOutput, err := bad.CombinedOutput()
+	assert.Error(t, err)
+	assert.Contains(t, string(badOutput), "quality, resolution, exposure, oldest, newest")
+}
+
+func TestCLI_DedupDelete(t *testing.T) {
+	binary := buildCLI(t)
+
+	photosDir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 64, int64(i))
+	}
+	data, err := os.ReadFile(filepath.Join(photosDir, "photo00.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo00_copy.png"), data, 0644))
+
+	outputDir := filepath.Join(t.TempDir(), "duplicates")
+	var stdout, stderr bytes.Buffer
+	dedup := exec.Command(binary, "dedup", "--path", photosDir, "--index", filepath.Join(t.TempDir(), "index.db"),
+		"--output", outputDir, "--delete")
+	dedup.Stdout, dedup.Stderr = &stdout, &stderr
+	require.NoError(t, dedup.Run(), stderr.String())
+	assert.Contains(t, stdout.String(), "Files moved/deleted: 1")
+
+	// One of the two copies is gone for good and nothing was moved aside
+	entries, err := os.ReadDir(photosDir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Len(t, names, 3)
+	assert.Contains(t, names, "photo01.png")
+	assert.Contains(t, names, "photo02.png")
+	assert.NoDirExists(t, outputDir)
+}
+
+func TestCLI_QualityRanksDirectory(t *testing.T) {
+	binary := buildCLI(t)
+
+	// Noise, a smooth gradient and a flat gray square score differently
+	photosDir := t.TempDir()
+	writeNoisePNG(t, filepath.Join(photosDir, "noise.png"), 64, 1)
+	gradient := image.NewGray(image.Rect(0, 0, 64, 64))
+	flat := image.NewGray(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetGray(x, y, color.Gray{Y: uint8(x * 4)})
+			flat.SetGray(x, y, color.Gray{Y: 128})
+		}
+	}
+	for name, img := range map[string]image.Image{"gradient.png": gradient, "flat.png": flat} {
+		file, err := os.Create(filepath.Join(photosDir, name))
+		require.NoError(t, err)
+		require.NoError(t, png.Encode(file, img))
+		require.NoError(t, file.Close())
+	}
+	indexPath := filepath.Join(t.TempDir(), "index.db")
+
+	// rank runs the command and returns the scores and paths of its table rows
+	rank := func(args ...string) ([]float64, []string) {
+		cmd := exec.Command(binary, append([]string{"quality", "--path", photosDir, "--index", indexPath}, args...)...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		require.NoError(t, cmd.Run(), stderr.String())
+
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		require.NotEmpty(t, lines)
+		assert.Contains(t, lines[0], "SCORE")
+		var scores []float64
+		var paths []string
+		for _, line := range lines[1:] {
+			fields := strings.Fields(line)
+			score, err := strconv.ParseFloat(fields[0], 64)
+			require.NoError(t, err, line)
+			scores = append(scores, score)
+			paths = append(paths, fields[len(fields)-1])
+		}
+		return scores, paths
+	}
+
+	desc, descPaths := rank("--sort", "desc")
+	require.Len(t, desc, 3)
+	assert.True(t, sort.IsSorted(sort.Reverse(sort.Float64Slice(desc))), "%v", desc)
+	assert.Less(t, desc[2], desc[0])
+
+	asc, _ := rank("--sort", "asc")
+	require.Len(t, asc, 3)
+	assert.True(t, sort.Float64sAreSorted(asc), "%v", asc)
+
+	top, topPaths := rank("--top", "2")
+	assert.Equal(t, desc[:2], top)
+	assert.Equal(t, descPaths[:2], topPaths)
+}
@@ -1 +1,61 @@
 package integration
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	imaged "github.com/HaiderBassem/imaged/pkg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickScan_ReportsScanDuration(t *testing.T) {
+	photosDir := t.TempDir()
+	for i := 0; i < 12; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 1024, int64(i))
+	}
+
+	// QuickScan keeps its index in the working directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	start := time.Now()
+	report, err := imaged.QuickScan(photosDir)
+	elapsed := time.Since(start)
+	require.NoError(t, err)
+
+	assert.Equal(t, 12, report.TotalFiles)
+	assert.Equal(t, 12, report.ProcessedImages)
+	assert.Zero(t, report.SkippedFiles)
+	assert.Positive(t, report.ScanDuration)
+	assert.LessOrEqual(t, report.ScanDuration, elapsed)
+	// Decoding and hashing dominate, so the scan accounts for most of the call
+	assert.Greater(t, report.ScanDuration, elapsed/2)
+	assert.Equal(t, report.ScanDuration, report.CompletedAt.Sub(report.StartedAt))
+}
+
+// writeNoisePNG writes a size x size PNG of pseudo-random pixels, which is
+// slow enough to decode and analyze that scan timings are measurable
+func writeNoisePNG(t *testing.T, path string, size int, seed int64) {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	state := uint32(seed*2654435761 + 1)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			state = state*1664525 + 1013904223
+			img.Set(x, y, color.RGBA{R: uint8(state >> 24), G: uint8(state >> 16), B: uint8(state >> 8), A: 255})
+		}
+	}
+
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, png.Encode(file, img))
+}
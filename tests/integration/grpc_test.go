@@ -0,0 +1,232 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/pkg/engine"
+	"github.com/HaiderBassem/imaged/pkg/server"
+	imagedv1 "github.com/HaiderBassem/imaged/proto/imaged/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// startGRPCServer serves a fresh engine on a random local port and returns a client for it
+func startGRPCServer(t *testing.T) imagedv1.ImageDClient {
+	t.Helper()
+
+	cfg := engine.DefaultConfig()
+	cfg.IndexPath = filepath.Join(t.TempDir(), "index.db")
+	cfg.LogLevel = "error"
+	eng, err := engine.NewEngine(cfg)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	imagedv1.RegisterImageDServer(grpcServer, server.NewGRPCService(eng))
+	served := make(chan error, 1)
+	go func() { served <- grpcServer.Serve(listener) }()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.GracefulStop()
+		assert.NoError(t, <-served)
+		eng.Close()
+	})
+
+	return imagedv1.NewImageDClient(conn)
+}
+
+func TestGRPC_ScanFindAndClean(t *testing.T) {
+	client := startGRPCServer(t)
+
+	photosDir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 64, int64(i))
+	}
+	data, err := os.ReadFile(filepath.Join(photosDir, "photo00.png"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(photosDir, "photo00_copy.png"), data, 0644))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	stream, err := client.Scan(ctx, &imagedv1.ScanRequest{Folder: photosDir, Workers: 2})
+	require.NoError(t, err)
+
+	var progress []*imagedv1.ScanProgress
+	var report *imagedv1.ScanReport
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		require.Nil(t, report, "no event may follow the report")
+
+		switch e := event.GetEvent().(type) {
+		case *imagedv1.ScanEvent_Progress:
+			progress = append(progress, e.Progress)
+		case *imagedv1.ScanEvent_Report:
+			report = e.Report
+		}
+	}
+
+	require.NotNil(t, report)
+	assert.EqualValues(t, 5, report.GetTotalFiles())
+	assert.EqualValues(t, 5, report.GetProcessedImages())
+	assert.Empty(t, report.GetErrors())
+	require.Len(t, progress, 5)
+	assert.EqualValues(t, 5, progress[len(progress)-1].GetCurrent())
+	assert.EqualValues(t, 5, progress[len(progress)-1].GetTotal())
+
+	duplicates, err := client.FindDuplicates(ctx, &imagedv1.FindDuplicatesRequest{ExactOnly: true})
+	require.NoError(t, err)
+	require.Len(t, duplicates.GetExact(), 1)
+	assert.Len(t, duplicates.GetExact()[0].GetDuplicateIds(), 1)
+	assert.Empty(t, duplicates.GetNear())
+
+	outputDir := t.TempDir()
+	clean, err := client.Clean(ctx, &imagedv1.CleanRequest{
+		DryRun:         true,
+		MoveDuplicates: true,
+		OutputDir:      outputDir,
+	})
+	require.NoError(t, err)
+	require.Len(t, clean.GetActions(), 1)
+	assert.Equal(t, duplicates.GetExact()[0].GetGroupId(), clean.GetActions()[0].GetGroupId())
+	assert.Zero(t, clean.GetMovedFiles())
+
+	// A dry run leaves every file where it was
+	entries, err := os.ReadDir(photosDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 5)
+}
+
+func TestGRPC_ScanErrors(t *testing.T) {
+	client := startGRPCServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	for name, tc := range map[string]struct {
+		folder string
+		code   codes.Code
+	}{
+		"empty folder":   {folder: "", code: codes.InvalidArgument},
+		"missing folder": {folder: filepath.Join(t.TempDir(), "missing"), code: codes.NotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stream, err := client.Scan(ctx, &imagedv1.ScanRequest{Folder: tc.folder})
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			assert.Equal(t, tc.code, status.Code(err), "%v", err)
+		})
+	}
+}
+
+func TestGRPC_ScanCancelledByClient(t *testing.T) {
+	client := startGRPCServer(t)
+
+	photosDir := t.TempDir()
+	for i := 0; i < 40; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 512, int64(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Scan(ctx, &imagedv1.ScanRequest{Folder: photosDir, Workers: 1})
+	require.NoError(t, err)
+
+	// Cancel as soon as the first image is done
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, event.GetProgress())
+	cancel()
+
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	assert.Equal(t, codes.Canceled, status.Code(err), "%v", err)
+}
+
+func TestGRPCServer_ShutdownFinishesRunningCalls(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the server")
+	}
+	binary := filepath.Join(t.TempDir(), "imaged-server")
+	build := exec.Command("go", "build", "-o", binary, "../../cmd/imaged-server")
+	output, err := build.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	photosDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		writeNoisePNG(t, filepath.Join(photosDir, fmt.Sprintf("photo%02d.png", i)), 256, int64(i))
+	}
+
+	server := exec.Command(binary, "--listen", "127.0.0.1:0", "--index", filepath.Join(t.TempDir(), "index.db"), "--workers", "1")
+	stdout, err := server.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	t.Cleanup(func() { server.Process.Kill() })
+
+	// The server prints the address it bound
+	lines := bufio.NewScanner(stdout)
+	var addr string
+	for addr == "" && lines.Scan() {
+		if match := regexp.MustCompile(`Serving gRPC on (\S+)`).FindStringSubmatch(lines.Text()); match != nil {
+			addr = match[1]
+		}
+	}
+	require.NotEmpty(t, addr)
+	go io.Copy(io.Discard, stdout)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := imagedv1.NewImageDClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stream, err := client.Scan(ctx, &imagedv1.ScanRequest{Folder: photosDir})
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, event.GetProgress())
+
+	// The scan in flight runs to completion before the server exits
+	require.NoError(t, server.Process.Signal(syscall.SIGTERM))
+	var report *imagedv1.ScanReport
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		if event.GetReport() != nil {
+			report = event.GetReport()
+		}
+	}
+	require.NotNil(t, report)
+	assert.EqualValues(t, 20, report.GetProcessedImages())
+	assert.NoError(t, server.Wait())
+}
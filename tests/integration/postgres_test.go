@@ -0,0 +1,125 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/index"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresTestDSN names the environment variable holding a disposable test database;
+// the Postgres tests also need the postgres build tag
+const postgresTestDSN = "IMAGED_TEST_POSTGRES_DSN"
+
+// openPostgresTestStore connects to the test database and empties it
+func openPostgresTestStore(t *testing.T, readOnly bool) index.Store {
+	t.Helper()
+	dsn := os.Getenv(postgresTestDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresTestDSN)
+	}
+
+	store, err := index.NewStore(index.Config{Type: index.StoreTypePostgres, Path: dsn, ReadOnly: readOnly})
+	if errors.Is(err, index.ErrPostgresDriverUnavailable) {
+		t.Skip("built without the postgres tag")
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	if !readOnly {
+		existing, err := store.ListFingerprints(0, 0)
+		require.NoError(t, err)
+		for _, fp := range existing {
+			require.NoError(t, store.DeleteFingerprint(fp.ID))
+		}
+	}
+	return store
+}
+
+// postgresFingerprint builds a fingerprint with the given content and perceptual hash
+func postgresFingerprint(id int, sha string, ahash uint64) api.ImageFingerprint {
+	return api.ImageFingerprint{
+		ID: api.ImageID(fmt.Sprintf("img_%03d", id)),
+		Metadata: api.ImageMetadata{
+			Path:      fmt.Sprintf("/photos/%03d.jpg", id),
+			SizeBytes: 1000,
+			SHA256:    sha,
+		},
+		PHashes:   api.PerceptualHashes{AHash: ahash},
+		Quality:   api.ImageQuality{FinalScore: float64(10 * id)},
+		CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestPostgresStore_RoundTrip(t *testing.T) {
+	store := openPostgresTestStore(t, false)
+
+	// The top bit exercises the unsigned to bigint mapping
+	const highHash = uint64(1)<<63 | 0xFF
+	fps := []api.ImageFingerprint{
+		postgresFingerprint(1, "aaa", highHash),
+		postgresFingerprint(2, "aaa", highHash^0b111),
+		postgresFingerprint(3, "bbb", 0x0F0F0F0F),
+	}
+	require.NoError(t, store.SaveFingerprints(fps))
+
+	fp, err := store.GetFingerprint("img_001")
+	require.NoError(t, err)
+	assert.Equal(t, highHash, fp.PHashes.AHash)
+	assert.Equal(t, "/photos/001.jpg", fp.Metadata.Path)
+
+	exists, id, err := store.Exists("/photos/002.jpg")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, api.ImageID("img_002"), id)
+
+	byPath, err := store.GetFingerprintByPath("/photos/003.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, api.ImageID("img_003"), byPath.ID)
+
+	matches, err := store.FindBySHA256("aaa")
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+
+	similar, err := store.FindSimilarHashes(highHash, 3, "ahash")
+	require.NoError(t, err)
+	assert.Len(t, similar, 2)
+
+	inRange, err := store.FindByQualityRange(15, 30)
+	require.NoError(t, err)
+	assert.Len(t, inRange, 2)
+
+	stats, err := store.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalImages)
+	assert.Equal(t, int64(3000), stats.TotalSizeBytes)
+	assert.Equal(t, 1, stats.DuplicateGroups)
+
+	require.NoError(t, store.DeleteFingerprint("img_001"))
+	_, err = store.GetFingerprint("img_001")
+	assert.ErrorIs(t, err, api.ErrImageNotFound)
+	exists, _, err = store.Exists("/photos/001.jpg")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	count, err := store.CountFingerprints()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestPostgresStore_ReadOnly(t *testing.T) {
+	writable := openPostgresTestStore(t, false)
+	require.NoError(t, writable.SaveFingerprint(postgresFingerprint(1, "aaa", 1)))
+
+	store := openPostgresTestStore(t, true)
+	_, err := store.GetFingerprint("img_001")
+	require.NoError(t, err)
+	assert.ErrorIs(t, store.SaveFingerprint(postgresFingerprint(2, "bbb", 2)), index.ErrReadOnly)
+	assert.ErrorIs(t, store.DeleteFingerprint("img_001"), index.ErrReadOnly)
+}
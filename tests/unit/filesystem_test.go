@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/HaiderBassem/imaged/internal/filesystem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveToTrash_XDG(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG trash layout is only used on Linux")
+	}
+
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		path := filepath.Join(dir, "my photo.jpg")
+		require.NoError(t, os.WriteFile(path, []byte("image"), 0644))
+		require.NoError(t, filesystem.MoveToTrash(path))
+		assert.NoFileExists(t, path)
+	}
+
+	// The second file with the same name gets a distinct trash name
+	trash := filepath.Join(dataHome, "Trash")
+	assert.FileExists(t, filepath.Join(trash, "files", "my photo.jpg"))
+	assert.FileExists(t, filepath.Join(trash, "files", "my photo.1.jpg"))
+
+	info, err := os.ReadFile(filepath.Join(trash, "info", "my photo.jpg.trashinfo"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(info)), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "[Trash Info]", lines[0])
+	assert.Equal(t, "Path="+strings.ReplaceAll(filepath.Join(dir, "my photo.jpg"), " ", "%20"), lines[1])
+	assert.Regexp(t, `^DeletionDate=\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}$`, lines[2])
+	assert.FileExists(t, filepath.Join(trash, "info", "my photo.1.jpg.trashinfo"))
+}
+
+func TestFilesEqual_StreamsLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 16<<20)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+
+	original := filepath.Join(dir, "original.bin")
+	identical := filepath.Join(dir, "identical.bin")
+	lastByte := filepath.Join(dir, "last_byte.bin")
+	truncated := filepath.Join(dir, "truncated.bin")
+	require.NoError(t, os.WriteFile(original, data, 0644))
+	require.NoError(t, os.WriteFile(identical, data, 0644))
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(lastByte, data, 0644))
+	require.NoError(t, os.WriteFile(truncated, data[:len(data)-1], 0644))
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	equal, err := filesystem.FilesEqual(original, identical)
+	runtime.ReadMemStats(&after)
+	require.NoError(t, err)
+	assert.True(t, equal)
+	// Only the two chunk buffers are allocated, not the 16MB contents
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+
+	equal, err = filesystem.FilesEqual(original, lastByte)
+	require.NoError(t, err)
+	assert.False(t, equal)
+
+	equal, err = filesystem.FilesEqual(original, truncated)
+	require.NoError(t, err)
+	assert.False(t, equal)
+
+	_, err = filesystem.FilesEqual(original, filepath.Join(dir, "missing.bin"))
+	assert.Error(t, err)
+}
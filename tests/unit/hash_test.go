@@ -1 +1,33 @@
 package unit
+
+import (
+	"testing"
+
+	"github.com/HaiderBassem/imaged/internal/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorSignature_EMDSeesHowFarColorsMove(t *testing.T) {
+	colors := hash.NewColorSignature(4)
+
+	// channelHist puts all of the red channel in one bin; green and blue match
+	channelHist := func(redBin int) []float64 {
+		hist := make([]float64, 12)
+		hist[redBin] = 1
+		hist[4], hist[8] = 1, 1
+		return hist
+	}
+	dark, darkish, bright := channelHist(0), channelHist(1), channelHist(3)
+
+	// Chi-squared only sees that the red mass left its bin, not how far it went
+	assert.Equal(t, colors.CompareHistograms(dark, darkish), colors.CompareHistograms(dark, bright))
+
+	near := colors.CompareHistogramsEMD(dark, darkish)
+	far := colors.CompareHistogramsEMD(dark, bright)
+	assert.Greater(t, near, far)
+	assert.InDelta(t, 1-1.0/9, near, 1e-9)
+	assert.InDelta(t, 1-1.0/3, far, 1e-9)
+	assert.Equal(t, 1.0, colors.CompareHistogramsEMD(dark, dark))
+	assert.Equal(t, far, colors.CompareHistogramsWith(hash.HistogramEMD, dark, bright))
+	assert.Equal(t, 0.0, colors.CompareHistogramsEMD(dark, dark[:9]))
+}
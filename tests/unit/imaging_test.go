@@ -0,0 +1,319 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	internalimaging "github.com/HaiderBassem/imaged/internal/imaging"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/HaiderBassem/imaged/pkg/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isoBox encodes an ISO base media box from its type and payload
+func isoBox(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box, uint32(8+len(body)))
+	copy(box[4:], typ)
+	return append(box, body...)
+}
+
+// fullBox prefixes payload with a zero version and the given flags
+func fullBox(typ string, flags byte, payload ...[]byte) []byte {
+	return isoBox(typ, append([][]byte{{0, 0, 0, flags}}, payload...)...)
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// sampleHEIC builds the container of an iPhone-style HEIC: a 512x512 tile
+// property and the primary grid item's 4032x3024 extents, with no pixel data
+func sampleHEIC() []byte {
+	ftyp := isoBox("ftyp", []byte("heic"), be32(0), []byte("mif1heic"))
+	hdlr := fullBox("hdlr", 0, be32(0), []byte("pict"), make([]byte, 12), []byte{0})
+	pitm := fullBox("pitm", 0, be16(1))
+	ipco := isoBox("ipco",
+		fullBox("ispe", 0, be32(512), be32(512)),
+		fullBox("ispe", 0, be32(4032), be32(3024)),
+	)
+	ipma := fullBox("ipma", 0, be32(2),
+		be16(1), []byte{1, 0x80 | 2},
+		be16(2), []byte{1, 0x80 | 1},
+	)
+	meta := fullBox("meta", 0, hdlr, pitm, isoBox("iprp", ipco, ipma))
+	return bytes.Join([][]byte{ftyp, meta, isoBox("mdat")}, nil)
+}
+
+func TestDecoder_HEICDimensions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "IMG_0001.heic")
+	require.NoError(t, os.WriteFile(path, sampleHEIC(), 0644))
+
+	decoder := imaging.NewDecoder()
+	config, format, err := decoder.DecodeImageConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "heic", format)
+	assert.Equal(t, 4032, config.Width)
+	assert.Equal(t, 3024, config.Height)
+
+	if !imaging.HEICDecodingSupported() {
+		_, err := decoder.DecodeImage(path)
+		assert.ErrorIs(t, err, imaging.ErrHEICDecoderUnavailable)
+	}
+}
+
+// writeSolidPNG writes a width x height PNG filled with c
+func writeSolidPNG(t *testing.T, path string, width, height int, c color.Color) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestThumbnailCache_HitMissAndInvalidation(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "photo.png")
+	writeSolidPNG(t, source, 800, 400, color.RGBA{R: 200, A: 255})
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(source, modTime, modTime))
+
+	cacheDir := t.TempDir()
+	cache, err := internalimaging.NewThumbnailCache(cacheDir)
+	require.NoError(t, err)
+	fp := api.ImageFingerprint{ID: "img_thumb", Metadata: api.ImageMetadata{Path: source}}
+
+	// Miss: the thumbnail is generated and written to disk
+	first, err := cache.GetOrCreateThumbnail(fp, 256)
+	require.NoError(t, err)
+	thumb, err := jpeg.Decode(bytes.NewReader(first))
+	require.NoError(t, err)
+	assert.Equal(t, 256, thumb.Bounds().Dx())
+	assert.Equal(t, 128, thumb.Bounds().Dy())
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Hit: new content with an unchanged mtime is still served from the cache
+	writeSolidPNG(t, source, 800, 400, color.RGBA{B: 200, A: 255})
+	require.NoError(t, os.Chtimes(source, modTime, modTime))
+	cached, err := cache.GetOrCreateThumbnail(fp, 256)
+	require.NoError(t, err)
+	assert.Equal(t, first, cached)
+
+	// Invalidation: a new mtime regenerates the thumbnail from the new content
+	changed := modTime.Add(time.Hour)
+	require.NoError(t, os.Chtimes(source, changed, changed))
+	rebuilt, err := cache.GetOrCreateThumbnail(fp, 256)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, rebuilt)
+
+	thumb, err = jpeg.Decode(bytes.NewReader(rebuilt))
+	require.NoError(t, err)
+	r, _, b, _ := thumb.At(10, 10).RGBA()
+	assert.Greater(t, b, r)
+}
+
+// encoderTestImage builds a smooth gradient with, optionally, varying alpha
+func encoderTestImage(withAlpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 67, 41))
+	for y := 0; y < 41; y++ {
+		for x := 0; x < 67; x++ {
+			c := color.NRGBA{R: uint8(x * 3), G: uint8(y * 5), B: uint8(x + 2*y), A: 255}
+			if withAlpha {
+				c.A = uint8(x + y)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestSaveImage_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		lossless  bool
+		withAlpha bool
+	}{
+		{"photo.jpg", false, false},
+		{"photo.png", true, true},
+		{"photo.webp", true, true},
+		{"opaque.webp", true, false},
+		{"photo.tiff", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := encoderTestImage(tt.withAlpha)
+			path := filepath.Join(t.TempDir(), tt.name)
+			require.NoError(t, imaging.SaveImage(src, path, imaging.EncodeOptions{Quality: 95}))
+
+			file, err := os.Open(path)
+			require.NoError(t, err)
+			defer file.Close()
+			decoded, _, _, err := imaging.DecodeRepresentativeFrame(file)
+			require.NoError(t, err)
+			require.Equal(t, src.Bounds(), decoded.Bounds())
+
+			var totalDiff, samples int
+			for y := 0; y < src.Bounds().Dy(); y++ {
+				for x := 0; x < src.Bounds().Dx(); x++ {
+					want := src.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if tt.lossless {
+						require.Equal(t, want, got, "pixel %d,%d", x, y)
+						continue
+					}
+					for _, d := range []int{int(want.R) - int(got.R), int(want.G) - int(got.G), int(want.B) - int(got.B)} {
+						if d < 0 {
+							d = -d
+						}
+						totalDiff += d
+						samples++
+					}
+				}
+			}
+			if !tt.lossless {
+				assert.Less(t, float64(totalDiff)/float64(samples), 4.0)
+			}
+		})
+	}
+
+	err := imaging.SaveImage(encoderTestImage(false), filepath.Join(t.TempDir(), "photo.bmpx"), imaging.EncodeOptions{})
+	assert.Error(t, err)
+}
+
+func TestColorSpace_DominantColorsRecoversRegions(t *testing.T) {
+	// Half red, a third of it blue and the rest green, each with slight noise
+	img := image.NewRGBA(image.Rect(0, 0, 120, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 120; x++ {
+			jitter := uint8((x*7 + y*13) % 9)
+			switch {
+			case x < 60:
+				img.Set(x, y, color.RGBA{R: 200 + jitter, G: 30, B: 30, A: 255})
+			case x < 100:
+				img.Set(x, y, color.RGBA{R: 20, G: 40, B: 190 + jitter, A: 255})
+			default:
+				img.Set(x, y, color.RGBA{R: 40, G: 180 + jitter, B: 50, A: 255})
+			}
+		}
+	}
+
+	dominant := imaging.NewColorSpace().DominantColors(img, 3)
+	require.Len(t, dominant, 3)
+
+	want := []struct {
+		color    color.RGBA
+		coverage float64
+	}{
+		{color.RGBA{R: 204, G: 30, B: 30, A: 255}, 0.5},
+		{color.RGBA{R: 20, G: 40, B: 194, A: 255}, 1.0 / 3},
+		{color.RGBA{R: 40, G: 184, B: 50, A: 255}, 1.0 / 6},
+	}
+	for i, w := range want {
+		assert.InDelta(t, w.coverage, dominant[i].Coverage, 0.03)
+		assert.InDelta(t, float64(w.color.R), float64(dominant[i].Color.R), 6)
+		assert.InDelta(t, float64(w.color.G), float64(dominant[i].Color.G), 6)
+		assert.InDelta(t, float64(w.color.B), float64(dominant[i].Color.B), 6)
+	}
+
+	// A single color covers the whole image
+	single := imaging.NewColorSpace().DominantColors(img, 1)
+	require.Len(t, single, 1)
+	assert.Equal(t, 1.0, single[0].Coverage)
+}
+
+func TestDecoder_RejectsImagesOverPixelBudget(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.png")
+	writeSolidPNG(t, small, 100, 100, color.RGBA{G: 200, A: 255})
+	large := filepath.Join(dir, "large.png")
+	writeSolidPNG(t, large, 200, 200, color.RGBA{G: 200, A: 255})
+
+	decoder := imaging.NewDecoder()
+	decoder.SetLimits(imaging.DecodeLimits{MaxPixels: 100 * 100, Timeout: time.Second})
+
+	img, err := decoder.DecodeImage(small)
+	require.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+
+	_, err = decoder.DecodeImage(large)
+	assert.ErrorIs(t, err, imaging.ErrImageTooLarge)
+}
+
+// slowReader delays every read, like a stalled network share
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	if len(p) > 16 {
+		p = p[:16]
+	}
+	return s.r.Read(p)
+}
+
+func TestDecodeWithLimits_TimesOutSlowReader(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))))
+
+	decode := func(r io.Reader) error {
+		_, err := png.Decode(r)
+		return err
+	}
+	limits := imaging.DecodeLimits{MaxPixels: 1 << 20, Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	err := imaging.DecodeWithLimits(context.Background(), &slowReader{r: bytes.NewReader(buf.Bytes()), delay: 20 * time.Millisecond}, limits, decode)
+	assert.ErrorIs(t, err, imaging.ErrDecodeTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	require.NoError(t, imaging.DecodeWithLimits(context.Background(), bytes.NewReader(buf.Bytes()), limits, decode))
+}
+
+func TestDecodeWithLimitsNotify_FinishesAfterAbandonedDecode(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))))
+
+	// The decode ignores the timeout until it is let go
+	unblock := make(chan struct{})
+	decode := func(r io.Reader) error {
+		<-unblock
+		return nil
+	}
+	finished := make(chan struct{})
+	limits := imaging.DecodeLimits{Timeout: 20 * time.Millisecond}
+
+	err := imaging.DecodeWithLimitsNotify(context.Background(), bytes.NewReader(buf.Bytes()), limits, decode, func() { close(finished) })
+	assert.ErrorIs(t, err, imaging.ErrDecodeTimeout)
+
+	select {
+	case <-finished:
+		t.Fatal("finished was called while the decode was still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(unblock)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("finished was not called after the decode returned")
+	}
+}
@@ -0,0 +1,854 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/index"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStores returns one store per backend, closed when the test ends
+func newTestStores(t *testing.T) map[string]index.Store {
+	t.Helper()
+	dir := t.TempDir()
+
+	stores := make(map[string]index.Store)
+	for name, cfg := range map[string]index.Config{
+		"memory": {Type: index.StoreTypeMemory},
+		"bolt":   {Type: index.StoreTypeBoltDB, Path: filepath.Join(dir, "index.db")},
+		"sqlite": {Type: index.StoreTypeSQLite, Path: filepath.Join(dir, "index.sqlite")},
+	} {
+		store, err := index.NewStore(cfg)
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		stores[name] = store
+	}
+	return stores
+}
+
+// testFingerprint builds a minimal fingerprint with the given content hash
+func testFingerprint(id int, sha string) api.ImageFingerprint {
+	return api.ImageFingerprint{
+		ID: api.ImageID(fmt.Sprintf("img_%03d", id)),
+		Metadata: api.ImageMetadata{
+			Path:      fmt.Sprintf("/photos/%03d.jpg", id),
+			SizeBytes: 1000,
+			SHA256:    sha,
+		},
+		PHashes: api.PerceptualHashes{AHash: uint64(id + 1)},
+		Quality: api.ImageQuality{FinalScore: 50},
+	}
+}
+
+func TestStore_StatsDuplicateGroups(t *testing.T) {
+	// Two groups: "aaa" shared by three images, "bbb" by two; "ccc" is unique
+	shas := []string{"aaa", "aaa", "aaa", "bbb", "bbb", "ccc"}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, sha := range shas {
+				require.NoError(t, store.SaveFingerprint(testFingerprint(i, sha)))
+			}
+
+			stats, err := store.GetStats()
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(shas)), stats.TotalImages)
+			assert.Equal(t, int64(len(shas)*1000), stats.TotalSizeBytes)
+			assert.Equal(t, 2, stats.DuplicateGroups)
+		})
+	}
+}
+
+func TestStore_StatsReclaimableBytes(t *testing.T) {
+	// Three copies of one photo, two copies of a second photo that shares its
+	// pHash with a resized third, and an unrelated fourth
+	files := []struct {
+		sha   string
+		pHash uint64
+		size  int64
+	}{
+		{"aaa", 0x1111, 5000},
+		{"aaa", 0x1111, 5000},
+		{"aaa", 0x1111, 5000},
+		{"bbb", 0x2222, 3000},
+		{"bbb", 0x2222, 3000},
+		{"ccc", 0x2222, 1200},
+		{"ddd", 0x4444, 7000},
+	}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, file := range files {
+				fp := testFingerprint(i, file.sha)
+				fp.PHashes.PHash = file.pHash
+				fp.Metadata.SizeBytes = file.size
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+
+			stats, err := store.GetStats()
+			require.NoError(t, err)
+			assert.Equal(t, 2, stats.DuplicateGroups)
+			assert.Equal(t, int64(2*5000+3000), stats.ExactDuplicateBytes)
+			assert.Equal(t, int64(1200), stats.NearDuplicateBytes)
+		})
+	}
+}
+
+func TestBoltStore_StatsIndexSize(t *testing.T) {
+	store, err := index.NewBoltStore(filepath.Join(t.TempDir(), "index.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, store.SaveFingerprint(testFingerprint(i, fmt.Sprintf("sha_%d", i))))
+	}
+
+	stats, err := store.GetStats()
+	require.NoError(t, err)
+	assert.Greater(t, stats.IndexSizeBytes, int64(0))
+}
+
+func TestStore_IterateFingerprints(t *testing.T) {
+	errStop := errors.New("stop")
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 25; i++ {
+				require.NoError(t, store.SaveFingerprint(testFingerprint(i, fmt.Sprintf("sha_%d", i))))
+			}
+
+			seen := make(map[api.ImageID]bool)
+			err := store.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+				assert.False(t, seen[fp.ID], "visited %s twice", fp.ID)
+				seen[fp.ID] = true
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Len(t, seen, 25)
+
+			// An error from fn stops the iteration and is returned unchanged
+			visits := 0
+			err = store.IterateFingerprints(context.Background(), func(fp api.ImageFingerprint) error {
+				visits++
+				return errStop
+			})
+			assert.ErrorIs(t, err, errStop)
+			assert.Equal(t, 1, visits)
+
+			// The store remains usable after an aborted iteration
+			_, err = store.GetFingerprint(testFingerprint(0, "").ID)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStore_ListFingerprintsPages(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			const total = 23
+			for i := total - 1; i >= 0; i-- {
+				require.NoError(t, store.SaveFingerprint(testFingerprint(i, fmt.Sprintf("sha_%d", i))))
+			}
+
+			count, err := store.CountFingerprints()
+			require.NoError(t, err)
+			assert.Equal(t, int64(total), count)
+
+			var ids []api.ImageID
+			for offset := 0; ; offset += 5 {
+				page, err := store.ListFingerprints(offset, 5)
+				require.NoError(t, err)
+				if len(page) == 0 {
+					break
+				}
+				assert.LessOrEqual(t, len(page), 5)
+				for _, fp := range page {
+					ids = append(ids, fp.ID)
+				}
+			}
+
+			// Pages concatenate to the full set, in ID order, with no gaps or overlaps
+			require.Len(t, ids, total)
+			for i, id := range ids {
+				assert.Equal(t, testFingerprint(i, "").ID, id)
+			}
+
+			all, err := store.ListFingerprints(0, 0)
+			require.NoError(t, err)
+			assert.Len(t, all, total)
+		})
+	}
+}
+
+func TestStore_GetFingerprintByPath(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.SaveFingerprint(testFingerprint(1, "sha_1")))
+
+			fp, err := store.GetFingerprintByPath("/photos/001.jpg")
+			require.NoError(t, err)
+			assert.Equal(t, api.ImageID("img_001"), fp.ID)
+
+			_, err = store.GetFingerprintByPath("/photos/missing.jpg")
+			assert.ErrorIs(t, err, api.ErrImageNotFound)
+		})
+	}
+}
+
+func TestStore_PalettePersists(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			fp := testFingerprint(1, "sha_1")
+			fp.Palette = []api.PaletteColor{
+				{R: 200, G: 60, B: 20, Coverage: 0.75},
+				{R: 20, G: 40, B: 180, Coverage: 0.25},
+			}
+			require.NoError(t, store.SaveFingerprint(fp))
+			require.NoError(t, store.SaveFingerprint(testFingerprint(2, "sha_2")))
+
+			got, err := store.GetFingerprint(fp.ID)
+			require.NoError(t, err)
+			assert.Equal(t, fp.Palette, got.Palette)
+
+			got, err = store.GetFingerprint("img_002")
+			require.NoError(t, err)
+			assert.Empty(t, got.Palette)
+		})
+	}
+}
+
+func TestStore_UpdateMetadataMovesPathOnly(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			fp := testFingerprint(1, "sha_1")
+			fp.Quality.FinalScore = 72
+			require.NoError(t, store.SaveFingerprint(fp))
+
+			meta := fp.Metadata
+			meta.Path = "/photos/moved/001.jpg"
+			require.NoError(t, store.UpdateMetadata(fp.ID, meta))
+
+			_, err := store.GetFingerprintByPath("/photos/001.jpg")
+			assert.ErrorIs(t, err, api.ErrImageNotFound)
+			got, err := store.GetFingerprintByPath(meta.Path)
+			require.NoError(t, err)
+			assert.Equal(t, fp.ID, got.ID)
+			assert.Equal(t, fp.PHashes, got.PHashes)
+			assert.Equal(t, 72.0, got.Quality.FinalScore)
+
+			// The SHA256 and hash indices still resolve to the one image
+			matches, err := store.FindBySHA256("sha_1")
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			assert.Equal(t, meta.Path, matches[0].Metadata.Path)
+
+			similar, err := store.FindSimilarHashes(fp.PHashes.AHash, 0, "ahash")
+			require.NoError(t, err)
+			require.Len(t, similar, 1)
+			assert.Equal(t, fp.ID, similar[0].ID)
+
+			err = store.UpdateMetadata("img_missing", meta)
+			assert.ErrorIs(t, err, api.ErrImageNotFound)
+		})
+	}
+}
+
+func TestStore_SaveGroupsRoundTrip(t *testing.T) {
+	groups := []api.DuplicateGroup{
+		{GroupID: "exact_a", MainImage: "img_001", DuplicateIDs: []api.ImageID{"img_002"}, Reason: api.ReasonExact, Confidence: 1, Threshold: 0.9},
+		{GroupID: "near_b", MainImage: "img_003", DuplicateIDs: []api.ImageID{"img_004", "img_005"}, Reason: api.ReasonNear, Confidence: 0.93, Threshold: 0.9},
+	}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			saved, err := store.GetGroups()
+			require.NoError(t, err)
+			assert.Empty(t, saved)
+
+			require.NoError(t, store.SaveGroups(groups))
+			saved, err = store.GetGroups()
+			require.NoError(t, err)
+			assert.Equal(t, groups, saved)
+
+			// Saving again replaces rather than appends
+			require.NoError(t, store.SaveGroups(groups[1:]))
+			saved, err = store.GetGroups()
+			require.NoError(t, err)
+			assert.Equal(t, groups[1:], saved)
+		})
+	}
+}
+
+func TestStore_FingerprintChangesInvalidateGroups(t *testing.T) {
+	groups := []api.DuplicateGroup{
+		{GroupID: "exact_a", MainImage: "img_001", DuplicateIDs: []api.ImageID{"img_002"}, Reason: api.ReasonExact, Confidence: 1},
+	}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.SaveFingerprints([]api.ImageFingerprint{
+				testFingerprint(1, "sha_1"), testFingerprint(2, "sha_1"), testFingerprint(3, "sha_3"),
+			}))
+
+			require.NoError(t, store.SaveGroups(groups))
+			require.NoError(t, store.DeleteFingerprint("img_002"))
+			saved, err := store.GetGroups()
+			require.NoError(t, err)
+			assert.Empty(t, saved, "delete")
+
+			require.NoError(t, store.SaveGroups(groups))
+			require.NoError(t, store.SaveFingerprint(testFingerprint(4, "sha_4")))
+			saved, err = store.GetGroups()
+			require.NoError(t, err)
+			assert.Empty(t, saved, "save")
+
+			require.NoError(t, store.SaveGroups(groups))
+			fp := testFingerprint(1, "sha_1")
+			fp.Metadata.Path = "/photos/moved/001.jpg"
+			require.NoError(t, store.UpdateMetadata(fp.ID, fp.Metadata))
+			saved, err = store.GetGroups()
+			require.NoError(t, err)
+			assert.Empty(t, saved, "update")
+		})
+	}
+}
+
+func TestStore_Exists(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.SaveFingerprint(testFingerprint(1, "sha_1")))
+
+			exists, id, err := store.Exists("/photos/001.jpg")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, api.ImageID("img_001"), id)
+
+			exists, id, err = store.Exists("/photos/002.jpg")
+			require.NoError(t, err)
+			assert.False(t, exists)
+			assert.Empty(t, id)
+
+			require.NoError(t, store.DeleteFingerprint("img_001"))
+			exists, _, err = store.Exists("/photos/001.jpg")
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestStore_ResaveUnderNewPathReleasesOldPath(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			fp := testFingerprint(1, "sha_1")
+			require.NoError(t, store.SaveFingerprint(fp))
+
+			fp.Metadata.Path = "/photos/moved/001.jpg"
+			require.NoError(t, store.SaveFingerprint(fp))
+
+			exists, _, err := store.Exists("/photos/001.jpg")
+			require.NoError(t, err)
+			assert.False(t, exists)
+			_, err = store.GetFingerprintByPath("/photos/001.jpg")
+			assert.ErrorIs(t, err, api.ErrImageNotFound)
+
+			exists, id, err := store.Exists(fp.Metadata.Path)
+			require.NoError(t, err)
+			assert.True(t, exists)
+			assert.Equal(t, fp.ID, id)
+		})
+	}
+}
+
+func TestMemoryStore_ConcurrentAccess(t *testing.T) {
+	store, err := index.NewMemoryStore()
+	require.NoError(t, err)
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				id := w*perWriter + i
+				fp := testFingerprint(id, fmt.Sprintf("sha_%d", id%10))
+				assert.NoError(t, store.SaveFingerprint(fp))
+				if i%5 == 0 {
+					assert.NoError(t, store.DeleteFingerprint(fp.ID))
+				}
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				store.GetFingerprint(testFingerprint(i, "").ID)
+				_, err := store.GetAllFingerprints()
+				assert.NoError(t, err)
+				_, err = store.FindBySHA256(fmt.Sprintf("sha_%d", i%10))
+				assert.NoError(t, err)
+				_, err = store.FindSimilarHashes(uint64(i), 4, "ahash")
+				assert.NoError(t, err)
+				_, err = store.GetStats()
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, err := store.CountFingerprints()
+	require.NoError(t, err)
+	assert.Equal(t, int64(writers*perWriter*4/5), count)
+}
+
+func TestStore_FindBySHA256ReturnsEveryDuplicate(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Two files with identical content, and one unrelated file
+			require.NoError(t, store.SaveFingerprint(testFingerprint(1, "same")))
+			require.NoError(t, store.SaveFingerprint(testFingerprint(2, "same")))
+			require.NoError(t, store.SaveFingerprint(testFingerprint(3, "other")))
+
+			matches, err := store.FindBySHA256("same")
+			require.NoError(t, err)
+			var ids []api.ImageID
+			for _, fp := range matches {
+				ids = append(ids, fp.ID)
+			}
+			assert.ElementsMatch(t, []api.ImageID{"img_001", "img_002"}, ids)
+
+			// Deleting one copy keeps the other indexed
+			require.NoError(t, store.DeleteFingerprint("img_001"))
+			matches, err = store.FindBySHA256("same")
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			assert.Equal(t, api.ImageID("img_002"), matches[0].ID)
+
+			// Re-saving an image with new content moves it to the new hash
+			changed := testFingerprint(2, "edited")
+			require.NoError(t, store.SaveFingerprint(changed))
+			matches, err = store.FindBySHA256("same")
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+		})
+	}
+}
+
+func TestStore_FindSimilarHashes(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			// Two images share one hash; a third is a bit away; a fourth is far off
+			for id, ahash := range map[int]uint64{1: 0xF0F0, 2: 0xF0F0, 3: 0xF0F1, 4: 0x0F0F} {
+				fp := testFingerprint(id, fmt.Sprintf("sha_%d", id))
+				fp.PHashes.AHash = ahash
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+			resaved := testFingerprint(1, "sha_1")
+			resaved.PHashes.AHash = 0xF0F0
+			require.NoError(t, store.SaveFingerprint(resaved))
+
+			matches, err := store.FindSimilarHashes(0xF0F0, 1, "ahash")
+			require.NoError(t, err)
+			var ids []api.ImageID
+			for _, fp := range matches {
+				ids = append(ids, fp.ID)
+			}
+			assert.ElementsMatch(t, []api.ImageID{"img_001", "img_002", "img_003"}, ids)
+
+			matches, err = store.FindSimilarHashes(0, 64, "ahash")
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+
+			_, err = store.FindSimilarHashes(0xF0F0, 1, "xhash")
+			assert.ErrorIs(t, err, index.ErrUnknownHashType)
+		})
+	}
+}
+
+func TestStore_FindByQualityRange(t *testing.T) {
+	scores := []float64{10, 39.99, 40, 55, 70, 70.01, 95}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, score := range scores {
+				fp := testFingerprint(i, fmt.Sprintf("sha_%d", i))
+				fp.Quality.FinalScore = score
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+
+			// Both bounds are inclusive
+			matches, err := store.FindByQualityRange(40, 70)
+			require.NoError(t, err)
+			var got []float64
+			for _, fp := range matches {
+				got = append(got, fp.Quality.FinalScore)
+			}
+			assert.ElementsMatch(t, []float64{40, 55, 70}, got)
+
+			matches, err = store.FindByQualityRange(96, 100)
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+		})
+	}
+}
+
+func TestStore_FindByCaptureDate(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	taken := map[int]time.Time{
+		0: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),    // trip start, inclusive
+		1: time.Date(2024, 5, 3, 18, 30, 15, 0, paris),    // during the trip
+		2: time.Date(2024, 5, 7, 23, 59, 59, 0, time.UTC), // trip end, inclusive
+		3: time.Date(2024, 4, 30, 23, 0, 0, 0, time.UTC),  // day before
+		4: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),   // weeks later
+	}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, at := range taken {
+				fp := testFingerprint(i, fmt.Sprintf("sha_%d", i))
+				fp.Metadata.EXIF = &api.EXIFInfo{TakenAt: at}
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+			// No EXIF at all, and EXIF without a capture time
+			require.NoError(t, store.SaveFingerprint(testFingerprint(5, "sha_5")))
+			undated := testFingerprint(6, "sha_6")
+			undated.Metadata.EXIF = &api.EXIFInfo{CameraModel: "X100"}
+			require.NoError(t, store.SaveFingerprint(undated))
+
+			matches, err := store.FindByCaptureDate(
+				time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 7, 23, 59, 59, 0, time.UTC))
+			require.NoError(t, err)
+
+			var ids []api.ImageID
+			for _, fp := range matches {
+				ids = append(ids, fp.ID)
+			}
+			assert.ElementsMatch(t, []api.ImageID{"img_000", "img_001", "img_002"}, ids)
+
+			// A range starting at the zero time still skips undated images
+			matches, err = store.FindByCaptureDate(time.Time{}, time.Date(2024, 4, 30, 23, 30, 0, 0, time.UTC))
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			assert.Equal(t, api.ImageID("img_003"), matches[0].ID)
+		})
+	}
+}
+
+func TestStore_FindNearLocation(t *testing.T) {
+	places := map[int]*api.EXIFInfo{
+		0: {GPSLat: 48.8584, GPSLon: 2.2945, HasGPS: true},   // Eiffel Tower
+		1: {GPSLat: 48.8606, GPSLon: 2.3376, HasGPS: true},   // Louvre
+		2: {GPSLat: 35.6586, GPSLon: 139.7454, HasGPS: true}, // Tokyo Tower
+		3: {CameraModel: "no fix"},                           // EXIF without GPS
+	}
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, exif := range places {
+				fp := testFingerprint(i, fmt.Sprintf("sha_%d", i))
+				fp.Metadata.EXIF = exif
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+			require.NoError(t, store.SaveFingerprint(testFingerprint(4, "sha_4")))
+
+			// Notre-Dame: both Paris photos are within 5 km, Tokyo is not
+			matches, err := store.FindNearLocation(48.8530, 2.3499, 5)
+			require.NoError(t, err)
+			var ids []api.ImageID
+			for _, fp := range matches {
+				ids = append(ids, fp.ID)
+			}
+			assert.ElementsMatch(t, []api.ImageID{"img_000", "img_001"}, ids)
+
+			matches, err = store.FindNearLocation(48.8530, 2.3499, 2)
+			require.NoError(t, err)
+			require.Len(t, matches, 1)
+			assert.Equal(t, api.ImageID("img_001"), matches[0].ID)
+		})
+	}
+}
+
+// writeBoltV1Fixture creates an unversioned (v1) Bolt index holding one
+// fingerprint and a stale path entry left over from a rename
+func writeBoltV1Fixture(t *testing.T, path string) api.ImageFingerprint {
+	t.Helper()
+	fp := testFingerprint(1, "sha_1")
+
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{"fingerprints", "sha256_index", "path_index"} {
+			if _, err := tx.CreateBucket([]byte(name)); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(fp)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket([]byte("fingerprints")).Put([]byte(fp.ID), data); err != nil {
+			return err
+		}
+		paths := tx.Bucket([]byte("path_index"))
+		if err := paths.Put([]byte(fp.Metadata.Path), []byte(fp.ID)); err != nil {
+			return err
+		}
+		return paths.Put([]byte("/photos/old-name.jpg"), []byte(fp.ID))
+	}))
+	return fp
+}
+
+func TestBoltStore_MigratesV1Index(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	fp := writeBoltV1Fixture(t, path)
+
+	store, err := index.NewBoltStore(path)
+	require.NoError(t, err)
+
+	version, err := store.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	require.NoError(t, store.Migrate())
+	version, err = store.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, index.CurrentSchemaVersion, version)
+
+	got, err := store.GetFingerprint(fp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fp.Metadata.Path, got.Metadata.Path)
+
+	// The SHA256 index is rebuilt from the stored fingerprints
+	matches, err := store.FindBySHA256(fp.Metadata.SHA256)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, fp.ID, matches[0].ID)
+	require.NoError(t, store.Close())
+
+	// The stale path entry was dropped, the live one kept
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.View(func(tx *bolt.Tx) error {
+		paths := tx.Bucket([]byte("path_index"))
+		assert.Nil(t, paths.Get([]byte("/photos/old-name.jpg")))
+		assert.Equal(t, []byte(fp.ID), paths.Get([]byte(fp.Metadata.Path)))
+		return nil
+	}))
+}
+
+func TestSQLiteStore_MigratesV1Index(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.sqlite")
+
+	// The original layout: base tables, no capture-time index, no version table
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE fingerprints (
+		id TEXT PRIMARY KEY, metadata TEXT NOT NULL, phashes TEXT NOT NULL,
+		quality TEXT NOT NULL, color_hist TEXT, feature_vec TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store, err := index.NewSQLiteStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	version, err := store.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	require.NoError(t, store.Migrate())
+	version, err = store.SchemaVersion()
+	require.NoError(t, err)
+	assert.Equal(t, index.CurrentSchemaVersion, version)
+
+	// Migrating an up-to-date index is a no-op
+	require.NoError(t, store.Migrate())
+}
+
+func TestStore_NewIndexUsesCurrentSchema(t *testing.T) {
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			version, err := store.SchemaVersion()
+			require.NoError(t, err)
+			assert.Equal(t, index.CurrentSchemaVersion, version)
+		})
+	}
+}
+
+func TestBoltStore_RejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	store, err := index.NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("metadata")).Put([]byte("schema_version"), []byte("99"))
+	}))
+	require.NoError(t, db.Close())
+
+	_, err = index.NewBoltStore(path)
+	assert.ErrorIs(t, err, index.ErrSchemaTooNew)
+}
+
+// benchmarkBatch builds n fingerprints with distinct hashes and paths
+func benchmarkBatch(n int) []api.ImageFingerprint {
+	fps := make([]api.ImageFingerprint, n)
+	for i := range fps {
+		fps[i] = testFingerprint(i, fmt.Sprintf("sha_%d", i))
+	}
+	return fps
+}
+
+// Each SaveFingerprint call commits (and fsyncs) its own transaction
+func BenchmarkBoltStore_SaveFingerprintEach(b *testing.B) {
+	fps := benchmarkBatch(256)
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		store, err := index.NewBoltStore(filepath.Join(b.TempDir(), "index.db"))
+		require.NoError(b, err)
+		b.StartTimer()
+
+		for _, fp := range fps {
+			require.NoError(b, store.SaveFingerprint(fp))
+		}
+
+		b.StopTimer()
+		store.Close()
+		b.StartTimer()
+	}
+}
+
+// SaveFingerprints commits the same 256 fingerprints in a single transaction
+func BenchmarkBoltStore_SaveFingerprintsBatch(b *testing.B) {
+	fps := benchmarkBatch(256)
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		store, err := index.NewBoltStore(filepath.Join(b.TempDir(), "index.db"))
+		require.NoError(b, err)
+		b.StartTimer()
+
+		require.NoError(b, store.SaveFingerprints(fps))
+
+		b.StopTimer()
+		store.Close()
+		b.StartTimer()
+	}
+}
+
+func TestStore_ReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	for name, cfg := range map[string]index.Config{
+		"bolt":   {Type: index.StoreTypeBoltDB, Path: filepath.Join(dir, "index.db")},
+		"sqlite": {Type: index.StoreTypeSQLite, Path: filepath.Join(dir, "index.sqlite")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			writable, err := index.NewStore(cfg)
+			require.NoError(t, err)
+			require.NoError(t, writable.SaveFingerprint(testFingerprint(1, "sha_1")))
+			require.NoError(t, writable.Close())
+
+			cfg.ReadOnly = true
+			store, err := index.NewStore(cfg)
+			require.NoError(t, err)
+			defer store.Close()
+
+			// Several read-only consumers may share the index
+			other, err := index.NewStore(cfg)
+			require.NoError(t, err)
+			defer other.Close()
+
+			fp, err := store.GetFingerprint("img_001")
+			require.NoError(t, err)
+			assert.Equal(t, "/photos/001.jpg", fp.Metadata.Path)
+			count, err := other.CountFingerprints()
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+
+			assert.ErrorIs(t, store.SaveFingerprint(testFingerprint(2, "sha_2")), index.ErrReadOnly)
+			assert.ErrorIs(t, store.SaveFingerprints([]api.ImageFingerprint{testFingerprint(3, "sha_3")}), index.ErrReadOnly)
+			assert.ErrorIs(t, store.DeleteFingerprint("img_001"), index.ErrReadOnly)
+			assert.ErrorIs(t, store.Compact(), index.ErrReadOnly)
+
+			_, err = store.GetFingerprint("img_001")
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestStore_ReadOnlyRequiresExistingIndex(t *testing.T) {
+	dir := t.TempDir()
+	_, err := index.NewStore(index.Config{Type: index.StoreTypeBoltDB, Path: filepath.Join(dir, "missing.db"), ReadOnly: true})
+	assert.Error(t, err)
+	_, err = index.NewStore(index.Config{Type: index.StoreTypeSQLite, Path: filepath.Join(dir, "missing.sqlite"), ReadOnly: true})
+	assert.Error(t, err)
+}
+
+func TestStore_BackupRestoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for name, cfg := range map[string]index.Config{
+		"memory": {Type: index.StoreTypeMemory},
+		"bolt":   {Type: index.StoreTypeBoltDB, Path: filepath.Join(dir, "index.db")},
+		"sqlite": {Type: index.StoreTypeSQLite, Path: filepath.Join(dir, "index.sqlite")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, err := index.NewStore(cfg)
+			require.NoError(t, err)
+			defer store.Close()
+
+			for i := 0; i < 20; i++ {
+				fp := testFingerprint(i, fmt.Sprintf("sha_%d", i%7))
+				fp.Quality.FinalScore = float64(i) * 4.5
+				fp.FeatureVec = []float32{float32(i), 0.5}
+				require.NoError(t, store.SaveFingerprint(fp))
+			}
+
+			// Backing up leaves the live store usable
+			var backup bytes.Buffer
+			require.NoError(t, store.Backup(&backup))
+			require.NoError(t, store.SaveFingerprint(testFingerprint(99, "after_backup")))
+
+			restoreCfg := cfg
+			if cfg.Path != "" {
+				restoreCfg.Path = cfg.Path + ".restored"
+			}
+			restored, err := index.NewStoreFromBackup(restoreCfg, &backup)
+			require.NoError(t, err)
+			defer restored.Close()
+
+			require.NoError(t, store.DeleteFingerprint(testFingerprint(99, "").ID))
+			want, err := store.ListFingerprints(0, 0)
+			require.NoError(t, err)
+			got, err := restored.ListFingerprints(0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			matches, err := restored.FindBySHA256("sha_0")
+			require.NoError(t, err)
+			assert.Len(t, matches, 3)
+
+			// File-backed restores never overwrite an existing index
+			if cfg.Path != "" {
+				_, err := index.NewStoreFromBackup(restoreCfg, bytes.NewReader(nil))
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -1 +1,74 @@
 package unit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/metadata"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHaversineKm(t *testing.T) {
+	assert.InDelta(t, 0, metadata.HaversineKm(48.8584, 2.2945, 48.8584, 2.2945), 1e-9)
+
+	// Eiffel Tower to Tokyo Tower is roughly 9,700 km
+	assert.InDelta(t, 9712, metadata.HaversineKm(48.8584, 2.2945, 35.6586, 139.7454), 15)
+
+	// Distance is symmetric
+	assert.InDelta(t,
+		metadata.HaversineKm(48.8584, 2.2945, 35.6586, 139.7454),
+		metadata.HaversineKm(35.6586, 139.7454, 48.8584, 2.2945), 1e-9)
+}
+
+// placeGeocoder names every location after one place, or fails when err is set
+type placeGeocoder struct {
+	place string
+	err   error
+}
+
+func (g placeGeocoder) PlaceName(lat, lon float64) (string, error) {
+	return g.place, g.err
+}
+
+// exifFingerprint builds a fingerprint captured at takenAt, geotagged when gps is set
+func exifFingerprint(id string, takenAt time.Time, gps bool, lat, lon float64) api.ImageFingerprint {
+	return api.ImageFingerprint{
+		ID: api.ImageID(id),
+		Metadata: api.ImageMetadata{EXIF: &api.EXIFInfo{
+			TakenAt: takenAt,
+			HasGPS:  gps,
+			GPSLat:  lat,
+			GPSLon:  lon,
+		}},
+	}
+}
+
+func TestClusterNamer_NamesSameDayPhotosByDateAndPlace(t *testing.T) {
+	day := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	members := []api.ImageFingerprint{
+		exifFingerprint("img_1", day, true, 48.8584, 2.2945),
+		exifFingerprint("img_2", day.Add(time.Hour), true, 48.8586, 2.2947),
+		exifFingerprint("img_3", day.Add(2*time.Hour), false, 0, 0),
+	}
+
+	assert.Equal(t, "2023-07 48.86,2.29", metadata.NewClusterNamer(nil).Name(members))
+	assert.Equal(t, "2023-07 Paris", metadata.NewClusterNamer(placeGeocoder{place: "Paris"}).Name(members))
+
+	// A failing geocoder falls back to coordinates
+	failing := placeGeocoder{err: errors.New("offline")}
+	assert.Equal(t, "2023-07 48.86,2.29", metadata.NewClusterNamer(failing).Name(members))
+}
+
+func TestClusterNamer_PicksDominantMonth(t *testing.T) {
+	namer := metadata.NewClusterNamer(nil)
+	members := []api.ImageFingerprint{
+		exifFingerprint("img_1", time.Date(2022, 12, 31, 23, 0, 0, 0, time.UTC), false, 0, 0),
+		exifFingerprint("img_2", time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC), false, 0, 0),
+		exifFingerprint("img_3", time.Date(2023, 1, 2, 9, 0, 0, 0, time.UTC), false, 0, 0),
+	}
+	assert.Equal(t, "2023-01", namer.Name(members))
+
+	assert.Empty(t, namer.Name([]api.ImageFingerprint{{ID: "img_4"}}))
+}
@@ -0,0 +1,50 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateEstimator_ETAFollowsRecentPace(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rate := utils.NewRateEstimator(10 * time.Second)
+
+	_, ok := rate.ETA(10000)
+	assert.False(t, ok)
+	rate.Observe(start, 0)
+	_, ok = rate.ETA(10000)
+	assert.False(t, ok)
+
+	// A slow start at 10 images per second
+	for s := 1; s <= 10; s++ {
+		rate.Observe(start.Add(time.Duration(s)*time.Second), 10*s)
+	}
+	assert.InDelta(t, 10.0, rate.Rate(), 1e-9)
+
+	// Then 85 per second; once the window has passed only that pace counts
+	count := 100
+	for s := 11; s <= 50; s++ {
+		count += 85
+		rate.Observe(start.Add(time.Duration(s)*time.Second), count)
+	}
+	assert.InDelta(t, 85.0, rate.Rate(), 1e-9)
+
+	eta, ok := rate.ETA(count + 5780)
+	assert.True(t, ok)
+	assert.Equal(t, 68*time.Second, eta)
+	assert.Equal(t, "1m08s", utils.FormatETA(eta))
+
+	eta, ok = rate.ETA(count)
+	assert.True(t, ok)
+	assert.Zero(t, eta)
+}
+
+func TestFormatETA(t *testing.T) {
+	assert.Equal(t, "0s", utils.FormatETA(0))
+	assert.Equal(t, "45s", utils.FormatETA(45*time.Second+200*time.Millisecond))
+	assert.Equal(t, "59m59s", utils.FormatETA(time.Hour-time.Second))
+	assert.Equal(t, "2h05m", utils.FormatETA(2*time.Hour+5*time.Minute+30*time.Second))
+}
@@ -1 +1,239 @@
 package unit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/HaiderBassem/imaged/internal/quality"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	pkgimaging "github.com/HaiderBassem/imaged/pkg/imaging"
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegRoundTrip encodes img as a JPEG at the given quality and decodes it again
+func jpegRoundTrip(t *testing.T, img image.Image, q int) image.Image {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}))
+	decoded, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	return decoded
+}
+
+func TestAnalyzer_CompressionReflectsJPEGQuality(t *testing.T) {
+	// Smooth waves with some finer texture, which heavy quantization breaks into blocks
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			v := 128 + 60*math.Sin(float64(x)/13)*math.Cos(float64(y)/17) + 30*math.Sin(float64(x+y)/5)
+			img.Set(x, y, color.RGBA{R: uint8(v), G: uint8(255 - v), B: uint8(v / 2), A: 255})
+		}
+	}
+
+	analyzer := quality.NewAnalyzer(quality.DefaultConfig())
+	heavy, err := analyzer.Analyze(jpegRoundTrip(t, img, 5))
+	require.NoError(t, err)
+	nearLossless, err := analyzer.Analyze(jpegRoundTrip(t, img, 100))
+	require.NoError(t, err)
+
+	assert.Greater(t, heavy.Compression, nearLossless.Compression)
+	assert.Greater(t, heavy.Compression, 0.2)
+	assert.Less(t, nearLossless.Compression, 0.05)
+	assert.Greater(t, nearLossless.FinalScore, heavy.FinalScore)
+}
+
+func TestAnalyzer_LuminanceWeightsGreen(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.RGBA{G: 255, A: 255})
+		}
+	}
+
+	// Rec. 601 weighs green at 0.587, where averaging the channels gives a third
+	assert.InDelta(t, 0.587, pkgimaging.Luminance(color.RGBA{G: 255, A: 255}), 1e-9)
+	assert.Equal(t, uint8(150), pkgimaging.Grayscale(img).GrayAt(5, 5).Y)
+
+	result, err := quality.NewAnalyzer(quality.DefaultConfig()).Analyze(img)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.587, result.Exposure, 0.01)
+
+	exposure, err := quality.NewExposureAnalyzer().AnalyzeExposure(img)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.587, exposure, 0.01)
+}
+
+func TestAnalyzer_PercentileExposureToleratesHighContrast(t *testing.T) {
+	// A night shot: 70% near-black sky over bright city lights, and an image
+	// that is simply too dark throughout
+	night := image.NewGray(image.Rect(0, 0, 100, 100))
+	dark := image.NewGray(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			night.SetGray(x, y, color.Gray{Y: 13})
+			if y >= 70 {
+				night.SetGray(x, y, color.Gray{Y: 180})
+			}
+			dark.SetGray(x, y, color.Gray{Y: 13})
+		}
+	}
+
+	mean := quality.DefaultConfig()
+	percentile := quality.DefaultConfig()
+	percentile.ExposureMethod = quality.ExposurePercentile
+
+	for _, tc := range []struct {
+		name           string
+		cfg            quality.Config
+		img            image.Image
+		underexposed   bool
+		minExp, maxExp float64
+	}{
+		{"mean scores the night shot underexposed", mean, night, true, 0, 0.1},
+		{"percentile keeps the night shot", percentile, night, false, 0.3, 0.4},
+		{"mean flags the dark image", mean, dark, true, 0, 0.1},
+		{"percentile flags the dark image", percentile, dark, true, 0, 0.1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			analyzer := quality.NewAnalyzer(tc.cfg)
+			result, err := analyzer.Analyze(tc.img)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, result.Exposure, tc.minExp)
+			assert.LessOrEqual(t, result.Exposure, tc.maxExp)
+			assert.Equal(t, tc.underexposed, analyzer.IsUnderexposed(*result))
+		})
+	}
+}
+
+func TestAnalyzer_SubjectSharpnessRewardsSharpCenter(t *testing.T) {
+	// Fine texture, and the same texture blurred as a shallow depth of field would
+	rng := rand.New(rand.NewSource(7))
+	sharp := image.NewGray(image.Rect(0, 0, 160, 160))
+	for i := range sharp.Pix {
+		sharp.Pix[i] = uint8(118 + rng.Intn(21))
+	}
+	blurred := imaging.Blur(sharp, 3)
+
+	// Sharp in the central half, blurred around it
+	bokeh := imaging.Clone(blurred)
+	center := image.Rect(40, 40, 120, 120)
+	draw.Draw(bokeh, center, sharp, center.Min, draw.Src)
+
+	score := func(subject quality.SharpnessSubject, img image.Image) float64 {
+		cfg := quality.DefaultConfig()
+		cfg.SharpnessSubject = subject
+		result, err := quality.NewAnalyzer(cfg).Analyze(img)
+		require.NoError(t, err)
+		return result.Sharpness
+	}
+
+	wholeFrame := score(quality.SubjectWholeFrame, bokeh)
+	assert.Greater(t, wholeFrame, score(quality.SubjectWholeFrame, blurred))
+	for _, subject := range []quality.SharpnessSubject{quality.SubjectCenter, quality.SubjectSharpest} {
+		assert.Greater(t, score(subject, bokeh), wholeFrame)
+		assert.Greater(t, score(subject, bokeh), 2*score(subject, blurred))
+	}
+}
+
+func TestAnalyzer_ExplainMapsMetricsToIssues(t *testing.T) {
+	good := api.ImageQuality{Sharpness: 0.6, Noise: 0.1, Exposure: 0.5, Contrast: 0.5, Compression: 0.05, ColorCast: 0.1, FinalScore: 80}
+
+	for _, tc := range []struct {
+		name     string
+		modify   func(q *api.ImageQuality)
+		metric   string
+		severity api.IssueSeverity
+		message  string
+	}{
+		{"blurry", func(q *api.ImageQuality) { q.Sharpness = 0.08 }, "sharpness", api.SeverityMinor, "blurry"},
+		{"very blurry", func(q *api.ImageQuality) { q.Sharpness = 0.02 }, "sharpness", api.SeverityMajor, "blurry"},
+		{"noisy", func(q *api.ImageQuality) { q.Noise = 0.9 }, "noise", api.SeverityMajor, "noise"},
+		{"underexposed", func(q *api.ImageQuality) { q.Exposure = 0.08 }, "exposure", api.SeverityMinor, "underexposed"},
+		{"overexposed", func(q *api.ImageQuality) { q.Exposure = 0.99 }, "exposure", api.SeverityMajor, "overexposed"},
+		{"flat", func(q *api.ImageQuality) { q.Contrast = 0.15 }, "contrast", api.SeverityMinor, "contrast"},
+		{"blocky", func(q *api.ImageQuality) { q.Compression = 0.3 }, "compression", api.SeverityMinor, "compression"},
+		{"tinted", func(q *api.ImageQuality) { q.ColorCast = 0.8 }, "color_cast", api.SeverityMajor, "color cast"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q := good
+			tc.modify(&q)
+
+			issues := quality.NewAnalyzer(quality.DefaultConfig()).Explain(q)
+			require.Len(t, issues, 1)
+			assert.Equal(t, tc.metric, issues[0].Metric)
+			assert.Equal(t, tc.severity, issues[0].Severity)
+			assert.Contains(t, issues[0].Message, tc.message)
+			assert.NotEmpty(t, issues[0].SuggestedAction)
+		})
+	}
+
+	assert.Empty(t, quality.NewAnalyzer(quality.DefaultConfig()).Explain(good))
+}
+
+func TestAnalyzer_TrimBordersRestoresContrast(t *testing.T) {
+	// A photo, and the same photo scanned with a white margin and letterboxed in black
+	photo := image.NewGray(image.Rect(0, 0, 96, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 96; x++ {
+			photo.SetGray(x, y, color.Gray{Y: uint8(40 + (x*2+y)%160)})
+		}
+	}
+	scanned := image.NewGray(image.Rect(0, 0, 136, 104))
+	draw.Draw(scanned, scanned.Bounds(), image.NewUniform(color.Gray{Y: 250}), image.Point{}, draw.Src)
+	draw.Draw(scanned, image.Rect(20, 20, 116, 84), photo, image.Point{}, draw.Src)
+	letterboxed := image.NewGray(image.Rect(0, 0, 96, 120))
+	draw.Draw(letterboxed, image.Rect(0, 28, 96, 92), photo, image.Point{}, draw.Src)
+
+	contrast := func(trim bool, img image.Image) float64 {
+		cfg := quality.DefaultConfig()
+		cfg.TrimBorders = trim
+		result, err := quality.NewAnalyzer(cfg).Analyze(img)
+		require.NoError(t, err)
+		return result.Contrast
+	}
+
+	original := contrast(false, photo)
+	for name, bordered := range map[string]image.Image{"scanned": scanned, "letterboxed": letterboxed} {
+		t.Run(name, func(t *testing.T) {
+			assert.Greater(t, math.Abs(contrast(false, bordered)-original), 0.05)
+			assert.InDelta(t, original, contrast(true, bordered), 0.01)
+		})
+	}
+	assert.Equal(t, original, contrast(true, photo))
+}
+
+func TestAnalyzer_ColorTemperaturePersists(t *testing.T) {
+	// A scene lit by warm tungsten light: red well above blue throughout
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(60 + (x+y)%100)
+			img.Set(x, y, color.RGBA{R: v + 90, G: v + 40, B: v, A: 255})
+		}
+	}
+
+	result, err := quality.NewAnalyzer(quality.DefaultConfig()).Analyze(img)
+	require.NoError(t, err)
+	assert.Equal(t, "warm", result.ColorTemperature)
+	assert.Greater(t, result.ColorTemperatureScore, 0.0)
+
+	for name, store := range newTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			fp := testFingerprint(1, "aaa")
+			fp.Quality = *result
+			require.NoError(t, store.SaveFingerprint(fp))
+
+			stored, err := store.GetFingerprint(fp.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "warm", stored.Quality.ColorTemperature)
+			assert.Equal(t, result.ColorTemperatureScore, stored.Quality.ColorTemperatureScore)
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package unit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HaiderBassem/imaged/internal/report"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractiveReport_Generate(t *testing.T) {
+	scanReport := &api.ScanReport{
+		ScanID: "scan_test",
+		Groups: []api.DuplicateGroup{
+			{GroupID: "exact_0", MainImage: "img_a", DuplicateIDs: []api.ImageID{"img_b"}, Reason: api.ReasonExact, Confidence: 1},
+			{GroupID: "near_0", MainImage: "img_c", DuplicateIDs: []api.ImageID{"img_d", "img_e"}, Reason: api.ReasonNear, Confidence: 0.9},
+		},
+	}
+
+	// Only img_a exists on disk, so it is the only image with a thumbnail
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: 128, A: 255})
+		}
+	}
+	pathA := filepath.Join(t.TempDir(), "a.png")
+	file, err := os.Create(pathA)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	require.NoError(t, file.Close())
+
+	generator := report.NewInteractiveReportGenerator(report.DefaultInteractiveReportConfig())
+	generator.SetFingerprints([]api.ImageFingerprint{
+		{ID: "img_a", Metadata: api.ImageMetadata{Path: pathA}},
+		{ID: "img_b", Metadata: api.ImageMetadata{Path: "/photos/b.jpg"}},
+	})
+
+	outputPath := filepath.Join(t.TempDir(), "review.html")
+	require.NoError(t, generator.Generate(scanReport, outputPath))
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	page := string(content)
+
+	// One checkbox per image, keyed by group
+	assert.Equal(t, 5, strings.Count(page, `class="delete-toggle"`))
+	assert.Contains(t, page, `data-group-id="exact_0" data-image-id="img_b" checked`)
+	assert.Contains(t, page, `data-group-id="near_0" data-image-id="img_e" checked`)
+	assert.NotContains(t, page, `data-image-id="img_a" checked`)
+
+	// Inlined group data consumed by the embedded script
+	assert.Contains(t, page, `<script id="review-data" type="application/json">`)
+	assert.Contains(t, page, `"group_id":"exact_0"`)
+	assert.Contains(t, page, `"path":"/photos/b.jpg"`)
+	assert.Contains(t, page, `"path":"img_e"`)
+	assert.Contains(t, page, "function downloadScript()")
+	assert.Equal(t, 1, strings.Count(page, `<img src="data:image/jpeg;base64,`))
+}
+
+func TestHTMLReport_EmbedsThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	var fingerprints []api.ImageFingerprint
+	for i, name := range []string{"a.png", "b.png"} {
+		img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+		for y := 0; y < 240; y++ {
+			for x := 0; x < 320; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(i * 100), A: 255})
+			}
+		}
+		path := filepath.Join(dir, name)
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		require.NoError(t, png.Encode(file, img))
+		require.NoError(t, file.Close())
+
+		fingerprints = append(fingerprints, api.ImageFingerprint{
+			ID:       api.ImageID("img_" + name),
+			Metadata: api.ImageMetadata{Path: path},
+		})
+	}
+
+	scanReport := &api.ScanReport{
+		ScanID:             "scan_test",
+		NearDuplicateCount: 1,
+		Groups: []api.DuplicateGroup{
+			{GroupID: "near_0", MainImage: "img_a.png", DuplicateIDs: []api.ImageID{"img_b.png", "img_missing"}, Reason: api.ReasonNear, Confidence: 0.93},
+		},
+	}
+
+	generator := report.NewHTMLReportGenerator()
+	generator.SetFingerprints(fingerprints)
+	outputPath := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, generator.Generate(scanReport, outputPath))
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	page := string(content)
+
+	assert.Equal(t, 2, strings.Count(page, `<img src="data:image/jpeg;base64,`))
+	assert.Contains(t, page, fingerprints[0].Metadata.Path)
+	assert.Contains(t, page, "img_missing")
+	assert.Contains(t, page, "Confidence: 93%</span>")
+}
+
+func TestHTMLReport_ComputesStatistics(t *testing.T) {
+	const mb = 1 << 20
+	fingerprints := []api.ImageFingerprint{
+		{ID: "img_a", Metadata: api.ImageMetadata{Path: "/photos/a.jpg", SizeBytes: 3 * mb}, Quality: api.ImageQuality{FinalScore: 80}},
+		{ID: "img_b", Metadata: api.ImageMetadata{Path: "/photos/b.jpg", SizeBytes: 1 * mb}, Quality: api.ImageQuality{FinalScore: 60}},
+		{ID: "img_c", Metadata: api.ImageMetadata{Path: "/photos/c.jpg", SizeBytes: 2 * mb}, Quality: api.ImageQuality{FinalScore: 70}},
+	}
+	scanReport := &api.ScanReport{
+		ScanID: "scan_test",
+		Groups: []api.DuplicateGroup{
+			{GroupID: "exact_0", MainImage: "img_a", DuplicateIDs: []api.ImageID{"img_b"}, Reason: api.ReasonExact, Confidence: 1},
+			// img_b is counted once even though it is in both groups
+			{GroupID: "near_0", MainImage: "img_a", DuplicateIDs: []api.ImageID{"img_b", "img_c"}, Reason: api.ReasonNear, Confidence: 0.9},
+		},
+	}
+
+	generator := report.NewHTMLReportGenerator()
+	generator.SetFingerprints(fingerprints)
+	outputPath := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, generator.Generate(scanReport, outputPath))
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	page := string(content)
+
+	assert.Contains(t, page, `<div class="stat-number">6.00 MB</div>`)
+	assert.Contains(t, page, `<div class="stat-number">2.00 MB</div>`)
+	assert.Contains(t, page, `<div class="stat-number">70.0</div>`)
+	assert.Contains(t, page, `<div class="stat-number">3.00 MB</div>`)
+}
+
+func TestJSONReport_DistributionsCoverEveryImage(t *testing.T) {
+	const mb = 1 << 20
+	fingerprints := []api.ImageFingerprint{
+		{ID: "img_a", Metadata: api.ImageMetadata{Format: "jpeg", SizeBytes: mb / 2}, Quality: api.ImageQuality{FinalScore: 15}},
+		{ID: "img_b", Metadata: api.ImageMetadata{Format: "jpeg", SizeBytes: 2 * mb}, Quality: api.ImageQuality{FinalScore: 55}},
+		{ID: "img_c", Metadata: api.ImageMetadata{Format: "png", SizeBytes: 3 * mb}, Quality: api.ImageQuality{FinalScore: 80}},
+		{ID: "img_d", Metadata: api.ImageMetadata{Format: "gif", SizeBytes: 10 * mb}, Quality: api.ImageQuality{FinalScore: 100}},
+	}
+	scanReport := &api.ScanReport{ScanID: "scan_test", ProcessedImages: len(fingerprints)}
+
+	generator := report.NewJSONReportGenerator()
+	generator.SetFingerprints(fingerprints)
+	outputPath := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, generator.Generate(scanReport, outputPath))
+
+	content, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	var enhanced report.EnhancedReport
+	require.NoError(t, json.Unmarshal(content, &enhanced))
+	stats := enhanced.Statistics
+
+	sum := func(distribution map[string]int) int {
+		total := 0
+		for _, n := range distribution {
+			total += n
+		}
+		return total
+	}
+	assert.Equal(t, scanReport.ProcessedImages, sum(stats.QualityDistribution))
+	assert.Equal(t, scanReport.ProcessedImages, sum(stats.FormatDistribution))
+	assert.Equal(t, scanReport.ProcessedImages, sum(stats.SizeDistribution))
+
+	assert.Equal(t, map[string]int{"0-20": 1, "40-60": 1, "80-100": 2}, stats.QualityDistribution)
+	assert.Equal(t, map[string]int{"jpeg": 2, "png": 1, "gif": 1}, stats.FormatDistribution)
+	assert.Equal(t, map[string]int{"<1MB": 1, "1-5MB": 2, ">5MB": 1}, stats.SizeDistribution)
+	assert.InDelta(t, 15.5, stats.TotalSizeMB, 1e-9)
+	assert.InDelta(t, 62.5, stats.AverageQuality, 1e-9)
+}
+
+func TestReportGenerator_AllFormats(t *testing.T) {
+	scanReport := &api.ScanReport{
+		ScanID:          "scan_formats",
+		TotalFiles:      2,
+		ProcessedImages: 2,
+		Groups: []api.DuplicateGroup{
+			{GroupID: "exact_0", MainImage: "img_a", DuplicateIDs: []api.ImageID{"img_b"}, Reason: api.ReasonExact, Confidence: 1},
+		},
+	}
+	fingerprints := []api.ImageFingerprint{
+		{ID: "img_a", Metadata: api.ImageMetadata{Path: "/photos/a.jpg", SizeBytes: 1024}},
+		{ID: "img_b", Metadata: api.ImageMetadata{Path: "/photos/b.jpg", SizeBytes: 2048}},
+	}
+
+	for _, format := range report.ReportFormats {
+		t.Run(format, func(t *testing.T) {
+			generator, err := report.NewReportGenerator(format)
+			require.NoError(t, err)
+			if setter, ok := generator.(report.FingerprintSetter); ok {
+				setter.SetFingerprints(fingerprints)
+			}
+
+			outputPath := filepath.Join(t.TempDir(), "report."+format)
+			require.NoError(t, generator.Generate(scanReport, outputPath))
+
+			content, err := os.ReadFile(outputPath)
+			require.NoError(t, err)
+			assert.NotEmpty(t, content)
+		})
+	}
+
+	_, err := report.NewReportGenerator("pdf")
+	assert.Error(t, err)
+}
+
+func TestCSVReport_OneRowPerImage(t *testing.T) {
+	scanReport := &api.ScanReport{
+		Groups: []api.DuplicateGroup{
+			{GroupID: "near_0", MainImage: "img_a", DuplicateIDs: []api.ImageID{"img_b", "img_c"}, Reason: api.ReasonNear, Confidence: 0.93},
+		},
+	}
+
+	generator := report.NewCSVReportGenerator()
+	generator.SetFingerprints([]api.ImageFingerprint{
+		{ID: "img_a", Metadata: api.ImageMetadata{Path: "/photos/a, copy.jpg", SizeBytes: 1024}},
+	})
+
+	outputPath := filepath.Join(t.TempDir(), "report.csv")
+	require.NoError(t, generator.Generate(scanReport, outputPath))
+
+	file, err := os.Open(outputPath)
+	require.NoError(t, err)
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, 4)
+	assert.Equal(t, "group_id", records[0][0])
+	assert.Equal(t, []string{"near_0", "near", "0.9300", "img_a", "true", "/photos/a, copy.jpg", "1024"}, records[1][:7])
+	assert.Equal(t, "false", records[2][4])
+}
@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HaiderBassem/imaged/internal/scanner"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScanTree creates a small nested tree of image and non-image files
+func writeScanTree(t *testing.T) (string, []string) {
+	t.Helper()
+	root := t.TempDir()
+	var images []string
+	for _, rel := range []string{"a.jpg", "b.txt", "sub/c.png", "sub/deeper/d.jpeg", ".git/e.jpg"} {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+		if rel != "b.txt" && rel != ".git/e.jpg" {
+			images = append(images, path)
+		}
+	}
+	return root, images
+}
+
+func TestScanner_ScanFolderProgressStreamsPaths(t *testing.T) {
+	root, images := writeScanTree(t)
+	s := scanner.NewScanner(scanner.DefaultConfig())
+
+	discovered := make(chan string)
+	done := make(chan struct{})
+	var paths []string
+	var scanErr error
+	go func() {
+		defer close(done)
+		paths, scanErr = s.ScanFolderProgress(context.Background(), root, discovered)
+	}()
+
+	// The channel is unbuffered, so the scan cannot return while paths remain unreceived
+	var streamed []string
+	for len(streamed) < len(images) {
+		select {
+		case path := <-discovered:
+			streamed = append(streamed, path)
+			if len(streamed) < len(images) {
+				select {
+				case <-done:
+					t.Fatal("scan returned before all paths were received")
+				default:
+				}
+			}
+		case <-done:
+			t.Fatalf("scan returned after streaming only %d of %d paths", len(streamed), len(images))
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for discovered paths")
+		}
+	}
+	<-done
+
+	require.NoError(t, scanErr)
+	assert.ElementsMatch(t, images, streamed)
+	assert.ElementsMatch(t, images, paths)
+}
+
+func TestScanner_ScanFolderProgressHonorsCancellation(t *testing.T) {
+	root, _ := writeScanTree(t)
+	s := scanner.NewScanner(scanner.DefaultConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	discovered := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ScanFolderProgress(ctx, root, discovered)
+	}()
+
+	// Nobody reads the channel, so the scan only returns once cancelled
+	<-discovered
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan did not return after cancellation")
+	}
+}
+
+func TestScanner_SetFilterSkipsSmallFilesAndExcludedExtensions(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+		return path
+	}
+	photo := write("photo.jpg", 4096)
+	write("thumb.jpg", 512)
+	write("scan.bmp", 4096)
+
+	s := scanner.NewScanner(scanner.DefaultConfig())
+	paths, err := s.ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.Len(t, paths, 3)
+
+	filter := scanner.GetDefaultImageFilter()
+	filter.AddExcludeExtension(".bmp")
+	s.SetFilter(filter)
+
+	paths, err = s.ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{photo}, paths)
+
+	// nil restores the filter derived from the scanner config
+	s.SetFilter(nil)
+	paths, err = s.ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.Len(t, paths, 3)
+}
+
+func TestScanner_MinDimensionsSkipsIcons(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string, width, height int) string {
+		path := filepath.Join(root, name)
+		file, err := os.Create(path)
+		require.NoError(t, err)
+		defer file.Close()
+		require.NoError(t, png.Encode(file, image.NewGray(image.Rect(0, 0, width, height))))
+		return path
+	}
+	write("icon.png", 16, 16)
+	photo := write("photo.png", 4000, 3000)
+
+	cfg := scanner.DefaultConfig()
+	cfg.MinWidth, cfg.MinHeight = 200, 200
+	s := scanner.NewScanner(cfg)
+
+	paths, err := s.ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{photo}, paths)
+
+	header, ok := s.ImageConfig(photo)
+	require.True(t, ok)
+	assert.Equal(t, 4000, header.Width)
+	assert.Equal(t, 3000, header.Height)
+}
+
+func TestScanner_ScanFolderWithResultReportsInaccessibleFiles(t *testing.T) {
+	root, images := writeScanTree(t)
+	broken := filepath.Join(root, "broken.jpg")
+	require.NoError(t, os.Symlink(filepath.Join(root, "missing.jpg"), broken))
+
+	s := scanner.NewScanner(scanner.DefaultConfig())
+	result, err := s.ScanFolderWithResult(context.Background(), root, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, images, result.ImagePaths)
+
+	require.Len(t, result.Errors, 1)
+	var fileErr *scanner.FileError
+	require.True(t, errors.As(result.Errors[0], &fileErr))
+	assert.Equal(t, broken, fileErr.Path)
+	assert.ErrorIs(t, result.Errors[0], os.ErrNotExist)
+}
+
+func TestScanner_MaxImagesStopsDiscovery(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir%d", i%4))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("img%02d.jpg", i)), []byte("data"), 0644))
+	}
+
+	cfg := scanner.DefaultConfig()
+	cfg.MaxImages = 5
+	s := scanner.NewScanner(cfg)
+
+	paths, err := s.ScanFolder(context.Background(), root)
+	assert.ErrorIs(t, err, api.ErrScanLimitReached)
+	assert.Len(t, paths, 5)
+
+	result, err := s.ScanFolderWithResult(context.Background(), root, nil)
+	assert.ErrorIs(t, err, api.ErrScanLimitReached)
+	require.NotNil(t, result)
+	assert.Len(t, result.ImagePaths, 5)
+}
+
+func TestScanner_IncludeAndExcludeGlobs(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"IMG_0001.jpg", "IMG_0002.jpg", "DSC_0003.jpg", "album/IMG_0004.jpg", "album/cache/IMG_0005.jpg", "album/cache/deep/IMG_0006.jpg"} {
+		path := filepath.Join(root, rel)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+	}
+	rels := func(paths []string) []string {
+		out := make([]string, 0, len(paths))
+		for _, p := range paths {
+			rel, err := filepath.Rel(root, p)
+			require.NoError(t, err)
+			out = append(out, filepath.ToSlash(rel))
+		}
+		return out
+	}
+
+	cfg := scanner.DefaultConfig()
+	cfg.IncludeGlobs = []string{"IMG_*.jpg"}
+	paths, err := scanner.NewScanner(cfg).ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"IMG_0001.jpg", "IMG_0002.jpg", "album/IMG_0004.jpg", "album/cache/IMG_0005.jpg", "album/cache/deep/IMG_0006.jpg"}, rels(paths))
+
+	cfg = scanner.DefaultConfig()
+	cfg.ExcludeGlobs = []string{"*/cache/*"}
+	paths, err = scanner.NewScanner(cfg).ScanFolder(context.Background(), root)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"IMG_0001.jpg", "IMG_0002.jpg", "DSC_0003.jpg", "album/IMG_0004.jpg"}, rels(paths))
+}
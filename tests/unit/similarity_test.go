@@ -1 +1,447 @@
 package unit
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/HaiderBassem/imaged/internal/similarity"
+	"github.com/HaiderBassem/imaged/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// syntheticHashes returns n random 64-bit hashes from a fixed seed
+func syntheticHashes(n int) []uint64 {
+	rng := rand.New(rand.NewSource(42))
+	hashes := make([]uint64, n)
+	for i := range hashes {
+		hashes[i] = rng.Uint64()
+	}
+	return hashes
+}
+
+func TestBKTree_QueryMatchesLinearScan(t *testing.T) {
+	hashes := syntheticHashes(2000)
+	// Plant near neighbors of the first hash
+	hashes[10] = hashes[0] ^ 0b101
+	hashes[20] = hashes[0]
+
+	tree := similarity.NewBKTree()
+	for i, h := range hashes {
+		tree.Insert(api.ImageID(fmt.Sprintf("img_%d", i)), h)
+	}
+	assert.Equal(t, len(hashes), tree.Len())
+
+	for _, maxDistance := range []int{0, 2, 10, 24} {
+		var expected []string
+		for i, h := range hashes {
+			if bits.OnesCount64(h^hashes[0]) <= maxDistance {
+				expected = append(expected, fmt.Sprintf("img_%d", i))
+			}
+		}
+
+		var got []string
+		for _, id := range tree.Query(hashes[0], maxDistance) {
+			got = append(got, string(id))
+		}
+
+		sort.Strings(expected)
+		sort.Strings(got)
+		assert.Equal(t, expected, got, "maxDistance=%d", maxDistance)
+	}
+}
+
+func TestBKTree_QueryWideMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	hashes := make([][]byte, 500)
+	for i := range hashes {
+		hashes[i] = make([]byte, 32) // a 16x16 hash
+		rng.Read(hashes[i])
+	}
+	hashes[10] = append([]byte(nil), hashes[0]...)
+	hashes[10][31] ^= 0b11
+
+	tree := similarity.NewBKTree()
+	for i, h := range hashes {
+		tree.InsertWide(api.ImageID(fmt.Sprintf("img_%d", i)), h)
+	}
+
+	for _, maxDistance := range []int{0, 2, 40, 110} {
+		var expected []string
+		for i, h := range hashes {
+			distance := 0
+			for k := range h {
+				distance += bits.OnesCount8(h[k] ^ hashes[0][k])
+			}
+			if distance <= maxDistance {
+				expected = append(expected, fmt.Sprintf("img_%d", i))
+			}
+		}
+
+		var got []string
+		for _, id := range tree.QueryWide(hashes[0], maxDistance) {
+			got = append(got, string(id))
+		}
+
+		sort.Strings(expected)
+		sort.Strings(got)
+		assert.Equal(t, expected, got, "maxDistance=%d", maxDistance)
+	}
+}
+
+func TestComparator_MaxPHashDistance(t *testing.T) {
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+
+	// A stricter threshold never allows a larger radius
+	assert.LessOrEqual(t, comparator.MaxPHashDistance(0.95), comparator.MaxPHashDistance(0.9))
+	assert.Equal(t, 64, comparator.MaxPHashDistance(0))
+
+	// Pairs at the radius can still reach the threshold when the other hashes match
+	radius := comparator.MaxPHashDistance(0.9)
+	a := api.ImageFingerprint{PHashes: api.PerceptualHashes{AHash: 1, PHash: 1, DHash: 1, WHash: 1}}
+	b := a
+	b.PHashes.PHash = a.PHashes.PHash ^ (1<<uint(radius) - 1)
+	score, err := comparator.CompareFingerprints(a, b)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, score, 0.9)
+}
+
+// blobFingerprint returns a fingerprint whose hashes differ from base in the given bits
+func blobFingerprint(id string, base api.PerceptualHashes, flips ...uint) api.ImageFingerprint {
+	hashes := base
+	for _, bit := range flips {
+		hashes.AHash ^= 1 << bit
+		hashes.PHash ^= 1 << bit
+		hashes.DHash ^= 1 << bit
+	}
+	return api.ImageFingerprint{ID: api.ImageID(id), PHashes: hashes}
+}
+
+func TestClusterer_DBSCANKeepsReachablePoints(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	base := api.PerceptualHashes{AHash: rng.Uint64(), PHash: rng.Uint64(), DHash: rng.Uint64()}
+
+	fingerprints := []api.ImageFingerprint{
+		blobFingerprint("blob_0", base),
+		blobFingerprint("blob_1", base, 1),
+		blobFingerprint("blob_2", base, 2),
+		blobFingerprint("blob_3", base, 1, 2),
+		blobFingerprint("blob_4", base, 3),
+		{ID: "outlier", PHashes: api.PerceptualHashes{AHash: ^base.AHash, PHash: ^base.PHash, DHash: ^base.DHash}},
+	}
+
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	clusterer := similarity.NewClusterer(comparator, fingerprints)
+	clusters := clusterer.DBSCANClustering(fingerprints, 0.9, 3)
+
+	if assert.Len(t, clusters, 1) {
+		assert.ElementsMatch(t,
+			[]api.ImageID{"blob_0", "blob_1", "blob_2", "blob_3", "blob_4"},
+			clusters[0].Images)
+	}
+}
+
+// chainFingerprints returns five images each four bits from the next, so
+// neighbours are similar while the ends of the chain are far apart, plus an
+// unrelated outlier
+func chainFingerprints() []api.ImageFingerprint {
+	rng := rand.New(rand.NewSource(7))
+	base := api.PerceptualHashes{AHash: rng.Uint64(), PHash: rng.Uint64(), DHash: rng.Uint64()}
+
+	var fingerprints []api.ImageFingerprint
+	var flips []uint
+	for i := 0; i < 5; i++ {
+		fingerprints = append(fingerprints, blobFingerprint(fmt.Sprintf("chain_%d", i), base, flips...))
+		flips = append(flips, uint(4*i), uint(4*i+1), uint(4*i+2), uint(4*i+3))
+	}
+	return append(fingerprints, api.ImageFingerprint{
+		ID:      "outlier",
+		PHashes: api.PerceptualHashes{AHash: ^base.AHash, PHash: ^base.PHash, DHash: ^base.DHash},
+	})
+}
+
+func TestClusterer_SingleLinkageChains(t *testing.T) {
+	fingerprints := chainFingerprints()
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	clusterer := similarity.NewClusterer(comparator, fingerprints)
+
+	clusters := clusterer.ClusterBySimilarity(fingerprints, 0.9, similarity.LinkageSingle)
+	if assert.Len(t, clusters, 2) {
+		assert.ElementsMatch(t,
+			[]api.ImageID{"chain_0", "chain_1", "chain_2", "chain_3", "chain_4"},
+			clusters[0].Images)
+		assert.Equal(t, []api.ImageID{"outlier"}, clusters[1].Images)
+	}
+}
+
+func TestClusterer_CompleteLinkageStaysCompact(t *testing.T) {
+	fingerprints := chainFingerprints()
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	clusterer := similarity.NewClusterer(comparator, fingerprints)
+
+	byID := make(map[api.ImageID]api.ImageFingerprint)
+	for _, fp := range fingerprints {
+		byID[fp.ID] = fp
+	}
+
+	for _, linkage := range []similarity.LinkageMethod{similarity.LinkageComplete, similarity.LinkageAverage} {
+		clusters := clusterer.ClusterBySimilarity(fingerprints, 0.9, linkage)
+
+		// The chain breaks up, and every member is close to every other
+		assert.Greater(t, len(clusters), 2, "linkage %d", linkage)
+		var total int
+		for _, cluster := range clusters {
+			total += len(cluster.Images)
+			for i, a := range cluster.Images {
+				for _, b := range cluster.Images[i+1:] {
+					score, err := comparator.CompareFingerprints(byID[a], byID[b])
+					assert.NoError(t, err)
+					assert.GreaterOrEqual(t, score, 0.9, "linkage %d: %s and %s", linkage, a, b)
+				}
+			}
+		}
+		assert.Equal(t, len(fingerprints), total)
+	}
+}
+
+func TestClusterer_KMeansPlusPlusSeparatesGroups(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+
+	// Three well-separated groups of eight, listed group by group so that
+	// seeding from the first k points would put every centroid in group 0
+	var fingerprints []api.ImageFingerprint
+	group := make(map[api.ImageID]int)
+	for g := 0; g < 3; g++ {
+		base := api.PerceptualHashes{AHash: rng.Uint64(), PHash: rng.Uint64(), DHash: rng.Uint64()}
+		for m := 0; m < 8; m++ {
+			fp := blobFingerprint(fmt.Sprintf("g%d_%d", g, m), base, uint(rng.Intn(64)), uint(rng.Intn(64)))
+			fingerprints = append(fingerprints, fp)
+			group[fp.ID] = g
+		}
+	}
+
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	for seed := int64(0); seed < 10; seed++ {
+		clusterer := similarity.NewClusterer(comparator, fingerprints)
+		clusterer.SetRandSource(rand.NewSource(seed))
+		clusters := clusterer.KMeansClustering(fingerprints, 3, 10)
+
+		// Purity: share of images belonging to their cluster's majority group
+		majority := 0
+		for _, cluster := range clusters {
+			counts := make(map[int]int)
+			best := 0
+			for _, id := range cluster.Images {
+				counts[group[id]]++
+				if counts[group[id]] > best {
+					best = counts[group[id]]
+				}
+			}
+			majority += best
+		}
+		assert.Equal(t, len(fingerprints), majority, "seed %d produced impure clusters", seed)
+	}
+}
+
+// channelHistogram builds a 16-bin-per-channel RGB histogram with all pixels in one bin per channel
+func channelHistogram(r, g, b int) []float64 {
+	hist := make([]float64, 48)
+	hist[r] = 1
+	hist[16+g] = 1
+	hist[32+b] = 1
+	return hist
+}
+
+func TestComparator_ColorHistLowersSimilarity(t *testing.T) {
+	hashes := api.PerceptualHashes{AHash: 0xF0F0, PHash: 0xABCD, DHash: 0x1234}
+	noon := api.ImageFingerprint{ID: "noon", PHashes: hashes, ColorHist: channelHistogram(8, 10, 14)}
+	sunset := api.ImageFingerprint{ID: "sunset", PHashes: hashes, ColorHist: channelHistogram(15, 6, 2)}
+
+	hashOnly := similarity.NewComparator(similarity.ComparatorConfig{})
+	withColor := similarity.NewComparator(similarity.ComparatorConfig{
+		AHashWeight: 0.2, PHashWeight: 0.4, DHashWeight: 0.3, ColorHistWeight: 0.5,
+	})
+
+	plain, err := hashOnly.CompareFingerprints(noon, sunset)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, plain)
+
+	combined, err := withColor.CompareFingerprints(noon, sunset)
+	assert.NoError(t, err)
+	assert.Less(t, combined, 0.8)
+
+	same, err := withColor.CompareFingerprints(noon, noon)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, same)
+}
+
+func TestComparator_WideHashesRefineSimilarity(t *testing.T) {
+	hashes := api.PerceptualHashes{AHash: 0xF0F0, PHash: 0xABCD, DHash: 0x1234}
+	coarse := api.ImageFingerprint{ID: "coarse", PHashes: hashes}
+
+	// Identical at 64 bits, but a quarter of the 256-bit hashes differ
+	wide1, wide2 := hashes, hashes
+	wide1.AHashWide, wide1.PHashWide, wide1.DHashWide = make([]byte, 32), make([]byte, 32), make([]byte, 32)
+	wide2.AHashWide, wide2.PHashWide, wide2.DHashWide = make([]byte, 32), make([]byte, 32), make([]byte, 32)
+	for i := 0; i < 8; i++ {
+		wide2.AHashWide[i], wide2.PHashWide[i], wide2.DHashWide[i] = 0xFF, 0xFF, 0xFF
+	}
+	fp1 := api.ImageFingerprint{ID: "wide1", PHashes: wide1}
+	fp2 := api.ImageFingerprint{ID: "wide2", PHashes: wide2}
+
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{AHashWeight: 0.2, PHashWeight: 0.4, DHashWeight: 0.3})
+
+	score, err := comparator.CompareFingerprints(fp1, fp2)
+	assert.NoError(t, err)
+	assert.Less(t, score, 0.8)
+
+	// Without wide hashes on both sides the 64-bit hashes decide
+	score, err = comparator.CompareFingerprints(fp1, coarse)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, score)
+
+	assert.False(t, comparator.MeetsHashMinimums(fp1, fp2, api.NearDuplicateOptions{MinAHash: 0.8}))
+	assert.True(t, comparator.MeetsHashMinimums(fp1, coarse, api.NearDuplicateOptions{MinAHash: 0.8}))
+}
+
+func TestComparator_MeetsHashMinimums(t *testing.T) {
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+
+	// Identical except for four dHash bits: a strong blended match
+	a := api.ImageFingerprint{ID: "a", PHashes: api.PerceptualHashes{AHash: 0xF0F0, PHash: 0xABCD, DHash: 0x1234}}
+	b := a
+	b.ID = "b"
+	b.PHashes.DHash ^= 0xF000
+
+	combined, err := comparator.CompareFingerprints(a, b)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, combined, 0.95)
+
+	assert.True(t, comparator.MeetsHashMinimums(a, b, api.NearDuplicateOptions{MinCombined: 0.95}))
+	assert.True(t, comparator.MeetsHashMinimums(a, b, api.NearDuplicateOptions{MinPHash: 1.0, MinDHash: 0.9}))
+	assert.False(t, comparator.MeetsHashMinimums(a, b, api.NearDuplicateOptions{MinDHash: 0.99}))
+
+	// A floor on a hash that was never computed cannot be met
+	assert.False(t, comparator.MeetsHashMinimums(a, b, api.NearDuplicateOptions{MinWHash: 0.5}))
+}
+
+const benchmarkHashCount = 50000
+
+// syntheticFingerprints models a photo library of bursts: groups of ten shots
+// whose hashes differ from a shared base by up to three bits
+func syntheticFingerprints(n int) []api.ImageFingerprint {
+	rng := rand.New(rand.NewSource(42))
+	fingerprints := make([]api.ImageFingerprint, 0, n)
+
+	for len(fingerprints) < n {
+		base := api.PerceptualHashes{AHash: rng.Uint64(), PHash: rng.Uint64(), DHash: rng.Uint64(), WHash: rng.Uint64()}
+		for shot := 0; shot < 10 && len(fingerprints) < n; shot++ {
+			hashes := base
+			for flips := rng.Intn(4); flips > 0; flips-- {
+				hashes.PHash ^= 1 << uint(rng.Intn(64))
+			}
+			fingerprints = append(fingerprints, api.ImageFingerprint{
+				ID:      api.ImageID(fmt.Sprintf("img_%d", len(fingerprints))),
+				PHashes: hashes,
+			})
+		}
+	}
+	return fingerprints
+}
+
+func BenchmarkNearDuplicates_Pairwise(b *testing.B) {
+	fingerprints := syntheticFingerprints(benchmarkHashCount)
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		matches := 0
+		for i := range fingerprints {
+			for j := i + 1; j < len(fingerprints); j++ {
+				if score, _ := comparator.CompareFingerprints(fingerprints[i], fingerprints[j]); score >= 0.9 {
+					matches++
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkNearDuplicates_BKTree(b *testing.B) {
+	fingerprints := syntheticFingerprints(benchmarkHashCount)
+	comparator := similarity.NewComparator(similarity.ComparatorConfig{})
+	maxDistance := comparator.MaxPHashDistance(0.9)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		tree := similarity.NewBKTree()
+		position := make(map[api.ImageID]int, len(fingerprints))
+		for i, fp := range fingerprints {
+			tree.Insert(fp.ID, fp.PHashes.PHash)
+			position[fp.ID] = i
+		}
+
+		matches := 0
+		for i, fp := range fingerprints {
+			for _, id := range tree.Query(fp.PHashes.PHash, maxDistance) {
+				j := position[id]
+				if j <= i {
+					continue
+				}
+				if score, _ := comparator.CompareFingerprints(fp, fingerprints[j]); score >= 0.9 {
+					matches++
+				}
+			}
+		}
+	}
+}
+
+func TestSSIM_DiscriminatesStructure(t *testing.T) {
+	// A dark image with a bright square, the same image nudged in brightness,
+	// and its negative
+	square := image.NewGray(image.Rect(0, 0, 128, 128))
+	brighter := image.NewGray(square.Bounds())
+	negative := image.NewGray(square.Bounds())
+	for y := 0; y < 128; y++ {
+		for x := 0; x < 128; x++ {
+			v := uint8(80)
+			if x >= 32 && x < 96 && y >= 32 && y < 96 {
+				v = 200
+			}
+			square.SetGray(x, y, color.Gray{Y: v})
+			brighter.SetGray(x, y, color.Gray{Y: v + 10})
+			negative.SetGray(x, y, color.Gray{Y: 255 - v})
+		}
+	}
+
+	a := similarity.SSIMImage(square)
+	assert.InDelta(t, 1.0, similarity.SSIM(a, a), 1e-9)
+	assert.Greater(t, similarity.SSIM(a, similarity.SSIMImage(brighter)), 0.9)
+	assert.Less(t, similarity.SSIM(a, similarity.SSIMImage(negative)), 0.5)
+
+	// Images of different sizes cannot be compared
+	assert.Zero(t, similarity.SSIM(a, image.NewGray(image.Rect(0, 0, 32, 32))))
+}
+
+func TestMirrorHashes(t *testing.T) {
+	// The first pixel of the top row moves to the end of that row
+	mirrored := similarity.MirrorHashes(api.PerceptualHashes{AHash: 1, PHash: 1 << 32, DHash: 1})
+	assert.Equal(t, uint64(1<<7), mirrored.AHash)
+	assert.Equal(t, uint64(1<<63), mirrored.PHash)
+	assert.Equal(t, ^uint64(1<<7), mirrored.DHash)
+	assert.Zero(t, mirrored.WHash)
+
+	h := api.PerceptualHashes{AHash: 0x0123456789abcdef, PHash: 0xfedcba9876543210, DHash: 0x00ff00ff00ff00ff, WHash: 42}
+	assert.Equal(t, h, similarity.MirrorHashes(similarity.MirrorHashes(h)))
+
+	// A 16x16 aHash has two bytes per row; the pHash folds 64-bit rows
+	wide := make([]byte, 32)
+	wide[0] = 1
+	mirrored = similarity.MirrorHashes(api.PerceptualHashes{AHashWide: wide, PHashWide: wide})
+	assert.Equal(t, byte(0x80), mirrored.AHashWide[1])
+	assert.Equal(t, byte(0x80), mirrored.PHashWide[7])
+	assert.Nil(t, mirrored.DHashWide)
+}